@@ -41,6 +41,18 @@ type BGPAdvertisementSpec struct {
 	// +optional
 	LocalPref uint32 `json:"localPref,omitempty"`
 
+	// The BGP MULTI_EXIT_DISC (MED) attribute which is used by BGP best path algorithm
+	// to choose between paths received from the same AS, the path with the lower MED is preferred.
+	// When not set, no MED is sent.
+	// +optional
+	MED *uint32 `json:"med,omitempty"`
+
+	// The number of additional times the local ASN is prepended to the AS path of the
+	// announcement, making the path less preferred by the peers of the neighboring ASes.
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	ASPathPrepend uint32 `json:"asPathPrepend,omitempty"`
+
 	// The BGP communities to be associated with the announcement. Each item can be a standard community of the
 	// form 1234:1234, a large community of the form large:1234:1234:1234 or the name of an alias defined in the
 	// Community CRD.
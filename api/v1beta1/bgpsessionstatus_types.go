@@ -0,0 +1,100 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BGPSessionState is the state of a BGP session.
+// +kubebuilder:validation:Enum=Established;Down
+type BGPSessionState string
+
+const (
+	// BGPSessionEstablished means the session is up and exchanging routes.
+	BGPSessionEstablished BGPSessionState = "Established"
+	// BGPSessionDown means the session is configured but not established.
+	BGPSessionDown BGPSessionState = "Down"
+)
+
+// +kubebuilder:object:generate=true
+
+// MetalLBBGPSessionStatus defines the observed state of BGPSessionStatus.
+type MetalLBBGPSessionStatus struct {
+	// Node indicates the node the session runs on.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Value is immutable"
+	Node string `json:"node,omitempty"`
+
+	// Peer indicates the name of the BGPPeer this status represents.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Value is immutable"
+	Peer string `json:"peer,omitempty"`
+
+	// State is the state of the session.
+	State BGPSessionState `json:"state,omitempty"`
+
+	// EstablishedTime is when the session was established, not set while
+	// it is down.
+	// +optional
+	EstablishedTime *metav1.Time `json:"establishedTime,omitempty"`
+
+	// LastError is the last error that brought the session down, or the
+	// reason why it can't be established.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// PrefixesSent is the number of prefixes advertised to the peer.
+	PrefixesSent int64 `json:"prefixesSent"`
+
+	// PrefixesReceived is the number of prefixes received from the peer.
+	PrefixesReceived int64 `json:"prefixesReceived"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.status.node`
+// +kubebuilder:printcolumn:name="Peer",type=string,JSONPath=`.status.peer`
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Established",type=date,JSONPath=`.status.establishedTime`
+// +kubebuilder:printcolumn:name="Sent",type=integer,JSONPath=`.status.prefixesSent`,priority=1
+// +kubebuilder:printcolumn:name="Received",type=integer,JSONPath=`.status.prefixesReceived`,priority=1
+// +kubebuilder:printcolumn:name="Last Error",type=string,JSONPath=`.status.lastError`,priority=1
+// BGPSessionStatus exposes the state of the BGP session of a node with a BGPPeer.
+type BGPSessionStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BGPSessionStatusSpec    `json:"spec,omitempty"`
+	Status MetalLBBGPSessionStatus `json:"status,omitempty"`
+}
+
+// BGPSessionStatusSpec defines the desired state of BGPSessionStatus.
+type BGPSessionStatusSpec struct {
+}
+
+// +kubebuilder:object:root=true
+
+// BGPSessionStatusList contains a list of BGPSessionStatus.
+type BGPSessionStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BGPSessionStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BGPSessionStatus{}, &BGPSessionStatusList{})
+}
@@ -40,6 +40,20 @@ type IPAddressPoolSpec struct {
 	// +kubebuilder:default:=false
 	AvoidBuggyIPs bool `json:"avoidBuggyIPs,omitempty"`
 
+	// ExcludedAddresses lists addresses of the pool that are never allocated
+	// automatically, for example a gateway sitting in the middle of a range.
+	// Each entry can be a single IP, a CIDR prefix, or an explicit start-end
+	// range of IPs, and must be part of the addresses of the pool.
+	// +optional
+	ExcludedAddresses []string `json:"excludedAddresses,omitempty"`
+
+	// AllowExplicitExcludedAddresses lets a service get one of the excluded
+	// addresses when it requests it explicitly, through the
+	// metallb.io/loadBalancerIPs annotation or spec.loadBalancerIP.
+	// +optional
+	// +kubebuilder:default:=false
+	AllowExplicitExcludedAddresses bool `json:"allowExplicitExcludedAddresses,omitempty"`
+
 	// AllocateTo makes ip pool allocation to specific namespace and/or service.
 	// The controller will use the pool with lowest value of priority in case of
 	// multiple matches. A pool with no priority set will be used only if the
@@ -49,6 +63,75 @@ type IPAddressPoolSpec struct {
 	// multiple IPAddressPools have the same priority, choice will be random.
 	// +optional
 	AllocateTo *ServiceAllocation `json:"serviceAllocation,omitempty"`
+
+	// AllocationStrategy defines how the addresses of the pool are picked
+	// for the services. The first free address is used when not set.
+	// +optional
+	AllocationStrategy *AllocationStrategy `json:"allocationStrategy,omitempty"`
+
+	// Quotas limit the number of addresses of the pool the services of a
+	// namespace can use. Each namespace selected by a quota has its own limit,
+	// the lowest one applies when a namespace is selected by several quotas.
+	// +optional
+	Quotas []NamespaceQuota `json:"quotas,omitempty"`
+}
+
+// NamespaceQuota limits the number of addresses of a pool used by the services
+// of each selected namespace.
+type NamespaceQuota struct {
+	// Namespaces the quota applies to.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespaceSelectors select the namespaces the quota applies to, an
+	// alternative to the namespace list.
+	// +optional
+	NamespaceSelectors []metav1.LabelSelector `json:"namespaceSelectors,omitempty"`
+	// MaxIPv4 is the maximum number of IPv4 addresses of the pool used by the
+	// services of each namespace. Not limited when not set.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxIPv4 *int64 `json:"maxIPv4,omitempty"`
+	// MaxIPv6 is the maximum number of IPv6 addresses of the pool used by the
+	// services of each namespace. Not limited when not set.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxIPv6 *int64 `json:"maxIPv6,omitempty"`
+}
+
+// AllocationStrategyType is the way addresses are picked from a pool.
+// +kubebuilder:validation:Enum=Sequential;Random;LeastRecentlyReleased;Hashed
+type AllocationStrategyType string
+
+const (
+	// SequentialAllocation picks the first free address of the pool.
+	SequentialAllocation AllocationStrategyType = "Sequential"
+	// RandomAllocation picks a random free address of the pool.
+	RandomAllocation AllocationStrategyType = "Random"
+	// LeastRecentlyReleasedAllocation picks the free address that was
+	// released the longest time ago, addresses never used first.
+	LeastRecentlyReleasedAllocation AllocationStrategyType = "LeastRecentlyReleased"
+	// HashedAllocation picks the free address given by a hash of the
+	// namespace and name of the service.
+	HashedAllocation AllocationStrategyType = "Hashed"
+)
+
+// AllocationStrategy defines how addresses are picked from an IPAddressPool.
+// +kubebuilder:validation:XValidation:message="quarantineTime is supported only by the LeastRecentlyReleased strategy",rule="!has(self.quarantineTime) || self.type == 'LeastRecentlyReleased'"
+type AllocationStrategy struct {
+	// Type is the strategy used to pick the addresses. Sequential picks the first
+	// free address, Random a random free address, LeastRecentlyReleased the free
+	// address released the longest time ago, and Hashed the free address given by
+	// a hash of the namespace and name of the service, so that a service that is
+	// deleted and created again gets the same address back when it is still free.
+	// +kubebuilder:default:=Sequential
+	// +optional
+	Type AllocationStrategyType `json:"type,omitempty"`
+
+	// QuarantineTime is how long an address released by a service is not given
+	// to other services, with the LeastRecentlyReleased strategy. The service
+	// that released it can get it back.
+	// +optional
+	QuarantineTime *metav1.Duration `json:"quarantineTime,omitempty"`
 }
 
 // ServiceAllocation defines ip pool allocation to namespace and/or service.
@@ -78,6 +161,43 @@ type IPAddressPoolStatus struct {
 
 	// AvailableIPv6 is the number of available IPv6 addresses.
 	AvailableIPv6 int64 `json:"availableIPv6"`
+
+	// ReservedIPv4 is the number of IPv4 addresses reserved by
+	// IPAddressReservations, assigned or not.
+	// +optional
+	ReservedIPv4 int64 `json:"reservedIPv4,omitempty"`
+
+	// ReservedIPv6 is the number of IPv6 addresses reserved by
+	// IPAddressReservations, assigned or not.
+	// +optional
+	ReservedIPv6 int64 `json:"reservedIPv6,omitempty"`
+
+	// Namespaces is the usage of the pool by the namespaces whose services
+	// have addresses assigned from it.
+	// +optional
+	Namespaces []NamespaceUsage `json:"namespaces,omitempty"`
+}
+
+// NamespaceUsage is the usage of a pool by the services of a namespace.
+type NamespaceUsage struct {
+	// Namespace is the name of the namespace.
+	Namespace string `json:"namespace"`
+
+	// AssignedIPv4 is the number of IPv4 addresses assigned to the services
+	// of the namespace.
+	AssignedIPv4 int64 `json:"assignedIPv4"`
+
+	// AssignedIPv6 is the number of IPv6 addresses assigned to the services
+	// of the namespace.
+	AssignedIPv6 int64 `json:"assignedIPv6"`
+
+	// MaxIPv4 is the IPv4 quota of the namespace, if any.
+	// +optional
+	MaxIPv4 *int64 `json:"maxIPv4,omitempty"`
+
+	// MaxIPv6 is the IPv6 quota of the namespace, if any.
+	// +optional
+	MaxIPv6 *int64 `json:"maxIPv6,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,82 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPAddressReservationSpec defines the desired state of IPAddressReservation.
+// +kubebuilder:validation:XValidation:message="exactly one of serviceRef and serviceSelector must be set",rule="has(self.serviceRef) != has(self.serviceSelector)"
+type IPAddressReservationSpec struct {
+	// IPAddressPool is the name of the IPAddressPool the addresses belong to.
+	// The reservation is ignored while the pool does not exist.
+	IPAddressPool string `json:"ipAddressPool"`
+
+	// Addresses are the reserved addresses, at most one per IP family.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=2
+	Addresses []string `json:"addresses"`
+
+	// ServiceRef binds the addresses to the service with the given namespace
+	// and name, even before it exists.
+	// +optional
+	ServiceRef *ServiceReference `json:"serviceRef,omitempty"`
+
+	// ServiceSelector binds the addresses to the services matching the selector.
+	// When several services match, the addresses are given to the first one
+	// they are allocated for.
+	// +optional
+	ServiceSelector *metav1.LabelSelector `json:"serviceSelector,omitempty"`
+}
+
+// ServiceReference identifies a service by namespace and name.
+type ServiceReference struct {
+	// Namespace of the service.
+	Namespace string `json:"namespace"`
+	// Name of the service.
+	Name string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Pool",type=string,JSONPath=`.spec.ipAddressPool`
+// +kubebuilder:printcolumn:name="Addresses",type=string,JSONPath=`.spec.addresses`
+// +kubebuilder:printcolumn:name="Service Namespace",type=string,JSONPath=`.spec.serviceRef.namespace`
+// +kubebuilder:printcolumn:name="Service Name",type=string,JSONPath=`.spec.serviceRef.name`
+
+// IPAddressReservation binds addresses of an IPAddressPool to a service,
+// so that the service gets the same addresses back when it is recreated.
+// Reserved addresses are never allocated to other services.
+type IPAddressReservation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec IPAddressReservationSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// IPAddressReservationList contains a list of IPAddressReservation.
+type IPAddressReservationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPAddressReservation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPAddressReservation{}, &IPAddressReservationList{})
+}
@@ -24,6 +24,7 @@ import (
 // NOTE: json tags are required. Any new fields you add must have json tags for the fields to be serialized.
 
 // L2AdvertisementSpec defines the desired state of L2Advertisement.
+// +kubebuilder:validation:XValidation:message="gratuitousDuration and gratuitousCount are mutually exclusive",rule="!has(self.gratuitousDuration) || !has(self.gratuitousCount)"
 type L2AdvertisementSpec struct {
 	// The list of IPAddressPools to advertise via this advertisement, selected by name.
 	// +optional
@@ -39,6 +40,51 @@ type L2AdvertisementSpec struct {
 	// If the field is not set, we advertise from all the interfaces on the host.
 	// +optional
 	Interfaces []string `json:"interfaces,omitempty"`
+	// GratuitousInterval is the interval between the gratuitous ARP / NDP
+	// announcements sent when a node starts announcing an IP, for example
+	// after a failover. 1100ms when not set.
+	// +optional
+	// +kubebuilder:validation:XValidation:message="gratuitous interval should be positive",rule="duration(self) > duration('0s')"
+	GratuitousInterval *metav1.Duration `json:"gratuitousInterval,omitempty"`
+	// GratuitousDuration is for how long the gratuitous announcements are
+	// sent. 5s when neither this nor GratuitousCount is set.
+	// +optional
+	// +kubebuilder:validation:XValidation:message="gratuitous duration should be positive",rule="duration(self) > duration('0s')"
+	GratuitousDuration *metav1.Duration `json:"gratuitousDuration,omitempty"`
+	// GratuitousCount is the number of gratuitous announcements sent, in place
+	// of sending them for GratuitousDuration.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	GratuitousCount *int32 `json:"gratuitousCount,omitempty"`
+	// NodePriorities give priorities to the nodes selected by the advertisement
+	// when electing the node announcing an IP: the IP is announced by a node with
+	// the lowest priority value among the available ones, the election between
+	// nodes with the same priority being as usual. The nodes not selected by any
+	// of the priorities come last.
+	// +optional
+	NodePriorities []NodePriority `json:"nodePriorities,omitempty"`
+	// Sticky enables the non-preemptive mode: the node announcing an IP keeps
+	// it while it remains available, even when a node with a higher priority,
+	// or one winning the election, becomes available.
+	// +optional
+	Sticky bool `json:"sticky,omitempty"`
+	// PerIPElection elects the node announcing each of the IPs of a service
+	// independently, instead of announcing all of them from a single node.
+	// Combined with DNS round-robin, it spreads the traffic of the services
+	// with several IPs over several nodes.
+	// +optional
+	PerIPElection bool `json:"perIPElection,omitempty"`
+}
+
+// NodePriority is the priority of the nodes selected by its node selectors.
+type NodePriority struct {
+	// Priority of the nodes, the lower the value the higher the priority.
+	// +kubebuilder:validation:Minimum=0
+	Priority int32 `json:"priority"`
+	// NodeSelectors select the nodes the priority applies to. When a node is
+	// selected by several priorities, the highest one applies.
+	// +kubebuilder:validation:MinItems=1
+	NodeSelectors []metav1.LabelSelector `json:"nodeSelectors"`
 }
 
 // L2AdvertisementStatus defines the observed state of L2Advertisement.
@@ -53,6 +99,7 @@ type L2AdvertisementStatus struct {
 //+kubebuilder:printcolumn:name="IPAddressPool Selectors",type=string,JSONPath=`.spec.ipAddressPoolSelectors`
 //+kubebuilder:printcolumn:name="Interfaces",type=string,JSONPath=`.spec.interfaces`
 //+kubebuilder:printcolumn:name="Node Selectors",type=string,JSONPath=`.spec.nodeSelectors`,priority=10
+//+kubebuilder:printcolumn:name="Sticky",type=boolean,JSONPath=`.spec.sticky`,priority=10
 
 // L2Advertisement allows to advertise the LoadBalancer IPs provided
 // by the selected pools via L2.
@@ -37,8 +37,46 @@ type MetalLBServiceBGPStatus struct {
 	ServiceNamespace string `json:"serviceNamespace,omitempty"`
 
 	// Peers indicate the BGP peers for which the service is configured to be advertised to.
-	// The service being actually advertised to a given peer depends on the session state and is not indicated here.
+	// The service being actually advertised to a given peer depends on the session state, see PeerStatuses.
 	Peers []string `json:"peers,omitempty"`
+
+	// PeerStatuses indicate, for each of the peers, the state of the session
+	// with the peer and the routes advertised to it for the service.
+	// +optional
+	PeerStatuses []ServiceBGPPeerStatus `json:"peerStatuses,omitempty"`
+}
+
+// ServiceBGPPeerStatus is the advertisement of a service to a BGP peer.
+type ServiceBGPPeerStatus struct {
+	// Peer is the name of the BGPPeer.
+	Peer string `json:"peer"`
+
+	// SessionState is the state of the session with the peer. The prefixes
+	// reach the peer only while the session is Established.
+	SessionState BGPSessionState `json:"sessionState,omitempty"`
+
+	// Prefixes are the routes advertised to the peer for the service, after
+	// aggregation.
+	// +optional
+	Prefixes []ServiceBGPPrefix `json:"prefixes,omitempty"`
+}
+
+// ServiceBGPPrefix is a route advertised to a BGP peer, with its attributes.
+type ServiceBGPPrefix struct {
+	// Prefix is the advertised prefix, in CIDR notation.
+	Prefix string `json:"prefix"`
+
+	// LocalPref is the local preference of the route, only sent to iBGP peers.
+	// +optional
+	LocalPref *uint32 `json:"localPref,omitempty"`
+
+	// MED is the multi-exit discriminator of the route.
+	// +optional
+	MED *uint32 `json:"med,omitempty"`
+
+	// Communities are the BGP communities attached to the route.
+	// +optional
+	Communities []string `json:"communities,omitempty"`
 }
 
 // +kubebuilder:object:root=true
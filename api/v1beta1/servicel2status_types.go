@@ -35,6 +35,10 @@ type MetalLBServiceL2Status struct {
 	ServiceNamespace string `json:"serviceNamespace,omitempty"`
 	// Interfaces indicates the interfaces that receive the directed traffic
 	Interfaces []InterfaceInfo `json:"interfaces,omitempty"`
+	// IPs indicates the IPs of the service announced from the node, a subset
+	// of them when the node announcing each IP is elected independently.
+	// +optional
+	IPs []string `json:"ips,omitempty"`
 }
 
 // InterfaceInfo defines interface info of layer2 announcement.
@@ -49,6 +53,7 @@ type InterfaceInfo struct {
 // +kubebuilder:printcolumn:name="Allocated Node",type=string,JSONPath=`.status.node`
 // +kubebuilder:printcolumn:name="Service Name",type=string,JSONPath=`.status.serviceName`
 // +kubebuilder:printcolumn:name="Service Namespace",type=string,JSONPath=`.status.serviceNamespace`
+// +kubebuilder:printcolumn:name="IPs",type=string,JSONPath=`.status.ips`,priority=10
 
 // ServiceL2Status reveals the actual traffic status of loadbalancer services in layer2 mode.
 type ServiceL2Status struct {
@@ -27,10 +27,30 @@ import (
 type ServiceUPnPStatusSpec struct {
 	// ExternalIP is the external IP address provided by the UPnP IGD router
 	ExternalIP string `json:"externalIP,omitempty"`
+	// Gateways lists the gateways the port mappings are programmed on
+	Gateways []UPnPGatewayStatus `json:"gateways,omitempty"`
 	// PortMappings contains the list of UPnP port mappings created for this service
 	PortMappings []UPnPPortMapping `json:"portMappings,omitempty"`
 	// Node is the name of the node that is handling the UPnP port forwarding
 	Node string `json:"node,omitempty"`
+	// ServiceName indicates the service this status represents
+	ServiceName string `json:"serviceName,omitempty"`
+	// ServiceNamespace indicates the namespace of the service
+	ServiceNamespace string `json:"serviceNamespace,omitempty"`
+}
+
+// UPnPGatewayStatus describes a gateway the port mappings are programmed on
+type UPnPGatewayStatus struct {
+	// Protocol is the protocol used to program the gateway (UPnP, NAT-PMP or PCP)
+	Protocol string `json:"protocol"`
+	// Location is the URL of the device description for UPnP, the address of the gateway for NAT-PMP and PCP
+	Location string `json:"location,omitempty"`
+	// UDN is the unique device name of the UPnP gateway
+	UDN string `json:"udn,omitempty"`
+	// FriendlyName is the friendly name of the UPnP gateway
+	FriendlyName string `json:"friendlyName,omitempty"`
+	// ExternalIP is the external IP address of the gateway
+	ExternalIP string `json:"externalIP,omitempty"`
 }
 
 // UPnPPortMapping represents a single UPnP port mapping
@@ -39,6 +59,8 @@ type UPnPPortMapping struct {
 	ExternalPort int32 `json:"externalPort"`
 	// InternalPort is the internal port on the service
 	InternalPort int32 `json:"internalPort"`
+	// InternalIP is the LoadBalancer IP the router forwards the traffic to
+	InternalIP string `json:"internalIP,omitempty"`
 	// Protocol is the protocol (TCP or UDP)
 	Protocol string `json:"protocol"`
 	// Description is the description of the port mapping
@@ -51,6 +73,8 @@ type UPnPPortMapping struct {
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="External IP",type=string,JSONPath=`.spec.externalIP`
 //+kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.node`
+//+kubebuilder:printcolumn:name="Service Name",type=string,JSONPath=`.spec.serviceName`
+//+kubebuilder:printcolumn:name="Service Namespace",type=string,JSONPath=`.spec.serviceNamespace`
 //+kubebuilder:printcolumn:name="Port Mappings",type=integer,JSONPath=`.spec.portMappings[*].externalPort`,priority=10
 
 // ServiceUPnPStatus shows the current UPnP port forwarding status for a service.
@@ -45,6 +45,43 @@ type UPnPAdvertisementSpec struct {
 	// +optional
 	// +kubebuilder:default="MetalLB LoadBalancer"
 	Description string `json:"description,omitempty"`
+	// Protocol is the protocol used to program the gateway. UPnP discovers an
+	// Internet Gateway Device on the local network, NAT-PMP and PCP talk to the
+	// gateway set in GatewayAddress.
+	// +optional
+	// +kubebuilder:validation:Enum=UPnP;NAT-PMP;PCP
+	// +kubebuilder:default="UPnP"
+	Protocol string `json:"protocol,omitempty"`
+	// GatewayAddress is the address of the NAT-PMP or PCP gateway, optionally
+	// followed by the port (5351 by default). Required when the protocol is
+	// NAT-PMP or PCP, ignored for UPnP.
+	// +optional
+	GatewayAddress string `json:"gatewayAddress,omitempty"`
+	// GatewaySelector pins the UPnP gateway to program when several are found
+	// on the network. All the fields set must match. Only valid for UPnP.
+	// +optional
+	GatewaySelector *UPnPGatewaySelector `json:"gatewaySelector,omitempty"`
+	// AllGateways programs every UPnP gateway found on the network, or
+	// matching GatewaySelector, instead of the first one. Only valid for UPnP.
+	// +optional
+	AllGateways bool `json:"allGateways,omitempty"`
+}
+
+// UPnPGatewaySelector selects a UPnP Internet Gateway Device.
+type UPnPGatewaySelector struct {
+	// Location is the URL of the device description, as advertised via SSDP.
+	// When set, the device is contacted directly instead of being discovered.
+	// +optional
+	Location string `json:"location,omitempty"`
+	// UDN is the unique device name of the gateway, e.g. uuid:12345678-...
+	// +optional
+	UDN string `json:"udn,omitempty"`
+	// FriendlyName is the friendly name of the gateway.
+	// +optional
+	FriendlyName string `json:"friendlyName,omitempty"`
+	// ExternalIP is the WAN IP address of the gateway.
+	// +optional
+	ExternalIP string `json:"externalIP,omitempty"`
 }
 
 // UPnPAdvertisementStatus defines the observed state of UPnPAdvertisement.
@@ -59,6 +96,7 @@ type UPnPAdvertisementStatus struct {
 //+kubebuilder:printcolumn:name="IPAddressPool Selectors",type=string,JSONPath=`.spec.ipAddressPoolSelectors`
 //+kubebuilder:printcolumn:name="Node Selectors",type=string,JSONPath=`.spec.nodeSelectors`,priority=10
 //+kubebuilder:printcolumn:name="Duration",type=integer,JSONPath=`.spec.duration`
+//+kubebuilder:printcolumn:name="Protocol",type=string,JSONPath=`.spec.protocol`
 
 // UPnPAdvertisement allows to advertise the LoadBalancer IPs provided
 // by the selected pools via UPnP IGD port forwarding.
@@ -25,6 +25,26 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllocationStrategy) DeepCopyInto(out *AllocationStrategy) {
+	*out = *in
+	if in.QuarantineTime != nil {
+		in, out := &in.QuarantineTime, &out.QuarantineTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllocationStrategy.
+func (in *AllocationStrategy) DeepCopy() *AllocationStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(AllocationStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BFDProfile) DeepCopyInto(out *BFDProfile) {
 	*out = *in
@@ -221,6 +241,11 @@ func (in *BGPAdvertisementSpec) DeepCopyInto(out *BGPAdvertisementSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.MED != nil {
+		in, out := &in.MED, &out.MED
+		*out = new(uint32)
+		**out = **in
+	}
 	if in.Communities != nil {
 		in, out := &in.Communities, &out.Communities
 		*out = make([]string, len(*in))
@@ -375,6 +400,80 @@ func (in *BGPPeerStatus) DeepCopy() *BGPPeerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPSessionStatus) DeepCopyInto(out *BGPSessionStatus) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPSessionStatus.
+func (in *BGPSessionStatus) DeepCopy() *BGPSessionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPSessionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BGPSessionStatus) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPSessionStatusList) DeepCopyInto(out *BGPSessionStatusList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BGPSessionStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPSessionStatusList.
+func (in *BGPSessionStatusList) DeepCopy() *BGPSessionStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPSessionStatusList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BGPSessionStatusList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BGPSessionStatusSpec) DeepCopyInto(out *BGPSessionStatusSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPSessionStatusSpec.
+func (in *BGPSessionStatusSpec) DeepCopy() *BGPSessionStatusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BGPSessionStatusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Community) DeepCopyInto(out *Community) {
 	*out = *in
@@ -490,7 +589,7 @@ func (in *IPAddressPool) DeepCopyInto(out *IPAddressPool) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAddressPool.
@@ -556,11 +655,28 @@ func (in *IPAddressPoolSpec) DeepCopyInto(out *IPAddressPoolSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ExcludedAddresses != nil {
+		in, out := &in.ExcludedAddresses, &out.ExcludedAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.AllocateTo != nil {
 		in, out := &in.AllocateTo, &out.AllocateTo
 		*out = new(ServiceAllocation)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllocationStrategy != nil {
+		in, out := &in.AllocationStrategy, &out.AllocationStrategy
+		*out = new(AllocationStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Quotas != nil {
+		in, out := &in.Quotas, &out.Quotas
+		*out = make([]NamespaceQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAddressPoolSpec.
@@ -576,6 +692,13 @@ func (in *IPAddressPoolSpec) DeepCopy() *IPAddressPoolSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPAddressPoolStatus) DeepCopyInto(out *IPAddressPoolStatus) {
 	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceUsage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAddressPoolStatus.
@@ -588,6 +711,94 @@ func (in *IPAddressPoolStatus) DeepCopy() *IPAddressPoolStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPAddressReservation) DeepCopyInto(out *IPAddressReservation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAddressReservation.
+func (in *IPAddressReservation) DeepCopy() *IPAddressReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(IPAddressReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPAddressReservation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPAddressReservationList) DeepCopyInto(out *IPAddressReservationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IPAddressReservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAddressReservationList.
+func (in *IPAddressReservationList) DeepCopy() *IPAddressReservationList {
+	if in == nil {
+		return nil
+	}
+	out := new(IPAddressReservationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPAddressReservationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPAddressReservationSpec) DeepCopyInto(out *IPAddressReservationSpec) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceRef != nil {
+		in, out := &in.ServiceRef, &out.ServiceRef
+		*out = new(ServiceReference)
+		**out = **in
+	}
+	if in.ServiceSelector != nil {
+		in, out := &in.ServiceSelector, &out.ServiceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAddressReservationSpec.
+func (in *IPAddressReservationSpec) DeepCopy() *IPAddressReservationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPAddressReservationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InterfaceInfo) DeepCopyInto(out *InterfaceInfo) {
 	*out = *in
@@ -689,6 +900,28 @@ func (in *L2AdvertisementSpec) DeepCopyInto(out *L2AdvertisementSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.GratuitousInterval != nil {
+		in, out := &in.GratuitousInterval, &out.GratuitousInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.GratuitousDuration != nil {
+		in, out := &in.GratuitousDuration, &out.GratuitousDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.GratuitousCount != nil {
+		in, out := &in.GratuitousCount, &out.GratuitousCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodePriorities != nil {
+		in, out := &in.NodePriorities, &out.NodePriorities
+		*out = make([]NodePriority, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new L2AdvertisementSpec.
@@ -736,6 +969,25 @@ func (in *MatchExpression) DeepCopy() *MatchExpression {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetalLBBGPSessionStatus) DeepCopyInto(out *MetalLBBGPSessionStatus) {
+	*out = *in
+	if in.EstablishedTime != nil {
+		in, out := &in.EstablishedTime, &out.EstablishedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetalLBBGPSessionStatus.
+func (in *MetalLBBGPSessionStatus) DeepCopy() *MetalLBBGPSessionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MetalLBBGPSessionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetalLBServiceBGPStatus) DeepCopyInto(out *MetalLBServiceBGPStatus) {
 	*out = *in
@@ -744,6 +996,13 @@ func (in *MetalLBServiceBGPStatus) DeepCopyInto(out *MetalLBServiceBGPStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PeerStatuses != nil {
+		in, out := &in.PeerStatuses, &out.PeerStatuses
+		*out = make([]ServiceBGPPeerStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetalLBServiceBGPStatus.
@@ -764,6 +1023,11 @@ func (in *MetalLBServiceL2Status) DeepCopyInto(out *MetalLBServiceL2Status) {
 		*out = make([]InterfaceInfo, len(*in))
 		copy(*out, *in)
 	}
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetalLBServiceL2Status.
@@ -776,6 +1040,90 @@ func (in *MetalLBServiceL2Status) DeepCopy() *MetalLBServiceL2Status {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceQuota) DeepCopyInto(out *NamespaceQuota) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelectors != nil {
+		in, out := &in.NamespaceSelectors, &out.NamespaceSelectors
+		*out = make([]v1.LabelSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaxIPv4 != nil {
+		in, out := &in.MaxIPv4, &out.MaxIPv4
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxIPv6 != nil {
+		in, out := &in.MaxIPv6, &out.MaxIPv6
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceQuota.
+func (in *NamespaceQuota) DeepCopy() *NamespaceQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceUsage) DeepCopyInto(out *NamespaceUsage) {
+	*out = *in
+	if in.MaxIPv4 != nil {
+		in, out := &in.MaxIPv4, &out.MaxIPv4
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxIPv6 != nil {
+		in, out := &in.MaxIPv6, &out.MaxIPv6
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceUsage.
+func (in *NamespaceUsage) DeepCopy() *NamespaceUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePriority) DeepCopyInto(out *NodePriority) {
+	*out = *in
+	if in.NodeSelectors != nil {
+		in, out := &in.NodeSelectors, &out.NodeSelectors
+		*out = make([]v1.LabelSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePriority.
+func (in *NodePriority) DeepCopy() *NodePriority {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePriority)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeSelector) DeepCopyInto(out *NodeSelector) {
 	*out = *in
@@ -839,6 +1187,58 @@ func (in *ServiceAllocation) DeepCopy() *ServiceAllocation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceBGPPeerStatus) DeepCopyInto(out *ServiceBGPPeerStatus) {
+	*out = *in
+	if in.Prefixes != nil {
+		in, out := &in.Prefixes, &out.Prefixes
+		*out = make([]ServiceBGPPrefix, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceBGPPeerStatus.
+func (in *ServiceBGPPeerStatus) DeepCopy() *ServiceBGPPeerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceBGPPeerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceBGPPrefix) DeepCopyInto(out *ServiceBGPPrefix) {
+	*out = *in
+	if in.LocalPref != nil {
+		in, out := &in.LocalPref, &out.LocalPref
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.MED != nil {
+		in, out := &in.MED, &out.MED
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.Communities != nil {
+		in, out := &in.Communities, &out.Communities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceBGPPrefix.
+func (in *ServiceBGPPrefix) DeepCopy() *ServiceBGPPrefix {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceBGPPrefix)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceBGPStatus) DeepCopyInto(out *ServiceBGPStatus) {
 	*out = *in
@@ -987,6 +1387,21 @@ func (in *ServiceL2StatusSpec) DeepCopy() *ServiceL2StatusSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceReference) DeepCopyInto(out *ServiceReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceReference.
+func (in *ServiceReference) DeepCopy() *ServiceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UPnPAdvertisement) DeepCopyInto(out *UPnPAdvertisement) {
 	*out = *in
@@ -1068,6 +1483,11 @@ func (in *UPnPAdvertisementSpec) DeepCopyInto(out *UPnPAdvertisementSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.GatewaySelector != nil {
+		in, out := &in.GatewaySelector, &out.GatewaySelector
+		*out = new(UPnPGatewaySelector)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UPnPAdvertisementSpec.
@@ -1156,6 +1576,11 @@ func (in *ServiceUPnPStatusList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceUPnPStatusSpec) DeepCopyInto(out *ServiceUPnPStatusSpec) {
 	*out = *in
+	if in.Gateways != nil {
+		in, out := &in.Gateways, &out.Gateways
+		*out = make([]UPnPGatewayStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.PortMappings != nil {
 		in, out := &in.PortMappings, &out.PortMappings
 		*out = make([]UPnPPortMapping, len(*in))
@@ -1173,6 +1598,36 @@ func (in *ServiceUPnPStatusSpec) DeepCopy() *ServiceUPnPStatusSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UPnPGatewaySelector) DeepCopyInto(out *UPnPGatewaySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UPnPGatewaySelector.
+func (in *UPnPGatewaySelector) DeepCopy() *UPnPGatewaySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(UPnPGatewaySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UPnPGatewayStatus) DeepCopyInto(out *UPnPGatewayStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UPnPGatewayStatus.
+func (in *UPnPGatewayStatus) DeepCopy() *UPnPGatewayStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UPnPGatewayStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UPnPPortMapping) DeepCopyInto(out *UPnPPortMapping) {
 	*out = *in
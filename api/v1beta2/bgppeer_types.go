@@ -60,6 +60,12 @@ type BGPPeerSpec struct {
 	// +optional
 	SrcAddress string `json:"sourceAddress,omitempty"`
 
+	// IPv6 address announced as the next hop of the IPv6 prefixes. When not set,
+	// the local address of the session is used if it is IPv6, otherwise a global
+	// IPv6 address of the interface holding it. Supported for native mode only.
+	// +optional
+	IPv6NextHop string `json:"ipv6NextHop,omitempty"`
+
 	// Port to dial when establishing the session.
 	// +optional
 	// +kubebuilder:validation:Minimum=1
@@ -108,11 +114,19 @@ type BGPPeerSpec struct {
 	// EnableGracefulRestart allows BGP peer to continue to forward data packets
 	// along known routes while the routing protocol information is being
 	// restored. This field is immutable because it requires restart of the BGP
-	// session. Supported for FRR mode only.
+	// session.
 	// +optional
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="EnableGracefulRestart cannot be changed after creation"
 	EnableGracefulRestart bool `json:"enableGracefulRestart,omitempty"`
 
+	// Restart time advertised in the Graceful Restart capability: how long the
+	// peer keeps the routes of the node after the session goes down. Defaults
+	// to 120s. Supported for native mode only.
+	// +kubebuilder:validation:XValidation:message="graceful restart time should be between 1 and 4095 seconds",rule="duration(self).getSeconds() >= 1 && duration(self).getSeconds() <= 4095"
+	// +kubebuilder:validation:XValidation:message="graceful restart time should contain a whole number of seconds",rule="duration(self).getMilliseconds() % 1000 == 0"
+	// +optional
+	GracefulRestartTime *metav1.Duration `json:"gracefulRestartTime,omitempty"`
+
 	// To set if the BGPPeer is multi-hops away. Needed for FRR mode only.
 	// +optional
 	EBGPMultiHop bool `json:"ebgpMultiHop,omitempty"`
@@ -138,8 +152,15 @@ type BGPPeerSpec struct {
 
 // BGPPeerStatus defines the observed state of Peer.
 type BGPPeerStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// EstablishedNodes lists the nodes whose session with the peer is
+	// established.
+	// +optional
+	EstablishedNodes []string `json:"establishedNodes,omitempty"`
+
+	// DownNodes lists the nodes configured to peer whose session with the
+	// peer is not established.
+	// +optional
+	DownNodes []string `json:"downNodes,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -149,6 +170,8 @@ type BGPPeerStatus struct {
 //+kubebuilder:printcolumn:name="ASN",type=string,JSONPath=`.spec.peerASN`
 //+kubebuilder:printcolumn:name="BFD Profile",type=string,JSONPath=`.spec.bfdProfile`
 //+kubebuilder:printcolumn:name="Multi Hops",type=string,JSONPath=`.spec.ebgpMultiHop`
+//+kubebuilder:printcolumn:name="Established Nodes",type=string,JSONPath=`.status.establishedNodes`,priority=1
+//+kubebuilder:printcolumn:name="Down Nodes",type=string,JSONPath=`.status.downNodes`,priority=1
 
 // BGPPeer is the Schema for the peers API.
 type BGPPeer struct {
@@ -31,7 +31,7 @@ func (in *BGPPeer) DeepCopyInto(out *BGPPeer) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeer.
@@ -110,6 +110,11 @@ func (in *BGPPeerSpec) DeepCopyInto(out *BGPPeerSpec) {
 		}
 	}
 	out.PasswordSecret = in.PasswordSecret
+	if in.GracefulRestartTime != nil {
+		in, out := &in.GracefulRestartTime, &out.GracefulRestartTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerSpec.
@@ -125,6 +130,16 @@ func (in *BGPPeerSpec) DeepCopy() *BGPPeerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BGPPeerStatus) DeepCopyInto(out *BGPPeerStatus) {
 	*out = *in
+	if in.EstablishedNodes != nil {
+		in, out := &in.EstablishedNodes, &out.EstablishedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DownNodes != nil {
+		in, out := &in.DownNodes, &out.DownNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BGPPeerStatus.
@@ -249,6 +249,7 @@ func main() {
 		LoadBalancerClass:   *loadBalancerClass,
 		PoolStatusChan:      poolStatusChan,
 		PoolCountersFetcher: c.ips.CountersForPool,
+		BGPPeerStatus:       true,
 	}
 	switch *webhookMode {
 	case "enabled":
@@ -258,6 +259,7 @@ func main() {
 	case "onlywebhook":
 		cfg.EnableWebhook = true
 		cfg.Listener = k8s.Listener{}
+		cfg.BGPPeerStatus = false
 	default:
 		level.Error(logger).Log("op", "startup", "error", "invalid webhookmode value", "value", *webhookMode)
 		os.Exit(1)
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"reflect"
@@ -25,6 +26,7 @@ import (
 	"github.com/go-kit/log/level"
 	v1 "k8s.io/api/core/v1"
 
+	"go.universe.tf/metallb/internal/allocator"
 	"go.universe.tf/metallb/internal/allocator/k8salloc"
 	"go.universe.tf/metallb/internal/ipfamily"
 )
@@ -177,7 +179,12 @@ func (c *controller) convergeBalancer(l log.Logger, key string, svc *v1.Service)
 		lbIPs, err = c.allocateIPs(key, svc)
 		if err != nil {
 			level.Error(l).Log("op", "allocateIPs", "error", err, "msg", "IP allocation failed")
-			c.client.Errorf(svc, "AllocationFailed", "Failed to allocate IP for %q: %s", key, err)
+			reason := "AllocationFailed"
+			var quotaErr *allocator.QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				reason = "QuotaExceeded"
+			}
+			c.client.Errorf(svc, reason, "Failed to allocate IP for %q: %s", key, err)
 			// The outer controller loop will retry converging this
 			// service when another service gets deleted, so there's
 			// nothing to do here but wait to get called again later.
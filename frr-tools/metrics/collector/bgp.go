@@ -60,8 +60,8 @@ var (
 	)
 
 	updatesReceivedDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(bgpmetrics.Namespace, bgpmetrics.Subsystem, "updates_total_received"),
-		"Number of BGP UPDATE messages received",
+		prometheus.BuildFQName(bgpmetrics.Namespace, bgpmetrics.Subsystem, bgpmetrics.UpdatesReceived.Name),
+		bgpmetrics.UpdatesReceived.Help,
 		labels,
 		nil,
 	)
@@ -133,7 +133,7 @@ func (c *bgp) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *bgp) Collect(ch chan<- prometheus.Metric) {
-	neighbors, err := getBGPNeighbors(c.frrCli)
+	neighbors, err := vtysh.Neighbors(c.frrCli)
 	if err != nil {
 		level.Error(c.Log).Log("error", err, "msg", "failed to fetch BGP neighbors from FRR")
 		return
@@ -166,24 +166,3 @@ func updateNeighborsMetrics(ch chan<- prometheus.Metric, neighbors map[string][]
 		}
 	}
 }
-
-func getBGPNeighbors(frrCli vtysh.Cli) (map[string][]*bgpfrr.Neighbor, error) {
-	vrfs, err := vtysh.VRFs(frrCli)
-	if err != nil {
-		return nil, err
-	}
-	neighbors := make(map[string][]*bgpfrr.Neighbor, 0)
-	for _, vrf := range vrfs {
-		res, err := frrCli(fmt.Sprintf("show bgp vrf %s neighbors json", vrf))
-		if err != nil {
-			return nil, err
-		}
-
-		neighborsPerVRF, err := bgpfrr.ParseNeighbours(res)
-		if err != nil {
-			return nil, err
-		}
-		neighbors[vrf] = neighborsPerVRF
-	}
-	return neighbors, nil
-}
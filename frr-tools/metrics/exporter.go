@@ -17,6 +17,7 @@ import (
 
 	"go.universe.tf/metallb/frr-tools/metrics/collector"
 	"go.universe.tf/metallb/frr-tools/metrics/liveness"
+	"go.universe.tf/metallb/frr-tools/metrics/neighbors"
 	"go.universe.tf/metallb/frr-tools/metrics/vtysh"
 	"go.universe.tf/metallb/internal/logging"
 	"go.universe.tf/metallb/internal/version"
@@ -64,6 +65,7 @@ func main() {
 	mux := http.NewServeMux()
 	mux.Handle(*metricsPath, metricsHandler(logger))
 	mux.Handle("/livez", liveness.Handler(vtysh.Run, logger))
+	mux.Handle("/bgp/neighbors", neighbors.Handler(vtysh.Run, logger))
 	level.Info(logger).Log("msg", "Starting exporter", "metricsPath", metricsPath, "port", metricsPort)
 
 	srv := &http.Server{
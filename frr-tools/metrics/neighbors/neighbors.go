@@ -0,0 +1,29 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package neighbors
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kit/log"
+
+	"go.universe.tf/metallb/frr-tools/metrics/vtysh"
+)
+
+// Handler serves the BGP neighbors of FRR as JSON, by VRF name, for the
+// speaker to report the state of the sessions.
+func Handler(frrCli vtysh.Cli, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		neighbors, err := vtysh.Neighbors(frrCli)
+		if err != nil {
+			http.Error(w, "failed to fetch BGP neighbors", http.StatusInternalServerError)
+			logger.Log("failed to fetch BGP neighbors", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(neighbors); err != nil {
+			logger.Log("failed to write BGP neighbors", err)
+		}
+	})
+}
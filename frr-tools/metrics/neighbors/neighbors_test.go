@@ -0,0 +1,102 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package neighbors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	bgpfrr "go.universe.tf/metallb/internal/bgp/frr"
+	"go.universe.tf/metallb/internal/logging"
+)
+
+const neighborsVtysh = `{
+  "172.18.0.2":{
+    "remoteAs":64512,
+    "localAs":64512,
+    "remoteRouterId":"172.18.0.2",
+    "bgpState":"Established",
+    "bgpTimerUpMsec":60000,
+    "addressFamilyInfo":{
+      "ipv4Unicast":{
+        "acceptedPrefixCounter":2,
+        "sentPrefixCounter":1
+      }
+    },
+    "lastResetDueTo":"Waiting for peer OPEN",
+    "portForeign":179
+  }
+}`
+
+func TestNeighbors(t *testing.T) {
+	tests := []struct {
+		desc               string
+		vtyshError         error
+		expectedStatusCode int
+	}{
+		{
+			desc:               "regular",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			desc:               "returns error",
+			vtyshError:         fmt.Errorf("failed to run"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	logger, err := logging.Init("error")
+	if err != nil {
+		t.Fatalf("failed to create logger %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/bgp/neighbors", nil)
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			vtysh := func(args string) (string, error) {
+				if test.vtyshError != nil {
+					return "", test.vtyshError
+				}
+				switch args {
+				case "show bgp vrf all json":
+					return `{"default":{}, "red":{}}`, nil
+				case "show bgp vrf red neighbors json":
+					return neighborsVtysh, nil
+				}
+				return "{}", nil
+			}
+			handler := Handler(vtysh, logger)
+			handler.ServeHTTP(w, req)
+			res := w.Result()
+			defer res.Body.Close()
+			if res.StatusCode != test.expectedStatusCode {
+				t.Fatalf("status code %d different from expected %d", res.StatusCode, test.expectedStatusCode)
+			}
+			if res.StatusCode != http.StatusOK {
+				return
+			}
+
+			got := map[string][]*bgpfrr.Neighbor{}
+			if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode the neighbors: %v", err)
+			}
+			if len(got["default"]) != 0 {
+				t.Fatalf("expected no neighbors in the default vrf, got %d", len(got["default"]))
+			}
+			if len(got["red"]) != 1 {
+				t.Fatalf("expected one neighbor in the red vrf, got %d", len(got["red"]))
+			}
+			n := got["red"][0]
+			if n.IP.String() != "172.18.0.2" || n.VRF != "red" || !n.Connected {
+				t.Fatalf("unexpected neighbor %+v", n)
+			}
+			if n.UpTime != time.Minute || n.PrefixSent != 1 || n.PrefixReceived != 2 {
+				t.Fatalf("unexpected neighbor counters %+v", n)
+			}
+		})
+	}
+}
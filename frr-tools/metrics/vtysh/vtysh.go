@@ -3,6 +3,7 @@
 package vtysh
 
 import (
+	"fmt"
 	"os/exec"
 
 	bgpfrr "go.universe.tf/metallb/internal/bgp/frr"
@@ -28,3 +29,28 @@ func VRFs(frrCli Cli) ([]string, error) {
 	}
 	return parsedVRFs, nil
 }
+
+// Neighbors returns the BGP neighbors of all the VRFs, by VRF name.
+func Neighbors(frrCli Cli) (map[string][]*bgpfrr.Neighbor, error) {
+	vrfs, err := VRFs(frrCli)
+	if err != nil {
+		return nil, err
+	}
+	neighbors := make(map[string][]*bgpfrr.Neighbor, 0)
+	for _, vrf := range vrfs {
+		res, err := frrCli(fmt.Sprintf("show bgp vrf %s neighbors json", vrf))
+		if err != nil {
+			return nil, err
+		}
+
+		neighborsPerVRF, err := bgpfrr.ParseNeighbours(res)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range neighborsPerVRF {
+			n.VRF = vrf
+		}
+		neighbors[vrf] = neighborsPerVRF
+	}
+	return neighbors, nil
+}
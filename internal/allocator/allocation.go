@@ -24,6 +24,22 @@ type Allocation struct {
 	PoolName string
 	IPV4     net.IP
 	IPV6     net.IP
+
+	// quotaErrs tell, by family, when an address was found but the quota
+	// of the namespace of the service does not allow it.
+	quotaErrs map[ipfamily.Family]error
+}
+
+// quotaErr returns the quota error preventing to allocate an address of
+// the given family, or of any family for dual-stack.
+func (a *Allocation) quotaErr(family ipfamily.Family) error {
+	if family != ipfamily.DualStack {
+		return a.quotaErrs[family]
+	}
+	if err := a.quotaErrs[ipfamily.IPv4]; err != nil {
+		return err
+	}
+	return a.quotaErrs[ipfamily.IPv6]
 }
 
 func (a *Allocation) getIPForFamily(family ipfamily.Family) net.IP {
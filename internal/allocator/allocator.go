@@ -5,11 +5,15 @@ package allocator // import "go.universe.tf/metallb/internal/allocator"
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"math/big"
+	"math/rand/v2"
 	"net"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"go.universe.tf/metallb/internal/config"
 	"go.universe.tf/metallb/internal/ipfamily"
@@ -17,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/mikioh/ipaddr"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // An Allocator tracks IP address pools and allocates addresses from them.
@@ -30,6 +35,10 @@ type Allocator struct {
 	poolIPsInUse    map[string]map[string]int  // poolName -> ip.String() -> number of users
 	poolIPV4InUse   map[string]map[string]int  // poolName -> ipv4.String() -> number of users
 	poolIPV6InUse   map[string]map[string]int  // poolName -> ipv6.String() -> number of users
+	// Addresses released from pools using the LeastRecentlyReleased
+	// allocation strategy, ip.String() -> release.
+	released map[string]release
+	now      func() time.Time
 
 	poolToCounters          map[string]PoolCounters // poolName -> Counters
 	countersMutex           sync.RWMutex
@@ -59,11 +68,45 @@ type alloc struct {
 	key
 }
 
+// release records when an address was last released, and by which service.
+type release struct {
+	at  time.Time
+	svc string
+}
+
 type PoolCounters struct {
 	AssignedIPv4  int64
 	AssignedIPv6  int64
 	AvailableIPv4 int64
 	AvailableIPv6 int64
+	ReservedIPv4  int64
+	ReservedIPv6  int64
+	// The usage of the pool by the namespaces whose services have
+	// addresses assigned from it, by namespace.
+	Namespaces map[string]NamespaceCounters
+}
+
+// NamespaceCounters is the usage of a pool by the services of a namespace.
+type NamespaceCounters struct {
+	AssignedIPv4 int64
+	AssignedIPv6 int64
+	// The quota of the namespace, nil when not limited.
+	MaxIPv4 *int64
+	MaxIPv6 *int64
+}
+
+// QuotaExceededError is returned when assigning addresses to a service would
+// make the services of its namespace use more addresses of a pool than the
+// quota of the namespace allows.
+type QuotaExceededError struct {
+	Pool      string
+	Namespace string
+	Family    ipfamily.Family
+	Max       int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("namespace %q reached its quota of %d %s addresses in pool %s", e.Namespace, e.Max, e.Family, e.Pool)
 }
 
 // New returns an Allocator managing no pools.
@@ -78,6 +121,8 @@ func New(countersCallback func(string)) *Allocator {
 		poolIPsInUse:            map[string]map[string]int{},
 		poolIPV4InUse:           map[string]map[string]int{},
 		poolIPV6InUse:           map[string]map[string]int{},
+		released:                map[string]release{},
+		now:                     time.Now,
 		poolToCounters:          map[string]PoolCounters{},
 		countersMutex:           sync.RWMutex{},
 		countersChangedCallback: countersCallback,
@@ -105,6 +150,12 @@ func (a *Allocator) SetPools(pools *config.Pools) {
 
 	a.pools = pools
 
+	for ip := range a.released {
+		if poolFor(a.pools.ByName, []net.IP{net.ParseIP(ip)}) == nil {
+			delete(a.released, ip)
+		}
+	}
+
 	// Need to rearrange existing pool mappings and counts
 	for svc, alloc := range a.allocated {
 		pool := poolFor(a.pools.ByName, alloc.ips)
@@ -145,6 +196,7 @@ func (a *Allocator) assign(svc string, alloc *alloc) {
 			a.servicesOnIP[ip.String()] = map[string]bool{}
 		}
 		a.servicesOnIP[ip.String()][svc] = true
+		delete(a.released, ip.String())
 		if a.poolIPsInUse[alloc.pool] == nil {
 			a.poolIPsInUse[alloc.pool] = map[string]int{}
 		}
@@ -180,6 +232,17 @@ func (a *Allocator) Assign(svcKey string, svc *v1.Service, ips []net.IP, ports [
 	if !a.isPoolCompatibleWithService(pool, svc) {
 		return fmt.Errorf("pool %s not compatible for ip assignment", pool.Name)
 	}
+	for _, ip := range ips {
+		if r := pool.Reservations[ip.String()]; r != nil && !reservationMatches(r, svcKey, svc) {
+			return fmt.Errorf("%q is reserved by %s", ip, r.Name)
+		}
+		if !pool.AllowExplicitExcluded && ipExcluded(pool, ip) {
+			return fmt.Errorf("%q is excluded from pool %s", ip, pool.Name)
+		}
+	}
+	if err := a.checkQuota(pool, svcKey, svc, ips); err != nil {
+		return err
+	}
 	// Check the dual-stack constraints:
 	// - Two addresses
 	// - Different families, ipv4 and ipv6
@@ -246,6 +309,9 @@ func (a *Allocator) Unassign(svc string) {
 		// is an accurate count of IPs in use.
 		if a.poolIPsInUse[al.pool][ip.String()] == 0 {
 			delete(a.poolIPsInUse[al.pool], ip.String())
+			if p := a.pools.ByName[al.pool]; p != nil && p.AllocationStrategy == config.LeastRecentlyReleasedAllocation {
+				a.released[ip.String()] = release{at: a.now(), svc: svc}
+			}
 		}
 		if a.poolIPV4InUse[al.pool][ip.String()] == 0 {
 			delete(a.poolIPV4InUse[al.pool], ip.String())
@@ -282,7 +348,14 @@ func (a *Allocator) getFreeIPsFromPool(
 		if ip := allocation.getIPForFamily(cidrIPFamily); ip != nil {
 			continue
 		}
-		if ip := a.getIPFromCIDR(cidr, pool.AvoidBuggyIPs, svcKey, ports, sharingKey, backendKey); ip != nil {
+		if ip := a.getIPFromCIDR(pool, cidr, svcKey, ports, sharingKey, backendKey); ip != nil {
+			if err := a.checkQuota(pool, svcKey, nil, []net.IP{ip}); err != nil {
+				if allocation.quotaErrs == nil {
+					allocation.quotaErrs = map[ipfamily.Family]error{}
+				}
+				allocation.quotaErrs[cidrIPFamily] = err
+				continue
+			}
 			allocation.setIPForFamily(cidrIPFamily, ip)
 		}
 	}
@@ -299,6 +372,7 @@ func (a *Allocator) findBestPoolForService(
 	sharingKey, backendKey string,
 ) (*Allocation, error) {
 	var primaryAllocationCandidate, secondaryAllocationCandidate *Allocation
+	var quotaErr error
 	// By default, ipv4 has higher priority.
 	primaryIPFamily := ipfamily.IPv4
 	secondaryIPFamily := ipfamily.IPv6
@@ -322,6 +396,9 @@ func (a *Allocator) findBestPoolForService(
 		if primaryIP != nil && secondaryIP != nil {
 			return allocation, nil
 		}
+		if err := allocation.quotaErr(serviceIPFamily); err != nil && quotaErr == nil {
+			quotaErr = err
+		}
 
 		// at this stage, we should not take this pool into account if
 		// not in PreferDualStack policy.
@@ -342,6 +419,9 @@ func (a *Allocator) findBestPoolForService(
 	if secondaryAllocationCandidate != nil {
 		return secondaryAllocationCandidate, nil
 	}
+	if quotaErr != nil {
+		return nil, quotaErr
+	}
 	return nil, fmt.Errorf("no suitable pool for %s IPFamily", serviceIPFamily)
 }
 
@@ -366,12 +446,18 @@ func (a *Allocator) Allocate(
 		}
 		return alloc.ips, nil
 	}
-	// First, check the pinned pools to see if we can assign.
+	// Addresses reserved for the service come before any pool.
+	if ips := a.allocateReserved(a.pools.ByName, svcKey, svc, serviceIPFamily, ports, sharingKey, backendKey); ips != nil {
+		return ips, nil
+	}
+	// Then, check the pinned pools to see if we can assign.
 	pinnedPools := a.pinnedPoolsForService(svc)
 	ips, err := a.allocateFromPools(pinnedPools, svcKey, svc, serviceIPFamily, ports, sharingKey, backendKey)
 	if err == nil {
 		return ips, nil
 	}
+	var quotaErr *QuotaExceededError
+	errors.As(err, &quotaErr)
 
 	// No suitable IPs in pinnedPools, use all pools instead.
 	allPools := []*config.Pool{}
@@ -386,7 +472,11 @@ func (a *Allocator) Allocate(
 		return ips, nil
 	}
 
-	// We will reach here only if there is really no suitable IP.
+	// We will reach here only if there is really no suitable IP. Tell
+	// when it is because of a quota, as the user can act on it.
+	if quotaErr != nil || errors.As(err, &quotaErr) {
+		return nil, quotaErr
+	}
 	return nil, errors.New("no available IPs")
 }
 
@@ -448,20 +538,77 @@ func (a *Allocator) AllocateFromPool(
 		return nil, fmt.Errorf("unknown pool %q", poolName)
 	}
 
+	if ips := a.allocateReserved(map[string]*config.Pool{poolName: pool}, svcKey, svc, serviceIPFamily, ports, sharingKey, backendKey); ips != nil {
+		return ips, nil
+	}
+
 	poolIps := a.getFreeIPsFromPool(pool, svcKey, ports, sharingKey, backendKey)
 	ips, err := poolIps.selectIPsForFamilyAndPolicy(serviceIPFamily, serviceIPFamilyPolicy)
-	if err != nil {
-		return nil, err
+	if err == nil {
+		err = a.Assign(svcKey, svc, ips, ports, sharingKey, backendKey)
 	}
-
-	err = a.Assign(svcKey, svc, ips, ports, sharingKey, backendKey)
 	if err != nil {
+		if quotaErr := poolIps.quotaErr(serviceIPFamily); quotaErr != nil {
+			return nil, quotaErr
+		}
 		return nil, err
 	}
 
 	return ips, nil
 }
 
+// allocateReserved assigns to the service the addresses reserved for it in
+// pools, and returns them. Matching reservations are tried by name, the ones
+// whose addresses can't be assigned, for example because another service is
+// using them, are skipped. It returns nil when no reservation could be used.
+func (a *Allocator) allocateReserved(
+	pools map[string]*config.Pool,
+	svcKey string,
+	svc *v1.Service,
+	serviceIPFamily ipfamily.Family,
+	ports []Port,
+	sharingKey, backendKey string,
+) []net.IP {
+	var reservations []*config.Reservation
+	seen := map[*config.Reservation]bool{}
+	for _, pool := range pools {
+		for _, r := range pool.Reservations {
+			if seen[r] || !reservationMatches(r, svcKey, svc) {
+				continue
+			}
+			seen[r] = true
+			reservations = append(reservations, r)
+		}
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].Name < reservations[j].Name
+	})
+
+	serviceIPFamilyPolicy := ipPolicyForService(svc)
+	for _, r := range reservations {
+		allocation := &Allocation{}
+		for _, ip := range r.IPs {
+			allocation.setIPForFamily(ipfamily.ForAddress(ip), ip)
+		}
+		ips, err := allocation.selectIPsForFamilyAndPolicy(serviceIPFamily, serviceIPFamilyPolicy)
+		if err != nil {
+			continue
+		}
+		if err := a.Assign(svcKey, svc, ips, ports, sharingKey, backendKey); err == nil {
+			return ips
+		}
+	}
+	return nil
+}
+
+// reservationMatches tells if r binds its addresses to the service.
+func reservationMatches(r *config.Reservation, svcKey string, svc *v1.Service) bool {
+	if r.Service != "" {
+		return r.Service == svcKey
+	}
+	return svc != nil && r.ServiceSelector != nil && r.ServiceSelector.Matches(labels.Set(svc.Labels))
+}
+
 // AllocateIPFromPoolForAdditionalFamily works specially for the preferDualStack
 // ipfamily policy in case there is only 1 assigned ip. It tries to allocate an
 // additional ip from the missing family while retaining the ip already allocated to the svc.
@@ -489,6 +636,9 @@ func (a *Allocator) AllocateFromPoolForAdditionalFamily(
 	if err != nil {
 		return nil, err
 	}
+	if quotaErr := poolIps.quotaErr(additionalFamily); additionalIPs[0] == nil && quotaErr != nil {
+		return nil, quotaErr
+	}
 	newIps := []net.IP{existingIP, additionalIPs[0]}
 	err = a.Assign(svcKey, svc, newIps, ports, sharingKey, backendKey)
 	if err != nil {
@@ -625,29 +775,7 @@ func poolCount(p *config.Pool) (int64, int64, int64) {
 			ipv6 = math.MaxInt64
 			continue
 		}
-		sz := int64(math.Pow(2, float64(b-o)))
-
-		cur := ipaddr.NewCursor([]ipaddr.Prefix{*ipaddr.NewPrefix(cidr)})
-		firstIP := cur.First().IP
-		lastIP := cur.Last().IP
-
-		if p.AvoidBuggyIPs {
-			if o <= 24 {
-				// A pair of buggy IPs occur for each /24 present in the range.
-				buggies := int64(math.Pow(2, float64(24-o))) * 2
-				sz -= buggies
-			} else {
-				// Ranges smaller than /24 contain 1 buggy IP if they
-				// start/end on a /24 boundary, otherwise they contain
-				// none.
-				if ipConfusesBuggyFirmwares(firstIP) {
-					sz--
-				}
-				if ipConfusesBuggyFirmwares(lastIP) {
-					sz--
-				}
-			}
-		}
+		sz := cidrCount(cidr, p.AvoidBuggyIPs)
 		total += sz
 		if cidr.IP.To4() == nil {
 			ipv6 += sz
@@ -655,9 +783,54 @@ func poolCount(p *config.Pool) (int64, int64, int64) {
 			ipv4 += sz
 		}
 	}
+	for _, cidr := range p.ExcludedCIDR {
+		if cidr.IP.To4() == nil && ipv6 == math.MaxInt64 {
+			// The pool will never run out anyway.
+			continue
+		}
+		sz := cidrCount(cidr, p.AvoidBuggyIPs)
+		if cidr.IP.To4() == nil {
+			ipv6 -= sz
+		} else {
+			ipv4 -= sz
+		}
+		if total != math.MaxInt64 {
+			total -= sz
+		}
+	}
 	return total, ipv4, ipv6
 }
 
+// cidrCount returns the number of usable addresses in cidr, which must
+// contain less than 2^62 addresses.
+func cidrCount(cidr *net.IPNet, avoidBuggyIPs bool) int64 {
+	o, b := cidr.Mask.Size()
+	sz := int64(math.Pow(2, float64(b-o)))
+	if !avoidBuggyIPs || cidr.IP.To4() == nil {
+		return sz
+	}
+
+	cur := ipaddr.NewCursor([]ipaddr.Prefix{*ipaddr.NewPrefix(cidr)})
+	firstIP := cur.First().IP
+	lastIP := cur.Last().IP
+
+	if o <= 24 {
+		// A pair of buggy IPs occur for each /24 present in the range.
+		buggies := int64(math.Pow(2, float64(24-o))) * 2
+		return sz - buggies
+	}
+	// Ranges smaller than /24 contain 1 buggy IP if they
+	// start/end on a /24 boundary, otherwise they contain
+	// none.
+	if ipConfusesBuggyFirmwares(firstIP) {
+		sz--
+	}
+	if ipConfusesBuggyFirmwares(lastIP) {
+		sz--
+	}
+	return sz
+}
+
 // poolFor returns the pool that owns the requested IPs, or "" if none.
 func poolFor(pools map[string]*config.Pool, ips []net.IP) *config.Pool {
 	for _, p := range pools {
@@ -680,6 +853,16 @@ func poolFor(pools map[string]*config.Pool, ips []net.IP) *config.Pool {
 	return nil
 }
 
+// ipExcluded tells if ip is one of the excluded addresses of the pool.
+func ipExcluded(pool *config.Pool, ip net.IP) bool {
+	for _, cidr := range pool.ExcludedCIDR {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // ipConfusesBuggyFirmwares returns true if ip is an IPv4 address ending in 0 or 255.
 //
 // Such addresses can confuse smurf protection on crappy CPE
@@ -692,24 +875,177 @@ func ipConfusesBuggyFirmwares(ip net.IP) bool {
 	return ip[3] == 0 || ip[3] == 255
 }
 
-func (a *Allocator) getIPFromCIDR(cidr *net.IPNet, avoidBuggyIPs bool, svc string, ports []Port, sharingKey, backendKey string) net.IP {
+// getIPFromCIDR returns an address of cidr that svc can use, picked with
+// the allocation strategy of the pool, or nil if there is none.
+func (a *Allocator) getIPFromCIDR(pool *config.Pool, cidr *net.IPNet, svc string, ports []Port, sharingKey, backendKey string) net.IP {
 	sk := &key{
 		sharing: sharingKey,
 		backend: backendKey,
 	}
+	usable := func(ip net.IP) bool {
+		if pool.AvoidBuggyIPs && ipConfusesBuggyFirmwares(ip) {
+			return false
+		}
+		// Reserved addresses are only given through their reservation.
+		if _, ok := pool.Reservations[ip.String()]; ok {
+			return false
+		}
+		if ipExcluded(pool, ip) {
+			return false
+		}
+		return a.checkSharing(svc, ip.String(), ports, sk) == nil
+	}
+
+	switch pool.AllocationStrategy {
+	case config.RandomAllocation:
+		return firstUsableIP(cidr, new(big.Int).SetUint64(rand.Uint64()), usable)
+	case config.HashedAllocation:
+		h := fnv.New64a()
+		h.Write([]byte(svc))
+		return firstUsableIP(cidr, new(big.Int).SetUint64(h.Sum64()), usable)
+	case config.LeastRecentlyReleasedAllocation:
+		return a.leastRecentlyReleasedIP(pool, cidr, svc, usable)
+	}
+	return firstUsableIP(cidr, big.NewInt(0), usable)
+}
+
+// firstUsableIP returns the first usable address of cidr, starting at the
+// given offset from the first address (modulo the size of the cidr) and
+// wrapping around.
+func firstUsableIP(cidr *net.IPNet, offset *big.Int, usable func(net.IP) bool) net.IP {
+	prefix := ipaddr.NewPrefix(cidr)
+	c := ipaddr.NewCursor([]ipaddr.Prefix{*prefix})
+	start := ipAtOffset(cidr, offset)
+	if err := c.Set(&ipaddr.Position{IP: start, Prefix: *prefix}); err != nil {
+		start = c.First().IP
+	}
+	for pos := c.Pos(); pos != nil; pos = c.Next() {
+		if usable(pos.IP) {
+			return pos.IP
+		}
+	}
+	c.Reset(nil)
+	for pos := c.Pos(); pos != nil && !pos.IP.Equal(start); pos = c.Next() {
+		if usable(pos.IP) {
+			return pos.IP
+		}
+	}
+	return nil
+}
+
+// ipAtOffset returns the address of cidr at offset from its first address,
+// modulo the size of cidr.
+func ipAtOffset(cidr *net.IPNet, offset *big.Int) net.IP {
+	first := cidr.IP.Mask(cidr.Mask)
+	ones, bits := cidr.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	n := new(big.Int).SetBytes(first)
+	n.Add(n, new(big.Int).Mod(offset, size))
+	return n.FillBytes(make(net.IP, len(first)))
+}
+
+// leastRecentlyReleasedIP returns the usable address of cidr released the
+// longest time ago, preferring the ones never released and the ones
+// released by svc itself. Addresses released by other services less than
+// the quarantine time of the pool ago are skipped.
+func (a *Allocator) leastRecentlyReleasedIP(pool *config.Pool, cidr *net.IPNet, svc string, usable func(net.IP) bool) net.IP {
+	var (
+		oldest   net.IP
+		oldestAt time.Time
+	)
+	now := a.now()
 	c := ipaddr.NewCursor([]ipaddr.Prefix{*ipaddr.NewPrefix(cidr)})
 	for pos := c.First(); pos != nil; pos = c.Next() {
-		if avoidBuggyIPs && ipConfusesBuggyFirmwares(pos.IP) {
+		if !usable(pos.IP) {
 			continue
 		}
-		if a.checkSharing(svc, pos.IP.String(), ports, sk) != nil {
+		r, ok := a.released[pos.IP.String()]
+		if !ok || r.svc == svc {
+			return pos.IP
+		}
+		if now.Sub(r.at) < pool.QuarantineTime {
 			continue
 		}
-		return pos.IP
+		if oldest == nil || r.at.Before(oldestAt) {
+			oldest, oldestAt = pos.IP, r.at
+		}
+	}
+	return oldest
+}
+
+// checkQuota returns a QuotaExceededError if giving ips of pool to the
+// service would make its namespace use more addresses of the pool than its
+// quota allows. The addresses already used by the namespace are not counted
+// twice, and the ones in the status of the service are not checked, so that
+// lowering a quota never takes addresses away from existing services.
+func (a *Allocator) checkQuota(pool *config.Pool, svcKey string, svc *v1.Service, ips []net.IP) error {
+	namespace := namespaceOf(svcKey)
+	quota := pool.Quotas[namespace]
+	if quota == nil {
+		return nil
+	}
+	held := map[string]bool{}
+	if svc != nil {
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			held[ingress.IP] = true
+		}
+	}
+
+	used := a.namespaceIPs(pool.Name, namespace, svcKey)
+	added := map[ipfamily.Family]bool{}
+	for _, ip := range ips {
+		if ip == nil || used[ip.String()] != nil || held[ip.String()] {
+			continue
+		}
+		used[ip.String()] = ip
+		added[ipfamily.ForAddress(ip)] = true
+	}
+	ipv4, ipv6 := countByFamily(used)
+	if added[ipfamily.IPv4] && quota.MaxIPv4 != nil && ipv4 > *quota.MaxIPv4 {
+		return &QuotaExceededError{Pool: pool.Name, Namespace: namespace, Family: ipfamily.IPv4, Max: *quota.MaxIPv4}
+	}
+	if added[ipfamily.IPv6] && quota.MaxIPv6 != nil && ipv6 > *quota.MaxIPv6 {
+		return &QuotaExceededError{Pool: pool.Name, Namespace: namespace, Family: ipfamily.IPv6, Max: *quota.MaxIPv6}
 	}
 	return nil
 }
 
+// namespaceIPs returns the addresses of the pool assigned to the services
+// of namespace, except the service with key exclude.
+func (a *Allocator) namespaceIPs(pool, namespace, exclude string) map[string]net.IP {
+	res := map[string]net.IP{}
+	for svc, alloc := range a.allocated {
+		if svc == exclude || alloc.pool != pool || namespaceOf(svc) != namespace {
+			continue
+		}
+		for _, ip := range alloc.ips {
+			res[ip.String()] = ip
+		}
+	}
+	return res
+}
+
+// namespaceOf returns the namespace part of a service key.
+func namespaceOf(svcKey string) string {
+	namespace, _, found := strings.Cut(svcKey, "/")
+	if !found {
+		return ""
+	}
+	return namespace
+}
+
+func countByFamily(ips map[string]net.IP) (int64, int64) {
+	var ipv4, ipv6 int64
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			ipv4++
+		} else {
+			ipv6++
+		}
+	}
+	return ipv4, ipv6
+}
+
 func (a *Allocator) checkSharing(svc string, ip string, ports []Port, sk *key) error {
 	if existingSK := a.sharingKeyForIP[ip]; existingSK != nil {
 		if err := sharingOK(existingSK, sk); err != nil {
@@ -755,10 +1091,77 @@ func (a *Allocator) updatePoolStats(p *config.Pool) {
 	stats.poolActive.WithLabelValues(p.Name).Set(float64(len(a.poolIPsInUse[p.Name])))
 	stats.ipv4PoolActive.WithLabelValues(p.Name).Set(float64(len(a.poolIPV4InUse[p.Name])))
 	stats.ipv6PoolActive.WithLabelValues(p.Name).Set(float64(len(a.poolIPV6InUse[p.Name])))
+	// Reserved addresses not in use are not available to other services.
+	var reservedIPv4, reservedIPv6, idleReservedIPv4, idleReservedIPv6 int64
+	for ip := range p.Reservations {
+		_, inUse := a.poolIPsInUse[p.Name][ip]
+		if net.ParseIP(ip).To4() != nil {
+			reservedIPv4++
+			if !inUse {
+				idleReservedIPv4++
+			}
+			continue
+		}
+		reservedIPv6++
+		if !inUse {
+			idleReservedIPv6++
+		}
+	}
+	// Excluded addresses requested explicitly are in use, but are not
+	// part of the available ones.
+	var excludedIPv4InUse, excludedIPv6InUse int64
+	if len(p.ExcludedCIDR) > 0 {
+		for ip := range a.poolIPsInUse[p.Name] {
+			addr := net.ParseIP(ip)
+			if !ipExcluded(p, addr) {
+				continue
+			}
+			if addr.To4() != nil {
+				excludedIPv4InUse++
+				continue
+			}
+			excludedIPv6InUse++
+		}
+	}
+
+	// Addresses shared by services of the same namespace count once.
+	namespaceIPs := map[string]map[string]net.IP{}
+	for svc, alloc := range a.allocated {
+		if alloc.pool != p.Name {
+			continue
+		}
+		namespace := namespaceOf(svc)
+		if namespaceIPs[namespace] == nil {
+			namespaceIPs[namespace] = map[string]net.IP{}
+		}
+		for _, ip := range alloc.ips {
+			namespaceIPs[namespace][ip.String()] = ip
+		}
+	}
+	var namespaces map[string]NamespaceCounters
+	stats.ipv4NamespaceActive.DeletePartialMatch(prometheus.Labels{"pool": p.Name})
+	stats.ipv6NamespaceActive.DeletePartialMatch(prometheus.Labels{"pool": p.Name})
+	for namespace, ips := range namespaceIPs {
+		if namespaces == nil {
+			namespaces = map[string]NamespaceCounters{}
+		}
+		c := NamespaceCounters{}
+		c.AssignedIPv4, c.AssignedIPv6 = countByFamily(ips)
+		if quota := p.Quotas[namespace]; quota != nil {
+			c.MaxIPv4, c.MaxIPv6 = quota.MaxIPv4, quota.MaxIPv6
+		}
+		namespaces[namespace] = c
+		stats.ipv4NamespaceActive.WithLabelValues(p.Name, namespace).Set(float64(c.AssignedIPv4))
+		stats.ipv6NamespaceActive.WithLabelValues(p.Name, namespace).Set(float64(c.AssignedIPv6))
+	}
+
 	a.poolToCounters[p.Name] = PoolCounters{
-		AvailableIPv4: ipv4 - int64(len(a.poolIPV4InUse[p.Name])),
-		AvailableIPv6: ipv6 - int64(len(a.poolIPV6InUse[p.Name])),
+		AvailableIPv4: ipv4 - int64(len(a.poolIPV4InUse[p.Name])) + excludedIPv4InUse - idleReservedIPv4,
+		AvailableIPv6: ipv6 - int64(len(a.poolIPV6InUse[p.Name])) + excludedIPv6InUse - idleReservedIPv6,
 		AssignedIPv4:  int64(len(a.poolIPV4InUse[p.Name])),
 		AssignedIPv6:  int64(len(a.poolIPV6InUse[p.Name])),
+		ReservedIPv4:  reservedIPv4,
+		ReservedIPv6:  reservedIPv6,
+		Namespaces:    namespaces,
 	}
 }
@@ -3,6 +3,7 @@
 package allocator
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -10,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"go.universe.tf/metallb/internal/config"
 	"go.universe.tf/metallb/internal/ipfamily"
@@ -18,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	ptu "github.com/prometheus/client_golang/prometheus/testutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
@@ -2048,6 +2051,438 @@ func TestAutoAssign(t *testing.T) {
 	}
 }
 
+func TestSequentialAllocation(t *testing.T) {
+	alloc := New(noopCallback)
+	alloc.SetPools(&config.Pools{ByName: map[string]*config.Pool{
+		"test": {
+			Name:       "test",
+			AutoAssign: true,
+			CIDR:       []*net.IPNet{ipnet("1.2.3.0/30")},
+		},
+	}})
+
+	mustAllocate(t, alloc, "s1", "1.2.3.0")
+	mustAllocate(t, alloc, "s2", "1.2.3.1")
+	alloc.Unassign("s1")
+	// The first free address is used again right away.
+	mustAllocate(t, alloc, "s3", "1.2.3.0")
+}
+
+func TestRandomAllocation(t *testing.T) {
+	alloc := New(noopCallback)
+	alloc.SetPools(&config.Pools{ByName: map[string]*config.Pool{
+		"test": {
+			Name:               "test",
+			AutoAssign:         true,
+			AvoidBuggyIPs:      true,
+			CIDR:               []*net.IPNet{ipnet("1.2.3.0/24")},
+			AllocationStrategy: config.RandomAllocation,
+		},
+		"small": {
+			Name:               "small",
+			AutoAssign:         false,
+			CIDR:               []*net.IPNet{ipnet("1.2.4.0/31")},
+			AllocationStrategy: config.RandomAllocation,
+		},
+	}})
+
+	seen := map[string]bool{}
+	sequential := true
+	for i := range 20 {
+		ips, err := alloc.Allocate(fmt.Sprintf("s%d", i), svc, ipfamily.IPv4, nil, "", "")
+		if err != nil {
+			t.Fatalf("Allocate(s%d): %s", i, err)
+		}
+		ip := ips[0]
+		if !ipnet("1.2.3.0/24").Contains(ip) || ipConfusesBuggyFirmwares(ip) {
+			t.Fatalf("s%d got unexpected IP %s", i, ip)
+		}
+		if seen[ip.String()] {
+			t.Fatalf("s%d got IP %s, already allocated", i, ip)
+		}
+		seen[ip.String()] = true
+		if ip.To4()[3] != byte(i+1) {
+			sequential = false
+		}
+	}
+	if sequential {
+		t.Fatalf("random allocation picked the addresses sequentially")
+	}
+
+	// Every address is found, even when the pool is almost full.
+	for i := range 2 {
+		if _, err := alloc.AllocateFromPool(fmt.Sprintf("small%d", i), svc, ipfamily.IPv4, "small", nil, "", ""); err != nil {
+			t.Fatalf("AllocateFromPool(small%d): %s", i, err)
+		}
+	}
+	if _, err := alloc.AllocateFromPool("small2", svc, ipfamily.IPv4, "small", nil, "", ""); err == nil {
+		t.Fatalf("AllocateFromPool(small2) succeeded on a full pool")
+	}
+}
+
+func TestLeastRecentlyReleasedAllocation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newAllocator := func(quarantine time.Duration) *Allocator {
+		alloc := New(noopCallback)
+		alloc.now = func() time.Time { return now }
+		alloc.SetPools(&config.Pools{ByName: map[string]*config.Pool{
+			"test": {
+				Name:               "test",
+				AutoAssign:         true,
+				CIDR:               []*net.IPNet{ipnet("1.2.3.0/30")},
+				AllocationStrategy: config.LeastRecentlyReleasedAllocation,
+				QuarantineTime:     quarantine,
+			},
+		}})
+		return alloc
+	}
+
+	t.Run("oldest release first", func(t *testing.T) {
+		alloc := newAllocator(0)
+		mustAllocate(t, alloc, "s1", "1.2.3.0")
+		mustAllocate(t, alloc, "s2", "1.2.3.1")
+		mustAllocate(t, alloc, "s3", "1.2.3.2")
+		alloc.Unassign("s2")
+		now = now.Add(time.Minute)
+		alloc.Unassign("s1")
+		now = now.Add(time.Minute)
+		// Addresses never used come first, then the ones released
+		// the longest time ago.
+		mustAllocate(t, alloc, "s4", "1.2.3.3")
+		mustAllocate(t, alloc, "s5", "1.2.3.1")
+		mustAllocate(t, alloc, "s6", "1.2.3.0")
+	})
+
+	t.Run("quarantine", func(t *testing.T) {
+		alloc := newAllocator(10 * time.Minute)
+		for i := range 4 {
+			mustAllocate(t, alloc, fmt.Sprintf("s%d", i), fmt.Sprintf("1.2.3.%d", i))
+		}
+		alloc.Unassign("s0")
+		now = now.Add(5 * time.Minute)
+		if ips, err := alloc.Allocate("s4", svc, ipfamily.IPv4, nil, "", ""); err == nil {
+			t.Fatalf("Allocate(s4) got quarantined IP %s", ips)
+		}
+		// The service that released the address can get it back.
+		mustAllocate(t, alloc, "s0", "1.2.3.0")
+		alloc.Unassign("s0")
+		now = now.Add(11 * time.Minute)
+		mustAllocate(t, alloc, "s4", "1.2.3.0")
+	})
+}
+
+func TestHashedAllocation(t *testing.T) {
+	pools := func() *config.Pools {
+		return &config.Pools{ByName: map[string]*config.Pool{
+			"test": {
+				Name:               "test",
+				AutoAssign:         true,
+				CIDR:               []*net.IPNet{ipnet("1.2.3.0/24"), ipnet("1000::/64")},
+				AllocationStrategy: config.HashedAllocation,
+			},
+		}}
+	}
+
+	alloc := New(noopCallback)
+	alloc.SetPools(pools())
+	ips, err := alloc.Allocate("ns/a", svcRequireDualStack, ipfamily.DualStack, nil, "", "")
+	if err != nil {
+		t.Fatalf("Allocate(ns/a): %s", err)
+	}
+	if len(ips) != 2 || ips[0].Equal(net.ParseIP("1.2.3.0")) || ips[1].Equal(net.ParseIP("1000::")) {
+		t.Fatalf("Allocate(ns/a) got %s, want addresses from the hash", ips)
+	}
+	if !ipnet("1000::/64").Contains(ips[1]) {
+		t.Fatalf("Allocate(ns/a) got %s, out of the pool", ips[1])
+	}
+
+	// The service gets the same addresses back once recreated, whatever
+	// the order of the allocations.
+	alloc.Unassign("ns/a")
+	for _, svcKey := range []string{"ns/b", "ns/c", "other/a"} {
+		if _, err := alloc.Allocate(svcKey, svcRequireDualStack, ipfamily.DualStack, nil, "", ""); err != nil {
+			t.Fatalf("Allocate(%s): %s", svcKey, err)
+		}
+	}
+	again, err := alloc.Allocate("ns/a", svcRequireDualStack, ipfamily.DualStack, nil, "", "")
+	if err != nil {
+		t.Fatalf("Allocate(ns/a): %s", err)
+	}
+	if diff := cmp.Diff(ips, again); diff != "" {
+		t.Fatalf("ns/a got different addresses once recreated (-want +got)\n%s", diff)
+	}
+
+	other := New(noopCallback)
+	other.SetPools(pools())
+	got, err := other.Allocate("ns/a", svcRequireDualStack, ipfamily.DualStack, nil, "", "")
+	if err != nil {
+		t.Fatalf("Allocate(ns/a): %s", err)
+	}
+	if diff := cmp.Diff(ips, got); diff != "" {
+		t.Fatalf("ns/a got different addresses from another allocator (-want +got)\n%s", diff)
+	}
+
+	// The next free address is used when the hashed one is taken.
+	if err := alloc.Assign("ns/d", svc, []net.IP{net.ParseIP("1.2.3.4")}, nil, "", ""); err != nil {
+		t.Fatalf("Assign(ns/d): %s", err)
+	}
+	alloc.Unassign("ns/a")
+	if err := alloc.Assign("ns/e", svc, []net.IP{ips[0]}, nil, "", ""); err != nil {
+		t.Fatalf("Assign(ns/e): %s", err)
+	}
+	ipv4, err := alloc.Allocate("ns/a", svc, ipfamily.IPv4, nil, "", "")
+	if err != nil {
+		t.Fatalf("Allocate(ns/a): %s", err)
+	}
+	if ipv4[0].Equal(ips[0]) || !ipnet("1.2.3.0/24").Contains(ipv4[0]) {
+		t.Fatalf("Allocate(ns/a) got %s, want another address of the pool", ipv4[0])
+	}
+}
+
+func TestReservations(t *testing.T) {
+	byName := &config.Reservation{
+		Name:    "by-name",
+		IPs:     []net.IP{net.ParseIP("1.2.3.1"), net.ParseIP("1000::1")},
+		Service: "ns/reserved",
+	}
+	byLabel := &config.Reservation{
+		Name:            "by-label",
+		IPs:             []net.IP{net.ParseIP("1.2.3.2")},
+		ServiceSelector: selector("app=web"),
+	}
+	alloc := New(noopCallback)
+	alloc.SetPools(&config.Pools{ByName: map[string]*config.Pool{
+		"test": {
+			Name:       "test",
+			AutoAssign: true,
+			CIDR:       []*net.IPNet{ipnet("1.2.3.0/30"), ipnet("1000::/126")},
+			Reservations: map[string]*config.Reservation{
+				"1.2.3.1": byName,
+				"1000::1": byName,
+				"1.2.3.2": byLabel,
+			},
+		},
+	}})
+
+	wantCounters := PoolCounters{
+		AvailableIPv4: 2,
+		AvailableIPv6: 3,
+		ReservedIPv4:  2,
+		ReservedIPv6:  1,
+	}
+	if diff := cmp.Diff(wantCounters, alloc.CountersForPool("test"), cmpopts.IgnoreFields(PoolCounters{}, "Namespaces")); diff != "" {
+		t.Fatalf("unexpected counters (-want +got)\n%s", diff)
+	}
+
+	// Reserved addresses are skipped by automatic allocation.
+	mustAllocate(t, alloc, "ns/other1", "1.2.3.0")
+	mustAllocate(t, alloc, "ns/other2", "1.2.3.3")
+	if ips, err := alloc.Allocate("ns/other3", svc, ipfamily.IPv4, nil, "", ""); err == nil {
+		t.Fatalf("Allocate(ns/other3) got reserved IP %s", ips)
+	}
+	if err := alloc.Assign("ns/other3", svc, []net.IP{net.ParseIP("1.2.3.1")}, nil, "", ""); err == nil {
+		t.Fatalf("Assign(ns/other3) succeeded with a reserved IP")
+	}
+
+	// The services bound by the reservations get their addresses.
+	ips, err := alloc.Allocate("ns/reserved", svcRequireDualStack, ipfamily.DualStack, nil, "", "")
+	if err != nil {
+		t.Fatalf("Allocate(ns/reserved): %s", err)
+	}
+	if diff := cmp.Diff([]net.IP{net.ParseIP("1.2.3.1"), net.ParseIP("1000::1")}, ips); diff != "" {
+		t.Fatalf("unexpected IPs for ns/reserved (-want +got)\n%s", diff)
+	}
+	web := svc.DeepCopy()
+	web.Labels = map[string]string{"app": "web"}
+	ips, err = alloc.AllocateFromPool("ns/web", web, ipfamily.IPv4, "test", nil, "", "")
+	if err != nil {
+		t.Fatalf("AllocateFromPool(ns/web): %s", err)
+	}
+	if !ips[0].Equal(net.ParseIP("1.2.3.2")) {
+		t.Fatalf("AllocateFromPool(ns/web) got %s, want 1.2.3.2", ips)
+	}
+
+	wantCounters = PoolCounters{
+		AssignedIPv4:  4,
+		AssignedIPv6:  1,
+		AvailableIPv4: 0,
+		AvailableIPv6: 3,
+		ReservedIPv4:  2,
+		ReservedIPv6:  1,
+	}
+	if diff := cmp.Diff(wantCounters, alloc.CountersForPool("test"), cmpopts.IgnoreFields(PoolCounters{}, "Namespaces")); diff != "" {
+		t.Fatalf("unexpected counters (-want +got)\n%s", diff)
+	}
+
+	// Once recreated, the service gets the same addresses back even if
+	// other services were allocated in the meantime.
+	alloc.Unassign("ns/reserved")
+	alloc.Unassign("ns/other1")
+	if ips, err := alloc.Allocate("ns/other3", svcRequireDualStack, ipfamily.DualStack, nil, "", ""); err != nil {
+		t.Fatalf("Allocate(ns/other3): %s", err)
+	} else if ips[0].Equal(net.ParseIP("1.2.3.1")) || ips[1].Equal(net.ParseIP("1000::1")) {
+		t.Fatalf("Allocate(ns/other3) got reserved IPs %s", ips)
+	}
+	ips, err = alloc.Allocate("ns/reserved", svc, ipfamily.IPv4, nil, "", "")
+	if err != nil {
+		t.Fatalf("Allocate(ns/reserved): %s", err)
+	}
+	if !ips[0].Equal(net.ParseIP("1.2.3.1")) {
+		t.Fatalf("Allocate(ns/reserved) got %s, want 1.2.3.1", ips)
+	}
+
+	// A service matching a reservation whose addresses are taken gets
+	// another address.
+	web2 := web.DeepCopy()
+	web2.Name = "web2"
+	alloc.Unassign("ns/other2")
+	ips, err = alloc.Allocate("ns/web2", web2, ipfamily.IPv4, nil, "", "")
+	if err != nil {
+		t.Fatalf("Allocate(ns/web2): %s", err)
+	}
+	if !ips[0].Equal(net.ParseIP("1.2.3.3")) {
+		t.Fatalf("Allocate(ns/web2) got %s, want 1.2.3.3", ips)
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	alloc := New(noopCallback)
+	alloc.SetPools(&config.Pools{ByName: map[string]*config.Pool{
+		"test": {
+			Name:       "test",
+			AutoAssign: true,
+			CIDR:       []*net.IPNet{ipnet("1.2.3.0/29"), ipnet("1000::/125")},
+			Quotas: map[string]*config.Quota{
+				"team-a": {MaxIPv4: ptr.To[int64](2), MaxIPv6: ptr.To[int64](1)},
+				"team-b": {MaxIPv4: ptr.To[int64](0)},
+			},
+		},
+	}})
+
+	mustBeOverQuota := func(err error, family ipfamily.Family) {
+		t.Helper()
+		var quotaErr *QuotaExceededError
+		if !errors.As(err, &quotaErr) {
+			t.Fatalf("expected a quota error, got %v", err)
+		}
+		if quotaErr.Pool != "test" || quotaErr.Family != family {
+			t.Fatalf("unexpected quota error %v", err)
+		}
+	}
+
+	if _, err := alloc.Allocate("team-a/s1", svc, ipfamily.IPv4, nil, "share", ""); err != nil {
+		t.Fatalf("Allocate(team-a/s1): %s", err)
+	}
+	mustAllocate(t, alloc, "team-a/s2", "1.2.3.1")
+	_, err := alloc.Allocate("team-a/s3", svc, ipfamily.IPv4, nil, "", "")
+	mustBeOverQuota(err, ipfamily.IPv4)
+	_, err = alloc.AllocateFromPool("team-a/s3", svc, ipfamily.IPv4, "test", nil, "", "")
+	mustBeOverQuota(err, ipfamily.IPv4)
+	err = alloc.Assign("team-a/s3", svc, []net.IP{net.ParseIP("1.2.3.7")}, nil, "", "")
+	mustBeOverQuota(err, ipfamily.IPv4)
+	_, err = alloc.Allocate("team-a/s3", svcRequireDualStack, ipfamily.DualStack, nil, "", "")
+	mustBeOverQuota(err, ipfamily.IPv4)
+	_, err = alloc.Allocate("team-b/s1", svc, ipfamily.IPv4, nil, "", "")
+	mustBeOverQuota(err, ipfamily.IPv4)
+
+	// Other namespaces are not limited, and the IPv6 quota is separate.
+	mustAllocate(t, alloc, "team-c/s1", "1.2.3.2")
+	if _, err := alloc.Allocate("team-a/s4", svc, ipfamily.IPv6, nil, "", ""); err != nil {
+		t.Fatalf("Allocate(team-a/s4): %s", err)
+	}
+
+	// An address shared in the namespace counts once.
+	if err := alloc.Assign("team-a/s3", svc, []net.IP{net.ParseIP("1.2.3.0")}, nil, "share", ""); err != nil {
+		t.Fatalf("Assign(team-a/s3): %s", err)
+	}
+
+	// Addresses the service already has are kept when over the quota.
+	held := svc.DeepCopy()
+	held.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.2.3.6"}}
+	if err := alloc.Assign("team-a/s5", held, []net.IP{net.ParseIP("1.2.3.6")}, nil, "", ""); err != nil {
+		t.Fatalf("Assign(team-a/s5): %s", err)
+	}
+
+	want := map[string]NamespaceCounters{
+		"team-a": {AssignedIPv4: 3, AssignedIPv6: 1, MaxIPv4: ptr.To[int64](2), MaxIPv6: ptr.To[int64](1)},
+		"team-c": {AssignedIPv4: 1},
+	}
+	if diff := cmp.Diff(want, alloc.CountersForPool("test").Namespaces); diff != "" {
+		t.Fatalf("unexpected namespace counters (-want +got)\n%s", diff)
+	}
+	value := ptu.ToFloat64(stats.ipv4NamespaceActive.WithLabelValues("test", "team-a"))
+	if value != 3 {
+		t.Fatalf("unexpected namespace_ipv4_addresses_in_use_total %f, expected 3", value)
+	}
+
+	// The namespace gets addresses again once below its quota.
+	alloc.Unassign("team-a/s2")
+	_, err = alloc.Allocate("team-a/s6", svc, ipfamily.IPv4, nil, "", "")
+	mustBeOverQuota(err, ipfamily.IPv4)
+	alloc.Unassign("team-a/s5")
+	mustAllocate(t, alloc, "team-a/s6", "1.2.3.1")
+}
+
+func TestExcludedAddresses(t *testing.T) {
+	alloc := New(noopCallback)
+	alloc.SetPools(&config.Pools{ByName: map[string]*config.Pool{
+		"test": {
+			Name:         "test",
+			AutoAssign:   true,
+			CIDR:         []*net.IPNet{ipnet("1.2.3.0/29")},
+			ExcludedCIDR: []*net.IPNet{ipnet("1.2.3.1/32"), ipnet("1.2.3.4/31")},
+		},
+	}})
+
+	wantCounters := PoolCounters{AvailableIPv4: 5}
+	if diff := cmp.Diff(wantCounters, alloc.CountersForPool("test"), cmpopts.IgnoreFields(PoolCounters{}, "Namespaces")); diff != "" {
+		t.Fatalf("unexpected counters (-want +got)\n%s", diff)
+	}
+
+	// Excluded addresses are skipped by automatic allocation.
+	mustAllocate(t, alloc, "ns/s1", "1.2.3.0")
+	mustAllocate(t, alloc, "ns/s2", "1.2.3.2")
+	mustAllocate(t, alloc, "ns/s3", "1.2.3.3")
+	mustAllocate(t, alloc, "ns/s4", "1.2.3.6")
+	if err := alloc.Assign("ns/s5", svc, []net.IP{net.ParseIP("1.2.3.4")}, nil, "", ""); err == nil {
+		t.Fatalf("Assign(ns/s5) succeeded with an excluded IP")
+	}
+
+	// They can be requested explicitly when the pool allows it.
+	alloc.SetPools(&config.Pools{ByName: map[string]*config.Pool{
+		"test": {
+			Name:                  "test",
+			AutoAssign:            true,
+			CIDR:                  []*net.IPNet{ipnet("1.2.3.0/29")},
+			ExcludedCIDR:          []*net.IPNet{ipnet("1.2.3.1/32"), ipnet("1.2.3.4/31")},
+			AllowExplicitExcluded: true,
+		},
+	}})
+	if err := alloc.Assign("ns/s5", svc, []net.IP{net.ParseIP("1.2.3.4")}, nil, "", ""); err != nil {
+		t.Fatalf("Assign(ns/s5): %s", err)
+	}
+	mustAllocate(t, alloc, "ns/s6", "1.2.3.7")
+	if ips, err := alloc.Allocate("ns/s7", svc, ipfamily.IPv4, nil, "", ""); err == nil {
+		t.Fatalf("Allocate(ns/s7) got excluded IP %s", ips)
+	}
+
+	wantCounters = PoolCounters{AssignedIPv4: 6}
+	if diff := cmp.Diff(wantCounters, alloc.CountersForPool("test"), cmpopts.IgnoreFields(PoolCounters{}, "Namespaces")); diff != "" {
+		t.Fatalf("unexpected counters (-want +got)\n%s", diff)
+	}
+}
+
+func mustAllocate(t *testing.T, alloc *Allocator, svcKey, want string) {
+	t.Helper()
+	ips, err := alloc.Allocate(svcKey, svc, ipfamily.IPv4, nil, "", "")
+	if err != nil {
+		t.Fatalf("Allocate(%s): %s", svcKey, err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP(want)) {
+		t.Fatalf("Allocate(%s) got %s, want %s", svcKey, ips, want)
+	}
+}
+
 func TestPoolCount(t *testing.T) {
 	tests := []struct {
 		desc string
@@ -2104,6 +2539,27 @@ func TestPoolCount(t *testing.T) {
 			ipv4: 1,
 			ipv6: 2,
 		},
+		{
+			desc: "excluded addresses, no buggy IPs",
+			pool: &config.Pool{
+				CIDR:          []*net.IPNet{ipnet("1.2.3.0/24"), ipnet("1000::/120")},
+				ExcludedCIDR:  []*net.IPNet{ipnet("1.2.3.0/30"), ipnet("1.2.3.8/30"), ipnet("1000::1/128")},
+				AvoidBuggyIPs: true,
+			},
+			want: 502,
+			ipv4: 247,
+			ipv6: 255,
+		},
+		{
+			desc: "excluded addresses in a BIG ipv6 range",
+			pool: &config.Pool{
+				CIDR:         []*net.IPNet{ipnet("1.2.3.0/24"), ipnet("1000::/64")},
+				ExcludedCIDR: []*net.IPNet{ipnet("1.2.3.1/32"), ipnet("1000::/120")},
+			},
+			want: math.MaxInt64,
+			ipv4: 255,
+			ipv6: math.MaxInt64,
+		},
 	}
 
 	for _, test := range tests {
@@ -2358,6 +2814,8 @@ func compareIPs(ips1, ips2 []string) bool {
 
 //nolint:unparam
 func validateCounters(c PoolCounters, avIPv4, avIPv6, asIPv4, asIPv6 int64) error {
+	// The usage per namespace is covered by TestQuotas.
+	c.Namespaces = nil
 	n := PoolCounters{
 		AvailableIPv4: avIPv4,
 		AvailableIPv6: avIPv6,
@@ -12,6 +12,9 @@ var stats = struct {
 	ipv4PoolActive   *prometheus.GaugeVec
 	ipv6PoolActive   *prometheus.GaugeVec
 	poolAllocated    *prometheus.GaugeVec
+
+	ipv4NamespaceActive *prometheus.GaugeVec
+	ipv6NamespaceActive *prometheus.GaugeVec
 }{
 	poolCapacity: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "metallb",
@@ -69,6 +72,24 @@ var stats = struct {
 	}, []string{
 		"pool",
 	}),
+	ipv4NamespaceActive: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "metallb",
+		Subsystem: "allocator",
+		Name:      "namespace_ipv4_addresses_in_use_total",
+		Help:      "Number of IPV4 addresses in use by the services of a namespace, per pool",
+	}, []string{
+		"pool",
+		"namespace",
+	}),
+	ipv6NamespaceActive: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "metallb",
+		Subsystem: "allocator",
+		Name:      "namespace_ipv6_addresses_in_use_total",
+		Help:      "Number of IPV6 addresses in use by the services of a namespace, per pool",
+	}, []string{
+		"pool",
+		"namespace",
+	}),
 }
 
 func deleteStatsFor(pool string) {
@@ -79,6 +100,8 @@ func deleteStatsFor(pool string) {
 	stats.poolAllocated.DeleteLabelValues(pool)
 	stats.ipv4PoolActive.DeleteLabelValues(pool)
 	stats.ipv6PoolActive.DeleteLabelValues(pool)
+	stats.ipv4NamespaceActive.DeletePartialMatch(prometheus.Labels{"pool": pool})
+	stats.ipv6NamespaceActive.DeletePartialMatch(prometheus.Labels{"pool": pool})
 }
 
 func init() {
@@ -89,4 +112,6 @@ func init() {
 	prometheus.MustRegister(stats.ipv4PoolActive)
 	prometheus.MustRegister(stats.ipv6PoolActive)
 	prometheus.MustRegister(stats.poolAllocated)
+	prometheus.MustRegister(stats.ipv4NamespaceActive)
+	prometheus.MustRegister(stats.ipv6NamespaceActive)
 }
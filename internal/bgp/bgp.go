@@ -21,6 +21,10 @@ type Advertisement struct {
 	// The local preference of this route. Only propagated to IBGP
 	// peers (i.e. where the peer ASN matches the local ASN).
 	LocalPref uint32
+	// The multi-exit discriminator of this route, not sent when nil.
+	MED *uint32
+	// How many more times the local ASN is prepended to the AS path.
+	ASPathPrepend uint32
 	// BGP communities to attach to the path.
 	Communities []community.BGPCommunity
 	// Used to declare the intent of announcing IPs
@@ -36,6 +40,12 @@ func (a *Advertisement) Equal(b *Advertisement) bool {
 	if a.LocalPref != b.LocalPref {
 		return false
 	}
+	if !reflect.DeepEqual(a.MED, b.MED) {
+		return false
+	}
+	if a.ASPathPrepend != b.ASPathPrepend {
+		return false
+	}
 
 	if !reflect.DeepEqual(a.Peers, b.Peers) {
 		return false
@@ -62,11 +72,57 @@ type Session interface {
 	Set(advs ...*Advertisement) error
 }
 
+// Route is a path received from a BGP peer.
+type Route struct {
+	Prefix      *net.IPNet
+	NextHop     net.IP
+	ASPath      []uint32
+	LocalPref   uint32
+	MED         uint32
+	Communities []community.BGPCommunity
+}
+
+// SessionStatus is the state of a BGP session and of the routes received on it.
+type SessionStatus struct {
+	Established bool
+	// Capabilities supported by both ends of the session.
+	Capabilities []string
+	// LastError is the last NOTIFICATION received, or the last error that
+	// brought the session down. The code and subcode are only set for
+	// NOTIFICATIONs.
+	LastError        string
+	LastErrorCode    uint8
+	LastErrorSubcode uint8
+	ReceivedPrefixes int
+	SentPrefixes     int
+	// UpSince is when the session was established, zero when it is down.
+	UpSince time.Time
+}
+
+// StatusReporter is implemented by the sessions exposing their status.
+type StatusReporter interface {
+	Status() SessionStatus
+}
+
+// RoutesReporter is implemented by the sessions exposing the routes
+// received from the peer (Adj-RIB-In).
+type RoutesReporter interface {
+	ReceivedRoutes() []Route
+}
+
+// PrefixReporter is implemented by the sessions that may not send all the
+// prefixes set on them to the peer, for example when they have no next hop
+// for their address family.
+type PrefixReporter interface {
+	Advertises(prefix *net.IPNet) bool
+}
+
 type SessionParameters struct {
 	PeerAddress            string
 	PeerPort               uint16
 	PeerInterface          string
 	SourceAddress          net.IP
+	IPv6NextHop            net.IP
 	MyASN                  uint32
 	RouterID               net.IP
 	PeerASN                uint32
@@ -79,11 +135,15 @@ type SessionParameters struct {
 	CurrentNode            string
 	BFDProfile             string
 	GracefulRestart        bool
+	GracefulRestartTime    *time.Duration
 	EBGPMultiHop           bool
 	VRFName                string
 	SessionName            string
 	DualStackAddressFamily bool
 	DisableMP              bool
+	// StatusChanged, if set, is called when the session gets established
+	// or goes down. Not all the implementations call it.
+	StatusChanged func()
 }
 type SessionManager interface {
 	NewSession(logger log.Logger, args SessionParameters) (Session, error)
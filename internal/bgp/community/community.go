@@ -117,6 +117,11 @@ func (b BGPCommunityLarge) String() string {
 	return fmt.Sprintf("%d:%d:%d", b.globalAdministrator, b.localDataPart1, b.localDataPart2)
 }
 
+// ToUint32s returns the global administrator and the two local data parts of this large community.
+func (b BGPCommunityLarge) ToUint32s() [3]uint32 {
+	return [3]uint32{b.globalAdministrator, b.localDataPart1, b.localDataPart2}
+}
+
 // IsLegacy returns true if this is a Legacy community.
 func IsLegacy(c BGPCommunity) bool {
 	_, ok := c.(BGPCommunityLegacy)
@@ -9,6 +9,7 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 	"syscall"
 	"text/template"
 	"time"
@@ -87,6 +88,8 @@ type neighborConfig struct {
 	prefixesV6Set            sets.Set[string]
 	CommunityPrefixModifiers map[string]CommunityPrefixList
 	LocalPrefPrefixModifiers map[string]LocalPrefPrefixList
+	MEDPrefixModifiers       map[string]MEDPrefixList
+	PrependPrefixModifiers   map[string]PrependPrefixList
 }
 
 func (n *neighborConfig) ID() string {
@@ -109,6 +112,14 @@ func (n *neighborConfig) LocalPrefPrefixLists() []LocalPrefPrefixList {
 	return sortMap(n.LocalPrefPrefixModifiers)
 }
 
+func (n *neighborConfig) MEDPrefixLists() []MEDPrefixList {
+	return sortMap(n.MEDPrefixModifiers)
+}
+
+func (n *neighborConfig) PrependPrefixLists() []PrependPrefixList {
+	return sortMap(n.PrependPrefixModifiers)
+}
+
 func (n *neighborConfig) ToAdvertisePrefixListV4() string {
 	return fmt.Sprintf("%s-allowed-%s", n.ID(), "ipv4")
 }
@@ -145,6 +156,29 @@ func (l LocalPrefPrefixList) SetStatement() string {
 	return fmt.Sprintf("set local-preference %d", l.LocalPreference)
 }
 
+type MEDPrefixList struct {
+	PropertyPrefixList
+	MED uint32
+}
+
+func (m MEDPrefixList) SetStatement() string {
+	return fmt.Sprintf("set metric %d", m.MED)
+}
+
+type PrependPrefixList struct {
+	PropertyPrefixList
+	ASN   uint32
+	Count uint32
+}
+
+func (p PrependPrefixList) SetStatement() string {
+	asns := make([]string, p.Count)
+	for i := range asns {
+		asns[i] = strconv.FormatUint(uint64(p.ASN), 10)
+	}
+	return "set as-path prepend " + strings.Join(asns, " ")
+}
+
 // RouterName() defines the format of the key of the "Routers" map in the
 // frrConfig struct.
 func RouterName(srcAddr string, myASN uint32, vrfName string) string {
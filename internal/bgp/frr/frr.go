@@ -31,6 +31,7 @@ type sessionManager struct {
 	extraConfig  string
 	reloadConfig chan reloadEvent
 	logLevel     string
+	neighbors    *neighborsCache
 	sync.Mutex
 }
 
@@ -293,6 +294,8 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				prefixesV6Set:            sets.New[string](),
 				CommunityPrefixModifiers: make(map[string]CommunityPrefixList),
 				LocalPrefPrefixModifiers: make(map[string]LocalPrefPrefixList),
+				MEDPrefixModifiers:       make(map[string]MEDPrefixList),
+				PrependPrefixModifiers:   make(map[string]PrependPrefixList),
 			}
 			if s.SourceAddress != nil {
 				neighbor.SrcAddr = s.SourceAddress.String()
@@ -348,6 +351,41 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				prefixList.prefixesSet.Insert(prefix)
 				neighbor.LocalPrefPrefixModifiers[prefixListName] = prefixList
 			}
+			if adv.MED != nil {
+				prefixListName := medPrefixList(neighbor, *adv.MED, frrFamily)
+				prefixList, ok := neighbor.MEDPrefixModifiers[prefixListName]
+				if !ok {
+					prefixList = MEDPrefixList{
+						PropertyPrefixList: PropertyPrefixList{
+							Name:        prefixListName,
+							IPFamily:    frrFamily,
+							prefixesSet: sets.New[string](),
+							Prefixes:    []string{},
+						},
+						MED: *adv.MED,
+					}
+				}
+				prefixList.prefixesSet.Insert(prefix)
+				neighbor.MEDPrefixModifiers[prefixListName] = prefixList
+			}
+			if adv.ASPathPrepend != 0 {
+				prefixListName := prependPrefixList(neighbor, adv.ASPathPrepend, frrFamily)
+				prefixList, ok := neighbor.PrependPrefixModifiers[prefixListName]
+				if !ok {
+					prefixList = PrependPrefixList{
+						PropertyPrefixList: PropertyPrefixList{
+							Name:        prefixListName,
+							IPFamily:    frrFamily,
+							prefixesSet: sets.New[string](),
+							Prefixes:    []string{},
+						},
+						ASN:   s.MyASN,
+						Count: adv.ASPathPrepend,
+					}
+				}
+				prefixList.prefixesSet.Insert(prefix)
+				neighbor.PrependPrefixModifiers[prefixListName] = prefixList
+			}
 
 			switch family {
 			case ipfamily.IPv4:
@@ -372,6 +410,14 @@ func (sm *sessionManager) createConfig() (*frrConfig, error) {
 				m.Prefixes = sets.List(n.LocalPrefPrefixModifiers[k].prefixesSet)
 				n.LocalPrefPrefixModifiers[k] = m
 			}
+			for k, m := range n.MEDPrefixModifiers {
+				m.Prefixes = sets.List(n.MEDPrefixModifiers[k].prefixesSet)
+				n.MEDPrefixModifiers[k] = m
+			}
+			for k, m := range n.PrependPrefixModifiers {
+				m.Prefixes = sets.List(n.PrependPrefixModifiers[k].prefixesSet)
+				n.PrependPrefixModifiers[k] = m
+			}
 		}
 		toAdd := &routerConfig{
 			MyASN:        r.myASN,
@@ -397,6 +443,14 @@ func localPrefPrefixList(neighbor *neighborConfig, localPreference uint32, ipFam
 	return fmt.Sprintf("%s-%d-%s-localpref-prefixes", neighbor.ID(), localPreference, ipFamily)
 }
 
+func medPrefixList(neighbor *neighborConfig, med uint32, ipFamily string) string {
+	return fmt.Sprintf("%s-%d-%s-med-prefixes", neighbor.ID(), med, ipFamily)
+}
+
+func prependPrefixList(neighbor *neighborConfig, count uint32, ipFamily string) string {
+	return fmt.Sprintf("%s-%d-%s-prepend-prefixes", neighbor.ID(), count, ipFamily)
+}
+
 func communityPrefixList(neighbor *neighborConfig, community, ipFamily string) string {
 	return fmt.Sprintf("%s-%s-%s-community-prefixes", neighbor.ID(), community, ipFamily)
 }
@@ -410,12 +464,16 @@ func (sm *sessionManager) SetEventCallback(func(interface{})) {}
 var debounceTimeout = 3 * time.Second
 var failureTimeout = time.Second * 5
 
-func NewSessionManager(l log.Logger, logLevel logging.Level) bgp.SessionManager {
+// NewSessionManager creates the FRR session manager. The status of the
+// sessions is read from the neighbors endpoint of the FRR metrics exporter,
+// at neighborsURL.
+func NewSessionManager(l log.Logger, logLevel logging.Level, neighborsURL string) bgp.SessionManager {
 	res := &sessionManager{
 		sessions:     map[string]*session{},
 		bfdProfiles:  []BFDProfile{},
 		reloadConfig: make(chan reloadEvent),
 		logLevel:     logLevelToFRR(logLevel),
+		neighbors:    &neighborsCache{fetch: httpNeighbors(neighborsURL)},
 	}
 	reload := func(config *frrConfig) error {
 		return generateAndReloadConfigFile(config, l)
@@ -434,6 +492,7 @@ func mockNewSessionManager(l log.Logger, logLevel logging.Level) *sessionManager
 		bfdProfiles:  []BFDProfile{},
 		reloadConfig: make(chan reloadEvent),
 		logLevel:     logLevelToFRR(logLevel),
+		neighbors:    &neighborsCache{},
 	}
 	reload := func(config *frrConfig) error {
 		return generateAndReloadConfigFile(config, l)
@@ -8,6 +8,7 @@ import (
 	"net"
 	"sort"
 	"strconv"
+	"time"
 
 	"errors"
 )
@@ -19,9 +20,14 @@ type Neighbor struct {
 	LocalAS        string
 	RemoteAS       string
 	PrefixSent     int
+	PrefixReceived int
 	Port           int
 	RemoteRouterID string
 	MsgStats       MessageStats
+	// UpTime is how long the session has been established.
+	UpTime time.Duration
+	// LastResetReason is why the session last went down.
+	LastResetReason string
 }
 
 type Route struct {
@@ -39,11 +45,14 @@ type FRRNeighbor struct {
 	RemoteRouterID    string       `json:"remoteRouterId"`
 	BgpVersion        int          `json:"bgpVersion"`
 	BgpState          string       `json:"bgpState"`
+	BgpTimerUpMsec    int64        `json:"bgpTimerUpMsec"`
+	LastResetDueTo    string       `json:"lastResetDueTo"`
 	PortForeign       int          `json:"portForeign"`
 	MsgStats          MessageStats `json:"messageStats"`
 	VRFName           string       `json:"vrf"`
 	AddressFamilyInfo map[string]struct {
-		SentPrefixCounter int `json:"sentPrefixCounter"`
+		SentPrefixCounter     int `json:"sentPrefixCounter"`
+		AcceptedPrefixCounter int `json:"acceptedPrefixCounter"`
 	} `json:"addressFamilyInfo"`
 }
 
@@ -123,19 +132,23 @@ func ParseNeighbour(vtyshRes string) (*Neighbor, error) {
 		if n.BgpState != bgpConnected {
 			connected = false
 		}
-		prefixSent := 0
+		prefixSent, prefixReceived := 0, 0
 		for _, s := range n.AddressFamilyInfo {
 			prefixSent += s.SentPrefixCounter
+			prefixReceived += s.AcceptedPrefixCounter
 		}
 		return &Neighbor{
-			IP:             ip,
-			Connected:      connected,
-			LocalAS:        strconv.Itoa(n.LocalAs),
-			RemoteAS:       strconv.Itoa(n.RemoteAs),
-			PrefixSent:     prefixSent,
-			Port:           n.PortForeign,
-			RemoteRouterID: n.RemoteRouterID,
-			MsgStats:       n.MsgStats,
+			IP:              ip,
+			Connected:       connected,
+			LocalAS:         strconv.Itoa(n.LocalAs),
+			RemoteAS:        strconv.Itoa(n.RemoteAs),
+			PrefixSent:      prefixSent,
+			PrefixReceived:  prefixReceived,
+			Port:            n.PortForeign,
+			RemoteRouterID:  n.RemoteRouterID,
+			MsgStats:        n.MsgStats,
+			UpTime:          time.Duration(n.BgpTimerUpMsec) * time.Millisecond,
+			LastResetReason: n.LastResetDueTo,
 		}, nil
 	}
 	return nil, errors.New("no peers were returned")
@@ -160,19 +173,23 @@ func ParseNeighbours(vtyshRes string) ([]*Neighbor, error) {
 		if n.BgpState != bgpConnected {
 			connected = false
 		}
-		prefixSent := 0
+		prefixSent, prefixReceived := 0, 0
 		for _, s := range n.AddressFamilyInfo {
 			prefixSent += s.SentPrefixCounter
+			prefixReceived += s.AcceptedPrefixCounter
 		}
 		res = append(res, &Neighbor{
-			IP:             ip,
-			Connected:      connected,
-			LocalAS:        strconv.Itoa(n.LocalAs),
-			RemoteAS:       strconv.Itoa(n.RemoteAs),
-			PrefixSent:     prefixSent,
-			Port:           n.PortForeign,
-			RemoteRouterID: n.RemoteRouterID,
-			MsgStats:       n.MsgStats,
+			IP:              ip,
+			Connected:       connected,
+			LocalAS:         strconv.Itoa(n.LocalAs),
+			RemoteAS:        strconv.Itoa(n.RemoteAs),
+			PrefixSent:      prefixSent,
+			PrefixReceived:  prefixReceived,
+			Port:            n.PortForeign,
+			RemoteRouterID:  n.RemoteRouterID,
+			MsgStats:        n.MsgStats,
+			UpTime:          time.Duration(n.BgpTimerUpMsec) * time.Millisecond,
+			LastResetReason: n.LastResetDueTo,
 		})
 	}
 	return res, nil
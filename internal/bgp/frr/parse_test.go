@@ -8,6 +8,7 @@ import (
 	"net"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -178,6 +179,9 @@ func TestNeighbour(t *testing.T) {
 			if !cmp.Equal(expectedStats, n.MsgStats) {
 				t.Fatal("unexpected BGP messages stats (-want +got)\n", cmp.Diff(expectedStats, n.MsgStats))
 			}
+			if n.LastResetReason != "Waiting for peer OPEN" {
+				t.Fatal("Expected last reset reason \"Waiting for peer OPEN\", got", n.LastResetReason)
+			}
 		})
 	}
 }
@@ -379,8 +383,8 @@ const threeNeighbours = `
     "remoteRouterId":"11.11.11.11",
     "localRouterId":"172.18.0.5",
     "bgpState":"Established",
-    "bgpTimerUpMsec":0,
-    "bgpTimerUpString":"00:00:00",
+    "bgpTimerUpMsec":78272000,
+    "bgpTimerUpString":"21:44:32",
     "bgpTimerUpEstablishedEpoch":1636386709,
     "bgpTimerLastRead":4000,
     "bgpTimerLastWrite":0,
@@ -466,7 +470,7 @@ const threeNeighbours = `
         "packetQueueLength":0,
         "routerAlwaysNextHop":true,
         "commAttriSentToNbr":"extendedAndStandard",
-        "acceptedPrefixCounter":0,
+        "acceptedPrefixCounter":3,
         "sentPrefixCounter":0
       }
     },
@@ -512,6 +516,16 @@ func TestNeighbours(t *testing.T) {
 		t.Fatal("neighbour ip not matching")
 	}
 
+	if nn[3].UpTime != 78272*time.Second {
+		t.Fatal("Expected up time", 78272*time.Second, "got", nn[3].UpTime)
+	}
+	if nn[3].PrefixReceived != 3 {
+		t.Fatal("Expected prefix received 3, got", nn[3].PrefixReceived)
+	}
+	if nn[3].LastResetReason != "No AFI/SAFI activated for peer" {
+		t.Fatal("Expected last reset reason \"No AFI/SAFI activated for peer\", got", nn[3].LastResetReason)
+	}
+
 	for i, n := range nn {
 		if !cmp.Equal(expectedStats, n.MsgStats) {
 			t.Fatal("unexpected BGP messages stats for neightbor", i, "(-want +got)\n", cmp.Diff(expectedStats, n.MsgStats))
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.universe.tf/metallb/internal/bgp"
+)
+
+// neighborsCacheTime is how long the neighbors fetched from FRR are used to
+// report the status of the sessions before being fetched again.
+const neighborsCacheTime = 5 * time.Second
+
+// neighborsFetcher returns the BGP neighbors of FRR, by VRF name.
+type neighborsFetcher func() (map[string][]*Neighbor, error)
+
+// neighborsCache holds the last BGP neighbors fetched from FRR, so that
+// the status of all the sessions can be reported without asking FRR for
+// each of them.
+type neighborsCache struct {
+	fetch     neighborsFetcher
+	mu        sync.Mutex
+	neighbors map[string][]*Neighbor
+	err       error
+	fetched   time.Time
+}
+
+func (c *neighborsCache) get() (map[string][]*Neighbor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fetch == nil {
+		return nil, fmt.Errorf("the BGP neighbors can't be fetched from FRR")
+	}
+	if time.Since(c.fetched) < neighborsCacheTime {
+		return c.neighbors, c.err
+	}
+	c.neighbors, c.err = c.fetch()
+	c.fetched = time.Now()
+	return c.neighbors, c.err
+}
+
+// httpNeighbors fetches the BGP neighbors from the endpoint of the FRR
+// metrics exporter running alongside FRR.
+func httpNeighbors(url string) neighborsFetcher {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return func() (map[string][]*Neighbor, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch BGP neighbors from %s: %w", url, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch BGP neighbors from %s: %s", url, resp.Status)
+		}
+		res := map[string][]*Neighbor{}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			return nil, fmt.Errorf("failed to parse BGP neighbors from %s: %w", url, err)
+		}
+		return res, nil
+	}
+}
+
+// Status returns the state of the session, as reported by FRR.
+func (s *session) Status() bgp.SessionStatus {
+	neighbors, err := s.sessionManager.neighbors.get()
+	if err != nil {
+		return bgp.SessionStatus{LastError: err.Error()}
+	}
+
+	vrf := s.VRFName
+	if vrf == "" {
+		vrf = "default"
+	}
+	peer := net.ParseIP(s.PeerAddress)
+	for _, n := range neighbors[vrf] {
+		if !n.IP.Equal(peer) {
+			continue
+		}
+		ret := bgp.SessionStatus{
+			Established:      n.Connected,
+			LastError:        n.LastResetReason,
+			ReceivedPrefixes: n.PrefixReceived,
+		}
+		if n.Connected {
+			ret.SentPrefixes = n.PrefixSent
+			ret.UpSince = time.Now().Add(-n.UpTime)
+		}
+		return ret
+	}
+	return bgp.SessionStatus{}
+}
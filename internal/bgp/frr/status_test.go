@@ -0,0 +1,156 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package frr
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.universe.tf/metallb/internal/bgp"
+)
+
+func TestSessionStatus(t *testing.T) {
+	neighbors := map[string][]*Neighbor{
+		"default": {
+			{
+				IP:              net.ParseIP("10.2.2.254"),
+				VRF:             "default",
+				Connected:       true,
+				PrefixSent:      2,
+				PrefixReceived:  3,
+				UpTime:          time.Hour,
+				LastResetReason: "Waiting for peer OPEN",
+			},
+			{
+				IP:              net.ParseIP("10.2.2.253"),
+				VRF:             "default",
+				PrefixSent:      2,
+				LastResetReason: "Peer closed the session",
+			},
+		},
+		"red": {
+			{
+				IP:        net.ParseIP("10.2.2.254"),
+				VRF:       "red",
+				Connected: false,
+			},
+		},
+	}
+
+	tests := []struct {
+		desc        string
+		params      bgp.SessionParameters
+		fetchErr    error
+		established bool
+		want        bgp.SessionStatus
+	}{
+		{
+			desc:        "established",
+			params:      bgp.SessionParameters{PeerAddress: "10.2.2.254"},
+			established: true,
+			want: bgp.SessionStatus{
+				Established:      true,
+				LastError:        "Waiting for peer OPEN",
+				ReceivedPrefixes: 3,
+				SentPrefixes:     2,
+			},
+		},
+		{
+			desc:   "down",
+			params: bgp.SessionParameters{PeerAddress: "10.2.2.253"},
+			want: bgp.SessionStatus{
+				LastError: "Peer closed the session",
+			},
+		},
+		{
+			desc:   "vrf",
+			params: bgp.SessionParameters{PeerAddress: "10.2.2.254", VRFName: "red"},
+			want:   bgp.SessionStatus{},
+		},
+		{
+			desc:   "not in frr",
+			params: bgp.SessionParameters{PeerAddress: "10.2.2.252"},
+			want:   bgp.SessionStatus{},
+		},
+		{
+			desc:     "fetch error",
+			params:   bgp.SessionParameters{PeerAddress: "10.2.2.254"},
+			fetchErr: errors.New("connection refused"),
+			want: bgp.SessionStatus{
+				LastError: "connection refused",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			s := &session{
+				SessionParameters: test.params,
+				sessionManager: &sessionManager{
+					neighbors: &neighborsCache{
+						fetch: func() (map[string][]*Neighbor, error) {
+							if test.fetchErr != nil {
+								return nil, test.fetchErr
+							}
+							return neighbors, nil
+						},
+					},
+				},
+			}
+			got := s.Status()
+			if test.established {
+				upSince := time.Now().Add(-time.Hour)
+				if got.UpSince.Sub(upSince).Abs() > time.Minute {
+					t.Fatalf("expected the session to be up since %s, got %s", upSince, got.UpSince)
+				}
+			} else if !got.UpSince.IsZero() {
+				t.Fatalf("expected the session to be down, got up since %s", got.UpSince)
+			}
+			got.UpSince = time.Time{}
+			if got.Established != test.want.Established || got.LastError != test.want.LastError ||
+				got.ReceivedPrefixes != test.want.ReceivedPrefixes || got.SentPrefixes != test.want.SentPrefixes {
+				t.Fatalf("expected status %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPNeighbors(t *testing.T) {
+	neighbors := map[string][]*Neighbor{
+		"default": {
+			{
+				IP:        net.ParseIP("10.2.2.254"),
+				VRF:       "default",
+				Connected: true,
+				UpTime:    time.Minute,
+			},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bgp/neighbors" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(neighbors)
+	}))
+	defer server.Close()
+
+	got, err := httpNeighbors(server.URL + "/bgp/neighbors")()
+	if err != nil {
+		t.Fatalf("failed to fetch the neighbors: %v", err)
+	}
+	if len(got["default"]) != 1 || !got["default"][0].IP.Equal(net.ParseIP("10.2.2.254")) ||
+		!got["default"][0].Connected || got["default"][0].UpTime != time.Minute {
+		t.Fatalf("unexpected neighbors %+v", got)
+	}
+
+	_, err = httpNeighbors(server.URL + "/wrong")()
+	if err == nil {
+		t.Fatal("expected an error fetching the neighbors from a wrong path")
+	}
+}
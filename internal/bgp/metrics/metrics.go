@@ -25,4 +25,19 @@ var (
 		Name: "announced_prefixes_total",
 		Help: "Number of prefixes currently being advertised on the BGP session",
 	}
+
+	UpdatesReceived = metric{
+		Name: "updates_total_received",
+		Help: "Number of BGP UPDATE messages received",
+	}
+
+	ReceivedPrefixes = metric{
+		Name: "received_prefixes_total",
+		Help: "Number of prefixes currently received on the BGP session",
+	}
+
+	NotificationsReceived = metric{
+		Name: "notifications_received",
+		Help: "Number of BGP notification messages received",
+	}
 )
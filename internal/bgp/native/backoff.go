@@ -5,13 +5,17 @@ package native
 import "time"
 
 const (
-	backoffMax    = 2 * time.Minute
-	backoffFactor = 2
+	DefaultBackoffMin = time.Second
+	DefaultBackoffMax = 2 * time.Minute
+	backoffFactor     = 2
 )
 
 // backoff implements multiplicative backoff for retrying failing
-// operations.
+// operations. The delay starts at min and doubles up to max, the
+// defaults are used when they are not set.
 type backoff struct {
+	min       time.Duration
+	max       time.Duration
 	nextDelay time.Duration
 }
 
@@ -19,12 +23,19 @@ type backoff struct {
 func (b *backoff) Duration() time.Duration {
 	ret := b.nextDelay
 	if b.nextDelay == 0 {
-		b.nextDelay = time.Second
+		b.nextDelay = b.min
+		if b.nextDelay == 0 {
+			b.nextDelay = DefaultBackoffMin
+		}
 	} else {
 		b.nextDelay *= backoffFactor
-		if b.nextDelay > backoffMax {
-			b.nextDelay = backoffMax
-		}
+	}
+	maxDelay := b.max
+	if maxDelay == 0 {
+		maxDelay = DefaultBackoffMax
+	}
+	if b.nextDelay > maxDelay {
+		b.nextDelay = maxDelay
 	}
 	return ret
 }
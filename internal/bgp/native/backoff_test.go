@@ -0,0 +1,46 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package native
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		desc string
+		b    backoff
+		want []time.Duration
+	}{
+		{
+			desc: "defaults",
+			b:    backoff{},
+			want: []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			desc: "custom bounds",
+			b:    backoff{min: 5 * time.Second, max: 12 * time.Second},
+			want: []time.Duration{0, 5 * time.Second, 10 * time.Second, 12 * time.Second, 12 * time.Second},
+		},
+		{
+			desc: "min above default max",
+			b:    backoff{min: 3 * time.Minute, max: 5 * time.Minute},
+			want: []time.Duration{0, 3 * time.Minute, 5 * time.Minute},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			for i, want := range test.want {
+				if got := test.b.Duration(); got != want {
+					t.Fatalf("retry %d: got delay %v, want %v", i, got, want)
+				}
+			}
+			test.b.Reset()
+			if got := test.b.Duration(); got != 0 {
+				t.Fatalf("got delay %v after reset, want 0", got)
+			}
+		})
+	}
+}
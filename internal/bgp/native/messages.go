@@ -12,10 +12,22 @@ import (
 
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
+	"go.universe.tf/metallb/internal/ipfamily"
 	"go.universe.tf/metallb/internal/safeconvert"
 )
 
-func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration) error {
+// gracefulRestart holds the graceful restart capability (RFC 4724)
+// advertised in the OPEN.
+type gracefulRestart struct {
+	// The speaker restarted and this is its first session with the peer.
+	restarted   bool
+	restartTime time.Duration
+}
+
+// sendOpen sends an OPEN advertising the multiprotocol capability for IPv4
+// unicast, and for IPv6 unicast when ipv6 is set. The graceful restart
+// capability, if any, lists the same address families.
+func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration, ipv6 bool, gr *gracefulRestart) error {
 	if routerID.To4() == nil {
 		panic("non-ipv4 address used as RouterID")
 	}
@@ -36,23 +48,6 @@ func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration)
 		OptsLen uint8
 		OptType uint8
 		OptLen  uint8
-
-		// Capabilities: multiprotocol extension for IPv4+IPv6
-		// unicast, and 4-byte ASNs
-
-		MP4Type uint8
-		MP4Len  uint8
-		AFI4    uint16
-		SAFI4   uint16
-
-		MP6Type uint8
-		MP6Len  uint8
-		AFI6    uint16
-		SAFI6   uint16
-
-		CapType uint8
-		CapLen  uint8
-		ASN32   uint32
 	}{
 		Marker1: 0xffffffffffffffff,
 		Marker2: 0xffffffffffffffff,
@@ -64,27 +59,50 @@ func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration)
 		HoldTime: uint16(holdTime.Seconds()),
 		// RouterID filled below
 
-		OptsLen: 20,
 		OptType: 2, // Capabilities
-		OptLen:  18,
-
-		MP4Type: 1, // BGP Multi-protocol Extensions
-		MP4Len:  4,
-		AFI4:    1, // IPv4
-		SAFI4:   1, // Unicast
+		// Lengths filled below
+	}
 
-		MP6Type: 1, // BGP Multi-protocol Extensions
-		MP6Len:  4,
-		AFI6:    2, // IPv6
-		SAFI6:   1, // Unicast
+	afis := []uint16{1} // IPv4
+	if ipv6 {
+		afis = append(afis, 2) // IPv6
+	}
 
-		CapType: 65, // 4-byte ASN
-		CapLen:  4,
-		ASN32:   asn,
+	// Capabilities: multiprotocol extension for the address families,
+	// 4-byte ASNs, and graceful restart.
+	var caps bytes.Buffer
+	for _, afi := range afis {
+		caps.Write([]byte{
+			1, 4, // BGP Multi-protocol Extensions
+			byte(afi >> 8), byte(afi),
+			0, 1, // Unicast
+		})
+	}
+	caps.Write([]byte{65, 4}) // 4-byte ASN
+	if err := binary.Write(&caps, binary.BigEndian, asn); err != nil {
+		return err
+	}
+	if gr != nil {
+		flagsTime := uint16(gr.restartTime.Seconds()) & 0x0fff
+		if gr.restarted {
+			flagsTime |= 0x8000
+		}
+		caps.Write([]byte{
+			64, byte(2 + 4*len(afis)), // Graceful Restart
+			byte(flagsTime >> 8), byte(flagsTime),
+		})
+		for _, afi := range afis {
+			caps.Write([]byte{byte(afi >> 8), byte(afi), 1, 0x80}) // unicast, forwarding state preserved
+		}
 	}
 
 	var err error
-	msg.Len, err = safeconvert.IntToUInt16(binary.Size(msg))
+	msg.OptLen, err = safeconvert.IntToUInt8(caps.Len())
+	if err != nil {
+		return fmt.Errorf("invalid capabilities len %w", err)
+	}
+	msg.OptsLen = msg.OptLen + 2
+	msg.Len, err = safeconvert.IntToUInt16(binary.Size(msg) + caps.Len())
 	if err != nil {
 		return fmt.Errorf("invalid message len %w", err)
 	}
@@ -94,7 +112,13 @@ func sendOpen(w io.Writer, asn uint32, routerID net.IP, holdTime time.Duration)
 	}
 	copy(msg.RouterID[:], routerID.To4())
 
-	return binary.Write(w, binary.BigEndian, msg)
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.BigEndian, msg); err != nil {
+		return err
+	}
+	b.Write(caps.Bytes())
+	_, err = io.Copy(w, &b)
+	return err
 }
 
 type openResult struct {
@@ -104,6 +128,9 @@ type openResult struct {
 	mp6      bool
 	// Four-byte ASN supported
 	fbasn bool
+	// Graceful restart supported, and the restart time of the peer
+	gracefulRestart bool
+	restartTime     time.Duration
 }
 
 var notificationCodes = map[uint16]string{
@@ -149,19 +176,38 @@ var notificationCodes = map[uint16]string{
 	0x0608: "Out of Resources",
 }
 
-// readNotification reads the body of a notification message (header
-// has already been consumed). It must always return an error, because
-// receiving a notification is an error.
-func readNotification(r io.Reader) error {
-	var code uint16
-	if err := binary.Read(r, binary.BigEndian, &code); err != nil {
-		return err
-	}
-	v, ok := notificationCodes[code]
+// notificationError is a NOTIFICATION received from the peer.
+type notificationError struct {
+	code    uint8
+	subcode uint8
+	data    []byte
+}
+
+func (e *notificationError) Error() string {
+	c := uint16(e.code)<<8 | uint16(e.subcode)
+	v, ok := notificationCodes[c]
 	if !ok {
 		v = "unknown code"
 	}
-	return fmt.Errorf("got BGP notification code 0x%04x (%s)", code, v)
+	return fmt.Sprintf("got BGP notification code 0x%04x (%s)", c, v)
+}
+
+// readNotification reads the body of a notification message of length
+// l (header has already been consumed). It must always return an
+// error, because receiving a notification is an error.
+func readNotification(r io.Reader, l uint16) error {
+	if l < 21 {
+		return fmt.Errorf("message length %d too small to be NOTIFICATION", l)
+	}
+	body := make([]byte, l-19)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return err
+	}
+	return &notificationError{
+		code:    body[0],
+		subcode: body[1],
+		data:    body[2:],
+	}
 }
 
 func readOpen(r io.Reader) (*openResult, error) {
@@ -178,7 +224,7 @@ func readOpen(r io.Reader) (*openResult, error) {
 		return nil, fmt.Errorf("synchronization error, incorrect header marker")
 	}
 	if hdr.Type == 3 {
-		return nil, readNotification(r)
+		return nil, readNotification(r, hdr.Len)
 	}
 	if hdr.Type != 1 {
 		return nil, fmt.Errorf("message type is not OPEN, got %d, want 1", hdr.Type)
@@ -283,6 +329,18 @@ func readCapabilities(r io.Reader, ret *openResult) error {
 			case af.AFI == 2 && af.SAFI == 1:
 				ret.mp6 = true
 			}
+		case 64:
+			var flagsTime uint16
+			if err := binary.Read(&lr, binary.BigEndian, &flagsTime); err != nil {
+				return err
+			}
+			ret.gracefulRestart = true
+			ret.restartTime = time.Duration(flagsTime&0x0fff) * time.Second
+			// The families whose forwarding state the peer preserves
+			// don't matter, since we don't install the routes it sends.
+			if _, err := io.Copy(io.Discard, &lr); err != nil {
+				return err
+			}
 		default:
 			// TODO: only ignore capabilities that we know are fine to
 			// ignore.
@@ -323,7 +381,10 @@ func sendUpdate(w io.Writer, asn uint32, ibgp, fbasn bool, nextHop net.IP, adv *
 		return err
 	}
 	binary.BigEndian.PutUint16(b.Bytes()[21:23], toWrite)
-	encodePrefixes(&b, []*net.IPNet{adv.Prefix})
+	// IPv6 prefixes are carried by the MP_REACH_NLRI attribute.
+	if adv.Prefix.IP.To4() != nil {
+		encodePrefixes(&b, []*net.IPNet{adv.Prefix})
+	}
 
 	toWrite, err = safeconvert.IntToUInt16(b.Len())
 	if err != nil {
@@ -340,8 +401,12 @@ func sendUpdate(w io.Writer, asn uint32, ibgp, fbasn bool, nextHop net.IP, adv *
 func encodePrefixes(b *bytes.Buffer, pfxs []*net.IPNet) {
 	for _, pfx := range pfxs {
 		o, _ := pfx.Mask.Size()
+		ip := pfx.IP.To4()
+		if ip == nil {
+			ip = pfx.IP.To16()
+		}
 		b.WriteByte(byte(o))
-		b.Write(pfx.IP.To4()[:bytesForBits(o)])
+		b.Write(ip[:bytesForBits(o)])
 	}
 }
 
@@ -360,24 +425,35 @@ func encodePathAttrs(b *bytes.Buffer, asn uint32, ibgp, fbasn bool, nextHop net.
 
 		0x40, 2, // mandatory, as-path
 	})
-	if ibgp {
+	// The local ASN starts the path sent to EBGP peers, and is
+	// prepended as many more times as requested by the advertisement.
+	count := int(adv.ASPathPrepend)
+	if !ibgp {
+		count++
+	}
+	if count == 0 {
 		b.WriteByte(0) // empty AS path
 	} else {
+		asnLen := 2
 		if fbasn {
-			b.Write([]byte{
-				6, // len (1x 4-byte ASN)
-				2, // AS_SEQUENCE
-				1, // len (in number of ASes)
-			})
-			if err := binary.Write(b, binary.BigEndian, asn); err != nil {
-				return err
+			asnLen = 4
+		}
+		segLen, err := safeconvert.IntToUInt8(2 + count*asnLen)
+		if err != nil {
+			return fmt.Errorf("invalid as-path prepend: %w", err)
+		}
+		b.Write([]byte{
+			segLen,
+			2,            // AS_SEQUENCE
+			uint8(count), // len (in number of ASes), smaller than segLen
+		})
+		for range count {
+			if fbasn {
+				if err := binary.Write(b, binary.BigEndian, asn); err != nil {
+					return err
+				}
+				continue
 			}
-		} else {
-			b.Write([]byte{
-				4, // len (1x 2-byte ASN)
-				2, // AS_SEQUENCE
-				1, // len (in number of ASes)
-			})
 			asnToWrite, err := safeconvert.Uint32ToInt16(asn)
 			if err != nil {
 				return fmt.Errorf("invalid asn: %w", err)
@@ -387,12 +463,23 @@ func encodePathAttrs(b *bytes.Buffer, asn uint32, ibgp, fbasn bool, nextHop net.
 			}
 		}
 	}
-	b.Write([]byte{
-		0x40, 3, // mandatory, next-hop
-		4, // len
-	})
+	if adv.Prefix.IP.To4() != nil {
+		b.Write([]byte{
+			0x40, 3, // mandatory, next-hop
+			4, // len
+		})
+		b.Write(nextHop.To4())
+	}
 
-	b.Write(nextHop)
+	if adv.MED != nil {
+		b.Write([]byte{
+			0x80, 4, // optional non-transitive, multi-exit-disc
+			4, // len
+		})
+		if err := binary.Write(b, binary.BigEndian, *adv.MED); err != nil {
+			return err
+		}
+	}
 
 	if ibgp {
 		b.Write([]byte{
@@ -404,18 +491,23 @@ func encodePathAttrs(b *bytes.Buffer, asn uint32, ibgp, fbasn bool, nextHop net.
 		}
 	}
 
-	if len(adv.Communities) > 0 {
-		// Convert communities to legacy communities uint32 representation. Throw an error if any non legacy type
-		// communities were found.
-		var legacyCommunities []uint32
-		for _, c := range adv.Communities {
-			legacyCommunity, ok := c.(community.BGPCommunityLegacy)
-			if !ok {
-				return fmt.Errorf("invalid community type for BGP native mode, community %s is not a legacy BGP "+
-					"Community", c)
-			}
-			legacyCommunities = append(legacyCommunities, legacyCommunity.ToUint32())
+	// Split communities into their legacy uint32 representation and the
+	// three uint32 of large communities, which go in separate attributes.
+	var legacyCommunities []uint32
+	var largeCommunities [][3]uint32
+	for _, c := range adv.Communities {
+		switch v := c.(type) {
+		case community.BGPCommunityLegacy:
+			legacyCommunities = append(legacyCommunities, v.ToUint32())
+		case community.BGPCommunityLarge:
+			largeCommunities = append(largeCommunities, v.ToUint32s())
+		default:
+			return fmt.Errorf("invalid community type for BGP native mode, community %s is neither a legacy nor "+
+				"a large BGP Community", c)
 		}
+	}
+
+	if len(legacyCommunities) > 0 {
 		b.Write([]byte{
 			0xc0, 8, // optional transitive, communities
 		})
@@ -434,12 +526,71 @@ func encodePathAttrs(b *bytes.Buffer, asn uint32, ibgp, fbasn bool, nextHop net.
 		}
 	}
 
+	if adv.Prefix.IP.To4() == nil {
+		var v bytes.Buffer
+		v.Write([]byte{
+			0, 2, // AFI IPv6
+			1,  // SAFI unicast
+			16, // next-hop len
+		})
+		v.Write(nextHop.To16())
+		v.WriteByte(0) // reserved
+		encodePrefixes(&v, []*net.IPNet{adv.Prefix})
+		if err := encodeOptionalAttr(b, 0x80, 14, v.Bytes()); err != nil { // optional, mp_reach_nlri
+			return err
+		}
+	}
+
+	if len(largeCommunities) > 0 {
+		var v bytes.Buffer
+		for _, c := range largeCommunities {
+			if err := binary.Write(&v, binary.BigEndian, c); err != nil {
+				return err
+			}
+		}
+		if err := encodeOptionalAttr(b, 0xc0, 32, v.Bytes()); err != nil { // optional transitive, large communities
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeOptionalAttr writes an optional path attribute with the given
+// flags, switching to the extended length encoding when the value does
+// not fit in one byte.
+func encodeOptionalAttr(b *bytes.Buffer, flags, typ uint8, value []byte) error {
+	if len(value) > 255 {
+		b.Write([]byte{flags | 0x10, typ}) // extended length
+		l, err := safeconvert.IntToUInt16(len(value))
+		if err != nil {
+			return fmt.Errorf("invalid attribute len %w", err)
+		}
+		if err := binary.Write(b, binary.BigEndian, l); err != nil {
+			return err
+		}
+	} else {
+		b.Write([]byte{flags, typ, byte(len(value))})
+	}
+	b.Write(value)
 	return nil
 }
 
+// sendWithdraw withdraws the given prefixes in a single UPDATE: IPv4
+// prefixes go in the withdrawn routes field, IPv6 ones in an
+// MP_UNREACH_NLRI attribute.
 func sendWithdraw(w io.Writer, prefixes []*net.IPNet) error {
 	var b bytes.Buffer
 
+	var v4, v6 []*net.IPNet
+	for _, pfx := range prefixes {
+		if pfx.IP.To4() != nil {
+			v4 = append(v4, pfx)
+		} else {
+			v6 = append(v6, pfx)
+		}
+	}
+
 	hdr := struct {
 		M1, M2 uint64
 		Len    uint16
@@ -454,15 +605,33 @@ func sendWithdraw(w io.Writer, prefixes []*net.IPNet) error {
 		return err
 	}
 	l := b.Len()
-	encodePrefixes(&b, prefixes)
+	encodePrefixes(&b, v4)
 	toWrite, err := safeconvert.IntToUInt16(b.Len() - l)
 	if err != nil {
 		return fmt.Errorf("invalid buffer %w", err)
 	}
 	binary.BigEndian.PutUint16(b.Bytes()[19:21], toWrite)
-	if err := binary.Write(&b, binary.BigEndian, uint16(0)); err != nil {
+
+	var attrs bytes.Buffer
+	if len(v6) > 0 {
+		var v bytes.Buffer
+		v.Write([]byte{
+			0, 2, // AFI IPv6
+			1, // SAFI unicast
+		})
+		encodePrefixes(&v, v6)
+		if err := encodeOptionalAttr(&attrs, 0x80, 15, v.Bytes()); err != nil { // optional, mp_unreach_nlri
+			return err
+		}
+	}
+	attrLen, err := safeconvert.IntToUInt16(attrs.Len())
+	if err != nil {
+		return fmt.Errorf("invalid buffer %w", err)
+	}
+	if err := binary.Write(&b, binary.BigEndian, attrLen); err != nil {
 		return err
 	}
+	b.Write(attrs.Bytes())
 
 	toWrite, err = safeconvert.IntToUInt16(b.Len())
 	if err != nil {
@@ -476,6 +645,372 @@ func sendWithdraw(w io.Writer, prefixes []*net.IPNet) error {
 	return nil
 }
 
+// sendEndOfRIB sends the End-of-RIB marker of the given address family,
+// per RFC 4724: an empty UPDATE for IPv4, and an UPDATE with an empty
+// MP_UNREACH_NLRI for IPv6.
+func sendEndOfRIB(w io.Writer, family ipfamily.Family) error {
+	if family == ipfamily.IPv4 {
+		return sendWithdraw(w, nil)
+	}
+
+	msg := struct {
+		M1, M2  uint64
+		Len     uint16
+		Type    uint8
+		WdrLen  uint16
+		AttrLen uint16
+
+		// MP_UNREACH_NLRI
+		MPFlags uint8
+		MPType  uint8
+		MPLen   uint8
+		AFI     uint16
+		SAFI    uint8
+	}{
+		M1:      uint64(0xffffffffffffffff),
+		M2:      uint64(0xffffffffffffffff),
+		Len:     29,
+		Type:    2,
+		AttrLen: 6,
+
+		MPFlags: 0x80, // optional
+		MPType:  15,   // mp_unreach_nlri
+		MPLen:   3,
+		AFI:     2, // IPv6
+		SAFI:    1, // Unicast
+	}
+	return binary.Write(w, binary.BigEndian, msg)
+}
+
+// update is a decoded UPDATE message.
+type update struct {
+	withdrawn []*net.IPNet
+	routes    []bgp.Route
+	// attrErr is set when a path attribute of the update was malformed.
+	// Following RFC 7606, the prefixes it announced are then turned into
+	// withdrawals instead of resetting the session.
+	attrErr error
+}
+
+// UPDATE Message Error subcodes sent when an UPDATE cannot be parsed.
+const (
+	malformedAttributeList = 1
+	invalidNetworkField    = 10
+)
+
+// updateError is an UPDATE whose framing is broken, so that nothing in
+// it can be trusted. The session must be reset, after sending the peer
+// an UPDATE Message Error NOTIFICATION with subcode.
+type updateError struct {
+	subcode uint8
+	err     error
+}
+
+func (e *updateError) Error() string {
+	return e.err.Error()
+}
+
+func (e *updateError) Unwrap() error {
+	return e.err
+}
+
+// readUpdate reads the body of an update message of length l (header
+// has already been consumed). fbasn tells whether the AS numbers in the
+// AS_PATH are 4 bytes long.
+func readUpdate(r io.Reader, l uint16, fbasn bool) (*update, error) {
+	if l < 23 {
+		return nil, &updateError{malformedAttributeList, fmt.Errorf("message length %d too small to be UPDATE", l)}
+	}
+	body := make([]byte, l-19)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return decodeUpdate(body, fbasn)
+}
+
+// decodeUpdate decodes the body of an update message. Errors in the
+// framing of the message are returned as an *updateError, while
+// malformed attribute values only set the attrErr of the update.
+func decodeUpdate(b []byte, fbasn bool) (*update, error) {
+	if len(b) < 4 {
+		return nil, &updateError{malformedAttributeList, fmt.Errorf("update of %d bytes too small", len(b))}
+	}
+	wdrLen := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < wdrLen+2 {
+		return nil, &updateError{malformedAttributeList, fmt.Errorf("withdrawn routes length %d overflows the message", wdrLen)}
+	}
+	withdrawn, err := decodePrefixes(b[:wdrLen], ipfamily.IPv4)
+	if err != nil {
+		return nil, &updateError{invalidNetworkField, fmt.Errorf("invalid withdrawn routes: %w", err)}
+	}
+	b = b[wdrLen:]
+	attrLen := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < attrLen {
+		return nil, &updateError{malformedAttributeList, fmt.Errorf("path attributes length %d overflows the message", attrLen)}
+	}
+	nlri, err := decodePrefixes(b[attrLen:], ipfamily.IPv4)
+	if err != nil {
+		return nil, &updateError{invalidNetworkField, fmt.Errorf("invalid NLRI: %w", err)}
+	}
+
+	ret := &update{withdrawn: withdrawn}
+	var (
+		attrs     bgp.Route
+		nextHop   net.IP
+		mpReach   []*net.IPNet
+		mpNextHop net.IP
+	)
+	// malformed records the first malformed attribute value. The
+	// attribute boundaries are still known, so parsing goes on.
+	malformed := func(err error) {
+		if ret.attrErr == nil {
+			ret.attrErr = err
+		}
+	}
+	b = b[:attrLen]
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, &updateError{malformedAttributeList, fmt.Errorf("truncated path attribute")}
+		}
+		flags, typ := b[0], b[1]
+		var l int
+		if flags&0x10 != 0 {
+			if len(b) < 4 {
+				return nil, &updateError{malformedAttributeList, fmt.Errorf("truncated path attribute %d", typ)}
+			}
+			l = int(binary.BigEndian.Uint16(b[2:4]))
+			b = b[4:]
+		} else {
+			l = int(b[2])
+			b = b[3:]
+		}
+		if len(b) < l {
+			return nil, &updateError{malformedAttributeList, fmt.Errorf("path attribute %d length %d overflows the message", typ, l)}
+		}
+		v := b[:l]
+		b = b[l:]
+
+		switch typ {
+		case 2: // as-path
+			asPath, err := decodeASPath(v, fbasn)
+			if err != nil {
+				malformed(err)
+				continue
+			}
+			attrs.ASPath = asPath
+		case 3: // next-hop
+			if l != 4 {
+				malformed(fmt.Errorf("invalid next-hop length %d", l))
+				continue
+			}
+			nextHop = net.IP(v).To16()
+		case 4: // med
+			if l != 4 {
+				malformed(fmt.Errorf("invalid med length %d", l))
+				continue
+			}
+			attrs.MED = binary.BigEndian.Uint32(v)
+		case 5: // localpref
+			if l != 4 {
+				malformed(fmt.Errorf("invalid localpref length %d", l))
+				continue
+			}
+			attrs.LocalPref = binary.BigEndian.Uint32(v)
+		case 8: // communities
+			cs, err := decodeCommunities(v)
+			if err != nil {
+				malformed(err)
+				continue
+			}
+			attrs.Communities = append(attrs.Communities, cs...)
+		case 14: // mp_reach_nlri
+			family, rest, err := decodeAFI(v)
+			if err != nil {
+				malformed(fmt.Errorf("invalid mp_reach_nlri: %w", err))
+				continue
+			}
+			if family == "" {
+				continue
+			}
+			if len(rest) < 1 || len(rest) < int(rest[0])+2 {
+				malformed(fmt.Errorf("truncated mp_reach_nlri"))
+				continue
+			}
+			nhLen := int(rest[0])
+			pfxs, err := decodePrefixes(rest[nhLen+2:], family)
+			if err != nil {
+				malformed(fmt.Errorf("invalid mp_reach_nlri: %w", err))
+				continue
+			}
+			// The next hop can be followed by a link-local address,
+			// only the first one is kept.
+			mpNextHop = net.IP(rest[1 : 1+min(nhLen, net.IPv6len)]).To16()
+			mpReach = pfxs
+		case 15: // mp_unreach_nlri
+			family, rest, err := decodeAFI(v)
+			if err != nil {
+				malformed(fmt.Errorf("invalid mp_unreach_nlri: %w", err))
+				continue
+			}
+			if family == "" {
+				continue
+			}
+			pfxs, err := decodePrefixes(rest, family)
+			if err != nil {
+				malformed(fmt.Errorf("invalid mp_unreach_nlri: %w", err))
+				continue
+			}
+			ret.withdrawn = append(ret.withdrawn, pfxs...)
+		case 32: // large communities
+			cs, err := decodeLargeCommunities(v)
+			if err != nil {
+				malformed(err)
+				continue
+			}
+			attrs.Communities = append(attrs.Communities, cs...)
+		}
+	}
+
+	if ret.attrErr != nil {
+		// Treat-as-withdraw: the prefixes announced with bad attributes
+		// are dropped, rather than kept with the attributes they had.
+		ret.withdrawn = append(ret.withdrawn, nlri...)
+		ret.withdrawn = append(ret.withdrawn, mpReach...)
+		return ret, nil
+	}
+	for _, pfx := range nlri {
+		r := attrs
+		r.Prefix = pfx
+		r.NextHop = nextHop
+		ret.routes = append(ret.routes, r)
+	}
+	for _, pfx := range mpReach {
+		r := attrs
+		r.Prefix = pfx
+		r.NextHop = mpNextHop
+		ret.routes = append(ret.routes, r)
+	}
+	return ret, nil
+}
+
+func decodeCommunities(b []byte) ([]community.BGPCommunity, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("invalid communities length %d", len(b))
+	}
+	ret := []community.BGPCommunity{}
+	for ; len(b) > 0; b = b[4:] {
+		c, err := community.New(fmt.Sprintf("%d:%d", binary.BigEndian.Uint16(b), binary.BigEndian.Uint16(b[2:])))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+func decodeLargeCommunities(b []byte) ([]community.BGPCommunity, error) {
+	if len(b)%12 != 0 {
+		return nil, fmt.Errorf("invalid large communities length %d", len(b))
+	}
+	ret := []community.BGPCommunity{}
+	for ; len(b) > 0; b = b[12:] {
+		c, err := community.New(fmt.Sprintf("large:%d:%d:%d",
+			binary.BigEndian.Uint32(b), binary.BigEndian.Uint32(b[4:]), binary.BigEndian.Uint32(b[8:])))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+// decodeAFI returns the address family of the AFI/SAFI at the start of
+// an MP_REACH_NLRI or MP_UNREACH_NLRI attribute, and the rest of the
+// attribute. The family is empty for anything but IPv4 and IPv6 unicast.
+func decodeAFI(b []byte) (ipfamily.Family, []byte, error) {
+	if len(b) < 3 {
+		return "", nil, fmt.Errorf("truncated address family")
+	}
+	afi, safi := binary.BigEndian.Uint16(b), b[2]
+	switch {
+	case afi == 1 && safi == 1:
+		return ipfamily.IPv4, b[3:], nil
+	case afi == 2 && safi == 1:
+		return ipfamily.IPv6, b[3:], nil
+	}
+	return "", b[3:], nil
+}
+
+func decodeASPath(b []byte, fbasn bool) ([]uint32, error) {
+	size := 2
+	if fbasn {
+		size = 4
+	}
+	ret := []uint32{}
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, fmt.Errorf("truncated as-path segment")
+		}
+		n := int(b[1])
+		b = b[2:]
+		if len(b) < n*size {
+			return nil, fmt.Errorf("as-path segment of %d ASes overflows the attribute", n)
+		}
+		for i := 0; i < n; i++ {
+			if fbasn {
+				ret = append(ret, binary.BigEndian.Uint32(b[i*size:]))
+			} else {
+				ret = append(ret, uint32(binary.BigEndian.Uint16(b[i*size:])))
+			}
+		}
+		b = b[n*size:]
+	}
+	return ret, nil
+}
+
+func decodePrefixes(b []byte, family ipfamily.Family) ([]*net.IPNet, error) {
+	size := net.IPv4len
+	if family == ipfamily.IPv6 {
+		size = net.IPv6len
+	}
+	ret := []*net.IPNet{}
+	for len(b) > 0 {
+		o := int(b[0])
+		n := bytesForBits(o)
+		if o > size*8 || len(b) < 1+n {
+			return nil, fmt.Errorf("invalid prefix length %d", o)
+		}
+		ip := make(net.IP, size)
+		copy(ip, b[1:1+n])
+		mask := net.CIDRMask(o, size*8)
+		ret = append(ret, &net.IPNet{IP: ip.Mask(mask), Mask: mask})
+		b = b[1+n:]
+	}
+	return ret, nil
+}
+
+// sendNotification sends a NOTIFICATION with the given error code and
+// subcode, and no data.
+func sendNotification(w io.Writer, code, subcode uint8) error {
+	msg := struct {
+		Marker1, Marker2 uint64
+		Len              uint16
+		Type             uint8
+		Code             uint8
+		Subcode          uint8
+	}{
+		Marker1: 0xffffffffffffffff,
+		Marker2: 0xffffffffffffffff,
+		Len:     21,
+		Type:    3,
+		Code:    code,
+		Subcode: subcode,
+	}
+	return binary.Write(w, binary.BigEndian, msg)
+}
+
 func sendKeepalive(w io.Writer) error {
 	msg := struct {
 		Marker1, Marker2 uint64
@@ -4,6 +4,7 @@ package native
 
 import (
 	"bytes"
+	"errors"
 	"net"
 	"os"
 	"path/filepath"
@@ -11,8 +12,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/bgp/community"
+	"go.universe.tf/metallb/internal/ipfamily"
+	"k8s.io/utils/ptr"
 )
 
 // Just test that sendOpen and readOpen can at least talk to each other.
@@ -20,7 +24,7 @@ func TestOpen(t *testing.T) {
 	var b bytes.Buffer
 	wantHold := 4 * time.Second
 	wantASN := uint32(12345)
-	if err := sendOpen(&b, wantASN, net.ParseIP("1.2.3.4"), wantHold); err != nil {
+	if err := sendOpen(&b, wantASN, net.ParseIP("1.2.3.4"), wantHold, true, nil); err != nil {
 		t.Fatalf("Send open: %s", err)
 	}
 	op, err := readOpen(&b)
@@ -35,6 +39,68 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpenGracefulRestart(t *testing.T) {
+	var b bytes.Buffer
+	gr := &gracefulRestart{
+		restarted:   true,
+		restartTime: 120 * time.Second,
+	}
+	if err := sendOpen(&b, 65000, net.ParseIP("1.2.3.4"), 90*time.Second, true, gr); err != nil {
+		t.Fatalf("Send open: %s", err)
+	}
+	want := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x3d, 0x01, // len, OPEN
+		0x04, 0xfd, 0xe8, 0x00, 0x5a, 0x01, 0x02, 0x03, 0x04, // version, asn, hold time, router id
+		0x20, 0x02, 0x1e, // capabilities
+		0x01, 0x04, 0x00, 0x01, 0x00, 0x01, // ipv4 unicast
+		0x01, 0x04, 0x00, 0x02, 0x00, 0x01, // ipv6 unicast
+		0x41, 0x04, 0x00, 0x00, 0xfd, 0xe8, // 4-byte asn
+		0x40, 0x0a, 0x80, 0x78, // graceful restart, restarted, 120s
+		0x00, 0x01, 0x01, 0x80, 0x00, 0x02, 0x01, 0x80,
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("Wrong OPEN, want\n% x\ngot\n% x", want, b.Bytes())
+	}
+
+	op, err := readOpen(&b)
+	if err != nil {
+		t.Fatalf("Read open: %s", err)
+	}
+	if !op.gracefulRestart {
+		t.Errorf("Graceful restart capability not found")
+	}
+	if op.restartTime != gr.restartTime {
+		t.Errorf("Wrong restart time, want %q, got %q", gr.restartTime, op.restartTime)
+	}
+
+	// Without IPv6, neither capability lists it.
+	b.Reset()
+	if err := sendOpen(&b, 65000, net.ParseIP("1.2.3.4"), 90*time.Second, false, gr); err != nil {
+		t.Fatalf("Send open: %s", err)
+	}
+	want = []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x33, 0x01, // len, OPEN
+		0x04, 0xfd, 0xe8, 0x00, 0x5a, 0x01, 0x02, 0x03, 0x04, // version, asn, hold time, router id
+		0x16, 0x02, 0x14, // capabilities
+		0x01, 0x04, 0x00, 0x01, 0x00, 0x01, // ipv4 unicast
+		0x41, 0x04, 0x00, 0x00, 0xfd, 0xe8, // 4-byte asn
+		0x40, 0x06, 0x80, 0x78, // graceful restart, restarted, 120s
+		0x00, 0x01, 0x01, 0x80,
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("Wrong OPEN, want\n% x\ngot\n% x", want, b.Bytes())
+	}
+	op, err = readOpen(&b)
+	if err != nil {
+		t.Fatalf("Read open: %s", err)
+	}
+	if op.mp6 {
+		t.Errorf("Unexpected IPv6 unicast capability")
+	}
+}
+
 func TestPcapInterop(t *testing.T) {
 	ms, err := filepath.Glob("testdata/open-*")
 	if err != nil {
@@ -99,8 +165,8 @@ func TestOpenFourByteASN(t *testing.T) {
 	}
 }
 
-// TestSendUpdate makes sure that sendUpdate fails if large communities are provided. The E2E tests take care of
-// testing further functionality. A decodeUpdate method would be needed for a more complete unit test.
+// TestSendUpdate makes sure that sendUpdate accepts legacy and large communities. The wire format is checked by
+// TestSendUpdateBytes.
 func TestSendUpdate(t *testing.T) {
 	tcs := map[string]struct {
 		asn         uint32
@@ -130,7 +196,7 @@ func TestSendUpdate(t *testing.T) {
 			},
 			errorString: "",
 		},
-		"send update with large communities should result in an error": {
+		"send update with large communities should succeed": {
 			asn:     65000,
 			ibgp:    false,
 			fbasn:   false,
@@ -148,7 +214,7 @@ func TestSendUpdate(t *testing.T) {
 				}(),
 				Peers: []string{},
 			},
-			errorString: "invalid community type for BGP native mode",
+			errorString: "",
 		},
 	}
 	for d, tc := range tcs {
@@ -163,6 +229,433 @@ func TestSendUpdate(t *testing.T) {
 	}
 }
 
+func TestSendUpdateBytes(t *testing.T) {
+	tcs := map[string]struct {
+		asn         uint32
+		ibgp        bool
+		fbasn       bool
+		nextHop     net.IP
+		prefix      string
+		communities []string
+		med         *uint32
+		prepend     uint32
+		want        []byte
+	}{
+		"ipv4 prefix over ebgp": {
+			asn:     65000,
+			fbasn:   true,
+			nextHop: net.ParseIP("192.168.123.10"),
+			prefix:  "172.16.0.0/24",
+			want: []byte{
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0x00, 0x2f, 0x02, // len, UPDATE
+				0x00, 0x00, // withdrawn routes len
+				0x00, 0x14, // path attributes len
+				0x40, 0x01, 0x01, 0x00, // origin
+				0x40, 0x02, 0x06, 0x02, 0x01, 0x00, 0x00, 0xfd, 0xe8, // as-path
+				0x40, 0x03, 0x04, 0xc0, 0xa8, 0x7b, 0x0a, // next-hop
+				0x18, 0xac, 0x10, 0x00, // nlri
+			},
+		},
+		"legacy and large communities": {
+			asn:         65000,
+			fbasn:       true,
+			nextHop:     net.ParseIP("192.168.123.10"),
+			prefix:      "172.16.0.0/24",
+			communities: []string{"0:1234", "large:123:456:789"},
+			want: []byte{
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0x00, 0x45, 0x02, // len, UPDATE
+				0x00, 0x00, // withdrawn routes len
+				0x00, 0x2a, // path attributes len
+				0x40, 0x01, 0x01, 0x00, // origin
+				0x40, 0x02, 0x06, 0x02, 0x01, 0x00, 0x00, 0xfd, 0xe8, // as-path
+				0x40, 0x03, 0x04, 0xc0, 0xa8, 0x7b, 0x0a, // next-hop
+				0xc0, 0x08, 0x04, 0x00, 0x00, 0x04, 0xd2, // communities
+				0xc0, 0x20, 0x0c, // large communities
+				0x00, 0x00, 0x00, 0x7b, 0x00, 0x00, 0x01, 0xc8, 0x00, 0x00, 0x03, 0x15,
+				0x18, 0xac, 0x10, 0x00, // nlri
+			},
+		},
+		"med and as-path prepend over ebgp": {
+			asn:     65000,
+			fbasn:   true,
+			nextHop: net.ParseIP("192.168.123.10"),
+			prefix:  "172.16.0.0/24",
+			med:     ptr.To[uint32](50),
+			prepend: 2,
+			want: []byte{
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0x00, 0x3e, 0x02, // len, UPDATE
+				0x00, 0x00, // withdrawn routes len
+				0x00, 0x23, // path attributes len
+				0x40, 0x01, 0x01, 0x00, // origin
+				0x40, 0x02, 0x0e, 0x02, 0x03, // as-path
+				0x00, 0x00, 0xfd, 0xe8, 0x00, 0x00, 0xfd, 0xe8, 0x00, 0x00, 0xfd, 0xe8,
+				0x40, 0x03, 0x04, 0xc0, 0xa8, 0x7b, 0x0a, // next-hop
+				0x80, 0x04, 0x04, 0x00, 0x00, 0x00, 0x32, // med
+				0x18, 0xac, 0x10, 0x00, // nlri
+			},
+		},
+		"as-path prepend over ibgp with 2-byte asns": {
+			asn:     100,
+			ibgp:    true,
+			nextHop: net.ParseIP("192.168.123.10"),
+			prefix:  "172.16.0.0/24",
+			prepend: 1,
+			want: []byte{
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0x00, 0x34, 0x02, // len, UPDATE
+				0x00, 0x00, // withdrawn routes len
+				0x00, 0x19, // path attributes len
+				0x40, 0x01, 0x01, 0x00, // origin
+				0x40, 0x02, 0x04, 0x02, 0x01, 0x00, 0x64, // as-path
+				0x40, 0x03, 0x04, 0xc0, 0xa8, 0x7b, 0x0a, // next-hop
+				0x40, 0x05, 0x04, 0x00, 0x00, 0x00, 0x64, // localpref
+				0x18, 0xac, 0x10, 0x00, // nlri
+			},
+		},
+		"ipv6 prefix over ibgp": {
+			asn:     65000,
+			ibgp:    true,
+			fbasn:   true,
+			nextHop: net.ParseIP("2001:db8::10"),
+			prefix:  "2001:db8:1::/48",
+			want: []byte{
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0x00, 0x44, 0x02, // len, UPDATE
+				0x00, 0x00, // withdrawn routes len
+				0x00, 0x2d, // path attributes len
+				0x40, 0x01, 0x01, 0x00, // origin
+				0x40, 0x02, 0x00, // empty as-path
+				0x40, 0x05, 0x04, 0x00, 0x00, 0x00, 0x64, // localpref
+				0x80, 0x0e, 0x1c, 0x00, 0x02, 0x01, 0x10, // mp_reach_nlri, ipv6 unicast
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+				0x00,                                     // reserved
+				0x30, 0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, // nlri
+			},
+		},
+	}
+	for d, tc := range tcs {
+		_, prefix, err := net.ParseCIDR(tc.prefix)
+		if err != nil {
+			t.Fatalf("%s: invalid prefix: %s", d, err)
+		}
+		adv := &bgp.Advertisement{
+			Prefix:        prefix,
+			LocalPref:     100,
+			MED:           tc.med,
+			ASPathPrepend: tc.prepend,
+		}
+		for _, c := range tc.communities {
+			bgpCommunity, err := community.New(c)
+			if err != nil {
+				t.Fatalf("%s: invalid community: %s", d, err)
+			}
+			adv.Communities = append(adv.Communities, bgpCommunity)
+		}
+		var b bytes.Buffer
+		if err := sendUpdate(&b, tc.asn, tc.ibgp, tc.fbasn, tc.nextHop, adv); err != nil {
+			t.Fatalf("%s: send update: %s", d, err)
+		}
+		if !bytes.Equal(b.Bytes(), tc.want) {
+			t.Errorf("%s: wrong UPDATE, want\n% x\ngot\n% x", d, tc.want, b.Bytes())
+		}
+	}
+}
+
+func TestSendWithdraw(t *testing.T) {
+	tcs := map[string]struct {
+		prefixes []string
+		want     []byte
+	}{
+		"ipv4 only": {
+			prefixes: []string{"172.16.0.0/24"},
+			want: []byte{
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0x00, 0x1b, 0x02, // len, UPDATE
+				0x00, 0x04, // withdrawn routes len
+				0x18, 0xac, 0x10, 0x00, // withdrawn routes
+				0x00, 0x00, // path attributes len
+			},
+		},
+		"ipv4 and ipv6": {
+			prefixes: []string{"172.16.0.0/24", "2001:db8:1::/48"},
+			want: []byte{
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0x00, 0x28, 0x02, // len, UPDATE
+				0x00, 0x04, // withdrawn routes len
+				0x18, 0xac, 0x10, 0x00, // withdrawn routes
+				0x00, 0x0d, // path attributes len
+				0x80, 0x0f, 0x0a, 0x00, 0x02, 0x01, // mp_unreach_nlri, ipv6 unicast
+				0x30, 0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, // withdrawn routes
+			},
+		},
+	}
+	for d, tc := range tcs {
+		prefixes := []*net.IPNet{}
+		for _, p := range tc.prefixes {
+			_, prefix, err := net.ParseCIDR(p)
+			if err != nil {
+				t.Fatalf("%s: invalid prefix: %s", d, err)
+			}
+			prefixes = append(prefixes, prefix)
+		}
+		var b bytes.Buffer
+		if err := sendWithdraw(&b, prefixes); err != nil {
+			t.Fatalf("%s: send withdraw: %s", d, err)
+		}
+		if !bytes.Equal(b.Bytes(), tc.want) {
+			t.Errorf("%s: wrong UPDATE, want\n% x\ngot\n% x", d, tc.want, b.Bytes())
+		}
+	}
+}
+
+func TestSendEndOfRIB(t *testing.T) {
+	tcs := map[ipfamily.Family][]byte{
+		ipfamily.IPv4: {
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0x00, 0x17, 0x02, // len, UPDATE
+			0x00, 0x00, // withdrawn routes len
+			0x00, 0x00, // path attributes len
+		},
+		ipfamily.IPv6: {
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0x00, 0x1d, 0x02, // len, UPDATE
+			0x00, 0x00, // withdrawn routes len
+			0x00, 0x06, // path attributes len
+			0x80, 0x0f, 0x03, 0x00, 0x02, 0x01, // empty mp_unreach_nlri, ipv6 unicast
+		},
+	}
+	for family, want := range tcs {
+		var b bytes.Buffer
+		if err := sendEndOfRIB(&b, family); err != nil {
+			t.Fatalf("%s: send End-of-RIB: %s", family, err)
+		}
+		if !bytes.Equal(b.Bytes(), want) {
+			t.Errorf("%s: wrong End-of-RIB, want\n% x\ngot\n% x", family, want, b.Bytes())
+		}
+	}
+}
+
+func TestReadUpdate(t *testing.T) {
+	communities := func(cs ...string) []community.BGPCommunity {
+		ret := []community.BGPCommunity{}
+		for _, c := range cs {
+			bgpCommunity, err := community.New(c)
+			if err != nil {
+				t.Fatalf("invalid community %q: %s", c, err)
+			}
+			ret = append(ret, bgpCommunity)
+		}
+		return ret
+	}
+	prefix := func(p string) *net.IPNet {
+		_, ret, err := net.ParseCIDR(p)
+		if err != nil {
+			t.Fatalf("invalid prefix %q: %s", p, err)
+		}
+		return ret
+	}
+
+	tcs := map[string]struct {
+		ibgp    bool
+		nextHop net.IP
+		adv     *bgp.Advertisement
+		want    bgp.Route
+	}{
+		"ipv4 with communities": {
+			nextHop: net.ParseIP("192.168.123.10"),
+			adv: &bgp.Advertisement{
+				Prefix:      prefix("172.16.0.0/24"),
+				Communities: communities("0:1234", "large:123:456:789"),
+			},
+			want: bgp.Route{
+				Prefix:      prefix("172.16.0.0/24"),
+				NextHop:     net.ParseIP("192.168.123.10"),
+				ASPath:      []uint32{65000},
+				Communities: communities("0:1234", "large:123:456:789"),
+			},
+		},
+		"ipv6 over ibgp": {
+			ibgp:    true,
+			nextHop: net.ParseIP("2001:db8::10"),
+			adv: &bgp.Advertisement{
+				Prefix:    prefix("2001:db8:1::/48"),
+				LocalPref: 200,
+			},
+			want: bgp.Route{
+				Prefix:    prefix("2001:db8:1::/48"),
+				NextHop:   net.ParseIP("2001:db8::10"),
+				ASPath:    []uint32{},
+				LocalPref: 200,
+			},
+		},
+	}
+	for d, tc := range tcs {
+		var b bytes.Buffer
+		if err := sendUpdate(&b, 65000, tc.ibgp, true, tc.nextHop, tc.adv); err != nil {
+			t.Fatalf("%s: send update: %s", d, err)
+		}
+		u, err := readUpdate(bytes.NewReader(b.Bytes()[19:]), uint16(b.Len()), true)
+		if err != nil {
+			t.Fatalf("%s: read update: %s", d, err)
+		}
+		if len(u.withdrawn) != 0 {
+			t.Errorf("%s: unexpected withdrawn routes %v", d, u.withdrawn)
+		}
+		if diff := cmp.Diff([]bgp.Route{tc.want}, u.routes,
+			cmp.AllowUnexported(community.BGPCommunityLegacy{}, community.BGPCommunityLarge{})); diff != "" {
+			t.Errorf("%s: unexpected routes (-want +got)\n%s", d, diff)
+		}
+	}
+
+	var b bytes.Buffer
+	withdrawn := []*net.IPNet{prefix("172.16.0.0/24"), prefix("2001:db8:1::/48")}
+	if err := sendWithdraw(&b, withdrawn); err != nil {
+		t.Fatalf("send withdraw: %s", err)
+	}
+	u, err := readUpdate(bytes.NewReader(b.Bytes()[19:]), uint16(b.Len()), true)
+	if err != nil {
+		t.Fatalf("read withdraw: %s", err)
+	}
+	if diff := cmp.Diff(withdrawn, u.withdrawn); diff != "" {
+		t.Errorf("unexpected withdrawn routes (-want +got)\n%s", diff)
+	}
+	if len(u.routes) != 0 {
+		t.Errorf("unexpected routes %v", u.routes)
+	}
+}
+
+// updateMessage returns an UPDATE message with the given path
+// attributes and IPv4 NLRI, and no withdrawn routes.
+func updateMessage(attrs, nlri []byte) []byte {
+	l := 19 + 4 + len(attrs) + len(nlri)
+	ret := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		byte(l >> 8), byte(l), 0x02, // len, UPDATE
+		0x00, 0x00, // withdrawn routes length
+		byte(len(attrs) >> 8), byte(len(attrs)),
+	}
+	ret = append(ret, attrs...)
+	return append(ret, nlri...)
+}
+
+func TestReadUpdateMalformed(t *testing.T) {
+	var (
+		origin  = []byte{0x40, 0x01, 0x01, 0x00}
+		asPath  = []byte{0x40, 0x02, 0x00}
+		nextHop = []byte{0x40, 0x03, 0x04, 0x0a, 0x00, 0x00, 0x01}
+		nlri    = []byte{0x18, 0x0a, 0x01, 0x02} // 10.1.2.0/24
+	)
+	concat := func(bs ...[]byte) []byte {
+		return bytes.Join(bs, nil)
+	}
+	_, want, _ := net.ParseCIDR("10.1.2.0/24")
+
+	tcs := map[string]struct {
+		msg []byte
+		// wantSubcode is set when the session must be reset.
+		wantSubcode uint8
+	}{
+		"bad localpref length": {
+			msg: updateMessage(concat(origin, asPath, nextHop, []byte{0x40, 0x05, 0x03, 0x00, 0x00, 0x64}), nlri),
+		},
+		"bad communities length": {
+			msg: updateMessage(concat(origin, asPath, nextHop, []byte{0xc0, 0x08, 0x02, 0x00, 0x01}), nlri),
+		},
+		"truncated as-path": {
+			msg: updateMessage(concat(origin, []byte{0x40, 0x02, 0x02, 0x02, 0x01}, nextHop), nlri),
+		},
+		"truncated mp_reach_nlri": {
+			msg: updateMessage(concat(origin, asPath, nextHop, []byte{0x80, 0x0e, 0x05, 0x00, 0x02, 0x01, 0x10, 0x20}), nlri),
+		},
+		"attribute overflowing the message": {
+			msg:         updateMessage(concat(origin, asPath, []byte{0x40, 0x03, 0x10, 0x0a}), nil),
+			wantSubcode: malformedAttributeList,
+		},
+		"invalid nlri": {
+			msg:         updateMessage(concat(origin, asPath, nextHop), []byte{0x21, 0x0a, 0x01, 0x02, 0x03, 0x04}),
+			wantSubcode: invalidNetworkField,
+		},
+	}
+	for d, tc := range tcs {
+		u, err := readUpdate(bytes.NewReader(tc.msg[19:]), uint16(len(tc.msg)), true)
+		if tc.wantSubcode != 0 {
+			var uerr *updateError
+			if !errors.As(err, &uerr) {
+				t.Errorf("%s: expected an update error, got %v", d, err)
+				continue
+			}
+			if uerr.subcode != tc.wantSubcode {
+				t.Errorf("%s: wrong subcode, want %d, got %d", d, tc.wantSubcode, uerr.subcode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: read update: %s", d, err)
+			continue
+		}
+		if u.attrErr == nil {
+			t.Errorf("%s: expected an attribute error", d)
+		}
+		if len(u.routes) != 0 {
+			t.Errorf("%s: unexpected routes %v", d, u.routes)
+		}
+		if diff := cmp.Diff([]*net.IPNet{want}, u.withdrawn); diff != "" {
+			t.Errorf("%s: update not treated as withdraw (-want +got)\n%s", d, diff)
+		}
+	}
+}
+
+func TestSendNotification(t *testing.T) {
+	var b bytes.Buffer
+	if err := sendNotification(&b, 3, malformedAttributeList); err != nil {
+		t.Fatalf("send notification: %s", err)
+	}
+	want := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x15, 0x03, // len, NOTIFICATION
+		0x03, 0x01, // update message error, malformed attribute list
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("Wrong NOTIFICATION, want\n% x\ngot\n% x", want, b.Bytes())
+	}
+}
+
+func TestReadNotification(t *testing.T) {
+	msg := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x17, 0x03, // len, NOTIFICATION
+		0x06, 0x02, // cease, administrative shutdown
+		0xbe, 0xef, // data
+	}
+	_, err := readOpen(bytes.NewReader(msg))
+	var n *notificationError
+	if !errors.As(err, &n) {
+		t.Fatalf("expected a notification error, got %v", err)
+	}
+	if n.code != 6 || n.subcode != 2 || !bytes.Equal(n.data, []byte{0xbe, 0xef}) {
+		t.Errorf("wrong notification %+v", n)
+	}
+	if want := "got BGP notification code 0x0602 (Administrative Shutdown)"; err.Error() != want {
+		t.Errorf("wrong error, want %q, got %q", want, err.Error())
+	}
+}
+
+func FuzzReadUpdate(f *testing.F) {
+	var b bytes.Buffer
+	_, prefix, _ := net.ParseCIDR("2001:db8:1::/48")
+	if err := sendUpdate(&b, 65000, false, true, net.ParseIP("2001:db8::10"), &bgp.Advertisement{Prefix: prefix}); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(b.Bytes()[19:])
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		_, _ = decodeUpdate(input, true)
+	})
+}
+
 func FuzzReadOpen(f *testing.F) {
 	ms, err := filepath.Glob("testdata/open-*")
 	if err != nil {
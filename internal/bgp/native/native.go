@@ -12,6 +12,8 @@ import (
 	"io"
 	"net"
 	"os"
+	"slices"
+	"sort"
 	"sync"
 	"syscall"
 	"time"
@@ -20,6 +22,7 @@ import (
 	"github.com/go-kit/log/level"
 	"go.universe.tf/metallb/internal/bgp"
 	"go.universe.tf/metallb/internal/config"
+	"go.universe.tf/metallb/internal/ipfamily"
 	"go.universe.tf/metallb/internal/safeconvert"
 	"golang.org/x/sys/unix"
 )
@@ -30,6 +33,11 @@ var errClosed = errors.New("session closed")
 type session struct {
 	bgp.SessionParameters
 	peerFBASNSupport bool
+	// Graceful restart negotiated with the peer, End-of-RIB markers
+	// are sent after the initial updates.
+	endOfRIB bool
+	// A session was established once already.
+	established bool
 
 	logger log.Logger
 
@@ -41,20 +49,39 @@ type session struct {
 	closed         bool
 	conn           net.Conn
 	actualHoldTime time.Duration
-	nextHop        net.IP
+	keepaliveTime  time.Duration
+	nextHop4       net.IP
+	nextHop6       net.IP
 	advertised     map[string]*bgp.Advertisement
 	new            map[string]*bgp.Advertisement
+	// Prefixes not sent to the peer for lack of a next hop of their
+	// address family, warned about once per connection.
+	skipped map[string]bool
+	// Routes received from the peer, by prefix.
+	adjRIBIn     map[string]bgp.Route
+	capabilities []string
+	lastError    error
+	upSince      time.Time
 
 	// peerName identifies this BGP session to be used for metrics
 	peerName string
 }
 
-// The 'Native' implementation does not require a session manager .
+// The 'Native' implementation does not require a session manager,
+// it only holds the bounds of the backoff between connection attempts.
 type sessionManager struct {
+	backoffMin time.Duration
+	backoffMax time.Duration
 }
 
-func NewSessionManager(l log.Logger) bgp.SessionManager {
-	return &sessionManager{}
+// NewSessionManager returns a session manager whose sessions retry
+// connecting with a delay doubling from backoffMin up to backoffMax,
+// unless the peer has a connect time.
+func NewSessionManager(l log.Logger, backoffMin, backoffMax time.Duration) bgp.SessionManager {
+	return &sessionManager{
+		backoffMin: backoffMin,
+		backoffMax: backoffMax,
+	}
 }
 
 // NewSession() creates a BGP session using the given session parameters.
@@ -69,11 +96,18 @@ func (sm *sessionManager) NewSession(l log.Logger, args bgp.SessionParameters) (
 		ht := 90 * time.Second
 		sessionsParams.HoldTime = &ht
 	}
+	if args.GracefulRestart && args.GracefulRestartTime == nil {
+		rt := 120 * time.Second
+		sessionsParams.GracefulRestartTime = &rt
+	}
 	ret := &session{
 		SessionParameters: sessionsParams,
 		logger:            log.With(l, "peer", args.PeerAddress, "localASN", args.MyASN, "peerASN", args.PeerASN),
 		newHoldTime:       make(chan bool, 1),
+		backoff:           backoff{min: sm.backoffMin, max: sm.backoffMax},
 		advertised:        map[string]*bgp.Advertisement{},
+		skipped:           map[string]bool{},
+		adjRIBIn:          map[string]bgp.Route{},
 		peerName:          fmt.Sprintf("%s:%d", args.PeerAddress, args.PeerPort),
 	}
 	ret.cond = sync.NewCond(&ret.mu)
@@ -110,8 +144,14 @@ func (s *session) run() {
 			if err == errClosed {
 				return
 			}
+			s.setLastError(err)
 			level.Error(s.logger).Log("op", "connect", "error", err, "msg", "failed to connect to peer")
 			backoff := s.backoff.Duration()
+			// The connect time replaces the backoff, as in FRR it is
+			// the fixed delay between connection attempts.
+			if s.ConnectTime != nil {
+				backoff = *s.ConnectTime
+			}
 			time.Sleep(backoff)
 			continue
 		}
@@ -119,12 +159,21 @@ func (s *session) run() {
 		s.backoff.Reset()
 
 		level.Info(s.logger).Log("event", "sessionUp", "msg", "BGP session established")
+		s.statusChanged()
 
 		if !s.sendUpdates() {
 			return
 		}
 		stats.SessionDown(s.peerName)
 		level.Warn(s.logger).Log("event", "sessionDown", "msg", "BGP session down")
+		s.statusChanged()
+	}
+}
+
+// statusChanged notifies the owner of the session that it went up or down.
+func (s *session) statusChanged() {
+	if s.StatusChanged != nil {
+		s.StatusChanged()
 	}
 }
 
@@ -149,14 +198,26 @@ func (s *session) sendUpdates() bool {
 	}
 
 	for c, adv := range s.advertised {
-		if err := sendUpdate(s.conn, s.MyASN, ibgp, fbasn, s.nextHop, adv); err != nil {
+		nextHop := s.nextHopToSend(adv)
+		if nextHop == nil {
+			continue
+		}
+		if err := sendUpdate(s.conn, s.MyASN, ibgp, fbasn, nextHop, adv); err != nil {
 			s.abort()
 			level.Error(s.logger).Log("op", "sendUpdate", "ip", c, "error", err, "msg", "failed to send BGP update")
 			return true
 		}
 		stats.UpdateSent(s.peerName)
 	}
-	stats.AdvertisedPrefixes(s.peerName, len(s.advertised))
+	stats.AdvertisedPrefixes(s.peerName, s.sentPrefixes())
+
+	if s.endOfRIB {
+		if err := s.sendEndOfRIB(); err != nil {
+			s.abort()
+			level.Error(s.logger).Log("op", "sendEndOfRIB", "error", err, "msg", "failed to send BGP End-of-RIB")
+			return true
+		}
+	}
 
 	for {
 		for s.new == nil && s.conn != nil {
@@ -181,8 +242,12 @@ func (s *session) sendUpdates() bool {
 				// advertisement, nothing to do.
 				continue
 			}
+			nextHop := s.nextHopToSend(adv)
+			if nextHop == nil {
+				continue
+			}
 
-			if err := sendUpdate(s.conn, s.MyASN, ibgp, fbasn, s.nextHop, adv); err != nil {
+			if err := sendUpdate(s.conn, s.MyASN, ibgp, fbasn, nextHop, adv); err != nil {
 				s.abort()
 				level.Error(s.logger).Log("op", "sendUpdate", "prefix", c, "error", err, "msg", "failed to send BGP update")
 				return true
@@ -192,7 +257,7 @@ func (s *session) sendUpdates() bool {
 
 		wdr := []*net.IPNet{}
 		for c, adv := range s.advertised {
-			if s.new[c] == nil {
+			if s.new[c] == nil && s.nextHopFor(adv.Prefix) != nil {
 				wdr = append(wdr, adv.Prefix)
 			}
 		}
@@ -207,7 +272,7 @@ func (s *session) sendUpdates() bool {
 			stats.UpdateSent(s.peerName)
 		}
 		s.advertised, s.new = s.new, nil
-		stats.AdvertisedPrefixes(s.peerName, len(s.advertised))
+		stats.AdvertisedPrefixes(s.peerName, s.sentPrefixes())
 	}
 }
 
@@ -239,17 +304,29 @@ func (s *session) connect() error {
 		conn.Close()
 		return fmt.Errorf("getting local addr for default nexthop to %q: %s", s.PeerAddress, err)
 	}
-	s.nextHop = addr.IP
+	s.nextHop4, s.nextHop6 = getNextHops(addr.IP, s.IPv6NextHop)
+	s.skipped = map[string]bool{}
 
 	routerID := s.RouterID
 	if routerID == nil {
-		routerID, err = getRouterID(s.nextHop, s.CurrentNode)
+		routerID, err = getRouterID(addr.IP, s.CurrentNode)
 		if err != nil {
 			return err
 		}
 	}
 
-	if err = sendOpen(conn, s.MyASN, routerID, *s.HoldTime); err != nil {
+	var gr *gracefulRestart
+	if s.GracefulRestart {
+		gr = &gracefulRestart{
+			restarted:   !s.established,
+			restartTime: *s.GracefulRestartTime,
+		}
+	}
+
+	// IPv6 unicast is only advertised when the session has an IPv6 next hop
+	// to announce IPv6 prefixes with.
+	ipv6 := s.nextHop6 != nil
+	if err = sendOpen(conn, s.MyASN, routerID, *s.HoldTime, ipv6, gr); err != nil {
 		conn.Close()
 		return fmt.Errorf("send OPEN to %q: %s", s.PeerAddress, err)
 	}
@@ -268,6 +345,25 @@ func (s *session) connect() error {
 		conn.Close()
 		return fmt.Errorf("peer does not support 4-byte ASNs")
 	}
+	op.mp6 = op.mp6 && ipv6
+	if !op.mp6 {
+		s.nextHop6 = nil
+	}
+	s.endOfRIB = s.GracefulRestart && op.gracefulRestart
+	if s.GracefulRestart && !op.gracefulRestart {
+		level.Warn(s.logger).Log("op", "connect", "msg", "peer does not support graceful restart, routes will be withdrawn on restarts")
+	}
+	if op.gracefulRestart {
+		level.Debug(s.logger).Log("op", "connect", "peerRestartTime", op.restartTime, "msg", "peer supports graceful restart")
+	}
+	s.capabilities = negotiatedCapabilities(op, s.GracefulRestart)
+	stats.Capabilities(s.peerName, s.capabilities)
+	if s.nextHop4 == nil {
+		level.Warn(s.logger).Log("op", "connect", "msg", "no IPv4 next hop found, IPv4 prefixes won't be advertised")
+	}
+	if s.nextHop6 == nil {
+		level.Warn(s.logger).Log("op", "connect", "msg", "no IPv6 next hop found or IPv6 unicast not supported by the peer, IPv6 prefixes won't be advertised")
+	}
 
 	// BGP session is established, clear the connect timeout deadline.
 	if err := conn.SetDeadline(time.Time{}); err != nil {
@@ -276,7 +372,7 @@ func (s *session) connect() error {
 	}
 
 	// Consume BGP messages until the connection closes.
-	go s.consumeBGP(conn)
+	go s.consumeBGP(conn, op.fbasn)
 
 	// Send one keepalive to say that yes, we accept the OPEN.
 	if err := sendKeepalive(conn); err != nil {
@@ -289,12 +385,18 @@ func (s *session) connect() error {
 	if op.holdTime < s.actualHoldTime {
 		s.actualHoldTime = op.holdTime
 	}
+	s.keepaliveTime = keepaliveInterval(s.actualHoldTime, s.KeepAliveTime)
 	select {
 	case s.newHoldTime <- true:
 	default:
 	}
 
 	s.conn = conn
+	s.established = true
+	s.upSince = time.Now()
+	// The OPEN exchange succeeded, the error of the previous session
+	// no longer applies.
+	s.lastError = nil
 	return nil
 }
 
@@ -313,45 +415,69 @@ func getRouterID(addr net.IP, myNode string) (net.IP, error) {
 	if addr.To4() != nil {
 		return addr, nil
 	}
+	if ip := sameInterfaceAddress(addr, isIPv4); ip != nil {
+		return ip, nil
+	}
+	return hashRouterID(myNode)
+}
+
+// getNextHops returns the next hops of the IPv4 and IPv6 prefixes for a
+// session whose local address is addr. The address of the other family
+// is picked on the same interface as addr, and is nil if there is none.
+func getNextHops(addr, ipv6NextHop net.IP) (net.IP, net.IP) {
+	if addr.To4() != nil {
+		if ipv6NextHop == nil {
+			ipv6NextHop = sameInterfaceAddress(addr, isGlobalIPv6)
+		}
+		return addr, ipv6NextHop
+	}
+	if ipv6NextHop == nil {
+		ipv6NextHop = addr
+	}
+	return sameInterfaceAddress(addr, isIPv4), ipv6NextHop
+}
+
+func isIPv4(ip net.IP) bool {
+	return ip.To4() != nil
+}
 
+func isGlobalIPv6(ip net.IP) bool {
+	return ip.To4() == nil && ip.IsGlobalUnicast()
+}
+
+// sameInterfaceAddress returns the first address accepted by match on the
+// interface holding addr, or nil if there is none.
+func sameInterfaceAddress(addr net.IP, match func(net.IP) bool) net.IP {
 	ifaces, err := net.Interfaces()
 	if err != nil {
-		return hashRouterID(myNode)
+		return nil
 	}
 	for _, i := range ifaces {
 		addrs, err := i.Addrs()
 		if err != nil {
 			continue
 		}
+		ips := make([]net.IP, 0, len(addrs))
 		for _, a := range addrs {
-			var ip net.IP
 			switch v := a.(type) {
 			case *net.IPNet:
-				ip = v.IP
+				ips = append(ips, v.IP)
 			case *net.IPAddr:
-				ip = v.IP
+				ips = append(ips, v.IP)
 			}
-
-			if ip.Equal(addr) {
-				// This is the interface.
-				// Loop through the addresses again and search for ipv4
-				for _, a := range addrs {
-					var ip net.IP
-					switch v := a.(type) {
-					case *net.IPNet:
-						ip = v.IP
-					case *net.IPAddr:
-						ip = v.IP
-					}
-					if ip.To4() != nil {
-						return ip, nil
-					}
-				}
-				return hashRouterID(myNode)
+		}
+		if !slices.ContainsFunc(ips, addr.Equal) {
+			continue
+		}
+		// This is the interface.
+		for _, ip := range ips {
+			if match(ip) {
+				return ip
 			}
 		}
+		return nil
 	}
-	return hashRouterID(myNode)
+	return nil
 }
 
 // sendKeepalives sends BGP KEEPALIVE packets at the negotiated rate
@@ -367,6 +493,7 @@ func (s *session) sendKeepalives() {
 		case <-s.newHoldTime:
 			s.mu.Lock()
 			ht := s.actualHoldTime
+			ka := s.keepaliveTime
 			s.mu.Unlock()
 			if t != nil {
 				t.Stop()
@@ -374,7 +501,7 @@ func (s *session) sendKeepalives() {
 				ch = nil
 			}
 			if ht != 0 {
-				t = time.NewTicker(ht / 3)
+				t = time.NewTicker(ka)
 				ch = t.C
 			}
 
@@ -388,6 +515,16 @@ func (s *session) sendKeepalives() {
 	}
 }
 
+// keepaliveInterval returns the interval between KEEPALIVE packets for the
+// negotiated hold time. The configured keepalive time is used when it is
+// shorter than the hold time, one third of the hold time otherwise.
+func keepaliveInterval(holdTime time.Duration, keepaliveTime *time.Duration) time.Duration {
+	if keepaliveTime != nil && *keepaliveTime > 0 && *keepaliveTime < holdTime {
+		return *keepaliveTime
+	}
+	return holdTime / 3
+}
+
 // sendKeepalive sends a single BGP KEEPALIVE packet.
 func (s *session) sendKeepalive() error {
 	s.mu.Lock()
@@ -407,10 +544,13 @@ func (s *session) sendKeepalive() error {
 	return nil
 }
 
-// consumeBGP receives BGP messages from the peer, and ignores
-// them. It does minimal checks for the well-formedness of messages,
-// and terminates the connection if something looks wrong.
-func (s *session) consumeBGP(conn io.ReadCloser) {
+// consumeBGP receives BGP messages from the peer, and keeps the routes
+// it sends in the Adj-RIB-In. It does minimal checks for the
+// well-formedness of messages, and terminates the connection if
+// something looks wrong. UPDATEs with malformed path attributes are
+// treated as withdrawals, the session is only reset when an UPDATE
+// cannot be parsed at all.
+func (s *session) consumeBGP(conn io.ReadWriteCloser, fbasn bool) {
 	defer func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
@@ -432,27 +572,133 @@ func (s *session) consumeBGP(conn io.ReadCloser) {
 			return
 		}
 		if hdr.Marker1 != 0xffffffffffffffff || hdr.Marker2 != 0xffffffffffffffff {
-			// TODO: propagate
+			level.Error(s.logger).Log("event", "peerMessage", "msg", "synchronization error, incorrect header marker, closing session")
 			return
 		}
-		if hdr.Type == 3 {
-			// TODO: propagate better than just logging directly.
-			err := readNotification(conn)
-			level.Error(s.logger).Log("event", "peerNotification", "error", err, "msg", "peer sent notification, closing session")
+		if hdr.Len < 19 || hdr.Len > 4096 {
+			level.Error(s.logger).Log("event", "peerMessage", "len", hdr.Len, "msg", "bad message length, closing session")
 			return
 		}
-		if _, err := io.Copy(io.Discard, io.LimitReader(conn, int64(hdr.Len)-19)); err != nil {
-			// TODO: propagate
+		switch hdr.Type {
+		case 2:
+			u, err := readUpdate(conn, hdr.Len, fbasn)
+			var uerr *updateError
+			if errors.As(err, &uerr) {
+				if err := sendNotification(conn, 3, uerr.subcode); err != nil {
+					level.Error(s.logger).Log("event", "peerUpdate", "error", err, "msg", "failed to send notification")
+				}
+				s.setLastError(err)
+				level.Error(s.logger).Log("event", "peerUpdate", "error", err, "msg", "malformed update, closing session")
+				return
+			}
+			if err != nil {
+				level.Error(s.logger).Log("event", "peerUpdate", "error", err, "msg", "failed to read update, closing session")
+				return
+			}
+			if u.attrErr != nil {
+				level.Warn(s.logger).Log("event", "peerUpdate", "error", u.attrErr, "msg", "malformed path attributes, treating update as withdraw")
+			}
+			s.receiveUpdate(conn, u)
+		case 3:
+			err := readNotification(conn, hdr.Len)
+			var n *notificationError
+			if errors.As(err, &n) {
+				stats.NotificationReceived(s.peerName, n.code, n.subcode)
+			}
+			s.setLastError(err)
+			level.Error(s.logger).Log("event", "peerNotification", "error", err, "msg", "peer sent notification, closing session")
 			return
+		default:
+			if _, err := io.Copy(io.Discard, io.LimitReader(conn, int64(hdr.Len)-19)); err != nil {
+				// TODO: propagate
+				return
+			}
 		}
 	}
 }
 
-func validate(adv *bgp.Advertisement) error {
-	if adv.Prefix.IP.To4() == nil {
-		return fmt.Errorf("cannot advertise non-v4 prefix %q", adv.Prefix)
+// receiveUpdate applies an update received on conn to the Adj-RIB-In.
+func (s *session) receiveUpdate(conn io.ReadCloser, u *update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != conn {
+		return
+	}
+	for _, pfx := range u.withdrawn {
+		delete(s.adjRIBIn, pfx.String())
+	}
+	for _, r := range u.routes {
+		s.adjRIBIn[r.Prefix.String()] = r
+	}
+	stats.UpdateReceived(s.peerName)
+	stats.ReceivedPrefixes(s.peerName, len(s.adjRIBIn))
+}
+
+func (s *session) setLastError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = err
+}
+
+// negotiatedCapabilities returns the capabilities advertised by both
+// ends of the session.
+func negotiatedCapabilities(op *openResult, gracefulRestart bool) []string {
+	ret := []string{}
+	if op.mp4 {
+		ret = append(ret, "ipv4-unicast")
+	}
+	if op.mp6 {
+		ret = append(ret, "ipv6-unicast")
+	}
+	if op.fbasn {
+		ret = append(ret, "4-byte-asn")
 	}
+	if gracefulRestart && op.gracefulRestart {
+		ret = append(ret, "graceful-restart")
+	}
+	return ret
+}
+
+// Status returns the state of the session.
+func (s *session) Status() bgp.SessionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret := bgp.SessionStatus{
+		Established:      s.conn != nil,
+		ReceivedPrefixes: len(s.adjRIBIn),
+	}
+	if s.conn != nil {
+		ret.Capabilities = slices.Clone(s.capabilities)
+		ret.SentPrefixes = s.sentPrefixes()
+		ret.UpSince = s.upSince
+	}
+	if s.lastError != nil {
+		ret.LastError = s.lastError.Error()
+		var n *notificationError
+		if errors.As(s.lastError, &n) {
+			ret.LastErrorCode, ret.LastErrorSubcode = n.code, n.subcode
+		}
+	}
+	return ret
+}
+
+// ReceivedRoutes returns the routes received from the peer, sorted by prefix.
+func (s *session) ReceivedRoutes() []bgp.Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	ret := make([]bgp.Route, 0, len(s.adjRIBIn))
+	for _, r := range s.adjRIBIn {
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Prefix.String() < ret[j].Prefix.String()
+	})
+	return ret
+}
+
+func validate(adv *bgp.Advertisement) error {
 	if len(adv.Communities) > 63 {
 		return fmt.Errorf("max supported communities is 63, got %d", len(adv.Communities))
 	}
@@ -484,14 +730,76 @@ func (s *session) Set(advs ...*bgp.Advertisement) error {
 	return nil
 }
 
+// sendEndOfRIB sends the End-of-RIB markers of the address families
+// advertised to the peer.
+func (s *session) sendEndOfRIB() error {
+	if s.nextHop4 != nil {
+		if err := sendEndOfRIB(s.conn, ipfamily.IPv4); err != nil {
+			return err
+		}
+		stats.UpdateSent(s.peerName)
+	}
+	if s.nextHop6 != nil {
+		if err := sendEndOfRIB(s.conn, ipfamily.IPv6); err != nil {
+			return err
+		}
+		stats.UpdateSent(s.peerName)
+	}
+	return nil
+}
+
+// nextHopFor returns the next hop to announce the prefix with, or nil
+// if its address family can't be advertised to the peer.
+func (s *session) nextHopFor(prefix *net.IPNet) net.IP {
+	if prefix.IP.To4() != nil {
+		return s.nextHop4
+	}
+	return s.nextHop6
+}
+
+// nextHopToSend returns the next hop to announce the advertisement with, or
+// nil if it can't be sent to the peer, warning the first time it can't.
+func (s *session) nextHopToSend(adv *bgp.Advertisement) net.IP {
+	nextHop := s.nextHopFor(adv.Prefix)
+	if nextHop == nil && !s.skipped[adv.Prefix.String()] {
+		level.Warn(s.logger).Log("op", "sendUpdate", "prefix", adv.Prefix, "msg", "no next hop for the address family of the prefix, not advertising it")
+		s.skipped[adv.Prefix.String()] = true
+	}
+	return nextHop
+}
+
+// sentPrefixes returns how many of the advertised prefixes are sent to the
+// peer, leaving out the ones whose address family has no next hop.
+func (s *session) sentPrefixes() int {
+	n := 0
+	for _, adv := range s.advertised {
+		if s.nextHopFor(adv.Prefix) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Advertises tells whether the prefix is sent to the peer when set on the
+// session. All the prefixes are assumed to be until the session is
+// established and the next hops are known.
+func (s *session) Advertises(prefix *net.IPNet) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn == nil || s.nextHopFor(prefix) != nil
+}
+
 // abort closes any existing connection, updates stats, and cleans up
 // state ready for another connection attempt.
 func (s *session) abort() {
 	if s.conn != nil {
 		s.conn.Close()
 		s.conn = nil
+		s.upSince = time.Time{}
 		stats.SessionDown(s.peerName)
 	}
+	// The routes received from the peer go away with the session.
+	s.adjRIBIn = map[string]bgp.Route{}
 	// Next time we retry the connection, we can just skip straight to
 	// the desired end state.
 	if s.new != nil {
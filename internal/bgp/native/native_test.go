@@ -0,0 +1,265 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package native
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"go.universe.tf/metallb/internal/bgp"
+)
+
+func TestConsumeBGPMalformedUpdates(t *testing.T) {
+	s := &session{
+		logger:   log.NewNopLogger(),
+		adjRIBIn: map[string]bgp.Route{},
+		peerName: "test",
+	}
+	s.cond = sync.NewCond(&s.mu)
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	s.conn = conn
+	done := make(chan struct{})
+	go func() {
+		s.consumeBGP(conn, true)
+		close(done)
+	}()
+
+	var (
+		origin  = []byte{0x40, 0x01, 0x01, 0x00}
+		asPath  = []byte{0x40, 0x02, 0x00}
+		nextHop = []byte{0x40, 0x03, 0x04, 0x0a, 0x00, 0x00, 0x01}
+		attrs   = bytes.Join([][]byte{origin, asPath, nextHop}, nil)
+	)
+	send := func(msg []byte) {
+		t.Helper()
+		if _, err := peer.Write(msg); err != nil {
+			t.Fatalf("write to session: %s", err)
+		}
+	}
+	waitForRoutes := func(want ...string) {
+		t.Helper()
+		var got []string
+		for i := 0; i < 100; i++ {
+			got = nil
+			for _, r := range s.ReceivedRoutes() {
+				got = append(got, r.Prefix.String())
+			}
+			if slices.Equal(got, want) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("wrong received routes, want %v, got %v", want, got)
+	}
+
+	send(updateMessage(attrs, []byte{0x18, 0x0a, 0x01, 0x02}))
+	waitForRoutes("10.1.2.0/24")
+
+	// A bad localpref turns the update into a withdraw, and the session
+	// stays up.
+	badLocalPref := append(bytes.Clone(attrs), 0x40, 0x05, 0x03, 0x00, 0x00, 0x64)
+	send(updateMessage(badLocalPref, []byte{0x18, 0x0a, 0x01, 0x02}))
+	send(updateMessage(attrs, []byte{0x18, 0x0a, 0x01, 0x03}))
+	waitForRoutes("10.1.3.0/24")
+
+	// An update that cannot be parsed resets the session, after a
+	// NOTIFICATION is sent to the peer.
+	send(updateMessage(bytes.Join([][]byte{origin, asPath, {0x40, 0x03, 0x10, 0x0a}}, nil), nil))
+	notification := make([]byte, 21)
+	if _, err := io.ReadFull(peer, notification); err != nil {
+		t.Fatalf("read notification: %s", err)
+	}
+	if want := []byte{0x00, 0x15, 0x03, 0x03, 0x01}; !bytes.Equal(notification[16:], want) {
+		t.Errorf("wrong notification, want % x, got % x", want, notification[16:])
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session not closed after a malformed update")
+	}
+	status := s.Status()
+	if status.Established {
+		t.Error("session still established after a malformed update")
+	}
+	if status.LastError == "" {
+		t.Error("malformed update not reported as the last error")
+	}
+	if routes := s.ReceivedRoutes(); len(routes) != 0 {
+		t.Errorf("routes kept after the session was reset: %v", routes)
+	}
+}
+
+func TestSessionStatusChanged(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	defer l.Close()
+
+	changed := make(chan struct{}, 10)
+	sm := NewSessionManager(log.NewNopLogger(), time.Millisecond, time.Millisecond)
+	s, err := sm.NewSession(log.NewNopLogger(), bgp.SessionParameters{
+		PeerAddress: "127.0.0.1",
+		PeerPort:    uint16(l.Addr().(*net.TCPAddr).Port),
+		MyASN:       64500,
+		PeerASN:     64501,
+		CurrentNode: "node",
+		StatusChanged: func() {
+			changed <- struct{}{}
+		},
+	})
+	if err != nil {
+		t.Fatalf("new session: %s", err)
+	}
+	defer s.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("accept: %s", err)
+	}
+	if _, err := readOpen(conn); err != nil {
+		t.Fatalf("read open: %s", err)
+	}
+	if err := sendOpen(conn, 64501, net.ParseIP("127.0.0.2"), 90*time.Second, false, nil); err != nil {
+		t.Fatalf("send open: %s", err)
+	}
+	if err := sendKeepalive(conn); err != nil {
+		t.Fatalf("send keepalive: %s", err)
+	}
+
+	waitForChange := func(established bool) {
+		t.Helper()
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no status change notified, want established %t", established)
+		}
+		if got := s.(*session).Status().Established; got != established {
+			t.Fatalf("wrong session state notified, want established %t, got %t", established, got)
+		}
+	}
+	waitForChange(true)
+
+	// The peer going away brings the session down.
+	l.Close()
+	conn.Close()
+	waitForChange(false)
+}
+
+// msgLogger records the messages logged.
+type msgLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *msgLogger) Log(keyvals ...interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == "msg" {
+			l.msgs = append(l.msgs, keyvals[i+1].(string))
+		}
+	}
+	return nil
+}
+
+func (l *msgLogger) count(msg string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, m := range l.msgs {
+		if m == msg {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSessionSkipsPrefixesWithoutNextHop(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	defer l.Close()
+
+	logger := &msgLogger{}
+	sm := NewSessionManager(log.NewNopLogger(), time.Millisecond, time.Millisecond)
+	s, err := sm.NewSession(logger, bgp.SessionParameters{
+		PeerAddress: "127.0.0.1",
+		PeerPort:    uint16(l.Addr().(*net.TCPAddr).Port),
+		MyASN:       64500,
+		PeerASN:     64501,
+		CurrentNode: "node",
+	})
+	if err != nil {
+		t.Fatalf("new session: %s", err)
+	}
+	defer s.Close()
+	sess := s.(*session)
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("accept: %s", err)
+	}
+	defer conn.Close()
+	if _, err := readOpen(conn); err != nil {
+		t.Fatalf("read open: %s", err)
+	}
+	// The peer does not support IPv6 unicast, so IPv6 prefixes have no
+	// next hop.
+	if err := sendOpen(conn, 64501, net.ParseIP("127.0.0.2"), 90*time.Second, false, nil); err != nil {
+		t.Fatalf("send open: %s", err)
+	}
+	if err := sendKeepalive(conn); err != nil {
+		t.Fatalf("send keepalive: %s", err)
+	}
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	ipnet := func(s string) *net.IPNet {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatalf("parse %q: %s", s, err)
+		}
+		return n
+	}
+	waitForSent := func(want int) {
+		t.Helper()
+		var got int
+		for i := 0; i < 500; i++ {
+			if got = sess.Status().SentPrefixes; got == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("wrong sent prefixes, want %d, got %d", want, got)
+	}
+
+	if err := s.Set(&bgp.Advertisement{Prefix: ipnet("10.1.0.0/24")}, &bgp.Advertisement{Prefix: ipnet("2001:db8::/64")}); err != nil {
+		t.Fatalf("set: %s", err)
+	}
+	waitForSent(1)
+	if !sess.Advertises(ipnet("10.1.0.0/24")) {
+		t.Error("IPv4 prefix reported as not advertised")
+	}
+	if sess.Advertises(ipnet("2001:db8::/64")) {
+		t.Error("IPv6 prefix reported as advertised without an IPv6 next hop")
+	}
+
+	// The skipped prefix is warned about once.
+	if err := s.Set(&bgp.Advertisement{Prefix: ipnet("10.1.0.0/24")}, &bgp.Advertisement{Prefix: ipnet("10.2.0.0/24")}, &bgp.Advertisement{Prefix: ipnet("2001:db8::/64"), LocalPref: 200}); err != nil {
+		t.Fatalf("set: %s", err)
+	}
+	waitForSent(2)
+	if n := logger.count("no next hop for the address family of the prefix, not advertising it"); n != 1 {
+		t.Errorf("expected 1 warning for the skipped prefix, got %d", n)
+	}
+}
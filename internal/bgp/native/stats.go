@@ -3,6 +3,8 @@
 package native
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 	bgpmetrics "go.universe.tf/metallb/internal/bgp/metrics"
 )
@@ -37,6 +39,34 @@ var stats = metrics{
 		Name:      "pending_prefixes_total",
 		Help:      "Number of prefixes that should be advertised on the BGP session",
 	}, labels),
+
+	updatesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: bgpmetrics.Namespace,
+		Subsystem: bgpmetrics.Subsystem,
+		Name:      bgpmetrics.UpdatesReceived.Name,
+		Help:      bgpmetrics.UpdatesReceived.Help,
+	}, labels),
+
+	receivedPrefixes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: bgpmetrics.Namespace,
+		Subsystem: bgpmetrics.Subsystem,
+		Name:      bgpmetrics.ReceivedPrefixes.Name,
+		Help:      bgpmetrics.ReceivedPrefixes.Help,
+	}, labels),
+
+	notificationsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: bgpmetrics.Namespace,
+		Subsystem: bgpmetrics.Subsystem,
+		Name:      bgpmetrics.NotificationsReceived.Name,
+		Help:      bgpmetrics.NotificationsReceived.Help,
+	}, []string{"peer", "code", "subcode"}),
+
+	capabilities: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: bgpmetrics.Namespace,
+		Subsystem: bgpmetrics.Subsystem,
+		Name:      "negotiated_capabilities",
+		Help:      "Capabilities negotiated on the BGP session (always 1)",
+	}, []string{"peer", "capability"}),
 }
 
 type metrics struct {
@@ -44,6 +74,11 @@ type metrics struct {
 	updatesSent     *prometheus.CounterVec
 	prefixes        *prometheus.GaugeVec
 	pendingPrefixes *prometheus.GaugeVec
+
+	updatesReceived       *prometheus.CounterVec
+	receivedPrefixes      *prometheus.GaugeVec
+	notificationsReceived *prometheus.CounterVec
+	capabilities          *prometheus.GaugeVec
 }
 
 func init() {
@@ -51,6 +86,10 @@ func init() {
 	prometheus.MustRegister(stats.updatesSent)
 	prometheus.MustRegister(stats.prefixes)
 	prometheus.MustRegister(stats.pendingPrefixes)
+	prometheus.MustRegister(stats.updatesReceived)
+	prometheus.MustRegister(stats.receivedPrefixes)
+	prometheus.MustRegister(stats.notificationsReceived)
+	prometheus.MustRegister(stats.capabilities)
 }
 
 func (m *metrics) DeleteSession(addr string) {
@@ -58,6 +97,10 @@ func (m *metrics) DeleteSession(addr string) {
 	m.prefixes.DeleteLabelValues(addr)
 	m.pendingPrefixes.DeleteLabelValues(addr)
 	m.updatesSent.DeleteLabelValues(addr)
+	m.updatesReceived.DeleteLabelValues(addr)
+	m.receivedPrefixes.DeleteLabelValues(addr)
+	m.notificationsReceived.DeletePartialMatch(prometheus.Labels{"peer": addr})
+	m.capabilities.DeletePartialMatch(prometheus.Labels{"peer": addr})
 }
 
 func (m *metrics) SessionUp(addr string) {
@@ -68,6 +111,8 @@ func (m *metrics) SessionUp(addr string) {
 func (m *metrics) SessionDown(addr string) {
 	m.sessionUp.WithLabelValues(addr).Set(0)
 	m.prefixes.WithLabelValues(addr).Set(0)
+	m.receivedPrefixes.WithLabelValues(addr).Set(0)
+	m.capabilities.DeletePartialMatch(prometheus.Labels{"peer": addr})
 }
 
 func (m *metrics) UpdateSent(addr string) {
@@ -82,3 +127,22 @@ func (m *metrics) AdvertisedPrefixes(addr string, n int) {
 	m.prefixes.WithLabelValues(addr).Set(float64(n))
 	m.pendingPrefixes.WithLabelValues(addr).Set(float64(n))
 }
+
+func (m *metrics) UpdateReceived(addr string) {
+	m.updatesReceived.WithLabelValues(addr).Inc()
+}
+
+func (m *metrics) ReceivedPrefixes(addr string, n int) {
+	m.receivedPrefixes.WithLabelValues(addr).Set(float64(n))
+}
+
+func (m *metrics) NotificationReceived(addr string, code, subcode uint8) {
+	m.notificationsReceived.WithLabelValues(addr, strconv.Itoa(int(code)), strconv.Itoa(int(subcode))).Inc()
+}
+
+func (m *metrics) Capabilities(addr string, capabilities []string) {
+	m.capabilities.DeletePartialMatch(prometheus.Labels{"peer": addr})
+	for _, c := range capabilities {
+		m.capabilities.WithLabelValues(addr, c).Set(1)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"net/url"
 	"reflect"
 	"slices"
 	"sort"
@@ -40,17 +41,18 @@ import (
 )
 
 type ClusterResources struct {
-	Pools           []metallbv1beta1.IPAddressPool     `json:"ipaddresspools"`
-	Peers           []metallbv1beta2.BGPPeer           `json:"bgppeers"`
-	BFDProfiles     []metallbv1beta1.BFDProfile        `json:"bfdprofiles"`
-	BGPAdvs         []metallbv1beta1.BGPAdvertisement  `json:"bgpadvertisements"`
-	L2Advs          []metallbv1beta1.L2Advertisement   `json:"l2advertisements"`
-	UPnPAdvs        []metallbv1beta1.UPnPAdvertisement `json:"upnpadvertisements"`
-	Communities     []metallbv1beta1.Community         `json:"communities"`
-	PasswordSecrets map[string]corev1.Secret           `json:"passwordsecrets"`
-	Nodes           []corev1.Node                      `json:"nodes"`
-	Namespaces      []corev1.Namespace                 `json:"namespaces"`
-	BGPExtras       corev1.ConfigMap                   `json:"bgpextras"`
+	Pools           []metallbv1beta1.IPAddressPool        `json:"ipaddresspools"`
+	Peers           []metallbv1beta2.BGPPeer              `json:"bgppeers"`
+	BFDProfiles     []metallbv1beta1.BFDProfile           `json:"bfdprofiles"`
+	BGPAdvs         []metallbv1beta1.BGPAdvertisement     `json:"bgpadvertisements"`
+	L2Advs          []metallbv1beta1.L2Advertisement      `json:"l2advertisements"`
+	UPnPAdvs        []metallbv1beta1.UPnPAdvertisement    `json:"upnpadvertisements"`
+	Communities     []metallbv1beta1.Community            `json:"communities"`
+	Reservations    []metallbv1beta1.IPAddressReservation `json:"ipaddressreservations"`
+	PasswordSecrets map[string]corev1.Secret              `json:"passwordsecrets"`
+	Nodes           []corev1.Node                         `json:"nodes"`
+	Namespaces      []corev1.Namespace                    `json:"namespaces"`
+	BGPExtras       corev1.ConfigMap                      `json:"bgpextras"`
 }
 
 // Config is a parsed MetalLB configuration.
@@ -87,6 +89,10 @@ const (
 
 const bgpExtrasField = "extras"
 
+// maxASPathPrepend is the highest number of times the local ASN can be
+// prepended, as FRR accepts.
+const maxASPathPrepend = 10
+
 var Protocols = []Proto{
 	BGP, Layer2, UPnP,
 }
@@ -108,6 +114,8 @@ type Peer struct {
 	Iface string
 	// Source address to use when establishing the session.
 	SrcAddr net.IP
+	// Next hop of the IPv6 prefixes, native mode only.
+	IPv6NextHop net.IP
 	// Port to dial when establishing the session.
 	Port uint16
 	// Requested BGP hold time, per RFC4271.
@@ -131,6 +139,8 @@ type Peer struct {
 	BFDProfile string
 	// Optional EnableGracefulRestart enable BGP graceful restart functionality at the peer level.
 	EnableGracefulRestart bool
+	// Restart time advertised in the graceful restart capability, native mode only.
+	GracefulRestartTime *time.Duration
 	// Optional ebgp peer is multi-hops away.
 	EBGPMultiHop bool
 	// Optional name of the vrf to establish the session from
@@ -158,6 +168,12 @@ type Pool struct {
 	// If false, prevents IP addresses to be automatically assigned
 	// from this pool.
 	AutoAssign bool
+	// Addresses of the pool that are never allocated automatically,
+	// expressed as CIDR prefixes.
+	ExcludedCIDR []*net.IPNet
+	// If true, services requesting an excluded address explicitly can
+	// get it.
+	AllowExplicitExcluded bool
 
 	// The list of BGPAdvertisements associated with this address pool.
 	BGPAdvertisements []*BGPAdvertisement
@@ -171,8 +187,57 @@ type Pool struct {
 	cidrsPerAddresses map[string][]*net.IPNet
 
 	ServiceAllocations *ServiceAllocation
+
+	// How the addresses of the pool are picked for the services.
+	AllocationStrategy AllocationStrategy
+	// How long an address released by a service is not given to other
+	// services. Used only by the LeastRecentlyReleased strategy.
+	QuarantineTime time.Duration
+
+	// The reservations of addresses of the pool, by address.
+	Reservations map[string]*Reservation
+
+	// The limits of the addresses of the pool used by the services of
+	// a namespace, by namespace.
+	Quotas map[string]*Quota
+}
+
+// Quota limits the addresses of a pool used by the services of a
+// namespace. A nil limit means no limit.
+type Quota struct {
+	MaxIPv4 *int64
+	MaxIPv6 *int64
 }
 
+// Reservation binds addresses of a pool to a service.
+type Reservation struct {
+	// Name of the IPAddressReservation this reservation comes from.
+	Name string
+	// The reserved addresses, at most one per IP family.
+	IPs []net.IP
+	// The key (namespace/name) of the service the addresses are bound
+	// to, empty when ServiceSelector is used.
+	Service string
+	// The selector of the services the addresses are bound to.
+	ServiceSelector labels.Selector
+}
+
+// AllocationStrategy is the way the addresses of a pool are picked.
+type AllocationStrategy int
+
+const (
+	// SequentialAllocation picks the first free address, the default.
+	SequentialAllocation AllocationStrategy = iota
+	// RandomAllocation picks a random free address.
+	RandomAllocation
+	// LeastRecentlyReleasedAllocation picks the free address released
+	// the longest time ago, the ones never used first.
+	LeastRecentlyReleasedAllocation
+	// HashedAllocation picks the first free address starting from a
+	// hash of the service key.
+	HashedAllocation
+)
+
 // ServiceAllocation makes ip pool allocation to specific namespace and/or service.
 type ServiceAllocation struct {
 	// The priority of ip pool for a given service allocation.
@@ -198,6 +263,11 @@ type BGPAdvertisement struct {
 	// Value of the LOCAL_PREF BGP path attribute. Used only when
 	// advertising to IBGP peers (i.e. Peer.MyASN == Peer.ASN).
 	LocalPref uint32
+	// Value of the MULTI_EXIT_DISC BGP path attribute, not sent when nil.
+	MED *uint32
+	// Number of additional times the local ASN is prepended to the
+	// AS_PATH BGP path attribute.
+	ASPathPrepend uint32
 	// Value of the COMMUNITIES path attribute.
 	Communities map[community.BGPCommunity]bool
 	// The map of nodes allowed for this advertisement
@@ -214,10 +284,24 @@ type L2Advertisement struct {
 	Interfaces []string
 	// AllInterfaces tells if all the interfaces are allowed for this advertisement
 	AllInterfaces bool
+	// The interval between the gratuitous announcements, the default when zero
+	GratuitousInterval time.Duration
+	// For how long the gratuitous announcements are sent, the default when zero
+	GratuitousDuration time.Duration
+	// The number of gratuitous announcements sent in place of a duration, if not zero
+	GratuitousCount int
+	// The priority of the nodes given one, the lower the higher
+	NodePriorities map[string]int
+	// Sticky tells if the node announcing an IP keeps it while available
+	Sticky bool
+	// PerIPElection tells if the node announcing each IP of a service is elected independently
+	PerIPElection bool
 }
 
 // UPnPAdvertisement describes a UPnP IGD port forwarding configuration.
 type UPnPAdvertisement struct {
+	// Name of the UPnPAdvertisement this configuration comes from
+	Name string
 	// The map of nodes allowed for this advertisement
 	Nodes map[string]bool
 	// Whether to enable UPnP IGD port forwarding
@@ -226,6 +310,22 @@ type UPnPAdvertisement struct {
 	Duration int
 	// Description for port mappings
 	Description string
+	// Protocol used to program the gateway: UPnP, NAT-PMP or PCP
+	Protocol string
+	// Address of the NAT-PMP or PCP gateway
+	GatewayAddress string
+	// Selector of the UPnP gateway to program
+	GatewaySelector UPnPGatewaySelector
+	// Whether to program all the UPnP gateways matching the selector
+	AllGateways bool
+}
+
+// UPnPGatewaySelector pins a UPnP gateway. Empty fields match any gateway.
+type UPnPGatewaySelector struct {
+	Location     string
+	UDN          string
+	FriendlyName string
+	ExternalIP   string
 }
 
 // BFDProfile describes a BFD profile to be applied to a set of peers.
@@ -372,6 +472,11 @@ func poolsFor(resources ClusterResources) (*Pools, error) {
 		return nil, err
 	}
 
+	err = setReservationsToPools(resources.Reservations, pools)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Pools{ByName: pools, ByNamespace: poolsByNamespace(pools),
 		ByServiceSelector: poolsByServiceSelector(pools)}, nil
 }
@@ -451,6 +556,13 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 	if p.Spec.SrcAddress != "" && src == nil {
 		return nil, fmt.Errorf("invalid source IP %q", p.Spec.SrcAddress)
 	}
+	var nextHop6 net.IP
+	if p.Spec.IPv6NextHop != "" {
+		nextHop6 = net.ParseIP(p.Spec.IPv6NextHop)
+		if nextHop6 == nil || nextHop6.To4() != nil {
+			return nil, fmt.Errorf("invalid IPv6 next hop %q", p.Spec.IPv6NextHop)
+		}
+	}
 
 	err = validateLabelSelectorDuplicate(p.Spec.NodeSelectors, "nodeSelectors")
 	if err != nil {
@@ -486,6 +598,18 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 		connectTime = ptr.To(p.Spec.ConnectTime.Duration)
 	}
 
+	var gracefulRestartTime *time.Duration
+	if p.Spec.GracefulRestartTime != nil {
+		if !p.Spec.EnableGracefulRestart {
+			return nil, fmt.Errorf("peer %s has gracefulRestartTime set without enableGracefulRestart", p.Name)
+		}
+		d := p.Spec.GracefulRestartTime.Duration
+		if d < time.Second || d > 4095*time.Second || d%time.Second != 0 {
+			return nil, fmt.Errorf("invalid gracefulRestartTime %s, must be a whole number of seconds between 1s and 4095s", d)
+		}
+		gracefulRestartTime = ptr.To(d)
+	}
+
 	return &Peer{
 		Name:                   p.Name,
 		MyASN:                  p.Spec.MyASN,
@@ -494,6 +618,7 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 		Addr:                   ip,
 		Iface:                  p.Spec.Interface,
 		SrcAddr:                src,
+		IPv6NextHop:            nextHop6,
 		Port:                   p.Spec.Port,
 		HoldTime:               holdTime,
 		KeepaliveTime:          keepaliveTime,
@@ -505,6 +630,7 @@ func peerFromCR(p metallbv1beta2.BGPPeer, passwordSecrets map[string]corev1.Secr
 		PasswordRef:            p.Spec.PasswordSecret,
 		BFDProfile:             p.Spec.BFDProfile,
 		EnableGracefulRestart:  p.Spec.EnableGracefulRestart,
+		GracefulRestartTime:    gracefulRestartTime,
 		EBGPMultiHop:           p.Spec.EBGPMultiHop,
 		VRF:                    p.Spec.VRFName,
 		DualStackAddressFamily: p.Spec.DualStackAddressFamily,
@@ -537,9 +663,10 @@ func addressPoolFromCR(p metallbv1beta1.IPAddressPool, namespaces []corev1.Names
 	}
 
 	ret := &Pool{
-		Name:          p.Name,
-		AvoidBuggyIPs: p.Spec.AvoidBuggyIPs,
-		AutoAssign:    true,
+		Name:                  p.Name,
+		AvoidBuggyIPs:         p.Spec.AvoidBuggyIPs,
+		AutoAssign:            true,
+		AllowExplicitExcluded: p.Spec.AllowExplicitExcludedAddresses,
 	}
 
 	if p.Spec.AutoAssign != nil {
@@ -560,15 +687,179 @@ func addressPoolFromCR(p metallbv1beta1.IPAddressPool, namespaces []corev1.Names
 		ret.cidrsPerAddresses[cidr] = nets
 	}
 
+	excluded, err := excludedCIDRsFromCR(p, ret.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	ret.ExcludedCIDR = excluded
+
 	serviceAllocations, err := addressPoolServiceAllocationsFromCR(p, namespaces)
 	if err != nil {
 		return nil, err
 	}
 	ret.ServiceAllocations = serviceAllocations
 
+	ret.AllocationStrategy, ret.QuarantineTime, err = allocationStrategyFromCR(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.Quotas, err = quotasFromCR(p, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
 	return ret, nil
 }
 
+// excludedCIDRsFromCR parses the excluded addresses of the given pool, which
+// must be part of its cidrs and must not overlap.
+func excludedCIDRsFromCR(p metallbv1beta1.IPAddressPool, cidrs []*net.IPNet) ([]*net.IPNet, error) {
+	err := validateDuplicate(p.Spec.ExcludedAddresses, "excludedAddresses")
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*net.IPNet
+	for _, excluded := range p.Spec.ExcludedAddresses {
+		nets, err := parseExcludedAddress(excluded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid excluded address %q in pool %q: %s", excluded, p.Name, err)
+		}
+		for _, n := range nets {
+			inPool := false
+			for _, cidr := range cidrs {
+				if cidrContainsCIDR(cidr, n) {
+					inPool = true
+					break
+				}
+			}
+			if !inPool {
+				return nil, fmt.Errorf("excluded address %q is not part of a single range of pool %q", excluded, p.Name)
+			}
+			for _, m := range ret {
+				if cidrsOverlap(n, m) {
+					return nil, fmt.Errorf("excluded address %q overlaps with another excluded address of pool %q", excluded, p.Name)
+				}
+			}
+			ret = append(ret, n)
+		}
+	}
+	return ret, nil
+}
+
+// parseExcludedAddress parses an excluded address, which can be a single
+// IP on top of the CIDRs and ranges accepted by ParseCIDR.
+func parseExcludedAddress(addr string) ([]*net.IPNet, error) {
+	if strings.Contains(addr, "/") || strings.Contains(addr, "-") {
+		return ParseCIDR(addr)
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP %q", addr)
+	}
+	bits := net.IPv6len * 8
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = net.IPv4len * 8
+	}
+	return []*net.IPNet{{IP: ip, Mask: net.CIDRMask(bits, bits)}}, nil
+}
+
+func allocationStrategyFromCR(p metallbv1beta1.IPAddressPool) (AllocationStrategy, time.Duration, error) {
+	if p.Spec.AllocationStrategy == nil {
+		return SequentialAllocation, 0, nil
+	}
+	var strategy AllocationStrategy
+	switch p.Spec.AllocationStrategy.Type {
+	case "", metallbv1beta1.SequentialAllocation:
+		strategy = SequentialAllocation
+	case metallbv1beta1.RandomAllocation:
+		strategy = RandomAllocation
+	case metallbv1beta1.LeastRecentlyReleasedAllocation:
+		strategy = LeastRecentlyReleasedAllocation
+	case metallbv1beta1.HashedAllocation:
+		strategy = HashedAllocation
+	default:
+		return SequentialAllocation, 0, fmt.Errorf("invalid allocation strategy %q in pool %q, must be one of Sequential, Random, LeastRecentlyReleased or Hashed",
+			p.Spec.AllocationStrategy.Type, p.Name)
+	}
+
+	quarantine := p.Spec.AllocationStrategy.QuarantineTime
+	if quarantine == nil {
+		return strategy, 0, nil
+	}
+	if strategy != LeastRecentlyReleasedAllocation {
+		return SequentialAllocation, 0, fmt.Errorf("pool %q has quarantineTime set, supported only by the LeastRecentlyReleased allocation strategy", p.Name)
+	}
+	if quarantine.Duration < 0 {
+		return SequentialAllocation, 0, fmt.Errorf("invalid quarantineTime %q in pool %q, must not be negative", quarantine.Duration, p.Name)
+	}
+	return strategy, quarantine.Duration, nil
+}
+
+// quotasFromCR returns the quota of each namespace selected by the quotas of
+// the pool. The lowest limit applies to the namespaces selected more than once.
+func quotasFromCR(p metallbv1beta1.IPAddressPool, namespaces []corev1.Namespace) (map[string]*Quota, error) {
+	if len(p.Spec.Quotas) == 0 {
+		return nil, nil
+	}
+	res := map[string]*Quota{}
+	for i, q := range p.Spec.Quotas {
+		if len(q.Namespaces) == 0 && len(q.NamespaceSelectors) == 0 {
+			return nil, fmt.Errorf("quota #%d selects no namespace", i+1)
+		}
+		if (q.MaxIPv4 != nil && *q.MaxIPv4 < 0) || (q.MaxIPv6 != nil && *q.MaxIPv6 < 0) {
+			return nil, fmt.Errorf("quota #%d has a negative limit", i+1)
+		}
+		err := validateDuplicate(q.Namespaces, "namespaces")
+		if err != nil {
+			return nil, err
+		}
+		err = validateLabelSelectorDuplicate(q.NamespaceSelectors, "namespaceSelectors")
+		if err != nil {
+			return nil, err
+		}
+
+		selected := sets.New(q.Namespaces...)
+		for j := range q.NamespaceSelectors {
+			l, err := metav1.LabelSelectorAsSelector(&q.NamespaceSelectors[j])
+			if err != nil {
+				return nil, errors.Join(err, fmt.Errorf("invalid namespace label selector %v in quota #%d", &q.NamespaceSelectors[j], i+1))
+			}
+			for _, ns := range namespaces {
+				if l.Matches(labels.Set(ns.Labels)) {
+					selected.Insert(ns.Name)
+				}
+			}
+		}
+
+		for ns := range selected {
+			quota, ok := res[ns]
+			if !ok {
+				quota = &Quota{}
+				res[ns] = quota
+			}
+			quota.MaxIPv4 = lowestLimit(quota.MaxIPv4, q.MaxIPv4)
+			quota.MaxIPv6 = lowestLimit(quota.MaxIPv6, q.MaxIPv6)
+		}
+	}
+	return res, nil
+}
+
+// lowestLimit returns the lowest of two limits, nil meaning no limit.
+func lowestLimit(a, b *int64) *int64 {
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		return ptr.To(*b)
+	case b == nil || *a <= *b:
+		return a
+	}
+	return ptr.To(*b)
+}
+
 func addressPoolServiceAllocationsFromCR(p metallbv1beta1.IPAddressPool, namespaces []corev1.Namespace) (*ServiceAllocation, error) {
 	if p.Spec.AllocateTo == nil {
 		return nil, nil
@@ -616,6 +907,91 @@ func addressPoolServiceAllocationsFromCR(p metallbv1beta1.IPAddressPool, namespa
 	return serviceAllocations, nil
 }
 
+// setReservationsToPools adds the reservations to the pools they belong to.
+// Reservations of pools that do not exist are ignored, as they can't be
+// allocated anyway.
+func setReservationsToPools(reservations []metallbv1beta1.IPAddressReservation, ipPoolMap map[string]*Pool) error {
+	reservedBy := map[string]string{}
+	for _, r := range reservations {
+		pool, ok := ipPoolMap[r.Spec.IPAddressPool]
+		if !ok {
+			continue
+		}
+		reservation, err := reservationFromCR(r, pool)
+		if err != nil {
+			return fmt.Errorf("parsing ip address reservation %s: %s", r.Name, err)
+		}
+		if pool.Reservations == nil {
+			pool.Reservations = map[string]*Reservation{}
+		}
+		for _, ip := range reservation.IPs {
+			if other, ok := reservedBy[ip.String()]; ok {
+				return fmt.Errorf("address %s reserved by both %s and %s", ip, other, r.Name)
+			}
+			reservedBy[ip.String()] = r.Name
+			pool.Reservations[ip.String()] = reservation
+		}
+	}
+	return nil
+}
+
+func reservationFromCR(r metallbv1beta1.IPAddressReservation, pool *Pool) (*Reservation, error) {
+	ret := &Reservation{Name: r.Name}
+
+	if len(r.Spec.Addresses) == 0 {
+		return nil, errors.New("no addresses defined")
+	}
+	families := map[ipfamily.Family]bool{}
+	for _, a := range r.Spec.Addresses {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address %q", a)
+		}
+		if !pool.contains(ip) {
+			return nil, fmt.Errorf("address %s is not part of pool %s", ip, pool.Name)
+		}
+		family := ipfamily.ForAddress(ip)
+		if families[family] {
+			return nil, fmt.Errorf("more than one %s address", family)
+		}
+		families[family] = true
+		ret.IPs = append(ret.IPs, ip)
+	}
+
+	switch {
+	case r.Spec.ServiceRef != nil && r.Spec.ServiceSelector != nil:
+		return nil, errors.New("both serviceRef and serviceSelector are set")
+	case r.Spec.ServiceRef != nil:
+		if r.Spec.ServiceRef.Namespace == "" || r.Spec.ServiceRef.Name == "" {
+			return nil, errors.New("serviceRef must have both a namespace and a name")
+		}
+		ret.Service = r.Spec.ServiceRef.Namespace + "/" + r.Spec.ServiceRef.Name
+	case r.Spec.ServiceSelector != nil:
+		l, err := metav1.LabelSelectorAsSelector(r.Spec.ServiceSelector)
+		if err != nil {
+			return nil, errors.Join(err, fmt.Errorf("invalid service label selector %v", r.Spec.ServiceSelector))
+		}
+		ret.ServiceSelector = l
+	default:
+		return nil, errors.New("one of serviceRef and serviceSelector must be set")
+	}
+
+	return ret, nil
+}
+
+// contains tells if ip is an address of the pool that can be allocated.
+func (p *Pool) contains(ip net.IP) bool {
+	if p.AvoidBuggyIPs && ip.To4() != nil && (ip.To4()[3] == 0 || ip.To4()[3] == 255) {
+		return false
+	}
+	for _, cidr := range p.CIDR {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func poolsByNamespace(pools map[string]*Pool) map[string][]string {
 	var poolsForNamespace map[string][]string
 	for _, pool := range pools {
@@ -771,6 +1147,54 @@ func l2AdvertisementFromCR(crdAd metallbv1beta1.L2Advertisement, nodes []corev1.
 	if len(crdAd.Spec.Interfaces) == 0 {
 		l2.AllInterfaces = true
 	}
+	if crdAd.Spec.GratuitousDuration != nil && crdAd.Spec.GratuitousCount != nil {
+		return nil, fmt.Errorf("invalid l2advertisement %s: gratuitousDuration and gratuitousCount are mutually exclusive", crdAd.Name)
+	}
+	if crdAd.Spec.GratuitousInterval != nil {
+		if crdAd.Spec.GratuitousInterval.Duration <= 0 {
+			return nil, fmt.Errorf("invalid l2advertisement %s: gratuitousInterval %s must be positive", crdAd.Name, crdAd.Spec.GratuitousInterval.Duration)
+		}
+		l2.GratuitousInterval = crdAd.Spec.GratuitousInterval.Duration
+	}
+	if crdAd.Spec.GratuitousDuration != nil {
+		if crdAd.Spec.GratuitousDuration.Duration <= 0 {
+			return nil, fmt.Errorf("invalid l2advertisement %s: gratuitousDuration %s must be positive", crdAd.Name, crdAd.Spec.GratuitousDuration.Duration)
+		}
+		l2.GratuitousDuration = crdAd.Spec.GratuitousDuration.Duration
+	}
+	if crdAd.Spec.GratuitousCount != nil {
+		if *crdAd.Spec.GratuitousCount < 1 {
+			return nil, fmt.Errorf("invalid l2advertisement %s: gratuitousCount %d must be at least 1", crdAd.Name, *crdAd.Spec.GratuitousCount)
+		}
+		l2.GratuitousCount = int(*crdAd.Spec.GratuitousCount)
+	}
+	if len(crdAd.Spec.NodePriorities) > 0 {
+		l2.NodePriorities = map[string]int{}
+	}
+	for _, p := range crdAd.Spec.NodePriorities {
+		if p.Priority < 0 {
+			return nil, fmt.Errorf("invalid l2advertisement %s: node priority %d must not be negative", crdAd.Name, p.Priority)
+		}
+		if len(p.NodeSelectors) == 0 {
+			return nil, fmt.Errorf("invalid l2advertisement %s: node priority %d has no node selectors", crdAd.Name, p.Priority)
+		}
+		err = validateLabelSelectorDuplicate(p.NodeSelectors, "nodePriorities")
+		if err != nil {
+			return nil, err
+		}
+		selected, err := selectedNodes(nodes, p.NodeSelectors)
+		if err != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to parse node priority selector for %s", crdAd.Name))
+		}
+		for node := range selected {
+			// The highest priority applies to the nodes selected several times.
+			if cur, ok := l2.NodePriorities[node]; !ok || int(p.Priority) < cur {
+				l2.NodePriorities[node] = int(p.Priority)
+			}
+		}
+	}
+	l2.Sticky = crdAd.Spec.Sticky
+	l2.PerIPElection = crdAd.Spec.PerIPElection
 	return l2, nil
 }
 
@@ -818,6 +1242,13 @@ func upnpAdvertisementFromCR(crdAd metallbv1beta1.UPnPAdvertisement, nodes []cor
 	if err != nil {
 		return nil, err
 	}
+	err = validatePoolNames(crdAd.Spec.IPAddressPools)
+	if err != nil {
+		return nil, err
+	}
+	if crdAd.Spec.Duration < 0 {
+		return nil, fmt.Errorf("invalid duration %d in upnpadvertisement %s, must be zero or positive", crdAd.Spec.Duration, crdAd.Name)
+	}
 	err = validateLabelSelectorDuplicate(crdAd.Spec.IPAddressPoolSelectors, "ipAddressPoolSelectors")
 	if err != nil {
 		return nil, err
@@ -837,15 +1268,72 @@ func upnpAdvertisementFromCR(crdAd metallbv1beta1.UPnPAdvertisement, nodes []cor
 	}
 
 	upnp := &UPnPAdvertisement{
+		Name:        crdAd.Name,
 		Nodes:       selected,
 		Enabled:     true,
 		Duration:    crdAd.Spec.Duration,
 		Description: description,
 	}
+	err = setUPnPGatewayFromCR(upnp, crdAd)
+	if err != nil {
+		return nil, err
+	}
 
 	return upnp, nil
 }
 
+// setUPnPGatewayFromCR validates the settings of the gateway of the given
+// advertisement and sets them in adv.
+func setUPnPGatewayFromCR(adv *UPnPAdvertisement, crdAd metallbv1beta1.UPnPAdvertisement) error {
+	switch crdAd.Spec.Protocol {
+	case "", "UPnP":
+		// The gateway is discovered, the address is ignored.
+		adv.Protocol = "UPnP"
+		adv.AllGateways = crdAd.Spec.AllGateways
+		if crdAd.Spec.GatewaySelector == nil {
+			return nil
+		}
+		s := crdAd.Spec.GatewaySelector
+		if s.Location != "" {
+			u, err := url.Parse(s.Location)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid gatewaySelector location %q in upnpadvertisement %s, must be an http URL", s.Location, crdAd.Name)
+			}
+		}
+		if s.ExternalIP != "" && net.ParseIP(s.ExternalIP) == nil {
+			return fmt.Errorf("invalid gatewaySelector externalIP %q in upnpadvertisement %s", s.ExternalIP, crdAd.Name)
+		}
+		adv.GatewaySelector = UPnPGatewaySelector{
+			Location:     s.Location,
+			UDN:          s.UDN,
+			FriendlyName: s.FriendlyName,
+			ExternalIP:   s.ExternalIP,
+		}
+		return nil
+	case "NAT-PMP", "PCP":
+	default:
+		return fmt.Errorf("invalid protocol %q in upnpadvertisement %s, must be one of UPnP, NAT-PMP or PCP", crdAd.Spec.Protocol, crdAd.Name)
+	}
+
+	if crdAd.Spec.GatewaySelector != nil || crdAd.Spec.AllGateways {
+		return fmt.Errorf("upnpadvertisement %s uses %s, gatewaySelector and allGateways are only valid for UPnP", crdAd.Name, crdAd.Spec.Protocol)
+	}
+	address := crdAd.Spec.GatewayAddress
+	if address == "" {
+		return fmt.Errorf("upnpadvertisement %s uses %s but has no gatewayAddress", crdAd.Name, crdAd.Spec.Protocol)
+	}
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) == nil {
+		return fmt.Errorf("invalid gatewayAddress %q in upnpadvertisement %s", address, crdAd.Name)
+	}
+	adv.Protocol = crdAd.Spec.Protocol
+	adv.GatewayAddress = address
+	return nil
+}
+
 func containsUPnPAdvertisement(advs []*UPnPAdvertisement, toCheck *UPnPAdvertisement) bool {
 	for _, adv := range advs {
 		if adv.Enabled != toCheck.Enabled {
@@ -857,6 +1345,12 @@ func containsUPnPAdvertisement(advs []*UPnPAdvertisement, toCheck *UPnPAdvertise
 		if adv.Description != toCheck.Description {
 			continue
 		}
+		if adv.Protocol != toCheck.Protocol || adv.GatewayAddress != toCheck.GatewayAddress {
+			continue
+		}
+		if adv.GatewaySelector != toCheck.GatewaySelector || adv.AllGateways != toCheck.AllGateways {
+			continue
+		}
 		if !reflect.DeepEqual(adv.Nodes, toCheck.Nodes) {
 			continue
 		}
@@ -909,6 +1403,13 @@ func bgpAdvertisementFromCR(crdAd metallbv1beta1.BGPAdvertisement, communities m
 	}
 
 	ad.LocalPref = crdAd.Spec.LocalPref
+	if crdAd.Spec.MED != nil {
+		ad.MED = ptr.To(*crdAd.Spec.MED)
+	}
+	if crdAd.Spec.ASPathPrepend > maxASPathPrepend {
+		return nil, fmt.Errorf("invalid as path prepend %d, must be at most %d", crdAd.Spec.ASPathPrepend, maxASPathPrepend)
+	}
+	ad.ASPathPrepend = crdAd.Spec.ASPathPrepend
 
 	if len(crdAd.Spec.Peers) > 0 {
 		ad.Peers = make([]string, 0, len(crdAd.Spec.Peers))
@@ -1011,11 +1512,30 @@ func validateBGPAdvPerPool(adv *BGPAdvertisement, pool *Pool) error {
 					"with common pools and aggregation lengths", adv.LocalPref, bgpAdv.LocalPref)
 			}
 		}
+		if !reflect.DeepEqual(adv.MED, bgpAdv.MED) {
+			if !advertisementsAreCompatible(adv, bgpAdv, pool) {
+				return fmt.Errorf("invalid MED %s: MED %s was already set for the same type of BGP update. "+
+					"Check existing BGP advertisements with common pools and aggregation lengths", medString(adv.MED), medString(bgpAdv.MED))
+			}
+		}
+		if adv.ASPathPrepend != bgpAdv.ASPathPrepend {
+			if !advertisementsAreCompatible(adv, bgpAdv, pool) {
+				return fmt.Errorf("invalid as path prepend %d: as path prepend %d was already set for the same type of BGP update. "+
+					"Check existing BGP advertisements with common pools and aggregation lengths", adv.ASPathPrepend, bgpAdv.ASPathPrepend)
+			}
+		}
 	}
 
 	return nil
 }
 
+func medString(med *uint32) string {
+	if med == nil {
+		return "unset"
+	}
+	return fmt.Sprintf("%d", *med)
+}
+
 func advertisementsAreCompatible(newAdv, adv *BGPAdvertisement, pool *Pool) bool {
 	if isAggrLengthDifferent(newAdv, adv, pool) {
 		return true
@@ -1175,6 +1695,17 @@ func containsAdvertisement(advs []*L2Advertisement, toCheck *L2Advertisement) bo
 		if !sets.New(adv.Interfaces...).Equal(sets.New(toCheck.Interfaces...)) {
 			continue
 		}
+		if adv.GratuitousInterval != toCheck.GratuitousInterval ||
+			adv.GratuitousDuration != toCheck.GratuitousDuration ||
+			adv.GratuitousCount != toCheck.GratuitousCount {
+			continue
+		}
+		if !reflect.DeepEqual(adv.NodePriorities, toCheck.NodePriorities) || adv.Sticky != toCheck.Sticky {
+			continue
+		}
+		if adv.PerIPElection != toCheck.PerIPElection {
+			continue
+		}
 		return true
 	}
 	return false
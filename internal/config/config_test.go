@@ -166,6 +166,8 @@ func TestParse(t *testing.T) {
 						Spec: v1beta1.BGPAdvertisementSpec{
 							AggregationLength: ptr.To[int32](32),
 							LocalPref:         uint32(100),
+							MED:               ptr.To[uint32](10),
+							ASPathPrepend:     2,
 							Communities:       []string{"bar"},
 							IPAddressPools:    []string{"pool1"},
 							Peers:             []string{"peer1"},
@@ -262,6 +264,8 @@ func TestParse(t *testing.T) {
 								AggregationLength:   32,
 								AggregationLengthV6: 128,
 								LocalPref:           100,
+								MED:                 ptr.To[uint32](10),
+								ASPathPrepend:       2,
 								Communities: func() map[community.BGPCommunity]bool {
 									c, _ := community.New("64512:1234")
 									return map[community.BGPCommunity]bool{
@@ -673,6 +677,222 @@ func TestParse(t *testing.T) {
 			},
 		},
 
+		{
+			desc: "ip address pool with allocation strategy",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"10.20.0.0/16"},
+							AllocationStrategy: &v1beta1.AllocationStrategy{
+								Type:           v1beta1.LeastRecentlyReleasedAllocation,
+								QuarantineTime: &metav1.Duration{Duration: 10 * time.Minute},
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool2",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"30.0.0.0/8"},
+							AllocationStrategy: &v1beta1.AllocationStrategy{
+								Type: v1beta1.HashedAllocation,
+							},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:               "pool1",
+						CIDR:               []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign:         true,
+						AllocationStrategy: LeastRecentlyReleasedAllocation,
+						QuarantineTime:     10 * time.Minute,
+					},
+					"pool2": {
+						Name:               "pool2",
+						CIDR:               []*net.IPNet{ipnet("30.0.0.0/8")},
+						AutoAssign:         true,
+						AllocationStrategy: HashedAllocation,
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+
+		{
+			desc: "ip address reservations",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"10.20.0.0/16", "2000::/64"},
+						},
+					},
+				},
+				Reservations: []v1beta1.IPAddressReservation{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "res1"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "pool1",
+							Addresses:     []string{"10.20.0.5", "2000::5"},
+							ServiceRef:    &v1beta1.ServiceReference{Namespace: "ns", Name: "svc"},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "res2"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "pool1",
+							Addresses:     []string{"10.20.0.6"},
+							ServiceSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{"app": "web"},
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "res3"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "missing",
+							Addresses:     []string{"10.20.0.5"},
+							ServiceRef:    &v1beta1.ServiceReference{Namespace: "ns", Name: "other"},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16"), ipnet("2000::/64")},
+						AutoAssign: true,
+						Reservations: map[string]*Reservation{
+							"10.20.0.5": {
+								Name:    "res1",
+								IPs:     []net.IP{net.ParseIP("10.20.0.5"), net.ParseIP("2000::5")},
+								Service: "ns/svc",
+							},
+							"2000::5": {
+								Name:    "res1",
+								IPs:     []net.IP{net.ParseIP("10.20.0.5"), net.ParseIP("2000::5")},
+								Service: "ns/svc",
+							},
+							"10.20.0.6": {
+								Name:            "res2",
+								IPs:             []net.IP{net.ParseIP("10.20.0.6")},
+								ServiceSelector: selector("app=web"),
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+
+		{
+			desc: "ip address pool with quotas",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"10.20.0.0/16"},
+							Quotas: []v1beta1.NamespaceQuota{
+								{
+									Namespaces: []string{"test-ns1"},
+									MaxIPv4:    ptr.To[int64](5),
+								},
+								{
+									NamespaceSelectors: []metav1.LabelSelector{
+										{MatchLabels: map[string]string{"team": "metallb"}},
+									},
+									MaxIPv4: ptr.To[int64](2),
+									MaxIPv6: ptr.To[int64](1),
+								},
+							},
+						},
+					},
+				},
+				Namespaces: []corev1.Namespace{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "test-ns1",
+							Labels: map[string]string{"team": "metallb"},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "test-ns2",
+							Labels: map[string]string{"team": "metallb"},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "test-ns3",
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign: true,
+						Quotas: map[string]*Quota{
+							"test-ns1": {MaxIPv4: ptr.To[int64](2), MaxIPv6: ptr.To[int64](1)},
+							"test-ns2": {MaxIPv4: ptr.To[int64](2), MaxIPv6: ptr.To[int64](1)},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+
+		{
+			desc: "ip address pool with excluded addresses",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses:                      []string{"10.20.0.0/24", "2000::/120"},
+							ExcludedAddresses:              []string{"10.20.0.1", "10.20.0.8-10.20.0.11", "2000::/126"},
+							AllowExplicitExcludedAddresses: true,
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:                  "pool1",
+						CIDR:                  []*net.IPNet{ipnet("10.20.0.0/24"), ipnet("2000::/120")},
+						ExcludedCIDR:          []*net.IPNet{ipnet("10.20.0.1/32"), ipnet("10.20.0.8/30"), ipnet("2000::/126")},
+						AllowExplicitExcluded: true,
+						AutoAssign:            true,
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+
 		{
 			desc: "peer-only",
 			crs: ClusterResources{
@@ -947,6 +1167,52 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "invalid IPv6 next hop",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:       42,
+							ASN:         42,
+							Address:     "1.2.3.4",
+							IPv6NextHop: "1.2.3.5",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "graceful restart time without graceful restart",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:               42,
+							ASN:                 42,
+							Address:             "1.2.3.4",
+							GracefulRestartTime: &metav1.Duration{Duration: 60 * time.Second},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "invalid graceful restart time",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:                 42,
+							ASN:                   42,
+							Address:               "1.2.3.4",
+							EnableGracefulRestart: true,
+							GracefulRestartTime:   &metav1.Duration{Duration: 5000 * time.Second},
+						},
+					},
+				},
+			},
+		},
 		{
 			desc: "empty node selector (select everything)",
 			crs: ClusterResources{
@@ -1187,7 +1453,7 @@ func TestParse(t *testing.T) {
 			},
 		},
 		{
-			desc: "simple advertisement",
+			desc: "invalid allocation strategy",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
@@ -1196,40 +1462,14 @@ func TestParse(t *testing.T) {
 							Addresses: []string{
 								"1.2.3.0/24",
 							},
+							AllocationStrategy: &v1beta1.AllocationStrategy{Type: "Sparse"},
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv3",
-						},
-					},
-				},
-			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv3",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
-							},
-						},
-					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
 			},
 		},
 		{
-			desc: "advertisement with default BGP settings",
+			desc: "quarantine time with sequential allocation",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
@@ -1238,157 +1478,201 @@ func TestParse(t *testing.T) {
 							Addresses: []string{
 								"1.2.3.0/24",
 							},
-						},
-					},
-				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv3",
-						},
-					},
-				},
-			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv3",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
+							AllocationStrategy: &v1beta1.AllocationStrategy{
+								Type:           v1beta1.SequentialAllocation,
+								QuarantineTime: &metav1.Duration{Duration: time.Minute},
 							},
 						},
 					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
+				},
 			},
 		},
 		{
-			desc: "bad aggregation length (too long)",
+			desc: "ip address reservation outside of the pool",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"1.2.3.10-1.2.3.1",
-							},
+							Addresses: []string{"1.2.3.0/24"},
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				Reservations: []v1beta1.IPAddressReservation{
 					{
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](34),
+						ObjectMeta: metav1.ObjectMeta{Name: "res1"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "pool1",
+							Addresses:     []string{"1.2.4.1"},
+							ServiceRef:    &v1beta1.ServiceReference{Namespace: "ns", Name: "svc"},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "bad aggregation length (incompatible with CIDR)",
+			desc: "ip address reservation with two addresses of the same family",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"10.20.30.40/24",
-								"1.2.3.0/28",
-							},
+							Addresses: []string{"1.2.3.0/24"},
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				Reservations: []v1beta1.IPAddressReservation{
 					{
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](26),
+						ObjectMeta: metav1.ObjectMeta{Name: "res1"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "pool1",
+							Addresses:     []string{"1.2.3.1", "1.2.3.2"},
+							ServiceRef:    &v1beta1.ServiceReference{Namespace: "ns", Name: "svc"},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "different local pref - same peers and nodes",
+			desc: "address reserved twice",
 			crs: ClusterResources{
-				Nodes: []corev1.Node{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "node1",
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"1.2.3.0/24"},
 						},
 					},
+				},
+				Reservations: []v1beta1.IPAddressReservation{
 					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "node2",
+						ObjectMeta: metav1.ObjectMeta{Name: "res1"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "pool1",
+							Addresses:     []string{"1.2.3.1"},
+							ServiceRef:    &v1beta1.ServiceReference{Namespace: "ns", Name: "svc"},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "res2"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "pool1",
+							Addresses:     []string{"1.2.3.1"},
+							ServiceSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{"app": "web"},
+							},
 						},
 					},
 				},
+			},
+		},
+		{
+			desc: "ip address reservation with no service",
+			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"10.20.30.40/24",
+							Addresses: []string{"1.2.3.0/24"},
+						},
+					},
+				},
+				Reservations: []v1beta1.IPAddressReservation{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "res1"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "pool1",
+							Addresses:     []string{"1.2.3.1"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "quota selecting no namespace",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{"1.2.3.0/24"},
+							Quotas: []v1beta1.NamespaceQuota{
+								{MaxIPv4: ptr.To[int64](1)},
 							},
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+			},
+		},
+		{
+			desc: "excluded address outside of the pool",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						Spec: v1beta1.BGPAdvertisementSpec{
-							LocalPref: 100,
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses:         []string{"1.2.3.0/24"},
+							ExcludedAddresses: []string{"1.2.4.1"},
 						},
 					},
+				},
+			},
+		},
+		{
+			desc: "overlapping excluded addresses",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						Spec: v1beta1.BGPAdvertisementSpec{
-							LocalPref: 200,
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses:         []string{"1.2.3.0/24"},
+							ExcludedAddresses: []string{"1.2.3.0/28", "1.2.3.5"},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "different local pref - different ipv4 aggregation length",
+			desc: "reserved address excluded from the pool",
 			crs: ClusterResources{
-				Nodes: []corev1.Node{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "node1",
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses:         []string{"1.2.3.0/24"},
+							ExcludedAddresses: []string{"1.2.3.5"},
 						},
 					},
+				},
+				Reservations: []v1beta1.IPAddressReservation{
 					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "node2",
+						ObjectMeta: metav1.ObjectMeta{Name: "reservation1"},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "pool1",
+							Addresses:     []string{"1.2.3.5"},
+							ServiceRef:    &v1beta1.ServiceReference{Namespace: "ns", Name: "svc"},
 						},
 					},
 				},
+			},
+		},
+		{
+			desc: "simple advertisement",
+			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"10.20.30.40/24",
+								"1.2.3.0/24",
 							},
 						},
 					},
 				},
 				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
-						Spec: v1beta1.BGPAdvertisementSpec{
-							LocalPref:         100,
-							AggregationLength: ptr.To[int32](24),
-						},
-					},
-					{
-						Spec: v1beta1.BGPAdvertisementSpec{
-							LocalPref: 200,
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv3",
 						},
 					},
 				},
@@ -1398,23 +1682,14 @@ func TestParse(t *testing.T) {
 					"pool1": {
 						Name:       "pool1",
 						AutoAssign: true,
-						CIDR: []*net.IPNet{
-							ipnet("10.20.30.40/24"),
-						},
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
 						BGPAdvertisements: []*BGPAdvertisement{
 							{
-								AggregationLength:   24,
-								AggregationLengthV6: 128,
-								LocalPref:           100,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"node1": true, "node2": true},
-							},
-							{
+								Name:                "adv3",
 								AggregationLength:   32,
 								AggregationLengthV6: 128,
-								LocalPref:           200,
 								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"node1": true, "node2": true},
+								Nodes:               map[string]bool{},
 							},
 						},
 					},
@@ -1423,43 +1698,23 @@ func TestParse(t *testing.T) {
 				Peers:       map[string]*Peer{},
 			},
 		},
-
 		{
-			desc: "different local pref - different aggregation lengths",
+			desc: "advertisement with default BGP settings",
 			crs: ClusterResources{
-				Nodes: []corev1.Node{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "node1",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "node2",
-						},
-					},
-				},
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"10.20.30.40/24",
+								"1.2.3.0/24",
 							},
 						},
 					},
 				},
 				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
-						Spec: v1beta1.BGPAdvertisementSpec{
-							LocalPref:           100,
-							AggregationLength:   ptr.To[int32](24),
-							AggregationLengthV6: ptr.To[int32](120),
-						},
-					},
-					{
-						Spec: v1beta1.BGPAdvertisementSpec{
-							LocalPref: 200,
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv3",
 						},
 					},
 				},
@@ -1469,23 +1724,14 @@ func TestParse(t *testing.T) {
 					"pool1": {
 						Name:       "pool1",
 						AutoAssign: true,
-						CIDR: []*net.IPNet{
-							ipnet("10.20.30.40/24"),
-						},
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
 						BGPAdvertisements: []*BGPAdvertisement{
 							{
-								AggregationLength:   24,
-								AggregationLengthV6: 120,
-								LocalPref:           100,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"node1": true, "node2": true},
-							},
-							{
+								Name:                "adv3",
 								AggregationLength:   32,
 								AggregationLengthV6: 128,
-								LocalPref:           200,
 								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"node1": true, "node2": true},
+								Nodes:               map[string]bool{},
 							},
 						},
 					},
@@ -1495,19 +1741,62 @@ func TestParse(t *testing.T) {
 			},
 		},
 		{
-			desc: "different local pref - same peers & different nodes",
+			desc: "bad aggregation length (too long)",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.10-1.2.3.1",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](34),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "bad aggregation length (incompatible with CIDR)",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.30.40/24",
+								"1.2.3.0/28",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](26),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "different local pref - same peers and nodes",
 			crs: ClusterResources{
 				Nodes: []corev1.Node{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "node1",
-							Labels: map[string]string{"node": "node1"},
+							Name: "node1",
 						},
 					},
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "node2",
-							Labels: map[string]string{"node": "node2"},
+							Name: "node2",
 						},
 					},
 				},
@@ -1525,57 +1814,18 @@ func TestParse(t *testing.T) {
 					{
 						Spec: v1beta1.BGPAdvertisementSpec{
 							LocalPref: 100,
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{"node": "node1"},
-								},
-							},
 						},
 					},
 					{
 						Spec: v1beta1.BGPAdvertisementSpec{
 							LocalPref: 200,
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{"node": "node2"},
-								},
-							},
 						},
 					},
 				},
 			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR: []*net.IPNet{
-							ipnet("10.20.30.40/24"),
-						},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								LocalPref:           100,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"node1": true},
-							},
-							{
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								LocalPref:           200,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"node2": true},
-							},
-						},
-					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
-			},
 		},
 		{
-			desc: "different local pref - different peers & same nodes",
+			desc: "different med - same peers and nodes",
 			crs: ClusterResources{
 				Nodes: []corev1.Node{
 					{
@@ -1602,59 +1852,111 @@ func TestParse(t *testing.T) {
 				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						Spec: v1beta1.BGPAdvertisementSpec{
-							LocalPref: 100,
-							Peers:     []string{"peer1"},
+							MED: ptr.To[uint32](100),
 						},
 					},
 					{
 						Spec: v1beta1.BGPAdvertisementSpec{
-							LocalPref: 200,
-							Peers:     []string{"peer2"},
+							MED: ptr.To[uint32](200),
 						},
 					},
 				},
 			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR: []*net.IPNet{
-							ipnet("10.20.30.40/24"),
+		},
+		{
+			desc: "different as path prepend - same peers and nodes",
+			crs: ClusterResources{
+				Nodes: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node1",
 						},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								LocalPref:           100,
-								Peers:               []string{"peer1"},
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"node1": true, "node2": true},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node2",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.30.40/24",
 							},
-							{
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								LocalPref:           200,
-								Peers:               []string{"peer2"},
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"node1": true, "node2": true},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ASPathPrepend: 1,
+						},
+					},
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ASPathPrepend: 2,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "as path prepend too long",
+			crs: ClusterResources{
+				Nodes: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node1",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node2",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.30.40/24",
 							},
 						},
 					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ASPathPrepend: 11,
+						},
+					},
+				},
 			},
 		},
 		{
-			desc: "aggregation length by range",
+			desc: "different local pref - different ipv4 aggregation length",
 			crs: ClusterResources{
+				Nodes: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node1",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node2",
+						},
+					},
+				},
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"3.3.3.2-3.3.3.254",
+								"10.20.30.40/24",
 							},
 						},
 					},
@@ -1662,7 +1964,13 @@ func TestParse(t *testing.T) {
 				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](26),
+							LocalPref:         100,
+							AggregationLength: ptr.To[int32](24),
+						},
+					},
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							LocalPref: 200,
 						},
 					},
 				},
@@ -1673,26 +1981,22 @@ func TestParse(t *testing.T) {
 						Name:       "pool1",
 						AutoAssign: true,
 						CIDR: []*net.IPNet{
-							ipnet("3.3.3.2/31"),
-							ipnet("3.3.3.4/30"),
-							ipnet("3.3.3.8/29"),
-							ipnet("3.3.3.16/28"),
-							ipnet("3.3.3.32/27"),
-							ipnet("3.3.3.64/26"),
-							ipnet("3.3.3.128/26"),
-							ipnet("3.3.3.192/27"),
-							ipnet("3.3.3.224/28"),
-							ipnet("3.3.3.240/29"),
-							ipnet("3.3.3.248/30"),
-							ipnet("3.3.3.252/31"),
-							ipnet("3.3.3.254/32"),
+							ipnet("10.20.30.40/24"),
 						},
 						BGPAdvertisements: []*BGPAdvertisement{
 							{
-								AggregationLength:   26,
+								AggregationLength:   24,
 								AggregationLengthV6: 128,
+								LocalPref:           100,
 								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
+								Nodes:               map[string]bool{"node1": true, "node2": true},
+							},
+							{
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           200,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{"node1": true, "node2": true},
 							},
 						},
 					},
@@ -1701,15 +2005,28 @@ func TestParse(t *testing.T) {
 				Peers:       map[string]*Peer{},
 			},
 		},
+
 		{
-			desc: "aggregation length by range, too wide",
+			desc: "different local pref - different aggregation lengths",
 			crs: ClusterResources{
+				Nodes: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node1",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node2",
+						},
+					},
+				},
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"3.3.3.2-3.3.3.254",
+								"10.20.30.40/24",
 							},
 						},
 					},
@@ -1717,145 +2034,1160 @@ func TestParse(t *testing.T) {
 				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](24),
+							LocalPref:           100,
+							AggregationLength:   ptr.To[int32](24),
+							AggregationLengthV6: ptr.To[int32](120),
 						},
 					},
-				},
-			},
-		},
-		{
-			desc: "duplicate ip address pools - in L2 adv",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{testPool},
-				L2Advs: []v1beta1.L2Advertisement{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
-						Spec: v1beta1.L2AdvertisementSpec{
-							IPAddressPools: []string{testPoolName, testPoolName},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							LocalPref: 200,
 						},
 					},
 				},
 			},
-		},
-		{
-			desc: "duplicate ip address pools - in BGP adv",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{testPool},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							IPAddressPools: []string{testPoolName, testPoolName},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR: []*net.IPNet{
+							ipnet("10.20.30.40/24"),
+						},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								AggregationLength:   24,
+								AggregationLengthV6: 120,
+								LocalPref:           100,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{"node1": true, "node2": true},
+							},
+							{
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           200,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{"node1": true, "node2": true},
+							},
 						},
 					},
-				},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
 			},
 		},
 		{
-			desc: "duplicate peers - in BGP adv",
+			desc: "different local pref - same peers & different nodes",
 			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{testPool},
-				Peers: []v1beta2.BGPPeer{
+				Nodes: []corev1.Node{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name:   "node1",
+							Labels: map[string]string{"node": "node1"},
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:   42,
-							ASN:     42,
-							Address: "1.2.3.4",
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "node2",
+							Labels: map[string]string{"node": "node2"},
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							IPAddressPools: []string{testPoolName},
-							Peers:          []string{"peer1", "peer1"},
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.30.40/24",
+							},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "bad community literal (wrong format) - in BGP adv",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{testPool},
 				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
 						Spec: v1beta1.BGPAdvertisementSpec{
-							Communities:    []string{"1234"},
-							IPAddressPools: []string{testPoolName},
+							LocalPref: 100,
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{"node": "node1"},
+								},
+							},
 						},
 					},
-				},
-			},
-		},
-		{
-			desc: "bad community literal (asn part doesn't fit) - in BGP adv",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{testPool},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
 						Spec: v1beta1.BGPAdvertisementSpec{
-							Communities:    []string{"99999999:1"},
-							IPAddressPools: []string{testPoolName},
+							LocalPref: 200,
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{"node": "node2"},
+								},
+							},
 						},
 					},
 				},
 			},
-		},
-		{
-			desc: "bad community literal (community# part doesn't fit) - in BGP adv",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{testPool},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							Communities:    []string{"1:99999999"},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR: []*net.IPNet{
+							ipnet("10.20.30.40/24"),
+						},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           100,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{"node1": true},
+							},
+							{
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           200,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{"node2": true},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+		{
+			desc: "different local pref - different peers & same nodes",
+			crs: ClusterResources{
+				Nodes: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node1",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node2",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.30.40/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							LocalPref: 100,
+							Peers:     []string{"peer1"},
+						},
+					},
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							LocalPref: 200,
+							Peers:     []string{"peer2"},
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR: []*net.IPNet{
+							ipnet("10.20.30.40/24"),
+						},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           100,
+								Peers:               []string{"peer1"},
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{"node1": true, "node2": true},
+							},
+							{
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           200,
+								Peers:               []string{"peer2"},
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{"node1": true, "node2": true},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+		{
+			desc: "aggregation length by range",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"3.3.3.2-3.3.3.254",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](26),
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR: []*net.IPNet{
+							ipnet("3.3.3.2/31"),
+							ipnet("3.3.3.4/30"),
+							ipnet("3.3.3.8/29"),
+							ipnet("3.3.3.16/28"),
+							ipnet("3.3.3.32/27"),
+							ipnet("3.3.3.64/26"),
+							ipnet("3.3.3.128/26"),
+							ipnet("3.3.3.192/27"),
+							ipnet("3.3.3.224/28"),
+							ipnet("3.3.3.240/29"),
+							ipnet("3.3.3.248/30"),
+							ipnet("3.3.3.252/31"),
+							ipnet("3.3.3.254/32"),
+						},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								AggregationLength:   26,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
+		},
+		{
+			desc: "aggregation length by range, too wide",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"3.3.3.2-3.3.3.254",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](24),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate ip address pools - in L2 adv",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{testPool},
+				L2Advs: []v1beta1.L2Advertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
+						Spec: v1beta1.L2AdvertisementSpec{
+							IPAddressPools: []string{testPoolName, testPoolName},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate ip address pools - in BGP adv",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{testPool},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{testPoolName, testPoolName},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate peers - in BGP adv",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{testPool},
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     42,
+							Address: "1.2.3.4",
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
+						Spec: v1beta1.BGPAdvertisementSpec{
 							IPAddressPools: []string{testPoolName},
+							Peers:          []string{"peer1", "peer1"},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "bad community ref (unknown ref) - in BGP adv",
+			desc: "bad community literal (wrong format) - in BGP adv",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{testPool},
 				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
 						Spec: v1beta1.BGPAdvertisementSpec{
-							Communities:    []string{"community"},
+							Communities:    []string{"1234"},
+							IPAddressPools: []string{testPoolName},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "bad community literal (asn part doesn't fit) - in BGP adv",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{testPool},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							Communities:    []string{"99999999:1"},
 							IPAddressPools: []string{testPoolName},
 						},
 					},
-				},
-			},
-		},
-		{
-			desc: "duplicate community literal - in BGP adv",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{testPool},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				},
+			},
+		},
+		{
+			desc: "bad community literal (community# part doesn't fit) - in BGP adv",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{testPool},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							Communities:    []string{"1:99999999"},
+							IPAddressPools: []string{testPoolName},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "bad community ref (unknown ref) - in BGP adv",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{testPool},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							Communities:    []string{"community"},
+							IPAddressPools: []string{testPoolName},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate community literal - in BGP adv",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{testPool},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							Communities:    []string{"1234:5678", "1234:5678"},
+							IPAddressPools: []string{testPoolName},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "bad community literal (wrong format) - in the community CR",
+			crs: ClusterResources{
+				Communities: []v1beta1.Community{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "community",
+						},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{
+								{
+									Name:  "bar",
+									Value: "1234",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "bad community literal (asn part doesn't fit) - in the community CR",
+			crs: ClusterResources{
+				Communities: []v1beta1.Community{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "community",
+						},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{
+								{
+									Name:  "bar",
+									Value: "99999999:1",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "bad community literal (community# part doesn't fit) - in the community CR",
+			crs: ClusterResources{
+				Communities: []v1beta1.Community{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "community",
+						},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{
+								{
+									Name:  "bar",
+									Value: "1:99999999",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate communities definition (in 2 different crs)",
+			crs: ClusterResources{
+				Communities: []v1beta1.Community{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "community1",
+						},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{
+								{
+									Name:  "bar",
+									Value: "1234:5678",
+								},
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "community2",
+						},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{
+								{
+									Name:  "bar",
+									Value: "1234:5678",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate communities definition (in the same cr)",
+			crs: ClusterResources{
+				Communities: []v1beta1.Community{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "community",
+						},
+						Spec: v1beta1.CommunitySpec{
+							Communities: []v1beta1.CommunityAlias{
+								{
+									Name:  "bar",
+									Value: "1234:5678",
+								},
+								{
+									Name:  "bar",
+									Value: "1234:5678",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate pool definition",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec:       v1beta1.IPAddressPoolSpec{},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool2"},
+						Spec:       v1beta1.IPAddressPoolSpec{},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec:       v1beta1.IPAddressPoolSpec{},
+					},
+				},
+			},
+		},
+		{
+			desc: "duplicate CIDRs",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								" 10.0.0.0/8",
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool2"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								" 10.0.0.0/8",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "overlapping CIDRs",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								" 10.0.0.0/8",
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pool2"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.0.0.0/16",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Session with default BFD Profile",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							ASN:        42,
+							Address:    "1.2.3.4",
+							BFDProfile: "default",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BFDProfiles: []v1beta1.BFDProfile{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "default",
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv3",
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           42,
+						Addr:          net.ParseIP("1.2.3.4"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						BFDProfile:    "default",
+					},
+				},
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv3",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{
+					"default": {
+						Name: "default",
+					},
+				},
+			},
+		},
+		{
+			desc: "BGP Peer with both password and secret ref set",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:    42,
+							ASN:      42,
+							Address:  "1.2.3.4",
+							Password: "nopass",
+							PasswordSecret: corev1.SecretReference{Name: "nosecret",
+								Namespace: "metallb-system"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "BGP Peer with invalid secret type",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     42,
+							Address: "1.2.3.4",
+							PasswordSecret: corev1.SecretReference{Name: "bgpsecret",
+								Namespace: "metallb-system"},
+						},
+					},
+				},
+				PasswordSecrets: map[string]corev1.Secret{
+					"bgpsecret": {Type: corev1.SecretTypeOpaque, ObjectMeta: metav1.ObjectMeta{Name: "bgpsecret", Namespace: "metallb-system"}},
+				},
+			},
+		},
+		{
+			desc: "BGP Peer without password set in the secret",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     42,
+							Address: "1.2.3.4",
+							PasswordSecret: corev1.SecretReference{Name: "bgpsecret",
+								Namespace: "metallb-system"},
+						},
+					},
+				},
+				PasswordSecrets: map[string]corev1.Secret{
+					"bgpsecret": {Type: corev1.SecretTypeBasicAuth, ObjectMeta: metav1.ObjectMeta{Name: "bgpsecret", Namespace: "metallb-system"}},
+				},
+			},
+		},
+		{
+			desc: "BGP Peer with a valid secret",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     42,
+							Port:    179,
+							Address: "1.2.3.4",
+							PasswordSecret: corev1.SecretReference{Name: "bgpsecret",
+								Namespace: "metallb-system"},
+						},
+					},
+				},
+				PasswordSecrets: map[string]corev1.Secret{
+					"bgpsecret": {Type: corev1.SecretTypeBasicAuth, ObjectMeta: metav1.ObjectMeta{Name: "bgpsecret", Namespace: "metallb-system"},
+						Data: map[string][]byte{"password": []byte("nopass")}},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:           "peer1",
+						MyASN:          42,
+						ASN:            42,
+						Addr:           net.ParseIP("1.2.3.4"),
+						Port:           179,
+						NodeSelectors:  []labels.Selector{labels.Everything()},
+						BFDProfile:     "",
+						SecretPassword: "nopass",
+						PasswordRef: corev1.SecretReference{
+							Name:      "bgpsecret",
+							Namespace: "metallb-system",
+						},
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "BGP Peer with unavailable secret ref",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     42,
+							Address: "1.2.3.4",
+							PasswordSecret: corev1.SecretReference{Name: "nosecret",
+								Namespace: "metallb-system"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Peer with non existing BFD Profile",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							ASN:        42,
+							Address:    "1.2.3.4",
+							BFDProfile: "default",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv3",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Multiple BFD Profiles with the same name",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BFDProfiles: []v1beta1.BFDProfile{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "default",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "foo",
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "foo",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Session with nondefault BFD Profile",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BFDProfiles: []v1beta1.BFDProfile{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "nondefault",
+						},
+						Spec: v1beta1.BFDProfileSpec{
+							ReceiveInterval:  ptr.To(uint32(50)),
+							TransmitInterval: ptr.To(uint32(51)),
+							DetectMultiplier: ptr.To(uint32(52)),
+							EchoInterval:     ptr.To(uint32(54)),
+							EchoMode:         ptr.To(true),
+							PassiveMode:      ptr.To(true),
+							MinimumTTL:       ptr.To(uint32(55)),
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv3",
+						},
+					},
+				},
+			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						AutoAssign: true,
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv3",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{
+					"nondefault": {
+						Name:             "nondefault",
+						ReceiveInterval:  ptr.To(uint32(50)),
+						DetectMultiplier: ptr.To(uint32(52)),
+						TransmitInterval: ptr.To(uint32(51)),
+						EchoInterval:     ptr.To(uint32(54)),
+						MinimumTTL:       ptr.To(uint32(55)),
+						EchoMode:         true,
+						PassiveMode:      true,
+					},
+				},
+				Peers: map[string]*Peer{},
+			},
+		},
+		{
+			desc: "BFD Profile with too low receive interval",
+			crs: ClusterResources{
+
+				BFDProfiles: []v1beta1.BFDProfile{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "default",
+						},
+						Spec: v1beta1.BFDProfileSpec{
+							ReceiveInterval: ptr.To(uint32(2)),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "BFD Profile with too high receive interval",
+			crs: ClusterResources{
+
+				BFDProfiles: []v1beta1.BFDProfile{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "default",
+						},
+						Spec: v1beta1.BFDProfileSpec{
+							ReceiveInterval: ptr.To(uint32(90000)),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Session with ipv6 and bfd echo mode",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"fc00:f853:0ccd:e793::/64",
+							},
+						},
+					},
+				},
+				BFDProfiles: []v1beta1.BFDProfile{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "nondefault",
+						},
+						Spec: v1beta1.BFDProfileSpec{
+							ReceiveInterval:  ptr.To(uint32(50)),
+							TransmitInterval: ptr.To(uint32(51)),
+							DetectMultiplier: ptr.To(uint32(52)),
+							EchoInterval:     ptr.To(uint32(54)),
+							EchoMode:         ptr.To(true),
+							PassiveMode:      ptr.To(true),
+							MinimumTTL:       ptr.To(uint32(55)),
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv3",
+						},
+					},
+				},
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:        42,
+							ASN:          142,
+							Address:      "1.2.3.4",
+							Port:         1179,
+							HoldTime:     ptr.To(metav1.Duration{Duration: 180 * time.Second}),
+							RouterID:     "10.20.30.40",
+							SrcAddress:   "10.20.30.40",
+							EBGPMultiHop: true,
+							BFDProfile:   "nondefault",
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Session with ipv4 and bfd echo mode",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"1.2.3.0/24",
+							},
+						},
+					},
+				},
+				BFDProfiles: []v1beta1.BFDProfile{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "with-echo",
+						},
+						Spec: v1beta1.BFDProfileSpec{
+							EchoMode: ptr.To(true),
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv",
+						},
+					},
+				},
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							ASN:        142,
+							Address:    "1.2.3.4",
+							Port:       1179,
+							BFDProfile: "with-echo",
+						},
+					},
+				},
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           142,
+						Addr:          net.ParseIP("1.2.3.4"),
+						Port:          1179,
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						BFDProfile:    "with-echo"},
+				},
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("1.2.3.4/24")},
+						AutoAssign: true,
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{
+					"with-echo": {
+						Name:     "with-echo",
+						EchoMode: true,
+					},
+				},
+			},
+		},
+		{
+			desc: "config IPAddressPool with large communities CR",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "peer1",
+						},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:        42,
+							ASN:          142,
+							Address:      "1.2.3.4",
+							Port:         1179,
+							HoldTime:     ptr.To(metav1.Duration{Duration: 180 * time.Second}),
+							RouterID:     "10.20.30.40",
+							SrcAddress:   "10.20.30.40",
+							EBGPMultiHop: true,
+							VRFName:      "foo",
+						},
+					},
+				},
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+								"10.50.0.0/24",
+							},
+							AvoidBuggyIPs: true,
+							AutoAssign:    ptr.To(false),
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
+							LocalPref:         uint32(100),
+							Communities:       []string{"bar"},
+							IPAddressPools:    []string{"pool1"},
+							Peers:             []string{"peer1"},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv2",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength:   ptr.To[int32](24),
+							AggregationLengthV6: ptr.To[int32](64),
+							IPAddressPools:      []string{"pool1"},
+						},
+					},
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: testAdvName},
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv3",
+						},
 						Spec: v1beta1.BGPAdvertisementSpec{
-							Communities:    []string{"1234:5678", "1234:5678"},
-							IPAddressPools: []string{testPoolName},
+							IPAddressPools: []string{"pool2"},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "bad community literal (wrong format) - in the community CR",
-			crs: ClusterResources{
 				Communities: []v1beta1.Community{
 					{
 						ObjectMeta: metav1.ObjectMeta{
@@ -1865,189 +3197,299 @@ func TestParse(t *testing.T) {
 							Communities: []v1beta1.CommunityAlias{
 								{
 									Name:  "bar",
-									Value: "1234",
+									Value: "large:123:64512:1234",
 								},
 							},
 						},
 					},
 				},
 			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           142,
+						Addr:          net.ParseIP("1.2.3.4"),
+						SrcAddr:       net.ParseIP("10.20.30.40"),
+						Port:          1179,
+						HoldTime:      ptr.To(180 * time.Second),
+						KeepaliveTime: ptr.To(60 * time.Second),
+						RouterID:      net.ParseIP("10.20.30.40"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						EBGPMultiHop:  true,
+						VRF:           "foo",
+					},
+				},
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:          "pool1",
+						CIDR:          []*net.IPNet{ipnet("10.20.0.0/16"), ipnet("10.50.0.0/24")},
+						AvoidBuggyIPs: true,
+						AutoAssign:    false,
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           100,
+								Communities: func() map[community.BGPCommunity]bool {
+									c, _ := community.New("large:123:64512:1234")
+									return map[community.BGPCommunity]bool{
+										c: true,
+									}
+								}(),
+								Nodes: map[string]bool{},
+								Peers: []string{"peer1"},
+							},
+							{
+								Name:                "adv2",
+								AggregationLength:   24,
+								AggregationLengthV6: 64,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+							},
+						},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
 		},
 		{
-			desc: "bad community literal (asn part doesn't fit) - in the community CR",
+			desc: "use ip pool selectors",
 			crs: ClusterResources{
-				Communities: []v1beta1.Community{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "community",
+							Name:   "pool1",
+							Labels: map[string]string{"test": "pool1"},
 						},
-						Spec: v1beta1.CommunitySpec{
-							Communities: []v1beta1.CommunityAlias{
-								{
-									Name:  "bar",
-									Value: "99999999:1",
-								},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
 							},
 						},
 					},
-				},
-			},
-		},
-		{
-			desc: "bad community literal (community# part doesn't fit) - in the community CR",
-			crs: ClusterResources{
-				Communities: []v1beta1.Community{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "community",
+							Name:   "pool2",
+							Labels: map[string]string{"test": "pool2"},
 						},
-						Spec: v1beta1.CommunitySpec{
-							Communities: []v1beta1.CommunityAlias{
-								{
-									Name:  "bar",
-									Value: "1:99999999",
-								},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"30.0.0.0/16",
 							},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "duplicate communities definition (in 2 different crs)",
-			crs: ClusterResources{
-				Communities: []v1beta1.Community{
+				L2Advs: []v1beta1.L2Advertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "community1",
+							Name: "l2adv1",
 						},
-						Spec: v1beta1.CommunitySpec{
-							Communities: []v1beta1.CommunityAlias{
+						Spec: v1beta1.L2AdvertisementSpec{
+							IPAddressPoolSelectors: []metav1.LabelSelector{
 								{
-									Name:  "bar",
-									Value: "1234:5678",
+									MatchLabels: map[string]string{
+										"test": "pool1",
+									},
 								},
 							},
 						},
 					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "community2",
+							Name: "adv1",
 						},
-						Spec: v1beta1.CommunitySpec{
-							Communities: []v1beta1.CommunityAlias{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
+							LocalPref:         uint32(100),
+							IPAddressPoolSelectors: []metav1.LabelSelector{
 								{
-									Name:  "bar",
-									Value: "1234:5678",
+									MatchLabels: map[string]string{
+										"test": "pool1",
+									},
 								},
 							},
 						},
 					},
-				},
-			},
-		},
-		{
-			desc: "duplicate communities definition (in the same cr)",
-			crs: ClusterResources{
-				Communities: []v1beta1.Community{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "community",
+							Name: "adv2",
 						},
-						Spec: v1beta1.CommunitySpec{
-							Communities: []v1beta1.CommunityAlias{
-								{
-									Name:  "bar",
-									Value: "1234:5678",
-								},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
+							LocalPref:         uint32(200),
+							IPAddressPoolSelectors: []metav1.LabelSelector{
 								{
-									Name:  "bar",
-									Value: "1234:5678",
+									MatchLabels: map[string]string{
+										"test": "pool2",
+									},
 								},
 							},
 						},
 					},
 				},
 			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign: true,
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           100,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+							},
+						},
+						L2Advertisements: []*L2Advertisement{{
+							Nodes:         map[string]bool{},
+							AllInterfaces: true,
+						}},
+					},
+					"pool2": {
+						Name:       "pool2",
+						CIDR:       []*net.IPNet{ipnet("30.0.0.0/16")},
+						AutoAssign: true,
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv2",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           200,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+							},
+						},
+						L2Advertisements: nil,
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
 		},
 		{
-			desc: "duplicate pool definition",
+			desc: "specify interfaces",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec:       v1beta1.IPAddressPoolSpec{},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool2"},
-						Spec:       v1beta1.IPAddressPoolSpec{},
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "pool1",
+							Labels: map[string]string{"test": "pool1"},
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
+						},
 					},
+				},
+				L2Advs: []v1beta1.L2Advertisement{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec:       v1beta1.IPAddressPoolSpec{},
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "l2adv1",
+						},
+						Spec: v1beta1.L2AdvertisementSpec{
+							IPAddressPoolSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"test": "pool1",
+									},
+								},
+							},
+							Interfaces: []string{"eth0"},
+						},
 					},
 				},
 			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign: true,
+						L2Advertisements: []*L2Advertisement{{
+							Nodes:         map[string]bool{},
+							AllInterfaces: false,
+							Interfaces:    []string{"eth0"},
+						}},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
 		},
 		{
-			desc: "duplicate CIDRs",
+			desc: "gratuitous settings",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								" 10.0.0.0/8",
+								"10.20.0.0/16",
 							},
 						},
 					},
+				},
+				L2Advs: []v1beta1.L2Advertisement{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool2"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								" 10.0.0.0/8",
-							},
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "l2adv1",
+						},
+						Spec: v1beta1.L2AdvertisementSpec{
+							GratuitousInterval: &metav1.Duration{Duration: 500 * time.Millisecond},
+							GratuitousCount:    ptr.To[int32](10),
 						},
 					},
 				},
 			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign: true,
+						L2Advertisements: []*L2Advertisement{{
+							Nodes:              map[string]bool{},
+							AllInterfaces:      true,
+							GratuitousInterval: 500 * time.Millisecond,
+							GratuitousCount:    10,
+						}},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
 		},
 		{
-			desc: "overlapping CIDRs",
+			desc: "node priorities and sticky",
 			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
+				Nodes: []corev1.Node{
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								" 10.0.0.0/8",
-							},
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "node1",
+							Labels: map[string]string{"uplink": "fast"},
 						},
 					},
 					{
-						ObjectMeta: metav1.ObjectMeta{Name: "pool2"},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"10.0.0.0/16",
-							},
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "node2",
+							Labels: map[string]string{"uplink": "fast", "rack": "a"},
 						},
 					},
-				},
-			},
-		},
-		{
-			desc: "Session with default BFD Profile",
-			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
-						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							ASN:        42,
-							Address:    "1.2.3.4",
-							BFDProfile: "default",
+							Name: "node3",
 						},
 					},
 				},
@@ -2058,196 +3500,178 @@ func TestParse(t *testing.T) {
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"1.2.3.0/24",
+								"10.20.0.0/16",
 							},
 						},
 					},
 				},
-				BFDProfiles: []v1beta1.BFDProfile{
+				L2Advs: []v1beta1.L2Advertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "default",
+							Name: "l2adv1",
 						},
-					},
-				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv3",
+						Spec: v1beta1.L2AdvertisementSpec{
+							NodePriorities: []v1beta1.NodePriority{
+								{
+									Priority: 10,
+									NodeSelectors: []metav1.LabelSelector{
+										{MatchLabels: map[string]string{"uplink": "fast"}},
+									},
+								},
+								{
+									Priority: 5,
+									NodeSelectors: []metav1.LabelSelector{
+										{MatchLabels: map[string]string{"rack": "a"}},
+									},
+								},
+							},
+							Sticky: true,
 						},
 					},
 				},
 			},
 			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						MyASN:         42,
-						ASN:           42,
-						Addr:          net.ParseIP("1.2.3.4"),
-						NodeSelectors: []labels.Selector{labels.Everything()},
-						BFDProfile:    "default",
-					},
-				},
 				Pools: &Pools{ByName: map[string]*Pool{
 					"pool1": {
 						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
 						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv3",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
-							},
-						},
+						L2Advertisements: []*L2Advertisement{{
+							Nodes:          map[string]bool{"node1": true, "node2": true, "node3": true},
+							AllInterfaces:  true,
+							NodePriorities: map[string]int{"node1": 10, "node2": 5},
+							Sticky:         true,
+						}},
 					},
 				}},
-				BFDProfiles: map[string]*BFDProfile{
-					"default": {
-						Name: "default",
-					},
-				},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
 			},
 		},
 		{
-			desc: "BGP Peer with both password and secret ref set",
+			desc: "per ip election",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:    42,
-							ASN:      42,
-							Address:  "1.2.3.4",
-							Password: "nopass",
-							PasswordSecret: corev1.SecretReference{Name: "nosecret",
-								Namespace: "metallb-system"},
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "BGP Peer with invalid secret type",
-			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				L2Advs: []v1beta1.L2Advertisement{
 					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:   42,
-							ASN:     42,
-							Address: "1.2.3.4",
-							PasswordSecret: corev1.SecretReference{Name: "bgpsecret",
-								Namespace: "metallb-system"},
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "l2adv1",
+						},
+						Spec: v1beta1.L2AdvertisementSpec{
+							PerIPElection: true,
 						},
 					},
 				},
-				PasswordSecrets: map[string]corev1.Secret{
-					"bgpsecret": {Type: corev1.SecretTypeOpaque, ObjectMeta: metav1.ObjectMeta{Name: "bgpsecret", Namespace: "metallb-system"}},
-				},
 			},
-		},
-		{
-			desc: "BGP Peer without password set in the secret",
-			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
-					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:   42,
-							ASN:     42,
-							Address: "1.2.3.4",
-							PasswordSecret: corev1.SecretReference{Name: "bgpsecret",
-								Namespace: "metallb-system"},
-						},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign: true,
+						L2Advertisements: []*L2Advertisement{{
+							Nodes:         map[string]bool{},
+							AllInterfaces: true,
+							PerIPElection: true,
+						}},
 					},
-				},
-				PasswordSecrets: map[string]corev1.Secret{
-					"bgpsecret": {Type: corev1.SecretTypeBasicAuth, ObjectMeta: metav1.ObjectMeta{Name: "bgpsecret", Namespace: "metallb-system"}},
-				},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
 			},
 		},
 		{
-			desc: "BGP Peer with a valid secret",
+			desc: "gratuitous duration and count both set",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "pool1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:   42,
-							ASN:     42,
-							Port:    179,
-							Address: "1.2.3.4",
-							PasswordSecret: corev1.SecretReference{Name: "bgpsecret",
-								Namespace: "metallb-system"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
 						},
 					},
 				},
-				PasswordSecrets: map[string]corev1.Secret{
-					"bgpsecret": {Type: corev1.SecretTypeBasicAuth, ObjectMeta: metav1.ObjectMeta{Name: "bgpsecret", Namespace: "metallb-system"},
-						Data: map[string][]byte{"password": []byte("nopass")}},
-				},
-			},
-			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:           "peer1",
-						MyASN:          42,
-						ASN:            42,
-						Addr:           net.ParseIP("1.2.3.4"),
-						Port:           179,
-						NodeSelectors:  []labels.Selector{labels.Everything()},
-						BFDProfile:     "",
-						SecretPassword: "nopass",
-						PasswordRef: corev1.SecretReference{
-							Name:      "bgpsecret",
-							Namespace: "metallb-system",
+				L2Advs: []v1beta1.L2Advertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "l2adv1",
+						},
+						Spec: v1beta1.L2AdvertisementSpec{
+							GratuitousDuration: &metav1.Duration{Duration: 10 * time.Second},
+							GratuitousCount:    ptr.To[int32](10),
 						},
 					},
 				},
-				Pools:       &Pools{ByName: map[string]*Pool{}},
-				BFDProfiles: map[string]*BFDProfile{},
 			},
 		},
 		{
-			desc: "BGP Peer with unavailable secret ref",
+			desc: "use duplicate match labels in ip pool selectors - in BGP adv",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:   42,
-							ASN:     42,
-							Address: "1.2.3.4",
-							PasswordSecret: corev1.SecretReference{Name: "nosecret",
-								Namespace: "metallb-system"},
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "pool1",
+							Labels: map[string]string{"test": "pool1"},
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
+							LocalPref:         uint32(100),
+							IPAddressPoolSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"test": "pool1",
+									},
+								},
+								{
+									MatchLabels: map[string]string{
+										"test": "pool1",
+									},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "Peer with non existing BFD Profile",
+			desc: "use duplicate match expression in ip pool selector - in BGP adv",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
-					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							ASN:        42,
-							Address:    "1.2.3.4",
-							BFDProfile: "default",
-						},
-					},
-				},
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "pool1",
+							Name:   "pool1",
+							Labels: map[string]string{"test": "pool1"},
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"1.2.3.0/24",
+								"10.20.0.0/16",
 							},
 						},
 					},
@@ -2255,151 +3679,167 @@ func TestParse(t *testing.T) {
 				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv3",
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
+							LocalPref:         uint32(100),
+							IPAddressPoolSelectors: []metav1.LabelSelector{
+								{
+									MatchExpressions: []metav1.LabelSelectorRequirement{
+										{
+											Key:      "test",
+											Operator: metav1.LabelSelectorOpIn,
+											Values:   []string{"pool1", "pool1"},
+										},
+									},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "Multiple BFD Profiles with the same name",
+			desc: "use duplicate ip pool selectors - in L2 adv",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "pool1",
+							Name:   "pool1",
+							Labels: map[string]string{"test": "pool1"},
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"1.2.3.0/24",
+								"10.20.0.0/16",
 							},
 						},
 					},
 				},
-				BFDProfiles: []v1beta1.BFDProfile{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "default",
-						},
-					},
+				L2Advs: []v1beta1.L2Advertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "foo",
+							Name: "adv1",
 						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "foo",
+						Spec: v1beta1.L2AdvertisementSpec{
+							IPAddressPoolSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"test": "pool1",
+									},
+								},
+								{
+									MatchLabels: map[string]string{
+										"test": "pool1",
+									},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "Session with nondefault BFD Profile",
+			desc: "use non existent label for ip pool selectors",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "pool1",
+							Name:   "pool1",
+							Labels: map[string]string{"test": "pool1"},
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"1.2.3.0/24",
+								"10.20.0.0/16",
 							},
 						},
 					},
-				},
-				BFDProfiles: []v1beta1.BFDProfile{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "nondefault",
+							Name:   "pool2",
+							Labels: map[string]string{"test": "pool2"},
 						},
-						Spec: v1beta1.BFDProfileSpec{
-							ReceiveInterval:  ptr.To(uint32(50)),
-							TransmitInterval: ptr.To(uint32(51)),
-							DetectMultiplier: ptr.To(uint32(52)),
-							EchoInterval:     ptr.To(uint32(54)),
-							EchoMode:         ptr.To(true),
-							PassiveMode:      ptr.To(true),
-							MinimumTTL:       ptr.To(uint32(55)),
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"30.0.0.0/16",
+							},
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				L2Advs: []v1beta1.L2Advertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv3",
+							Name: "l2adv1",
 						},
-					},
-				},
-			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv3",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
+						Spec: v1beta1.L2AdvertisementSpec{
+							IPAddressPoolSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"test": "do-not-select-pool",
+									},
+								},
 							},
 						},
 					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{
-					"nondefault": {
-						Name:             "nondefault",
-						ReceiveInterval:  ptr.To(uint32(50)),
-						DetectMultiplier: ptr.To(uint32(52)),
-						TransmitInterval: ptr.To(uint32(51)),
-						EchoInterval:     ptr.To(uint32(54)),
-						MinimumTTL:       ptr.To(uint32(55)),
-						EchoMode:         true,
-						PassiveMode:      true,
-					},
 				},
-				Peers: map[string]*Peer{},
-			},
-		},
-		{
-			desc: "BFD Profile with too low receive interval",
-			crs: ClusterResources{
-
-				BFDProfiles: []v1beta1.BFDProfile{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "default",
+							Name: "adv1",
 						},
-						Spec: v1beta1.BFDProfileSpec{
-							ReceiveInterval: ptr.To(uint32(2)),
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
+							LocalPref:         uint32(100),
+							IPAddressPoolSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"test": "do-not-select-pool",
+									},
+								},
+							},
 						},
 					},
-				},
-			},
-		},
-		{
-			desc: "BFD Profile with too high receive interval",
-			crs: ClusterResources{
-
-				BFDProfiles: []v1beta1.BFDProfile{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "default",
+							Name: "adv2",
 						},
-						Spec: v1beta1.BFDProfileSpec{
-							ReceiveInterval: ptr.To(uint32(90000)),
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
+							LocalPref:         uint32(200),
+							IPAddressPoolSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"test": "do-not-select-pool",
+									},
+								},
+							},
 						},
 					},
 				},
 			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:              "pool1",
+						CIDR:              []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign:        true,
+						BGPAdvertisements: nil,
+						L2Advertisements:  nil,
+					},
+					"pool2": {
+						Name:              "pool2",
+						CIDR:              []*net.IPNet{ipnet("30.0.0.0/16")},
+						AutoAssign:        true,
+						BGPAdvertisements: nil,
+						L2Advertisements:  nil,
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
 		},
 		{
-			desc: "Session with ipv6 and bfd echo mode",
+			desc: "use node selectors",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
@@ -2408,157 +3848,133 @@ func TestParse(t *testing.T) {
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"fc00:f853:0ccd:e793::/64",
+								"10.20.0.0/16",
 							},
 						},
 					},
-				},
-				BFDProfiles: []v1beta1.BFDProfile{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "nondefault",
-						},
-						Spec: v1beta1.BFDProfileSpec{
-							ReceiveInterval:  ptr.To(uint32(50)),
-							TransmitInterval: ptr.To(uint32(51)),
-							DetectMultiplier: ptr.To(uint32(52)),
-							EchoInterval:     ptr.To(uint32(54)),
-							EchoMode:         ptr.To(true),
-							PassiveMode:      ptr.To(true),
-							MinimumTTL:       ptr.To(uint32(55)),
-						},
-					},
-				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv3",
-						},
-					},
-				},
-				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "pool2",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:        42,
-							ASN:          142,
-							Address:      "1.2.3.4",
-							Port:         1179,
-							HoldTime:     ptr.To(metav1.Duration{Duration: 180 * time.Second}),
-							RouterID:     "10.20.30.40",
-							SrcAddress:   "10.20.30.40",
-							EBGPMultiHop: true,
-							BFDProfile:   "nondefault",
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"30.0.0.0/16",
+							},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "Session with ipv4 and bfd echo mode",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "pool1",
+							Name: "adv1",
 						},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"1.2.3.0/24",
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"second": "true",
+									},
+								},
 							},
 						},
 					},
-				},
-				BFDProfiles: []v1beta1.BFDProfile{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "with-echo",
+							Name: "adv2",
 						},
-						Spec: v1beta1.BFDProfileSpec{
-							EchoMode: ptr.To(true),
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool2"},
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"first": "true",
+									},
+								},
+							},
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				L2Advs: []v1beta1.L2Advertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv",
+							Name: "l2adv1",
+						},
+						Spec: v1beta1.L2AdvertisementSpec{
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"first": "true",
+									},
+								},
+							},
+							IPAddressPools: []string{"pool1"},
 						},
 					},
 				},
-				Peers: []v1beta2.BGPPeer{
+				Nodes: []corev1.Node{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "first",
+							Labels: map[string]string{
+								"first": "true",
+							},
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							ASN:        142,
-							Address:    "1.2.3.4",
-							Port:       1179,
-							BFDProfile: "with-echo",
+					}, {
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "second",
+							Labels: map[string]string{
+								"second": "true",
+							},
 						},
 					},
 				},
 			},
 			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						MyASN:         42,
-						ASN:           142,
-						Addr:          net.ParseIP("1.2.3.4"),
-						Port:          1179,
-						NodeSelectors: []labels.Selector{labels.Everything()},
-						BFDProfile:    "with-echo"},
-				},
 				Pools: &Pools{ByName: map[string]*Pool{
 					"pool1": {
 						Name:       "pool1",
-						CIDR:       []*net.IPNet{ipnet("1.2.3.4/24")},
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
 						AutoAssign: true,
 						BGPAdvertisements: []*BGPAdvertisement{
 							{
-								Name:                "adv",
+								Name:                "adv1",
 								AggregationLength:   32,
 								AggregationLengthV6: 128,
 								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
+								Nodes:               map[string]bool{"second": true},
 							},
 						},
+						L2Advertisements: []*L2Advertisement{{
+							Nodes: map[string]bool{
+								"first": true,
+							},
+							AllInterfaces: true,
+						}},
 					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{
-					"with-echo": {
-						Name:     "with-echo",
-						EchoMode: true,
+					"pool2": {
+						Name:       "pool2",
+						CIDR:       []*net.IPNet{ipnet("30.0.0.0/16")},
+						AutoAssign: true,
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv2",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{"first": true},
+							},
+						},
+						L2Advertisements: nil,
 					},
-				},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
 			},
 		},
 		{
-			desc: "config IPAddressPool with large communities CR",
+			desc: "use duplicate match labels in node selectors",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
-						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:        42,
-							ASN:          142,
-							Address:      "1.2.3.4",
-							Port:         1179,
-							HoldTime:     ptr.To(metav1.Duration{Duration: 180 * time.Second}),
-							RouterID:     "10.20.30.40",
-							SrcAddress:   "10.20.30.40",
-							EBGPMultiHop: true,
-							VRFName:      "foo",
-						},
-					},
-				},
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
@@ -2567,10 +3983,7 @@ func TestParse(t *testing.T) {
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
 								"10.20.0.0/16",
-								"10.50.0.0/24",
 							},
-							AvoidBuggyIPs: true,
-							AutoAssign:    ptr.To(false),
 						},
 					},
 				},
@@ -2580,107 +3993,151 @@ func TestParse(t *testing.T) {
 							Name: "adv1",
 						},
 						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							LocalPref:         uint32(100),
-							Communities:       []string{"bar"},
-							IPAddressPools:    []string{"pool1"},
-							Peers:             []string{"peer1"},
+							IPAddressPools: []string{"pool1"},
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"second": "true",
+									},
+								},
+								{
+									MatchLabels: map[string]string{
+										"second": "true",
+									},
+								},
+							},
 						},
 					},
+				},
+				L2Advs: []v1beta1.L2Advertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv2",
+							Name: "l2adv1",
 						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength:   ptr.To[int32](24),
-							AggregationLengthV6: ptr.To[int32](64),
-							IPAddressPools:      []string{"pool1"},
+						Spec: v1beta1.L2AdvertisementSpec{
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"first": "true",
+									},
+								},
+								{
+									MatchLabels: map[string]string{
+										"first": "true",
+									},
+								},
+							},
+							IPAddressPools: []string{"pool1"},
 						},
 					},
+				},
+				Nodes: []corev1.Node{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv3",
+							Name: "first",
+							Labels: map[string]string{
+								"first": "true",
+							},
 						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							IPAddressPools: []string{"pool2"},
+					}, {
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "second",
+							Labels: map[string]string{
+								"second": "true",
+							},
 						},
 					},
 				},
-				Communities: []v1beta1.Community{
+			},
+		},
+		{
+			desc: "use duplicate match expression values in node selector",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "community",
+							Name: "pool1",
 						},
-						Spec: v1beta1.CommunitySpec{
-							Communities: []v1beta1.CommunityAlias{
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							NodeSelectors: []metav1.LabelSelector{
 								{
-									Name:  "bar",
-									Value: "large:123:64512:1234",
+									MatchExpressions: []metav1.LabelSelectorRequirement{
+										{
+											Key:      "kubernetes.io/hostname",
+											Operator: metav1.LabelSelectorOpIn,
+											Values:   []string{"first", "second"},
+										},
+										{
+											Key:      "kubernetes.io/hostname",
+											Operator: metav1.LabelSelectorOpIn,
+											Values:   []string{"second", "first"},
+										},
+									},
 								},
 							},
 						},
 					},
 				},
-			},
-			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						MyASN:         42,
-						ASN:           142,
-						Addr:          net.ParseIP("1.2.3.4"),
-						SrcAddr:       net.ParseIP("10.20.30.40"),
-						Port:          1179,
-						HoldTime:      ptr.To(180 * time.Second),
-						KeepaliveTime: ptr.To(60 * time.Second),
-						RouterID:      net.ParseIP("10.20.30.40"),
-						NodeSelectors: []labels.Selector{labels.Everything()},
-						EBGPMultiHop:  true,
-						VRF:           "foo",
+				L2Advs: []v1beta1.L2Advertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "l2adv1",
+						},
+						Spec: v1beta1.L2AdvertisementSpec{
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchExpressions: []metav1.LabelSelectorRequirement{
+										{
+											Key:      "kubernetes.io/hostname",
+											Operator: metav1.LabelSelectorOpIn,
+											Values:   []string{"first"},
+										},
+									},
+								},
+							},
+							IPAddressPools: []string{"pool1"},
+						},
 					},
 				},
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:          "pool1",
-						CIDR:          []*net.IPNet{ipnet("10.20.0.0/16"), ipnet("10.50.0.0/24")},
-						AvoidBuggyIPs: true,
-						AutoAssign:    false,
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv1",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								LocalPref:           100,
-								Communities: func() map[community.BGPCommunity]bool {
-									c, _ := community.New("large:123:64512:1234")
-									return map[community.BGPCommunity]bool{
-										c: true,
-									}
-								}(),
-								Nodes: map[string]bool{},
-								Peers: []string{"peer1"},
+				Nodes: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "first",
+							Labels: map[string]string{
+								"first": "true",
 							},
-							{
-								Name:                "adv2",
-								AggregationLength:   24,
-								AggregationLengthV6: 64,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
+						},
+					}, {
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "second",
+							Labels: map[string]string{
+								"second": "true",
 							},
 						},
 					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
+				},
 			},
 		},
 		{
-			desc: "use ip pool selectors",
+			desc: "no nodes means all nodes",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "pool1",
-							Labels: map[string]string{"test": "pool1"},
+							Name: "pool1",
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
@@ -2688,15 +4145,11 @@ func TestParse(t *testing.T) {
 							},
 						},
 					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "pool2",
-							Labels: map[string]string{"test": "pool2"},
-						},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"30.0.0.0/16",
-							},
+							Name: "adv1",
 						},
 					},
 				},
@@ -2705,47 +4158,21 @@ func TestParse(t *testing.T) {
 						ObjectMeta: metav1.ObjectMeta{
 							Name: "l2adv1",
 						},
-						Spec: v1beta1.L2AdvertisementSpec{
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "pool1",
-									},
-								},
-							},
-						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				Nodes: []corev1.Node{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
-						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							LocalPref:         uint32(100),
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "pool1",
-									},
-								},
+							Name: "first",
+							Labels: map[string]string{
+								"first": "true",
 							},
 						},
-					},
-					{
+					}, {
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv2",
-						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							LocalPref:         uint32(200),
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "pool2",
-									},
-								},
+							Name: "second",
+							Labels: map[string]string{
+								"second": "true",
 							},
 						},
 					},
@@ -2762,216 +4189,265 @@ func TestParse(t *testing.T) {
 								Name:                "adv1",
 								AggregationLength:   32,
 								AggregationLengthV6: 128,
-								LocalPref:           100,
 								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
+								Nodes: map[string]bool{
+									"first":  true,
+									"second": true,
+								},
 							},
 						},
 						L2Advertisements: []*L2Advertisement{{
-							Nodes:         map[string]bool{},
+							Nodes: map[string]bool{
+								"first":  true,
+								"second": true,
+							},
 							AllInterfaces: true,
 						}},
 					},
-					"pool2": {
-						Name:       "pool2",
-						CIDR:       []*net.IPNet{ipnet("30.0.0.0/16")},
-						AutoAssign: true,
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv2",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								LocalPref:           200,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
-							},
-						},
-						L2Advertisements: nil,
-					},
 				}},
 				BFDProfiles: map[string]*BFDProfile{},
 				Peers:       map[string]*Peer{},
 			},
 		},
 		{
-			desc: "specify interfaces",
+			desc: "advertisement with peer selector",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "pool1",
-							Labels: map[string]string{"test": "pool1"},
+							Name: "pool1",
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"10.20.0.0/16",
+								"1.2.3.0/24",
 							},
 						},
 					},
 				},
-				L2Advs: []v1beta1.L2Advertisement{
+				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "l2adv1",
+							Name: "peer1",
 						},
-						Spec: v1beta1.L2AdvertisementSpec{
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "pool1",
-									},
-								},
-							},
-							Interfaces: []string{"eth0"},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:   42,
+							ASN:     42,
+							Address: "1.2.3.4",
+						},
+					},
+				},
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "adv1",
+						},
+						Spec: v1beta1.BGPAdvertisementSpec{
+							AggregationLength: ptr.To[int32](32),
+							LocalPref:         uint32(100),
+							IPAddressPools:    []string{"pool1"},
+							Peers:             []string{"peer1"},
 						},
 					},
 				},
 			},
 			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						ASN:           42,
+						Addr:          net.ParseIP("1.2.3.4"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						EBGPMultiHop:  false,
+					},
+				},
 				Pools: &Pools{ByName: map[string]*Pool{
 					"pool1": {
 						Name:       "pool1",
-						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
 						AutoAssign: true,
-						L2Advertisements: []*L2Advertisement{{
-							Nodes:         map[string]bool{},
-							AllInterfaces: false,
-							Interfaces:    []string{"eth0"},
-						}},
+						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
+						BGPAdvertisements: []*BGPAdvertisement{
+							{
+								Name:                "adv1",
+								AggregationLength:   32,
+								AggregationLengthV6: 128,
+								LocalPref:           100,
+								Communities:         map[community.BGPCommunity]bool{},
+								Nodes:               map[string]bool{},
+								Peers:               []string{"peer1"},
+							},
+						},
 					},
 				}},
 				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
 			},
 		},
 		{
-			desc: "use duplicate match labels in ip pool selectors - in BGP adv",
+			desc: "peer with dynamic asn",
 			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
+
+				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "pool1",
-							Labels: map[string]string{"test": "pool1"},
+							Name: "peer1",
 						},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"10.20.0.0/16",
-							},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							DynamicASN: v1beta2.InternalASNMode,
+							Address:    "1.2.3.4",
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						MyASN:         42,
+						DynamicASN:    "internal",
+						Addr:          net.ParseIP("1.2.3.4"),
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						EBGPMultiHop:  false,
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "peer without asn or dynamic asn",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
 					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							ASN:        0,
+							DynamicASN: "",
 						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							LocalPref:         uint32(100),
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "pool1",
-									},
-								},
-								{
-									MatchLabels: map[string]string{
-										"test": "pool1",
-									},
-								},
-							},
+					},
+				},
+			},
+		},
+		{
+			desc: "peer with both asn and dynamic asn",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							ASN:        42,
+							DynamicASN: v1beta2.InternalASNMode,
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "use duplicate match expression in ip pool selector - in BGP adv",
+			desc: "unnumbered peer without address with interface ok",
 			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
+
+				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "pool1",
-							Labels: map[string]string{"test": "pool1"},
+							Name: "peer1",
 						},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"10.20.0.0/16",
-							},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							DynamicASN: v1beta2.InternalASNMode,
+							Interface:  "net0",
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:          "peer1",
+						Iface:         "net0",
+						MyASN:         42,
+						DynamicASN:    "internal",
+						NodeSelectors: []labels.Selector{labels.Everything()},
+						EBGPMultiHop:  false,
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
+		},
+		{
+			desc: "unnumbered peer without address without interface nok",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
+							Name: "invalid",
 						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							LocalPref:         uint32(100),
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchExpressions: []metav1.LabelSelectorRequirement{
-										{
-											Key:      "test",
-											Operator: metav1.LabelSelectorOpIn,
-											Values:   []string{"pool1", "pool1"},
-										},
-									},
-								},
-							},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:      42,
+							DynamicASN: "internal",
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "use duplicate ip pool selectors - in L2 adv",
+			desc: "unnumbered peer with address with interface nok",
 			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
+				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "pool1",
-							Labels: map[string]string{"test": "pool1"},
+							Name: "invalid",
 						},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"10.20.0.0/16",
-							},
+						Spec: v1beta2.BGPPeerSpec{
+							Address:    "1.2.3.4",
+							Interface:  "net0",
+							MyASN:      42,
+							DynamicASN: "internal",
 						},
 					},
 				},
-				L2Advs: []v1beta1.L2Advertisement{
+			},
+		},
+		{
+			desc: "peer with DisableMP field",
+			crs: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
+							Name: "peer1",
 						},
-						Spec: v1beta1.L2AdvertisementSpec{
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "pool1",
-									},
-								},
-								{
-									MatchLabels: map[string]string{
-										"test": "pool1",
-									},
-								},
-							},
+						Spec: v1beta2.BGPPeerSpec{
+							MyASN:     42,
+							ASN:       142,
+							Address:   "1.2.3.4",
+							DisableMP: true,
 						},
 					},
 				},
 			},
+			want: &Config{
+				Peers: map[string]*Peer{
+					"peer1": {
+						Name:                   "peer1",
+						MyASN:                  42,
+						ASN:                    142,
+						Addr:                   net.ParseIP("1.2.3.4"),
+						NodeSelectors:          []labels.Selector{labels.Everything()},
+						DisableMP:              true,
+						DualStackAddressFamily: false,
+					},
+				},
+				Pools:       &Pools{ByName: map[string]*Pool{}},
+				BFDProfiles: map[string]*BFDProfile{},
+			},
 		},
 		{
-			desc: "use non existent label for ip pool selectors",
+			desc: "upnp advertisement",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:   "pool1",
-							Labels: map[string]string{"test": "pool1"},
+							Name: "pool1",
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
@@ -2979,64 +4455,31 @@ func TestParse(t *testing.T) {
 							},
 						},
 					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:   "pool2",
-							Labels: map[string]string{"test": "pool2"},
-						},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"30.0.0.0/16",
-							},
-						},
-					},
 				},
-				L2Advs: []v1beta1.L2Advertisement{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "l2adv1",
+							Name: "upnpadv1",
 						},
-						Spec: v1beta1.L2AdvertisementSpec{
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "do-not-select-pool",
-									},
-								},
-							},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							Duration:       3600,
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				Nodes: []corev1.Node{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
-						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							LocalPref:         uint32(100),
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "do-not-select-pool",
-									},
-								},
+							Name: "first",
+							Labels: map[string]string{
+								"first": "true",
 							},
 						},
-					},
-					{
+					}, {
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv2",
-						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							LocalPref:         uint32(200),
-							IPAddressPoolSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"test": "do-not-select-pool",
-									},
-								},
+							Name: "second",
+							Labels: map[string]string{
+								"second": "true",
 							},
 						},
 					},
@@ -3045,95 +4488,50 @@ func TestParse(t *testing.T) {
 			want: &Config{
 				Pools: &Pools{ByName: map[string]*Pool{
 					"pool1": {
-						Name:              "pool1",
-						CIDR:              []*net.IPNet{ipnet("10.20.0.0/16")},
-						AutoAssign:        true,
-						BGPAdvertisements: nil,
-						L2Advertisements:  nil,
-					},
-					"pool2": {
-						Name:              "pool2",
-						CIDR:              []*net.IPNet{ipnet("30.0.0.0/16")},
-						AutoAssign:        true,
-						BGPAdvertisements: nil,
-						L2Advertisements:  nil,
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign: true,
+						UPnPAdvertisements: []*UPnPAdvertisement{{
+							Name: "upnpadv1",
+							Nodes: map[string]bool{
+								"first":  true,
+								"second": true,
+							},
+							Enabled:     true,
+							Duration:    3600,
+							Description: "MetalLB LoadBalancer",
+							Protocol:    "UPnP",
+						}},
 					},
 				}},
 				BFDProfiles: map[string]*BFDProfile{},
 				Peers:       map[string]*Peer{},
 			},
 		},
-		{
-			desc: "use node selectors",
-			crs: ClusterResources{
-				Pools: []v1beta1.IPAddressPool{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "pool1",
-						},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"10.20.0.0/16",
-							},
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "pool2",
-						},
-						Spec: v1beta1.IPAddressPoolSpec{
-							Addresses: []string{
-								"30.0.0.0/16",
-							},
-						},
-					},
-				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
-						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							IPAddressPools: []string{"pool1"},
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"second": "true",
-									},
-								},
-							},
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv2",
-						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							IPAddressPools: []string{"pool2"},
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"first": "true",
-									},
-								},
+		{
+			desc: "pcp upnp advertisement",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
 							},
 						},
 					},
 				},
-				L2Advs: []v1beta1.L2Advertisement{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "l2adv1",
+							Name: "upnpadv1",
 						},
-						Spec: v1beta1.L2AdvertisementSpec{
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"first": "true",
-									},
-								},
-							},
+						Spec: v1beta1.UPnPAdvertisementSpec{
 							IPAddressPools: []string{"pool1"},
+							Protocol:       "PCP",
+							GatewayAddress: "192.168.1.1:5351",
 						},
 					},
 				},
@@ -3141,16 +4539,6 @@ func TestParse(t *testing.T) {
 					{
 						ObjectMeta: metav1.ObjectMeta{
 							Name: "first",
-							Labels: map[string]string{
-								"first": "true",
-							},
-						},
-					}, {
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "second",
-							Labels: map[string]string{
-								"second": "true",
-							},
 						},
 					},
 				},
@@ -3161,44 +4549,24 @@ func TestParse(t *testing.T) {
 						Name:       "pool1",
 						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
 						AutoAssign: true,
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv1",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"second": true},
-							},
-						},
-						L2Advertisements: []*L2Advertisement{{
+						UPnPAdvertisements: []*UPnPAdvertisement{{
+							Name: "upnpadv1",
 							Nodes: map[string]bool{
 								"first": true,
 							},
-							AllInterfaces: true,
+							Enabled:        true,
+							Description:    "MetalLB LoadBalancer",
+							Protocol:       "PCP",
+							GatewayAddress: "192.168.1.1:5351",
 						}},
 					},
-					"pool2": {
-						Name:       "pool2",
-						CIDR:       []*net.IPNet{ipnet("30.0.0.0/16")},
-						AutoAssign: true,
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv2",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{"first": true},
-							},
-						},
-						L2Advertisements: nil,
-					},
 				}},
 				BFDProfiles: map[string]*BFDProfile{},
 				Peers:       map[string]*Peer{},
 			},
 		},
 		{
-			desc: "use duplicate match labels in node selectors",
+			desc: "nat-pmp upnp advertisement without gateway address",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
@@ -3212,71 +4580,48 @@ func TestParse(t *testing.T) {
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
+							Name: "upnpadv1",
 						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							IPAddressPools: []string{"pool1"},
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"second": "true",
-									},
-								},
-								{
-									MatchLabels: map[string]string{
-										"second": "true",
-									},
-								},
-							},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							Protocol: "NAT-PMP",
 						},
 					},
 				},
-				L2Advs: []v1beta1.L2Advertisement{
+			},
+		},
+		{
+			desc: "pcp upnp advertisement with invalid gateway address",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "l2adv1",
+							Name: "pool1",
 						},
-						Spec: v1beta1.L2AdvertisementSpec{
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchLabels: map[string]string{
-										"first": "true",
-									},
-								},
-								{
-									MatchLabels: map[string]string{
-										"first": "true",
-									},
-								},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
 							},
-							IPAddressPools: []string{"pool1"},
 						},
 					},
 				},
-				Nodes: []corev1.Node{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "first",
-							Labels: map[string]string{
-								"first": "true",
-							},
+							Name: "upnpadv1",
 						},
-					}, {
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "second",
-							Labels: map[string]string{
-								"second": "true",
-							},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							Protocol:       "PCP",
+							GatewayAddress: "router.lan",
 						},
 					},
 				},
 			},
 		},
 		{
-			desc: "use duplicate match expression values in node selector",
+			desc: "upnp advertisement with invalid protocol",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
@@ -3290,74 +4635,84 @@ func TestParse(t *testing.T) {
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
+							Name: "upnpadv1",
 						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							IPAddressPools: []string{"pool1"},
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchExpressions: []metav1.LabelSelectorRequirement{
-										{
-											Key:      "kubernetes.io/hostname",
-											Operator: metav1.LabelSelectorOpIn,
-											Values:   []string{"first", "second"},
-										},
-										{
-											Key:      "kubernetes.io/hostname",
-											Operator: metav1.LabelSelectorOpIn,
-											Values:   []string{"second", "first"},
-										},
-									},
-								},
-							},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							Protocol: "SSDP",
 						},
 					},
 				},
-				L2Advs: []v1beta1.L2Advertisement{
+			},
+		},
+		{
+			desc: "upnp advertisement with gateway selector",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "l2adv1",
+							Name: "pool1",
 						},
-						Spec: v1beta1.L2AdvertisementSpec{
-							NodeSelectors: []metav1.LabelSelector{
-								{
-									MatchExpressions: []metav1.LabelSelectorRequirement{
-										{
-											Key:      "kubernetes.io/hostname",
-											Operator: metav1.LabelSelectorOpIn,
-											Values:   []string{"first"},
-										},
-									},
-								},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
 							},
-							IPAddressPools: []string{"pool1"},
 						},
 					},
 				},
-				Nodes: []corev1.Node{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "first",
-							Labels: map[string]string{
-								"first": "true",
+							Name: "upnpadv1",
+						},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
+							GatewaySelector: &v1beta1.UPnPGatewaySelector{
+								Location:   "http://192.168.1.1:5000/rootDesc.xml",
+								ExternalIP: "203.0.113.7",
 							},
+							AllGateways: true,
 						},
-					}, {
+					},
+				},
+				Nodes: []corev1.Node{
+					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "second",
-							Labels: map[string]string{
-								"second": "true",
-							},
+							Name: "first",
 						},
 					},
 				},
 			},
+			want: &Config{
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign: true,
+						UPnPAdvertisements: []*UPnPAdvertisement{{
+							Name: "upnpadv1",
+							Nodes: map[string]bool{
+								"first": true,
+							},
+							Enabled:     true,
+							Description: "MetalLB LoadBalancer",
+							Protocol:    "UPnP",
+							GatewaySelector: UPnPGatewaySelector{
+								Location:   "http://192.168.1.1:5000/rootDesc.xml",
+								ExternalIP: "203.0.113.7",
+							},
+							AllGateways: true,
+						}},
+					},
+				}},
+				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
+			},
 		},
 		{
-			desc: "no nodes means all nodes",
+			desc: "pcp upnp advertisement with gateway selector",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
@@ -3371,71 +4726,82 @@ func TestParse(t *testing.T) {
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
+							Name: "upnpadv1",
+						},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							Protocol:       "PCP",
+							GatewayAddress: "192.168.1.1",
+							GatewaySelector: &v1beta1.UPnPGatewaySelector{
+								UDN: "uuid:11111111-2222-3333-4444-555555555555",
+							},
 						},
 					},
 				},
-				L2Advs: []v1beta1.L2Advertisement{
+			},
+		},
+		{
+			desc: "upnp advertisement with invalid gateway selector external ip",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "l2adv1",
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
 						},
 					},
 				},
-				Nodes: []corev1.Node{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "first",
-							Labels: map[string]string{
-								"first": "true",
-							},
+							Name: "upnpadv1",
 						},
-					}, {
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "second",
-							Labels: map[string]string{
-								"second": "true",
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							GatewaySelector: &v1beta1.UPnPGatewaySelector{
+								ExternalIP: "203.0.113",
 							},
 						},
 					},
 				},
 			},
-			want: &Config{
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
-						AutoAssign: true,
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv1",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes: map[string]bool{
-									"first":  true,
-									"second": true,
-								},
+		},
+		{
+			desc: "upnp advertisement with invalid gateway selector location",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
 							},
 						},
-						L2Advertisements: []*L2Advertisement{{
-							Nodes: map[string]bool{
-								"first":  true,
-								"second": true,
+					},
+				},
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "upnpadv1",
+						},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							GatewaySelector: &v1beta1.UPnPGatewaySelector{
+								Location: "192.168.1.1/rootDesc.xml",
 							},
-							AllInterfaces: true,
-						}},
+						},
 					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
-				Peers:       map[string]*Peer{},
+				},
 			},
 		},
 		{
-			desc: "advertisement with peer selector",
+			desc: "upnp advertisement with negative duration",
 			crs: ClusterResources{
 				Pools: []v1beta1.IPAddressPool{
 					{
@@ -3444,226 +4810,231 @@ func TestParse(t *testing.T) {
 						},
 						Spec: v1beta1.IPAddressPoolSpec{
 							Addresses: []string{
-								"1.2.3.0/24",
+								"10.20.0.0/16",
 							},
 						},
 					},
 				},
-				Peers: []v1beta2.BGPPeer{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "upnpadv1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:   42,
-							ASN:     42,
-							Address: "1.2.3.4",
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							Duration: -1,
 						},
 					},
 				},
-				BGPAdvs: []v1beta1.BGPAdvertisement{
+			},
+		},
+		{
+			desc: "upnp advertisement with invalid pool name",
+			crs: ClusterResources{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "adv1",
+							Name: "pool1",
 						},
-						Spec: v1beta1.BGPAdvertisementSpec{
-							AggregationLength: ptr.To[int32](32),
-							LocalPref:         uint32(100),
-							IPAddressPools:    []string{"pool1"},
-							Peers:             []string{"peer1"},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
 						},
 					},
 				},
-			},
-			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						MyASN:         42,
-						ASN:           42,
-						Addr:          net.ParseIP("1.2.3.4"),
-						NodeSelectors: []labels.Selector{labels.Everything()},
-						EBGPMultiHop:  false,
-					},
-				},
-				Pools: &Pools{ByName: map[string]*Pool{
-					"pool1": {
-						Name:       "pool1",
-						AutoAssign: true,
-						CIDR:       []*net.IPNet{ipnet("1.2.3.0/24")},
-						BGPAdvertisements: []*BGPAdvertisement{
-							{
-								Name:                "adv1",
-								AggregationLength:   32,
-								AggregationLengthV6: 128,
-								LocalPref:           100,
-								Communities:         map[community.BGPCommunity]bool{},
-								Nodes:               map[string]bool{},
-								Peers:               []string{"peer1"},
-							},
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "upnpadv1",
+						},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							IPAddressPools: []string{"Pool_1"},
 						},
 					},
-				}},
-				BFDProfiles: map[string]*BFDProfile{},
+				},
 			},
 		},
 		{
-			desc: "peer with dynamic asn",
+			desc: "upnp advertisement with duplicate pools",
 			crs: ClusterResources{
-
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "pool1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							DynamicASN: v1beta2.InternalASNMode,
-							Address:    "1.2.3.4",
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
 						},
 					},
 				},
-			},
-			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						MyASN:         42,
-						DynamicASN:    "internal",
-						Addr:          net.ParseIP("1.2.3.4"),
-						NodeSelectors: []labels.Selector{labels.Everything()},
-						EBGPMultiHop:  false,
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "upnpadv1",
+						},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							IPAddressPools: []string{"pool1", "pool1"},
+						},
 					},
 				},
-				Pools:       &Pools{ByName: map[string]*Pool{}},
-				BFDProfiles: map[string]*BFDProfile{},
 			},
 		},
 		{
-			desc: "peer without asn or dynamic asn",
+			desc: "overlapping upnp advertisements with different settings",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							ASN:        0,
-							DynamicASN: "",
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "pool1",
+						},
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "peer with both asn and dynamic asn",
-			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							ASN:        42,
-							DynamicASN: v1beta2.InternalASNMode,
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "upnpadv1",
+						},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"first": "true",
+									},
+								},
+							},
+							Duration: 3600,
 						},
 					},
-				},
-			},
-		},
-		{
-			desc: "unnumbered peer without address with interface ok",
-			crs: ClusterResources{
-
-				Peers: []v1beta2.BGPPeer{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "upnpadv2",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							DynamicASN: v1beta2.InternalASNMode,
-							Interface:  "net0",
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							IPAddressPools: []string{"pool1"},
 						},
 					},
 				},
-			},
-			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:          "peer1",
-						Iface:         "net0",
-						MyASN:         42,
-						DynamicASN:    "internal",
-						NodeSelectors: []labels.Selector{labels.Everything()},
-						EBGPMultiHop:  false,
+				Nodes: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "first",
+							Labels: map[string]string{
+								"first": "true",
+							},
+						},
+					}, {
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "second",
+							Labels: map[string]string{
+								"second": "true",
+							},
+						},
 					},
 				},
-				Pools:       &Pools{ByName: map[string]*Pool{}},
-				BFDProfiles: map[string]*BFDProfile{},
 			},
 		},
 		{
-			desc: "unnumbered peer without address without interface nok",
+			desc: "upnp advertisements with different settings on different nodes",
 			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Pools: []v1beta1.IPAddressPool{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "invalid",
+							Name: "pool1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:      42,
-							DynamicASN: "internal",
+						Spec: v1beta1.IPAddressPoolSpec{
+							Addresses: []string{
+								"10.20.0.0/16",
+							},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "unnumbered peer with address with interface nok",
-			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				UPnPAdvs: []v1beta1.UPnPAdvertisement{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "invalid",
+							Name: "upnpadv1",
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							Address:    "1.2.3.4",
-							Interface:  "net0",
-							MyASN:      42,
-							DynamicASN: "internal",
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"first": "true",
+									},
+								},
+							},
+							Duration: 3600,
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "upnpadv2",
+						},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							NodeSelectors: []metav1.LabelSelector{
+								{
+									MatchLabels: map[string]string{
+										"second": "true",
+									},
+								},
+							},
 						},
 					},
 				},
-			},
-		},
-		{
-			desc: "peer with DisableMP field",
-			crs: ClusterResources{
-				Peers: []v1beta2.BGPPeer{
+				Nodes: []corev1.Node{
 					{
 						ObjectMeta: metav1.ObjectMeta{
-							Name: "peer1",
+							Name: "first",
+							Labels: map[string]string{
+								"first": "true",
+							},
 						},
-						Spec: v1beta2.BGPPeerSpec{
-							MyASN:     42,
-							ASN:       142,
-							Address:   "1.2.3.4",
-							DisableMP: true,
+					}, {
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "second",
+							Labels: map[string]string{
+								"second": "true",
+							},
 						},
 					},
 				},
 			},
 			want: &Config{
-				Peers: map[string]*Peer{
-					"peer1": {
-						Name:                   "peer1",
-						MyASN:                  42,
-						ASN:                    142,
-						Addr:                   net.ParseIP("1.2.3.4"),
-						NodeSelectors:          []labels.Selector{labels.Everything()},
-						DisableMP:              true,
-						DualStackAddressFamily: false,
+				Pools: &Pools{ByName: map[string]*Pool{
+					"pool1": {
+						Name:       "pool1",
+						CIDR:       []*net.IPNet{ipnet("10.20.0.0/16")},
+						AutoAssign: true,
+						UPnPAdvertisements: []*UPnPAdvertisement{
+							{
+								Name: "upnpadv1",
+								Nodes: map[string]bool{
+									"first": true,
+								},
+								Enabled:     true,
+								Duration:    3600,
+								Description: "MetalLB LoadBalancer",
+								Protocol:    "UPnP",
+							},
+							{
+								Name: "upnpadv2",
+								Nodes: map[string]bool{
+									"second": true,
+								},
+								Enabled:     true,
+								Description: "MetalLB LoadBalancer",
+								Protocol:    "UPnP",
+							},
+						},
 					},
-				},
-				Pools:       &Pools{ByName: map[string]*Pool{}},
+				}},
 				BFDProfiles: map[string]*BFDProfile{},
+				Peers:       map[string]*Peer{},
 			},
 		},
 	}
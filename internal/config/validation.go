@@ -5,13 +5,12 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
+	"strings"
 
-	metallbv1beta1 "go.universe.tf/metallb/api/v1beta1"
 	metallbv1beta2 "go.universe.tf/metallb/api/v1beta2"
-	"go.universe.tf/metallb/internal/bgp/community"
 	"go.universe.tf/metallb/internal/ipfamily"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type Validate func(ClusterResources) error
@@ -21,7 +20,7 @@ func ValidationFor(bgpImpl string) Validate {
 	case "frr":
 		return DiscardNativeOnly
 	case "frr-k8s":
-		return DiscardNativeOnly
+		return DiscardNotInFRRK8s
 	case "native":
 		return DiscardFRROnly
 	}
@@ -35,18 +34,9 @@ func DiscardFRROnly(c ClusterResources) error {
 		if p.Spec.BFDProfile != "" {
 			return fmt.Errorf("peer %s has bfd-profile set on native bgp mode", p.Spec.Address)
 		}
-		if p.Spec.KeepaliveTime != nil && p.Spec.KeepaliveTime.Duration != 0 {
-			return fmt.Errorf("peer %s has keepalive-time set on native bgp mode", p.Spec.Address)
-		}
 		if p.Spec.VRFName != "" {
 			return fmt.Errorf("peer %s has vrf set on native bgp mode", p.Spec.Address)
 		}
-		if p.Spec.ConnectTime != nil {
-			return fmt.Errorf("peer %s has connect time set on native bgp mode", p.Spec.Address)
-		}
-		if p.Spec.EnableGracefulRestart {
-			return fmt.Errorf("peer %s has EnableGracefulRestart flag set on native bgp mode", p.Spec.Address)
-		}
 		if p.Spec.DisableMP {
 			return fmt.Errorf("peer %s has disable MP flag set on native bgp mode", p.Spec.Address)
 		}
@@ -63,76 +53,6 @@ func DiscardFRROnly(c ClusterResources) error {
 	if len(c.BFDProfiles) > 0 {
 		return errors.New("bfd profiles section set")
 	}
-	// Only IPv4 BGP advertisements are supported in native mode.
-	if err := findIPv6BGPAdvertisement(c); err != nil {
-		return err
-	}
-	// Only legacy type communities are supported in native mode.
-	return findNonLegacyCommunity(c)
-}
-
-// findIPv6BGPAdvertisement checks for IPv6 addresses. If it finds at least one IPv6 BGP advertisement, it will throw
-// and error as it's not supported in native mode.
-func findIPv6BGPAdvertisement(c ClusterResources) error {
-	if len(c.BGPAdvs) == 0 {
-		return nil
-	}
-
-	bgpSelectors, err := poolSelectorsForBGP(c)
-	if err != nil {
-		return err
-	}
-
-	for _, p := range c.Pools {
-		if !bgpSelectors.matchesPool(p) {
-			continue
-		}
-
-		for _, cidr := range p.Spec.Addresses {
-			nets, err := ParseCIDR(cidr)
-			if err != nil {
-				return fmt.Errorf("invalid CIDR %q in pool %q: %s", cidr, p.Name, err)
-			}
-			for _, n := range nets {
-				if n.IP.To4() == nil {
-					return fmt.Errorf("pool %q has ipv6 CIDR %s, native bgp mode does not support ipv6", p.Name, n)
-				}
-			}
-		}
-	}
-	return nil
-}
-
-// findNonLegacyCommunity returns an error if it can find a non legacy community. If a community string can not be
-// parsed, the string will be ignored.
-func findNonLegacyCommunity(c ClusterResources) error {
-	for _, adv := range c.BGPAdvs {
-		for _, cs := range adv.Spec.Communities {
-			c, err := community.New(cs)
-			if err != nil {
-				// Skip aliases.
-				continue
-			}
-			if !community.IsLegacy(c) {
-				return fmt.Errorf("native BGP mode only supports legacy communities, BGP advertisement %q "+
-					"has non legacy community %q", adv.Name, cs)
-			}
-		}
-	}
-	for _, co := range c.Communities {
-		for _, cs := range co.Spec.Communities {
-			c, err := community.New(cs.Value)
-			if err != nil {
-				// Skip it if we cannot parse it - the purpose of this very verification is not to make sure that
-				// a string can be parsed or not.
-				continue
-			}
-			if !community.IsLegacy(c) {
-				return fmt.Errorf("native BGP mode only supports legacy communities, BGP community CR %q "+
-					"has non legacy community %q", co.Name, cs)
-			}
-		}
-	}
 	return nil
 }
 
@@ -145,6 +65,14 @@ func DontValidate(c ClusterResources) error {
 // DiscardNativeOnly returns an error if the current configFile contains
 // any options that are available only in the native implementation.
 func DiscardNativeOnly(c ClusterResources) error {
+	for _, p := range c.Peers {
+		if p.Spec.IPv6NextHop != "" {
+			return fmt.Errorf("peer %s has ipv6NextHop set on frr bgp mode", p.Spec.Address)
+		}
+		if p.Spec.GracefulRestartTime != nil {
+			return fmt.Errorf("peer %s has gracefulRestartTime set on frr bgp mode", p.Spec.Address)
+		}
+	}
 	if len(c.Peers) > 1 {
 		peerAddr := make(map[string]bool)
 		routerID := c.Peers[0].Spec.RouterID
@@ -172,9 +100,37 @@ func DiscardNativeOnly(c ClusterResources) error {
 	return nil
 }
 
+// DiscardNotInFRRK8s returns an error if the current configFile contains
+// any options that are available only in the native implementation, or
+// that can't be expressed in the frr-k8s configuration.
+func DiscardNotInFRRK8s(c ClusterResources) error {
+	if err := DiscardNativeOnly(c); err != nil {
+		return err
+	}
+	for _, adv := range c.BGPAdvs {
+		if adv.Spec.MED != nil {
+			return fmt.Errorf("bgpadvertisement %s has med set on frr-k8s bgp mode", adv.Name)
+		}
+		if adv.Spec.ASPathPrepend != 0 {
+			return fmt.Errorf("bgpadvertisement %s has asPathPrepend set on frr-k8s bgp mode", adv.Name)
+		}
+	}
+	return nil
+}
+
 // validateConfig is meant to validate all the inter-dependencies of a parsed configuration.
-// In this case, we ensure that bfd echo is not enabled on a v6 pool.
+// In this case, we ensure that bfd echo is not enabled on a v6 pool, that
+// the upnp advertisements of a pool do not conflict with each other and
+// that no address is both reserved and excluded.
 func validateConfig(cfg *Config) error {
+	for _, p := range cfg.Pools.ByName {
+		if err := validateUPnPAdvertisementsOverlap(p); err != nil {
+			return err
+		}
+		if err := validateExcludedNotReserved(p); err != nil {
+			return err
+		}
+	}
 	for _, p := range cfg.Pools.ByName {
 		containsV6 := false
 		for _, cidr := range p.CIDR {
@@ -207,6 +163,51 @@ func validateConfig(cfg *Config) error {
 	return nil
 }
 
+// validateUPnPAdvertisementsOverlap returns an error if two upnp advertisements
+// applied to the same pool can be used from the same node with different
+// mapping settings, as the speaker has no way to choose between them.
+func validateUPnPAdvertisementsOverlap(p *Pool) error {
+	for i, a := range p.UPnPAdvertisements {
+		for _, b := range p.UPnPAdvertisements[i+1:] {
+			if a.Duration == b.Duration && a.Description == b.Description &&
+				a.Protocol == b.Protocol && a.GatewayAddress == b.GatewayAddress &&
+				a.GatewaySelector == b.GatewaySelector && a.AllGateways == b.AllGateways {
+				continue
+			}
+			for node := range a.Nodes {
+				if b.Nodes[node] {
+					return fmt.Errorf("pool %s has upnpadvertisements %s and %s overlapping on node %s with different duration, description or gateway", p.Name, a.Name, b.Name, node)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateExcludedNotReserved returns an error if an excluded address of
+// the pool is reserved for a service.
+func validateExcludedNotReserved(p *Pool) error {
+	for ip, r := range p.Reservations {
+		for _, cidr := range p.ExcludedCIDR {
+			if cidr.Contains(net.ParseIP(ip)) {
+				return fmt.Errorf("ipaddressreservation %s reserves %s, which is excluded from pool %s", r.Name, ip, p.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePoolNames returns an error if any of the given names can not be
+// the name of an IPAddressPool.
+func validatePoolNames(names []string) error {
+	for _, n := range names {
+		if errs := validation.IsDNS1123Subdomain(n); len(errs) > 0 {
+			return fmt.Errorf("invalid ipAddressPool name %q: %s", n, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
 func hasBFDEcho(peer *Peer, bfdProfiles map[string]*BFDProfile) bool {
 	profile, ok := bfdProfiles[peer.BFDProfile]
 	if !ok {
@@ -225,49 +226,3 @@ func peerIdentifier(peer metallbv1beta2.BGPPeerSpec) string {
 	}
 	return fmt.Sprintf("%s-%s", id, peer.VRFName)
 }
-
-type poolSelector struct {
-	byName   map[string]struct{}
-	byLabels []labels.Selector
-}
-
-func (s poolSelector) matchesPool(p metallbv1beta1.IPAddressPool) bool {
-	if len(s.byLabels) == 0 && len(s.byName) == 0 {
-		return true
-	}
-
-	if _, ok := s.byName[p.Name]; ok {
-		return true
-	}
-	for _, l := range s.byLabels {
-		if l.Matches(labels.Set(p.Labels)) {
-			return true
-		}
-	}
-	return false
-}
-
-func poolSelectorsForBGP(c ClusterResources) (poolSelector, error) {
-	selectedPools := make(map[string]struct{})
-	poolsSelectors := []labels.Selector{}
-	for _, adv := range c.BGPAdvs {
-		if len(adv.Spec.IPAddressPools) == 0 &&
-			len(adv.Spec.IPAddressPoolSelectors) == 0 {
-			return poolSelector{}, nil // no selectors, let's catch em all!
-		}
-		for _, p := range adv.Spec.IPAddressPools {
-			selectedPools[p] = struct{}{}
-		}
-		for _, selector := range adv.Spec.IPAddressPoolSelectors {
-			l, err := metav1.LabelSelectorAsSelector(&selector)
-			if err != nil {
-				return poolSelector{}, fmt.Errorf("invalid label selector %v", selector)
-			}
-			poolsSelectors = append(poolsSelectors, l)
-		}
-	}
-	return poolSelector{
-		byName:   selectedPools,
-		byLabels: poolsSelectors,
-	}, nil
-}
@@ -68,7 +68,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "v6 address but pool not selected",
@@ -127,7 +126,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "v6 address and selected by labels",
@@ -166,7 +164,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "enable BGP GracefulRestart",
@@ -179,7 +176,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "disable BGP MP",
@@ -231,7 +227,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "connect time",
@@ -244,7 +239,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "large BGP community inside BGP Advertisement",
@@ -257,7 +251,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "large BGP community inside Community CR",
@@ -274,7 +267,6 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			mustFail: true,
 		},
 		{
 			desc: "should pass",
@@ -373,6 +365,35 @@ func TestValidateFRR(t *testing.T) {
 			},
 			mustFail: true,
 		},
+		{
+			desc: "graceful restart time set",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address:               "1.2.3.4",
+							EnableGracefulRestart: true,
+							GracefulRestartTime:   &v1.Duration{Duration: 60 * time.Second},
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "ipv6 next hop set",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							Address:     "1.2.3.4",
+							IPv6NextHop: "2001:db8::1",
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
 		{
 			desc: "bfd profile set",
 			config: ClusterResources{
@@ -615,3 +636,75 @@ func TestValidateFRR(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateFRRK8s(t *testing.T) {
+	tests := []struct {
+		desc     string
+		config   ClusterResources
+		mustFail bool
+	}{
+		{
+			desc: "med set",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							MED: ptr.To(uint32(10)),
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "as path prepend set",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							ASPathPrepend: 2,
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "native only field set",
+			config: ClusterResources{
+				Peers: []v1beta2.BGPPeer{
+					{
+						Spec: v1beta2.BGPPeerSpec{
+							IPv6NextHop: "2001:db8::1",
+						},
+					},
+				},
+			},
+			mustFail: true,
+		},
+		{
+			desc: "should pass",
+			config: ClusterResources{
+				BGPAdvs: []v1beta1.BGPAdvertisement{
+					{
+						Spec: v1beta1.BGPAdvertisementSpec{
+							LocalPref: 100,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := DiscardNotInFRRK8s(test.config)
+			if test.mustFail && err == nil {
+				t.Fatalf("Expected error for %s", test.desc)
+			}
+			if !test.mustFail && err != nil {
+				t.Fatalf("Not expected error %s for %s", err, test.desc)
+			}
+		})
+	}
+}
@@ -30,12 +30,14 @@ type validator struct {
 
 func (v *validator) Validate(resources ...client.ObjectList) error {
 	clusterResources := ClusterResources{
-		Pools:       make([]metallbv1beta1.IPAddressPool, 0),
-		Peers:       make([]metallbv1beta2.BGPPeer, 0),
-		BFDProfiles: make([]metallbv1beta1.BFDProfile, 0),
-		BGPAdvs:     make([]metallbv1beta1.BGPAdvertisement, 0),
-		L2Advs:      make([]metallbv1beta1.L2Advertisement, 0),
-		Communities: make([]metallbv1beta1.Community, 0),
+		Pools:        make([]metallbv1beta1.IPAddressPool, 0),
+		Peers:        make([]metallbv1beta2.BGPPeer, 0),
+		BFDProfiles:  make([]metallbv1beta1.BFDProfile, 0),
+		BGPAdvs:      make([]metallbv1beta1.BGPAdvertisement, 0),
+		L2Advs:       make([]metallbv1beta1.L2Advertisement, 0),
+		UPnPAdvs:     make([]metallbv1beta1.UPnPAdvertisement, 0),
+		Communities:  make([]metallbv1beta1.Community, 0),
+		Reservations: make([]metallbv1beta1.IPAddressReservation, 0),
 	}
 	for _, list := range resources {
 		switch list := list.(type) {
@@ -49,8 +51,12 @@ func (v *validator) Validate(resources ...client.ObjectList) error {
 			clusterResources.BGPAdvs = append(clusterResources.BGPAdvs, list.Items...)
 		case *metallbv1beta1.L2AdvertisementList:
 			clusterResources.L2Advs = append(clusterResources.L2Advs, list.Items...)
+		case *metallbv1beta1.UPnPAdvertisementList:
+			clusterResources.UPnPAdvs = append(clusterResources.UPnPAdvs, list.Items...)
 		case *metallbv1beta1.CommunityList:
 			clusterResources.Communities = append(clusterResources.Communities, list.Items...)
+		case *metallbv1beta1.IPAddressReservationList:
+			clusterResources.Reservations = append(clusterResources.Reservations, list.Items...)
 		case *v1.NodeList:
 			clusterResources.Nodes = append(clusterResources.Nodes, list.Items...)
 		}
@@ -9,6 +9,7 @@ import (
 	metallbv1beta1 "go.universe.tf/metallb/api/v1beta1"
 	metallbv1beta2 "go.universe.tf/metallb/api/v1beta2"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestValidator(t *testing.T) {
@@ -32,6 +33,42 @@ func TestValidator(t *testing.T) {
 	}
 }
 
+func TestValidatorReservations(t *testing.T) {
+	v := validator{DontValidate}
+
+	pools := metallbv1beta1.IPAddressPoolList{
+		Items: []metallbv1beta1.IPAddressPool{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool"},
+				Spec: metallbv1beta1.IPAddressPoolSpec{
+					Addresses: []string{"10.20.0.0/24"},
+				},
+			},
+		},
+	}
+	reservation := func(address string) *metallbv1beta1.IPAddressReservationList {
+		return &metallbv1beta1.IPAddressReservationList{
+			Items: []metallbv1beta1.IPAddressReservation{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "reservation"},
+					Spec: metallbv1beta1.IPAddressReservationSpec{
+						IPAddressPool: "pool",
+						Addresses:     []string{address},
+						ServiceRef:    &metallbv1beta1.ServiceReference{Namespace: "default", Name: "svc"},
+					},
+				},
+			},
+		}
+	}
+
+	if err := v.Validate(&pools, reservation("10.20.0.1")); err != nil {
+		t.Errorf("The validator should accept a reservation of an address of the pool: %s", err)
+	}
+	if err := v.Validate(&pools, reservation("10.30.0.1")); err == nil {
+		t.Error("The validator should reject a reservation of an address outside of the pool")
+	}
+}
+
 func TestResetTransientErrorsFields(t *testing.T) {
 	tests := []struct {
 		desc             string
@@ -0,0 +1,322 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	frrv1beta1 "github.com/metallb/frr-k8s/api/v1beta1"
+	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/api/v1beta2"
+	"go.universe.tf/metallb/internal/bgp"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	bgpSessionIndexName = "status.bgpPeer"
+	// bgpSessionResyncPeriod is how often the state of the sessions is read
+	// again, as only the native implementation notifies when a session goes
+	// up or down, and none notifies the other changes of its state.
+	bgpSessionResyncPeriod = 30 * time.Second
+
+	frrK8sNodeLabel = "frrk8s.metallb.io/node"
+	frrK8sPeerLabel = "frrk8s.metallb.io/peer"
+	frrK8sVRFLabel  = "frrk8s.metallb.io/vrf"
+)
+
+// BGPSessionStatusFetcher returns the status of the session of this node with
+// the given BGPPeer, and false if there is no such session.
+type BGPSessionStatusFetcher func(peer string) (bgp.SessionStatus, bool)
+
+type bgpSessionStatusEvent struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+}
+
+func (evt *bgpSessionStatusEvent) DeepCopyObject() runtime.Object {
+	res := new(bgpSessionStatusEvent)
+	res.Name = evt.Name
+	res.Namespace = evt.Namespace
+	return res
+}
+
+func NewBGPSessionStatusEvent(namespace, name string) event.GenericEvent {
+	evt := bgpSessionStatusEvent{}
+	evt.Name = name
+	evt.Namespace = namespace
+	return event.GenericEvent{Object: &evt}
+}
+
+// BGPSessionStatusReconciler keeps one BGPSessionStatus per BGPPeer the node
+// has a session with.
+type BGPSessionStatusReconciler struct {
+	client.Client
+	Logger        log.Logger
+	NodeName      string
+	Namespace     string
+	SpeakerPod    *v1.Pod
+	ReconcileChan <-chan event.GenericEvent
+	StatusFetcher BGPSessionStatusFetcher
+	// FRRK8sNamespace is set when the sessions are run by frr-k8s, their
+	// state is then read from the BGPSessionStates of frr-k8s.
+	FRRK8sNamespace string
+}
+
+func (r *BGPSessionStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	level.Info(r.Logger).Log("controller", "BGPSessionStatus", "start reconcile", req.String())
+	defer level.Info(r.Logger).Log("controller", "BGPSessionStatus", "end reconcile", req.String())
+
+	peerName := req.Name
+
+	var sessionStatuses v1beta1.BGPSessionStatusList
+	err := r.List(ctx, &sessionStatuses, client.MatchingFields{
+		bgpSessionIndexName: bgpSessionIndexFor(peerName, r.NodeName),
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	sessionStatus, ok := r.StatusFetcher(peerName)
+	if !ok {
+		errs := []error{}
+		for i := range sessionStatuses.Items {
+			if sessionStatuses.Items[i].Labels[LabelAnnounceNode] != r.NodeName { // shouldn't happen because of the indexing, just in case
+				continue
+			}
+			if err := r.Delete(ctx, &sessionStatuses.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, err)
+			}
+		}
+
+		return ctrl.Result{}, utilerrors.NewAggregate(errs)
+	}
+
+	deleteRedundantErrs := []error{}
+	if len(sessionStatuses.Items) > 1 {
+		// We shouldn't get here, just in case the controller created redundant resources
+		for i := range sessionStatuses.Items[1:] {
+			if sessionStatuses.Items[i+1].Labels[LabelAnnounceNode] != r.NodeName {
+				continue
+			}
+			if err := r.Delete(ctx, &sessionStatuses.Items[i+1]); err != nil && !apierrors.IsNotFound(err) {
+				deleteRedundantErrs = append(deleteRedundantErrs, err)
+			}
+		}
+	}
+
+	if len(deleteRedundantErrs) > 0 {
+		return ctrl.Result{}, utilerrors.NewAggregate(deleteRedundantErrs)
+	}
+
+	if r.FRRK8sNamespace != "" {
+		sessionStatus, err = r.frrK8sSessionStatus(ctx, peerName)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	var state = &v1beta1.BGPSessionStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "bgp-session-",
+			Namespace:    r.Namespace,
+		},
+	}
+
+	if len(sessionStatuses.Items) > 0 {
+		state = &sessionStatuses.Items[0]
+	}
+
+	desiredStatus := v1beta1.MetalLBBGPSessionStatus{
+		Node:             r.NodeName,
+		Peer:             peerName,
+		State:            v1beta1.BGPSessionDown,
+		LastError:        sessionStatus.LastError,
+		PrefixesSent:     int64(sessionStatus.SentPrefixes),
+		PrefixesReceived: int64(sessionStatus.ReceivedPrefixes),
+	}
+	if sessionStatus.Established {
+		desiredStatus.State = v1beta1.BGPSessionEstablished
+		if !sessionStatus.UpSince.IsZero() {
+			desiredStatus.EstablishedTime = &metav1.Time{Time: sessionStatus.UpSince.Truncate(time.Second)}
+		}
+	}
+	// FRR reports the uptime of the sessions, not when they were established:
+	// keep the time already reported unless the session went down meanwhile.
+	if desiredStatus.EstablishedTime != nil && state.Status.EstablishedTime != nil &&
+		desiredStatus.EstablishedTime.Sub(state.Status.EstablishedTime.Time).Abs() < bgpSessionResyncPeriod {
+		desiredStatus.EstablishedTime = state.Status.EstablishedTime
+	}
+
+	if reflect.DeepEqual(state.Status, desiredStatus) {
+		return ctrl.Result{RequeueAfter: bgpSessionResyncPeriod}, nil
+	}
+
+	var result controllerutil.OperationResult
+	result, err = controllerutil.CreateOrPatch(ctx, r.Client, state, func() error {
+		state.Labels = map[string]string{
+			LabelAnnounceNode: r.NodeName,
+			LabelBGPPeer:      peerName,
+		}
+		state.Status = desiredStatus
+		err = controllerutil.SetOwnerReference(r.SpeakerPod, state, r.Scheme())
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result == controllerutil.OperationResultCreated {
+		// According to https://pkg.go.dev/sigs.k8s.io/controller-runtime/pkg/controller/controllerutil#CreateOrPatch
+		// If the object is created, we have to patch it again to ensure the status is created.
+		// This will happen when we reconcile the creation event.
+		level.Debug(r.Logger).Log("controller", "BGPSessionStatus", "created state", dumpResource(state))
+		return ctrl.Result{}, nil
+	}
+
+	level.Debug(r.Logger).Log("controller", "BGPSessionStatus", "updated state", dumpResource(state))
+	return ctrl.Result{RequeueAfter: bgpSessionResyncPeriod}, nil
+}
+
+// frrK8sSessionStatus returns the status of the session with the given peer
+// from the BGPSessionState frr-k8s exposes for it. frr-k8s reports only
+// whether the session is established.
+func (r *BGPSessionStatusReconciler) frrK8sSessionStatus(ctx context.Context, peerName string) (bgp.SessionStatus, error) {
+	var peer v1beta2.BGPPeer
+	err := r.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: peerName}, &peer)
+	if apierrors.IsNotFound(err) {
+		return bgp.SessionStatus{}, nil
+	}
+	if err != nil {
+		return bgp.SessionStatus{}, err
+	}
+
+	neighbor := peer.Spec.Address
+	if peer.Spec.Interface != "" {
+		neighbor = peer.Spec.Interface
+	}
+
+	var states frrv1beta1.BGPSessionStateList
+	err = r.List(ctx, &states, client.InNamespace(r.FRRK8sNamespace), client.MatchingLabels{
+		frrK8sNodeLabel: r.NodeName,
+		frrK8sPeerLabel: frrK8sPeerLabelFor(neighbor),
+		frrK8sVRFLabel:  peer.Spec.VRFName,
+	})
+	if err != nil {
+		return bgp.SessionStatus{}, err
+	}
+	if len(states.Items) == 0 {
+		return bgp.SessionStatus{}, nil
+	}
+	return bgp.SessionStatus{
+		Established: states.Items[0].Status.BGPStatus == string(v1beta1.BGPSessionEstablished),
+	}, nil
+}
+
+// frrK8sPeerLabelFor returns the value frr-k8s uses to label the state of
+// the sessions with the given neighbor, since a label can't contain ":".
+func frrK8sPeerLabelFor(neighbor string) string {
+	addr, err := netip.ParseAddr(neighbor)
+	if err != nil || addr.Is4() {
+		return neighbor
+	}
+	return strings.ReplaceAll(addr.StringExpanded(), ":", "-")
+}
+
+func (r *BGPSessionStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	p := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		_, ok := o.(*v1beta1.BGPSessionStatus)
+		if !ok {
+			return true
+		}
+
+		labels := o.GetLabels()
+
+		if labels == nil {
+			level.Error(r.Logger).Log("controller", "BGPSessionStatus", "object has no labels", o)
+			return false
+		}
+
+		if _, ok = labels[LabelBGPPeer]; !ok {
+			level.Error(r.Logger).Log("controller", "BGPSessionStatus", "object does not have the bgp peer label", o)
+			return false
+		}
+
+		var node string
+		if node, ok = labels[LabelAnnounceNode]; !ok {
+			level.Error(r.Logger).Log("controller", "BGPSessionStatus", "object does not have the node name label", o)
+			return false
+		}
+
+		if node != r.NodeName {
+			return false
+		}
+
+		return true
+	})
+
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta1.BGPSessionStatus{}, bgpSessionIndexName,
+		func(o client.Object) []string {
+			s, ok := o.(*v1beta1.BGPSessionStatus)
+			if s == nil {
+				level.Error(r.Logger).Log("controller", "fieldindexer", "error", "received nil BGPSessionStatus")
+				return nil
+			}
+
+			if !ok {
+				level.Error(r.Logger).Log("controller", "fieldindexer", "error", "received object that is not BGPSessionStatus", "object", o)
+				return nil
+			}
+
+			labels := s.GetLabels()
+			if labels == nil {
+				level.Error(r.Logger).Log("controller", "fieldindexer", "error", "received BGPSessionStatus without labels", "object", o)
+				return nil
+			}
+
+			return []string{bgpSessionIndexFor(labels[LabelBGPPeer], labels[LabelAnnounceNode])}
+		})
+
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("bgpsessionstatus").
+		Watches(&v1beta1.BGPSessionStatus{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, object client.Object) []reconcile.Request {
+				level.Debug(r.Logger).Log("controller", "BGPSessionStatus", "enqueueing", "object", object)
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Name:      object.GetLabels()[LabelBGPPeer],
+					Namespace: r.Namespace,
+				}}}
+			})).
+		WatchesRawSource(source.Channel(r.ReconcileChan, &handler.EnqueueRequestForObject{})).
+		WithEventFilter(p).
+		Complete(r)
+}
+
+func bgpSessionIndexFor(peer, node string) string {
+	return fmt.Sprintf("%s/%s", peer, node)
+}
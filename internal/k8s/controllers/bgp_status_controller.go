@@ -6,10 +6,14 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"slices"
+	"sort"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/api/v1beta2"
+	"go.universe.tf/metallb/internal/bgp"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -17,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -29,6 +34,10 @@ import (
 
 type PeersForService func(key string) sets.Set[string]
 
+// AdvertisementsForService returns the advertisements of the service sent to
+// each of the peers, by peer name.
+type AdvertisementsForService func(key string) map[string][]*bgp.Advertisement
+
 type bgpStatusEvent struct {
 	metav1.TypeMeta
 	metav1.ObjectMeta
@@ -56,6 +65,9 @@ type ServiceBGPStatusReconciler struct {
 	SpeakerPod    *v1.Pod
 	ReconcileChan <-chan event.GenericEvent
 	PeersFetcher  PeersForService
+	// AdvertisementsFetcher, when set, is used to report the prefixes sent
+	// to each of the peers.
+	AdvertisementsFetcher AdvertisementsForService
 }
 
 func (r *ServiceBGPStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -121,6 +133,10 @@ func (r *ServiceBGPStatusReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		ServiceNamespace: serviceNamespace,
 		Peers:            sets.List(peers),
 	}
+	desiredStatus.PeerStatuses, err = r.peerStatuses(ctx, req.String(), sets.List(peers))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
 	if reflect.DeepEqual(state.Status, desiredStatus) {
 		return ctrl.Result{}, nil
@@ -155,6 +171,80 @@ func (r *ServiceBGPStatusReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	return ctrl.Result{}, nil
 }
 
+// peerStatuses returns the state of the sessions of the node with the given
+// peers, along with the prefixes of the service sent to each of them.
+func (r *ServiceBGPStatusReconciler) peerStatuses(ctx context.Context, key string, peers []string) ([]v1beta1.ServiceBGPPeerStatus, error) {
+	var sessionStatuses v1beta1.BGPSessionStatusList
+	err := r.List(ctx, &sessionStatuses, client.InNamespace(r.Namespace), client.MatchingLabels{
+		LabelAnnounceNode: r.NodeName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sessionStates := map[string]v1beta1.BGPSessionState{}
+	for _, s := range sessionStatuses.Items {
+		sessionStates[s.Labels[LabelBGPPeer]] = s.Status.State
+	}
+
+	var ads map[string][]*bgp.Advertisement
+	if r.AdvertisementsFetcher != nil {
+		ads = r.AdvertisementsFetcher(key)
+	}
+
+	res := make([]v1beta1.ServiceBGPPeerStatus, 0, len(peers))
+	for _, peerName := range peers {
+		status := v1beta1.ServiceBGPPeerStatus{
+			Peer:         peerName,
+			SessionState: v1beta1.BGPSessionDown,
+		}
+		if state, ok := sessionStates[peerName]; ok {
+			status.SessionState = state
+		}
+
+		ibgp, err := r.isIBGP(ctx, peerName)
+		if err != nil {
+			return nil, err
+		}
+		for _, ad := range ads[peerName] {
+			prefix := v1beta1.ServiceBGPPrefix{
+				Prefix: ad.Prefix.String(),
+			}
+			if ibgp && ad.LocalPref != 0 {
+				prefix.LocalPref = ptr.To(ad.LocalPref)
+			}
+			if ad.MED != nil {
+				prefix.MED = ptr.To(*ad.MED)
+			}
+			for _, c := range ad.Communities {
+				prefix.Communities = append(prefix.Communities, c.String())
+			}
+			status.Prefixes = append(status.Prefixes, prefix)
+		}
+		sort.SliceStable(status.Prefixes, func(i, j int) bool {
+			return status.Prefixes[i].Prefix < status.Prefixes[j].Prefix
+		})
+		res = append(res, status)
+	}
+	return res, nil
+}
+
+// isIBGP tells if the session with the given peer is an iBGP one, the only
+// ones the local preference is sent to.
+func (r *ServiceBGPStatusReconciler) isIBGP(ctx context.Context, peerName string) (bool, error) {
+	var peer v1beta2.BGPPeer
+	err := r.Get(ctx, types.NamespacedName{Name: peerName, Namespace: r.Namespace}, &peer)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if peer.Spec.DynamicASN != "" {
+		return peer.Spec.DynamicASN == v1beta2.InternalASNMode, nil
+	}
+	return peer.Spec.ASN == peer.Spec.MyASN, nil
+}
+
 func (r *ServiceBGPStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	p := predicate.NewPredicateFuncs(func(o client.Object) bool {
 		_, ok := o.(*v1beta1.ServiceBGPStatus)
@@ -229,11 +319,43 @@ func (r *ServiceBGPStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					Namespace: labels[LabelServiceNamespace],
 				}}}
 			})).
+		Watches(&v1beta1.BGPSessionStatus{}, handler.EnqueueRequestsFromMapFunc(r.servicesForSession)).
 		WatchesRawSource(source.Channel(r.ReconcileChan, &handler.EnqueueRequestForObject{})).
 		WithEventFilter(p).
 		Complete(r)
 }
 
+// servicesForSession returns the services of the node advertised to the peer
+// of the given BGPSessionStatus, whose status depends on the session state.
+func (r *ServiceBGPStatusReconciler) servicesForSession(ctx context.Context, object client.Object) []reconcile.Request {
+	labels := object.GetLabels()
+	peer, ok := labels[LabelBGPPeer]
+	if !ok || labels[LabelAnnounceNode] != r.NodeName {
+		return nil
+	}
+
+	var serviceBGPStatuses v1beta1.ServiceBGPStatusList
+	err := r.List(ctx, &serviceBGPStatuses, client.InNamespace(r.Namespace), client.MatchingLabels{
+		LabelAnnounceNode: r.NodeName,
+	})
+	if err != nil {
+		level.Error(r.Logger).Log("controller", "ServiceBGPStatus", "error", "failed to list the service statuses", "peer", peer, "err", err)
+		return nil
+	}
+
+	res := []reconcile.Request{}
+	for _, s := range serviceBGPStatuses.Items {
+		if !slices.Contains(s.Status.Peers, peer) {
+			continue
+		}
+		res = append(res, reconcile.Request{NamespacedName: types.NamespacedName{
+			Name:      s.Status.ServiceName,
+			Namespace: s.Status.ServiceNamespace,
+		}})
+	}
+	return res
+}
+
 func indexFor(svcNs, svcName, node string) string {
 	return fmt.Sprintf("%s/%s-%s", svcNs, svcName, node)
 }
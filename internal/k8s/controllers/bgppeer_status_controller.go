@@ -0,0 +1,100 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/api/v1beta2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// BGPPeerStatusReconciler summarizes in the status of the BGPPeers the
+// BGPSessionStatuses reported by the speakers.
+type BGPPeerStatusReconciler struct {
+	client.Client
+	Logger    log.Logger
+	Namespace string
+}
+
+func (r *BGPPeerStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	level.Info(r.Logger).Log("controller", "BGPPeerStatusReconciler", "start reconcile", req.String())
+	defer level.Info(r.Logger).Log("controller", "BGPPeerStatusReconciler", "end reconcile", req.String())
+
+	var peer v1beta2.BGPPeer
+	err := r.Get(ctx, req.NamespacedName, &peer)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var sessionStatuses v1beta1.BGPSessionStatusList
+	err = r.List(ctx, &sessionStatuses, client.InNamespace(r.Namespace), client.MatchingLabels{
+		LabelBGPPeer: peer.Name,
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	newStatus := v1beta2.BGPPeerStatus{}
+	for _, s := range sessionStatuses.Items {
+		if s.Status.State == v1beta1.BGPSessionEstablished {
+			newStatus.EstablishedNodes = append(newStatus.EstablishedNodes, s.Status.Node)
+			continue
+		}
+		newStatus.DownNodes = append(newStatus.DownNodes, s.Status.Node)
+	}
+	sort.Strings(newStatus.EstablishedNodes)
+	sort.Strings(newStatus.DownNodes)
+
+	if reflect.DeepEqual(peer.Status, newStatus) {
+		return ctrl.Result{}, nil
+	}
+
+	peer.Status = newStatus
+	err = r.Client.Status().Update(ctx, &peer)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *BGPPeerStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	p := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return filterBGPPeerStatusEvent(e)
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("BGPPeerStatusController").
+		For(&v1beta2.BGPPeer{}).
+		Watches(&v1beta1.BGPSessionStatus{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, object client.Object) []reconcile.Request {
+				peer, ok := object.GetLabels()[LabelBGPPeer]
+				if !ok {
+					return nil
+				}
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Name:      peer,
+					Namespace: object.GetNamespace(),
+				}}}
+			})).
+		WithEventFilter(p).
+		Complete(r)
+}
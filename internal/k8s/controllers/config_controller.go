@@ -83,6 +83,12 @@ var requestHandler = func(r *ConfigReconciler, ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, err
 	}
 
+	var upnpAdvertisements metallbv1beta1.UPnPAdvertisementList
+	if err := r.List(ctx, &upnpAdvertisements, client.InNamespace(r.Namespace)); err != nil {
+		level.Error(r.Logger).Log("controller", "ConfigReconciler", "message", "failed to get upnp advertisements", "error", err)
+		return ctrl.Result{}, err
+	}
+
 	var bgpAdvertisements metallbv1beta1.BGPAdvertisementList
 	if err := r.List(ctx, &bgpAdvertisements, client.InNamespace(r.Namespace)); err != nil {
 		level.Error(r.Logger).Log("controller", "ConfigReconciler", "message", "failed to get bgp advertisements", "error", err)
@@ -124,6 +130,7 @@ var requestHandler = func(r *ConfigReconciler, ctx context.Context, req ctrl.Req
 		Peers:           bgpPeers.Items,
 		BFDProfiles:     bfdProfiles.Items,
 		L2Advs:          l2Advertisements.Items,
+		UPnPAdvs:        upnpAdvertisements.Items,
 		BGPAdvs:         bgpAdvertisements.Items,
 		Communities:     communities.Items,
 		PasswordSecrets: secrets,
@@ -182,7 +189,7 @@ var requestHandler = func(r *ConfigReconciler, ctx context.Context, req ctrl.Req
 func (r *ConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	p := predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			return filterNodeEvent(e) && filterNamespaceEvent(e) && filterConfigmapEvent(e)
+			return filterNodeEvent(e) && filterNamespaceEvent(e) && filterConfigmapEvent(e) && filterBGPPeerStatusEvent(e)
 		},
 	}
 	return ctrl.NewControllerManagedBy(mgr).
@@ -191,6 +198,7 @@ func (r *ConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(&corev1.Node{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.BGPAdvertisement{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.L2Advertisement{}, &handler.EnqueueRequestForObject{}).
+		Watches(&metallbv1beta1.UPnPAdvertisement{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.BFDProfile{}, &handler.EnqueueRequestForObject{}).
 		Watches(&metallbv1beta1.Community{}, &handler.EnqueueRequestForObject{}).
 		Watches(&corev1.Secret{}, &handler.EnqueueRequestForObject{}).
@@ -231,6 +239,18 @@ func filterNamespaceEvent(e event.UpdateEvent) bool {
 	return true
 }
 
+// filterBGPPeerStatusEvent ignores the updates of the status of the
+// BGPPeers, which don't change the configuration.
+func filterBGPPeerStatusEvent(e event.UpdateEvent) bool {
+	if _, ok := e.ObjectNew.(*metallbv1beta2.BGPPeer); !ok {
+		return true
+	}
+	if _, ok := e.ObjectOld.(*metallbv1beta2.BGPPeer); !ok {
+		return true
+	}
+	return predicate.GenerationChangedPredicate{}.Update(e)
+}
+
 func filterConfigmapEvent(e event.UpdateEvent) bool {
 	cm, ok := e.ObjectNew.(*corev1.ConfigMap)
 	if !ok {
@@ -14,6 +14,7 @@ func toConfig(fromK8s config.ClusterResources, validate config.Validate) (*confi
 		Peers:           sortedCopy(fromK8s.Peers),
 		BFDProfiles:     sortedCopy(fromK8s.BFDProfiles),
 		L2Advs:          sortedCopy(fromK8s.L2Advs),
+		UPnPAdvs:        sortedCopy(fromK8s.UPnPAdvs),
 		BGPAdvs:         sortedCopy(fromK8s.BGPAdvs),
 		Communities:     sortedCopy(fromK8s.Communities),
 		PasswordSecrets: fromK8s.PasswordSecrets,
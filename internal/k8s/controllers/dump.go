@@ -13,13 +13,15 @@ import (
 
 func dumpClusterResources(c *config.ClusterResources) string {
 	withNoSecret := config.ClusterResources{
-		Pools:       c.Pools,
-		Peers:       sanitizeBGPPeer(c.Peers...),
-		BFDProfiles: c.BFDProfiles,
-		L2Advs:      c.L2Advs,
-		BGPAdvs:     c.BGPAdvs,
-		Communities: c.Communities,
-		BGPExtras:   c.BGPExtras,
+		Pools:        c.Pools,
+		Peers:        sanitizeBGPPeer(c.Peers...),
+		BFDProfiles:  c.BFDProfiles,
+		L2Advs:       c.L2Advs,
+		UPnPAdvs:     c.UPnPAdvs,
+		BGPAdvs:      c.BGPAdvs,
+		Communities:  c.Communities,
+		Reservations: c.Reservations,
+		BGPExtras:    c.BGPExtras,
 	}
 	withNoSecret.PasswordSecrets = make(map[string]corev1.Secret)
 	for k, s := range c.PasswordSecrets {
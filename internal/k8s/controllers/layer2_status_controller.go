@@ -5,7 +5,10 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net"
 	"reflect"
+	"slices"
+	"sort"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -91,23 +94,6 @@ func (r *Layer2StatusReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, utilerrors.NewAggregate(errs)
 	}
 
-	deleteRedundantErrs := []error{}
-	if len(serviceL2statuses.Items) > 1 {
-		// We shouldn't get here, just in case the controller created redundant resources
-		for i := range serviceL2statuses.Items[1:] {
-			if serviceL2statuses.Items[i+1].Labels[LabelAnnounceNode] != r.NodeName {
-				continue
-			}
-			if err := r.Delete(ctx, &serviceL2statuses.Items[i+1]); err != nil && !errors.IsNotFound(err) {
-				deleteRedundantErrs = append(deleteRedundantErrs, err)
-			}
-		}
-	}
-
-	if len(deleteRedundantErrs) > 0 {
-		return ctrl.Result{}, utilerrors.NewAggregate(deleteRedundantErrs)
-	}
-
 	// creating a brand new cr
 	var state = &v1beta1.ServiceL2Status{
 		ObjectMeta: metav1.ObjectMeta{
@@ -115,9 +101,28 @@ func (r *Layer2StatusReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			Namespace:    r.Namespace,
 		},
 	}
-	// update an existing cr
-	if len(serviceL2statuses.Items) > 0 {
-		state = &serviceL2statuses.Items[0]
+
+	// update an existing cr, the statuses of the other nodes holding the
+	// service being left alone
+	deleteRedundantErrs := []error{}
+	found := false
+	for i := range serviceL2statuses.Items {
+		if serviceL2statuses.Items[i].Labels[LabelAnnounceNode] != r.NodeName {
+			continue
+		}
+		if !found {
+			state = &serviceL2statuses.Items[i]
+			found = true
+			continue
+		}
+		// We shouldn't get here, just in case the controller created redundant resources
+		if err := r.Delete(ctx, &serviceL2statuses.Items[i]); err != nil && !errors.IsNotFound(err) {
+			deleteRedundantErrs = append(deleteRedundantErrs, err)
+		}
+	}
+
+	if len(deleteRedundantErrs) > 0 {
+		return ctrl.Result{}, utilerrors.NewAggregate(deleteRedundantErrs)
 	}
 
 	desiredStatus := r.buildDesiredStatus(ipAdvS, serviceName, serviceNamespace)
@@ -225,17 +230,49 @@ func (r *Layer2StatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
+// Layer2Holders returns the nodes announcing the given ip of the service in
+// layer2 mode according to their ServiceL2Status, the most recent holder first.
+// The statuses not listing the announced IPs are assumed to hold all of them.
+func Layer2Holders(ctx context.Context, c client.Reader, svc types.NamespacedName, ip net.IP) ([]string, error) {
+	var serviceL2statuses v1beta1.ServiceL2StatusList
+	if err := c.List(ctx, &serviceL2statuses, client.MatchingFields{
+		serviceIndexName: svc.String(),
+	}); err != nil {
+		return nil, err
+	}
+	items := serviceL2statuses.Items
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreationTimestamp.Equal(&items[j].CreationTimestamp) {
+			return items[j].CreationTimestamp.Before(&items[i].CreationTimestamp)
+		}
+		return items[i].Name < items[j].Name
+	})
+	res := []string{}
+	for _, s := range items {
+		if len(s.Status.IPs) > 0 && !slices.Contains(s.Status.IPs, ip.String()) {
+			continue
+		}
+		if node, ok := s.Labels[LabelAnnounceNode]; ok {
+			res = append(res, node)
+		}
+	}
+	return res, nil
+}
+
 func (r *Layer2StatusReconciler) buildDesiredStatus(
 	advertisements []layer2.IPAdvertisement,
 	serviceName,
 	serviceNamespace string,
 ) v1beta1.MetalLBServiceL2Status {
-	// todo: add advertise ip or not?
 	s := v1beta1.MetalLBServiceL2Status{
 		Node:             r.NodeName,
 		ServiceName:      serviceName,
 		ServiceNamespace: serviceNamespace,
 	}
+	for _, adv := range advertisements {
+		s.IPs = append(s.IPs, adv.GetIP().String())
+	}
+	sort.Strings(s.IPs)
 	// multiple advertisement objects share all fields except lb ip, so we use the first one
 	adv := advertisements[0]
 	if !adv.IsAllInterfaces() {
@@ -61,6 +61,12 @@ func (r *PoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
+	var reservations metallbv1beta1.IPAddressReservationList
+	if err := r.List(ctx, &reservations, client.InNamespace(r.Namespace)); err != nil {
+		level.Error(r.Logger).Log("controller", "PoolReconciler", "message", "failed to get ipaddressreservations", "error", err)
+		return ctrl.Result{}, err
+	}
+
 	var namespaces corev1.NamespaceList
 	if err := r.List(ctx, &namespaces); err != nil {
 		level.Error(r.Logger).Log("controller", "ConfigReconciler", "message", "failed to get namespaces", "error", err)
@@ -68,9 +74,10 @@ func (r *PoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 	}
 
 	resources := config.ClusterResources{
-		Pools:       ipAddressPools.Items,
-		Communities: communities.Items,
-		Namespaces:  namespaces.Items,
+		Pools:        ipAddressPools.Items,
+		Communities:  communities.Items,
+		Reservations: reservations.Items,
+		Namespaces:   namespaces.Items,
 	}
 
 	level.Debug(r.Logger).Log("controller", "PoolReconciler", "metallb CRs", dumpClusterResources(&resources))
@@ -122,6 +129,7 @@ func (r *PoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&metallbv1beta1.IPAddressPool{}).
 		Watches(&metallbv1beta1.Community{}, &handler.EnqueueRequestForObject{}).
+		Watches(&metallbv1beta1.IPAddressReservation{}, &handler.EnqueueRequestForObject{}).
 		Watches(&corev1.Namespace{}, &handler.EnqueueRequestForObject{}).
 		WithEventFilter(p).
 		Complete(r)
@@ -5,6 +5,7 @@ package controllers
 import (
 	"context"
 	"reflect"
+	"sort"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -69,7 +70,21 @@ func (r *PoolStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		AssignedIPv6:  c.AssignedIPv6,
 		AvailableIPv4: c.AvailableIPv4,
 		AvailableIPv6: c.AvailableIPv6,
+		ReservedIPv4:  c.ReservedIPv4,
+		ReservedIPv6:  c.ReservedIPv6,
 	}
+	for namespace, nc := range c.Namespaces {
+		newStatus.Namespaces = append(newStatus.Namespaces, v1beta1.NamespaceUsage{
+			Namespace:    namespace,
+			AssignedIPv4: nc.AssignedIPv4,
+			AssignedIPv6: nc.AssignedIPv6,
+			MaxIPv4:      nc.MaxIPv4,
+			MaxIPv6:      nc.MaxIPv6,
+		})
+	}
+	sort.Slice(newStatus.Namespaces, func(i, j int) bool {
+		return newStatus.Namespaces[i].Namespace < newStatus.Namespaces[j].Namespace
+	})
 
 	if reflect.DeepEqual(pool.Status, newStatus) {
 		return ctrl.Result{}, nil
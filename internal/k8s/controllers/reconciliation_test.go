@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -35,9 +36,11 @@ import (
 	v1beta1 "go.universe.tf/metallb/api/v1beta1"
 	v1beta2 "go.universe.tf/metallb/api/v1beta2"
 	"go.universe.tf/metallb/internal/allocator"
+	"go.universe.tf/metallb/internal/bgp"
 	frrk8s "go.universe.tf/metallb/internal/bgp/frrk8s"
 	"go.universe.tf/metallb/internal/config"
 	"go.universe.tf/metallb/internal/layer2"
+	"go.universe.tf/metallb/internal/upnp"
 )
 
 /*
@@ -74,8 +77,17 @@ var (
 
 	bgpStatusReconcileChan = make(chan event.GenericEvent)
 	bgpAdvs                = map[string]sets.Set[string]{}
+	bgpServiceAds          = map[string]map[string][]*bgp.Advertisement{}
 	bgpAdvsMutex           = sync.Mutex{}
 
+	bgpSessionStatusReconcileChan = make(chan event.GenericEvent)
+	bgpSessions                   = map[string]bgp.SessionStatus{}
+	bgpSessionsMutex              sync.Mutex
+
+	upnpStatusReconcileChan = make(chan event.GenericEvent)
+	upnpStatuses            = map[string]upnp.ServiceStatus{}
+	upnpStatusesMutex       sync.Mutex
+
 	poolStatusReconcileChan = make(chan event.GenericEvent)
 	poolCounters            = map[string]allocator.PoolCounters{}
 	poolCountersMutex       sync.Mutex
@@ -237,10 +249,56 @@ var _ = BeforeSuite(func() {
 			defer bgpAdvsMutex.Unlock()
 			return bgpAdvs[key]
 		},
+		AdvertisementsFetcher: func(key string) map[string][]*bgp.Advertisement {
+			bgpAdvsMutex.Lock()
+			defer bgpAdvsMutex.Unlock()
+			return bgpServiceAds[key]
+		},
 	}
 	err = bgpStatusReconciler.SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	bgpSessionStatusReconciler := &BGPSessionStatusReconciler{
+		Client:        k8sManager.GetClient(),
+		Logger:        log.NewNopLogger(),
+		NodeName:      testNodeName,
+		Namespace:     speakerNamespace,
+		SpeakerPod:    speakerPod,
+		ReconcileChan: bgpSessionStatusReconcileChan,
+		StatusFetcher: func(peer string) (bgp.SessionStatus, bool) {
+			bgpSessionsMutex.Lock()
+			defer bgpSessionsMutex.Unlock()
+			s, ok := bgpSessions[peer]
+			return s, ok
+		},
+	}
+	err = bgpSessionStatusReconciler.SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	bgpPeerStatusReconciler := &BGPPeerStatusReconciler{
+		Client:    k8sManager.GetClient(),
+		Logger:    log.NewNopLogger(),
+		Namespace: speakerNamespace,
+	}
+	err = bgpPeerStatusReconciler.SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	upnpStatusReconciler := &UPnPStatusReconciler{
+		Client:        k8sManager.GetClient(),
+		Logger:        log.NewNopLogger(),
+		NodeName:      testNodeName,
+		Namespace:     speakerNamespace,
+		SpeakerPod:    speakerPod,
+		ReconcileChan: upnpStatusReconcileChan,
+		StatusFetcher: func(nn types.NamespacedName) upnp.ServiceStatus {
+			upnpStatusesMutex.Lock()
+			defer upnpStatusesMutex.Unlock()
+			return upnpStatuses[nn.String()]
+		},
+	}
+	err = upnpStatusReconciler.SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	poolStatusReconciler := &PoolStatusReconciler{
 		Client: k8sManager.GetClient(),
 		Logger: log.NewNopLogger(),
@@ -747,6 +805,471 @@ var _ = Describe("BGP Status Controller", func() {
 				return nil
 			}, 1*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
 		})
+
+		It("Should report the state of the sessions with the peers", func() {
+			serviceKey := types.NamespacedName{Namespace: testNamespace, Name: testServiceName}.String()
+			validatePeerStatuses := func(expected []v1beta1.ServiceBGPPeerStatus) {
+				Eventually(func() error {
+					list := v1beta1.ServiceBGPStatusList{}
+					err := k8sClient.List(context.TODO(), &list)
+					if err != nil {
+						return err
+					}
+
+					if len(list.Items) != 1 {
+						return fmt.Errorf("expected 1 status, got %v", list.Items)
+					}
+
+					if !reflect.DeepEqual(expected, list.Items[0].Status.PeerStatuses) {
+						return fmt.Errorf("expected peer statuses to be %v, got %v", expected, list.Items[0].Status.PeerStatuses)
+					}
+
+					return nil
+				}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+			}
+
+			_, prefix, _ := net.ParseCIDR("192.168.10.1/32")
+			bgpAdvsMutex.Lock()
+			bgpAdvs[serviceKey] = sets.New[string]("peer1")
+			bgpServiceAds[serviceKey] = map[string][]*bgp.Advertisement{
+				"peer1": {{Prefix: prefix, MED: ptr.To(uint32(10))}},
+			}
+			bgpAdvsMutex.Unlock()
+			bgpStatusReconcileChan <- NewBGPStatusEvent(testNamespace, testServiceName)
+			expectedPrefixes := []v1beta1.ServiceBGPPrefix{{Prefix: "192.168.10.1/32", MED: ptr.To(uint32(10))}}
+			validatePeerStatuses([]v1beta1.ServiceBGPPeerStatus{
+				{Peer: "peer1", SessionState: v1beta1.BGPSessionDown, Prefixes: expectedPrefixes},
+			})
+
+			// The changes of the session are reflected without any event for
+			// the service, through its BGPSessionStatus.
+			bgpSessionsMutex.Lock()
+			bgpSessions["peer1"] = bgp.SessionStatus{Established: true}
+			bgpSessionsMutex.Unlock()
+			bgpSessionStatusReconcileChan <- NewBGPSessionStatusEvent(speakerNamespace, "peer1")
+			validatePeerStatuses([]v1beta1.ServiceBGPPeerStatus{
+				{Peer: "peer1", SessionState: v1beta1.BGPSessionEstablished, Prefixes: expectedPrefixes},
+			})
+
+			bgpSessionsMutex.Lock()
+			bgpSessions["peer1"] = bgp.SessionStatus{}
+			bgpSessionsMutex.Unlock()
+			bgpSessionStatusReconcileChan <- NewBGPSessionStatusEvent(speakerNamespace, "peer1")
+			validatePeerStatuses([]v1beta1.ServiceBGPPeerStatus{
+				{Peer: "peer1", SessionState: v1beta1.BGPSessionDown, Prefixes: expectedPrefixes},
+			})
+
+			bgpSessionsMutex.Lock()
+			delete(bgpSessions, "peer1")
+			bgpSessionsMutex.Unlock()
+			bgpSessionStatusReconcileChan <- NewBGPSessionStatusEvent(speakerNamespace, "peer1")
+			bgpAdvsMutex.Lock()
+			delete(bgpAdvs, serviceKey)
+			delete(bgpServiceAds, serviceKey)
+			bgpAdvsMutex.Unlock()
+			bgpStatusReconcileChan <- NewBGPStatusEvent(testNamespace, testServiceName)
+			Eventually(func() error {
+				statuses := v1beta1.ServiceBGPStatusList{}
+				err := k8sClient.List(context.TODO(), &statuses)
+				if err != nil {
+					return err
+				}
+				sessions := v1beta1.BGPSessionStatusList{}
+				err = k8sClient.List(context.TODO(), &sessions, client.MatchingLabels{LabelBGPPeer: "peer1"})
+				if err != nil {
+					return err
+				}
+
+				if len(statuses.Items) != 0 || len(sessions.Items) != 0 {
+					return fmt.Errorf("expected no statuses, got %v and %v", statuses.Items, sessions.Items)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("BGPSessionStatus Controller", func() {
+	Context("SetupWithManager", func() {
+		It("Should Reconcile correctly", func() {
+			const peerName = "session-peer"
+			getStatus := func() (*v1beta1.BGPSessionStatus, error) {
+				list := v1beta1.BGPSessionStatusList{}
+				err := k8sClient.List(context.TODO(), &list, client.MatchingLabels{LabelBGPPeer: peerName})
+				if err != nil {
+					return nil, err
+				}
+
+				if len(list.Items) != 1 {
+					return nil, fmt.Errorf("expected 1 status, got %v", list.Items)
+				}
+
+				status := list.Items[0]
+				if len(status.OwnerReferences) != 1 {
+					return nil, fmt.Errorf("expected 1 owner reference, got %v", status.OwnerReferences)
+				}
+
+				ownerRef := status.OwnerReferences[0]
+				if ownerRef.UID != speakerPod.UID {
+					return nil, fmt.Errorf("owner reference is not speaker pod, got %v", ownerRef)
+				}
+
+				if status.Labels[LabelAnnounceNode] != testNodeName {
+					return nil, fmt.Errorf("labels do not match node, got %v", status.Labels)
+				}
+
+				if status.Status.Node != testNodeName {
+					return nil, fmt.Errorf("status does not match node, got %v", status.Status)
+				}
+
+				if status.Status.Peer != peerName {
+					return nil, fmt.Errorf("status does not match peer, got %v", status.Status)
+				}
+
+				return &status, nil
+			}
+
+			bgpSessionsMutex.Lock()
+			bgpSessions[peerName] = bgp.SessionStatus{
+				Established:      true,
+				SentPrefixes:     2,
+				ReceivedPrefixes: 1,
+			}
+			bgpSessionsMutex.Unlock()
+			bgpSessionStatusReconcileChan <- NewBGPSessionStatusEvent(speakerNamespace, peerName)
+			Eventually(func() error {
+				s, err := getStatus()
+				if err != nil {
+					return err
+				}
+
+				if s.Status.State != v1beta1.BGPSessionEstablished {
+					return fmt.Errorf("expected session to be established, got %v", s.Status)
+				}
+
+				if s.Status.PrefixesSent != 2 || s.Status.PrefixesReceived != 1 {
+					return fmt.Errorf("expected 2 prefixes sent and 1 received, got %v", s.Status)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+
+			bgpSessionsMutex.Lock()
+			bgpSessions[peerName] = bgp.SessionStatus{
+				LastError: "connection refused",
+			}
+			bgpSessionsMutex.Unlock()
+			bgpSessionStatusReconcileChan <- NewBGPSessionStatusEvent(speakerNamespace, peerName)
+			expectedStatus := v1beta1.MetalLBBGPSessionStatus{
+				Node:      testNodeName,
+				Peer:      peerName,
+				State:     v1beta1.BGPSessionDown,
+				LastError: "connection refused",
+			}
+			Eventually(func() error {
+				s, err := getStatus()
+				if err != nil {
+					return err
+				}
+
+				if !reflect.DeepEqual(expectedStatus, s.Status) {
+					return fmt.Errorf("expected status to be %v, got %v", expectedStatus, s.Status)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+
+			// Manual updates should be reverted by the controller
+			status, err := getStatus()
+			Expect(err).ToNot(HaveOccurred())
+			status.Status.State = v1beta1.BGPSessionEstablished
+			err = k8sClient.Status().Update(context.TODO(), status)
+			Expect(err).To(Not(HaveOccurred()))
+			Eventually(func() error {
+				s, err := getStatus()
+				if err != nil {
+					return err
+				}
+
+				if !reflect.DeepEqual(expectedStatus, s.Status) {
+					return fmt.Errorf("expected status to be %v, got %v", expectedStatus, s.Status)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+
+			bgpSessionsMutex.Lock()
+			delete(bgpSessions, peerName)
+			bgpSessionsMutex.Unlock()
+			bgpSessionStatusReconcileChan <- NewBGPSessionStatusEvent(speakerNamespace, peerName)
+			Eventually(func() error {
+				list := v1beta1.BGPSessionStatusList{}
+				err := k8sClient.List(context.TODO(), &list, client.MatchingLabels{LabelBGPPeer: peerName})
+				if err != nil {
+					return err
+				}
+
+				if len(list.Items) != 0 {
+					return fmt.Errorf("expected no statuses, got %v", list.Items)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("BGPPeer Status Controller", func() {
+	Context("SetupWithManager", func() {
+		It("Should Reconcile correctly", func() {
+			peer := &v1beta2.BGPPeer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "status-peer",
+					Namespace: speakerNamespace,
+				},
+				Spec: v1beta2.BGPPeerSpec{
+					MyASN:   64500,
+					ASN:     64501,
+					Address: "10.0.0.1",
+				},
+			}
+			err := k8sClient.Create(ctx, peer)
+			Expect(err).ToNot(HaveOccurred())
+
+			// The sessions of other nodes, which the BGPSessionStatus
+			// controller of the test node leaves alone.
+			createSessionStatus := func(node string, state v1beta1.BGPSessionState) *v1beta1.BGPSessionStatus {
+				s := &v1beta1.BGPSessionStatus{
+					ObjectMeta: metav1.ObjectMeta{
+						GenerateName: "bgp-session-",
+						Namespace:    speakerNamespace,
+						Labels: map[string]string{
+							LabelAnnounceNode: node,
+							LabelBGPPeer:      peer.Name,
+						},
+					},
+				}
+				err := k8sClient.Create(ctx, s)
+				Expect(err).ToNot(HaveOccurred())
+				s.Status = v1beta1.MetalLBBGPSessionStatus{
+					Node:  node,
+					Peer:  peer.Name,
+					State: state,
+				}
+				err = k8sClient.Status().Update(ctx, s)
+				Expect(err).ToNot(HaveOccurred())
+				return s
+			}
+			validateStatus := func(expected v1beta2.BGPPeerStatus) {
+				Eventually(func() error {
+					p := v1beta2.BGPPeer{}
+					err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(peer), &p)
+					if err != nil {
+						return err
+					}
+					if !reflect.DeepEqual(p.Status, expected) {
+						return fmt.Errorf("peer status does not match, got [%v] expected [%v]", p.Status, expected)
+					}
+					return nil
+				}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+			}
+
+			node1 := createSessionStatus("node1", v1beta1.BGPSessionEstablished)
+			node2 := createSessionStatus("node2", v1beta1.BGPSessionDown)
+			validateStatus(v1beta2.BGPPeerStatus{
+				EstablishedNodes: []string{"node1"},
+				DownNodes:        []string{"node2"},
+			})
+
+			node2.Status.State = v1beta1.BGPSessionEstablished
+			err = k8sClient.Status().Update(ctx, node2)
+			Expect(err).ToNot(HaveOccurred())
+			validateStatus(v1beta2.BGPPeerStatus{
+				EstablishedNodes: []string{"node1", "node2"},
+			})
+
+			err = k8sClient.Delete(ctx, node1)
+			Expect(err).ToNot(HaveOccurred())
+			validateStatus(v1beta2.BGPPeerStatus{
+				EstablishedNodes: []string{"node2"},
+			})
+
+			err = k8sClient.Delete(ctx, node2)
+			Expect(err).ToNot(HaveOccurred())
+			validateStatus(v1beta2.BGPPeerStatus{})
+
+			err = k8sClient.Delete(ctx, peer)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("UPnP Status Controller", func() {
+	Context("SetupWithManager", func() {
+		It("Should Reconcile correctly", func() {
+			serviceKey := types.NamespacedName{Namespace: testNamespace, Name: testServiceName}.String()
+			getStatus := func() (*v1beta1.ServiceUPnPStatus, error) {
+				list := v1beta1.ServiceUPnPStatusList{}
+				err := k8sClient.List(context.TODO(), &list)
+				if err != nil {
+					return nil, err
+				}
+
+				if len(list.Items) != 1 {
+					return nil, fmt.Errorf("expected 1 status, got %v", list.Items)
+				}
+
+				status := list.Items[0]
+				if len(status.OwnerReferences) != 1 {
+					return nil, fmt.Errorf("expected 1 owner reference, got %v", status.OwnerReferences)
+				}
+
+				ownerRef := status.OwnerReferences[0]
+				if ownerRef.UID != speakerPod.UID {
+					return nil, fmt.Errorf("owner reference is not speaker pod, got %v", ownerRef)
+				}
+
+				if status.Labels[LabelAnnounceNode] != testNodeName {
+					return nil, fmt.Errorf("labels do not match node, got %v", status.Labels)
+				}
+
+				if status.Spec.Node != testNodeName {
+					return nil, fmt.Errorf("spec does not match node, got %v", status.Spec)
+				}
+
+				if status.Labels[LabelServiceNamespace] != testNamespace {
+					return nil, fmt.Errorf("labels do not match namespace, got %v", status.Labels)
+				}
+
+				if status.Spec.ServiceNamespace != testNamespace {
+					return nil, fmt.Errorf("spec does not match namespace, got %v", status.Spec)
+				}
+
+				if status.Labels[LabelServiceName] != testServiceName {
+					return nil, fmt.Errorf("labels do not match service name, got %v", status.Labels)
+				}
+
+				if status.Spec.ServiceName != testServiceName {
+					return nil, fmt.Errorf("spec does not match service name, got %v", status.Spec)
+				}
+
+				return &status, nil
+			}
+
+			upnpStatusesMutex.Lock()
+			upnpStatuses[serviceKey] = upnp.ServiceStatus{
+				ExternalIP: net.ParseIP("203.0.113.1"),
+				Gateways: []upnp.GatewayStatus{{
+					Device: upnp.Device{
+						Protocol:     upnp.ProtocolUPnP,
+						Location:     "http://192.168.1.1:5000/rootDesc.xml",
+						UDN:          "uuid:11111111-2222-3333-4444-555555555555",
+						FriendlyName: "router",
+					},
+					ExternalIP: net.ParseIP("203.0.113.1"),
+				}},
+				Mappings: []upnp.PortMapping{
+					{ExternalPort: 80, InternalPort: 80, InternalIP: net.ParseIP("192.168.1.100"), Protocol: "tcp"},
+				},
+			}
+			upnpStatusesMutex.Unlock()
+			upnpStatusReconcileChan <- NewUPnPStatusEvent(testNamespace, testServiceName)
+			expectedMappings := []v1beta1.UPnPPortMapping{
+				{ExternalPort: 80, InternalPort: 80, InternalIP: "192.168.1.100", Protocol: "TCP"},
+			}
+			expectedGateways := []v1beta1.UPnPGatewayStatus{{
+				Protocol:     "UPnP",
+				Location:     "http://192.168.1.1:5000/rootDesc.xml",
+				UDN:          "uuid:11111111-2222-3333-4444-555555555555",
+				FriendlyName: "router",
+				ExternalIP:   "203.0.113.1",
+			}}
+			Eventually(func() error {
+				s, err := getStatus()
+				if err != nil {
+					return err
+				}
+
+				if s.Spec.ExternalIP != "203.0.113.1" {
+					return fmt.Errorf("expected external ip to be 203.0.113.1, got %s", s.Spec.ExternalIP)
+				}
+
+				if !reflect.DeepEqual(expectedMappings, s.Spec.PortMappings) {
+					return fmt.Errorf("expected port mappings to be %v, got %v", expectedMappings, s.Spec.PortMappings)
+				}
+
+				if !reflect.DeepEqual(expectedGateways, s.Spec.Gateways) {
+					return fmt.Errorf("expected gateways to be %v, got %v", expectedGateways, s.Spec.Gateways)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+
+			upnpStatusesMutex.Lock()
+			upnpStatuses[serviceKey] = upnp.ServiceStatus{
+				ExternalIP: net.ParseIP("203.0.113.1"),
+				Mappings: []upnp.PortMapping{
+					{ExternalPort: 443, InternalPort: 443, InternalIP: net.ParseIP("192.168.1.100"), Protocol: "tcp"},
+					{ExternalPort: 80, InternalPort: 80, InternalIP: net.ParseIP("192.168.1.100"), Protocol: "tcp"},
+				},
+			}
+			upnpStatusesMutex.Unlock()
+			upnpStatusReconcileChan <- NewUPnPStatusEvent(testNamespace, testServiceName)
+			expectedMappings = []v1beta1.UPnPPortMapping{
+				{ExternalPort: 80, InternalPort: 80, InternalIP: "192.168.1.100", Protocol: "TCP"},
+				{ExternalPort: 443, InternalPort: 443, InternalIP: "192.168.1.100", Protocol: "TCP"},
+			}
+			Eventually(func() error {
+				s, err := getStatus()
+				if err != nil {
+					return err
+				}
+
+				if !reflect.DeepEqual(expectedMappings, s.Spec.PortMappings) {
+					return fmt.Errorf("expected port mappings to be %v, got %v", expectedMappings, s.Spec.PortMappings)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+
+			// Manual updates should be reverted by the controller
+			status, err := getStatus()
+			Expect(err).ToNot(HaveOccurred())
+			status.Spec.PortMappings = nil
+			err = k8sClient.Update(context.TODO(), status)
+			Expect(err).To(Not(HaveOccurred()))
+			Eventually(func() error {
+				s, err := getStatus()
+				if err != nil {
+					return err
+				}
+
+				if !reflect.DeepEqual(expectedMappings, s.Spec.PortMappings) {
+					return fmt.Errorf("expected port mappings to be %v, got %v", expectedMappings, s.Spec.PortMappings)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+
+			upnpStatusesMutex.Lock()
+			delete(upnpStatuses, serviceKey)
+			upnpStatusesMutex.Unlock()
+			upnpStatusReconcileChan <- NewUPnPStatusEvent(testNamespace, testServiceName)
+			Eventually(func() error {
+				list := v1beta1.ServiceUPnPStatusList{}
+				err := k8sClient.List(context.TODO(), &list)
+				if err != nil {
+					return err
+				}
+
+				if len(list.Items) != 0 {
+					return fmt.Errorf("expected no statuses, got %v", list.Items)
+				}
+
+				return nil
+			}, 5*time.Second, 200*time.Millisecond).ShouldNot(HaveOccurred())
+		})
 	})
 })
 
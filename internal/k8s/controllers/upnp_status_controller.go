@@ -0,0 +1,296 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"go.universe.tf/metallb/api/v1beta1"
+	"go.universe.tf/metallb/internal/safeconvert"
+	"go.universe.tf/metallb/internal/upnp"
+)
+
+type UPnPStatusFetcher func(types.NamespacedName) upnp.ServiceStatus
+
+type upnpStatusEvent struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+}
+
+func (evt *upnpStatusEvent) DeepCopyObject() runtime.Object {
+	res := new(upnpStatusEvent)
+	res.Name = evt.Name
+	res.Namespace = evt.Namespace
+	return res
+}
+
+func NewUPnPStatusEvent(namespace, name string) event.GenericEvent {
+	evt := upnpStatusEvent{}
+	evt.Name = name
+	evt.Namespace = namespace
+	return event.GenericEvent{Object: &evt}
+}
+
+type UPnPStatusReconciler struct {
+	client.Client
+	Logger        log.Logger
+	NodeName      string
+	Namespace     string
+	SpeakerPod    *v1.Pod
+	ReconcileChan <-chan event.GenericEvent
+	StatusFetcher UPnPStatusFetcher
+}
+
+func (r *UPnPStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	level.Info(r.Logger).Log("controller", "UPnPStatusReconciler", "start reconcile", req.String())
+	defer level.Info(r.Logger).Log("controller", "UPnPStatusReconciler", "end reconcile", req.String())
+
+	serviceName, serviceNamespace := req.Name, req.Namespace
+
+	var serviceUPnPStatuses v1beta1.ServiceUPnPStatusList
+	err := r.List(ctx, &serviceUPnPStatuses, client.MatchingFields{
+		serviceIndexName: indexFor(serviceNamespace, serviceName, r.NodeName),
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := r.StatusFetcher(types.NamespacedName{Name: serviceName, Namespace: serviceNamespace})
+	if len(status.Mappings) == 0 {
+		errs := []error{}
+		for i := range serviceUPnPStatuses.Items {
+			if serviceUPnPStatuses.Items[i].Labels[LabelAnnounceNode] != r.NodeName { // shouldn't happen because of the indexing, just in case
+				continue
+			}
+			if err := r.Delete(ctx, &serviceUPnPStatuses.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, err)
+			}
+		}
+		return ctrl.Result{}, utilerrors.NewAggregate(errs)
+	}
+
+	deleteRedundantErrs := []error{}
+	if len(serviceUPnPStatuses.Items) > 1 {
+		// We shouldn't get here, just in case the controller created redundant resources
+		for i := range serviceUPnPStatuses.Items[1:] {
+			if serviceUPnPStatuses.Items[i+1].Labels[LabelAnnounceNode] != r.NodeName {
+				continue
+			}
+			if err := r.Delete(ctx, &serviceUPnPStatuses.Items[i+1]); err != nil && !apierrors.IsNotFound(err) {
+				deleteRedundantErrs = append(deleteRedundantErrs, err)
+			}
+		}
+	}
+
+	if len(deleteRedundantErrs) > 0 {
+		return ctrl.Result{}, utilerrors.NewAggregate(deleteRedundantErrs)
+	}
+
+	var state = &v1beta1.ServiceUPnPStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "upnp-",
+			Namespace:    r.Namespace,
+		},
+	}
+
+	if len(serviceUPnPStatuses.Items) > 0 {
+		state = &serviceUPnPStatuses.Items[0]
+	}
+
+	desiredSpec, err := r.buildDesiredSpec(status, serviceName, serviceNamespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if reflect.DeepEqual(state.Spec, desiredSpec) {
+		return ctrl.Result{}, nil
+	}
+
+	var result controllerutil.OperationResult
+	result, err = controllerutil.CreateOrPatch(ctx, r.Client, state, func() error {
+		state.Labels = map[string]string{
+			LabelAnnounceNode:     r.NodeName,
+			LabelServiceName:      serviceName,
+			LabelServiceNamespace: serviceNamespace,
+		}
+		state.Spec = desiredSpec
+		err = controllerutil.SetOwnerReference(r.SpeakerPod, state, r.Scheme())
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result == controllerutil.OperationResultCreated {
+		level.Debug(r.Logger).Log("controller", "UPnPStatusReconciler", "created state", dumpResource(state))
+		return ctrl.Result{}, nil
+	}
+
+	level.Debug(r.Logger).Log("controller", "UPnPStatusReconciler", "updated state", dumpResource(state))
+	return ctrl.Result{}, nil
+}
+
+func (r *UPnPStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	p := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		_, ok := o.(*v1beta1.ServiceUPnPStatus)
+		if !ok {
+			return true
+		}
+
+		labels := o.GetLabels()
+
+		if labels == nil {
+			level.Error(r.Logger).Log("controller", "UPnPStatusReconciler", "object has no labels", o)
+			return false
+		}
+
+		if _, ok = labels[LabelServiceName]; !ok {
+			level.Error(r.Logger).Log("controller", "UPnPStatusReconciler", "object does not have the servicename label", o)
+			return false
+		}
+
+		if _, ok = labels[LabelServiceNamespace]; !ok {
+			level.Error(r.Logger).Log("controller", "UPnPStatusReconciler", "object does not have the servicenamespace label", o)
+			return false
+		}
+
+		var node string
+		if node, ok = labels[LabelAnnounceNode]; !ok {
+			level.Error(r.Logger).Log("controller", "UPnPStatusReconciler", "object does not have the node name label", o)
+			return false
+		}
+
+		// only trigger the reconciler if the service is forwarded by this node
+		if node != r.NodeName {
+			return false
+		}
+
+		return true
+	})
+
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta1.ServiceUPnPStatus{}, serviceIndexName,
+		func(o client.Object) []string {
+			s, ok := o.(*v1beta1.ServiceUPnPStatus)
+			if s == nil {
+				level.Error(r.Logger).Log("controller", "fieldindexer", "error", "received nil ServiceUPnPStatus")
+				return nil
+			}
+
+			if !ok {
+				level.Error(r.Logger).Log("controller", "fieldindexer", "error", "received object that is not ServiceUPnPStatus", "object", o)
+				return nil
+			}
+
+			labels := s.GetLabels()
+			if labels == nil {
+				level.Error(r.Logger).Log("controller", "fieldindexer", "error", "received ServiceUPnPStatus without labels", "object", o)
+				return nil
+			}
+
+			return []string{indexFor(labels[LabelServiceNamespace], labels[LabelServiceName], labels[LabelAnnounceNode])}
+		})
+
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("serviceupnpstatus").
+		Watches(&v1beta1.ServiceUPnPStatus{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, object client.Object) []reconcile.Request {
+				level.Debug(r.Logger).Log("controller", "UPnPStatusReconciler", "enqueueing", "object", object)
+				labels := object.GetLabels()
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Name:      labels[LabelServiceName],
+					Namespace: labels[LabelServiceNamespace],
+				}}}
+			})).
+		WatchesRawSource(source.Channel(r.ReconcileChan, &handler.EnqueueRequestForObject{})).
+		WithEventFilter(p).
+		Complete(r)
+}
+
+func (r *UPnPStatusReconciler) buildDesiredSpec(
+	status upnp.ServiceStatus,
+	serviceName,
+	serviceNamespace string,
+) (v1beta1.ServiceUPnPStatusSpec, error) {
+	s := v1beta1.ServiceUPnPStatusSpec{
+		Node:             r.NodeName,
+		ServiceName:      serviceName,
+		ServiceNamespace: serviceNamespace,
+	}
+	if status.ExternalIP != nil {
+		s.ExternalIP = status.ExternalIP.String()
+	}
+	for _, g := range status.Gateways {
+		gs := v1beta1.UPnPGatewayStatus{
+			Protocol:     g.Protocol,
+			Location:     g.Location,
+			UDN:          g.UDN,
+			FriendlyName: g.FriendlyName,
+		}
+		if g.ExternalIP != nil {
+			gs.ExternalIP = g.ExternalIP.String()
+		}
+		s.Gateways = append(s.Gateways, gs)
+	}
+	for _, m := range status.Mappings {
+		externalPort, err := safeconvert.IntToInt32(m.ExternalPort)
+		if err != nil {
+			return v1beta1.ServiceUPnPStatusSpec{}, err
+		}
+		internalPort, err := safeconvert.IntToInt32(m.InternalPort)
+		if err != nil {
+			return v1beta1.ServiceUPnPStatusSpec{}, err
+		}
+		duration, err := safeconvert.IntToInt32(m.Duration)
+		if err != nil {
+			return v1beta1.ServiceUPnPStatusSpec{}, err
+		}
+		pm := v1beta1.UPnPPortMapping{
+			ExternalPort: externalPort,
+			InternalPort: internalPort,
+			Protocol:     strings.ToUpper(m.Protocol),
+			Description:  m.Description,
+			Duration:     duration,
+		}
+		if m.InternalIP != nil {
+			pm.InternalIP = m.InternalIP.String()
+		}
+		s.PortMappings = append(s.PortMappings, pm)
+	}
+	// the mappings are kept sorted so that the comparison with the existing
+	// resource does not depend on the order the router was programmed in
+	sort.Slice(s.PortMappings, func(i, j int) bool {
+		if s.PortMappings[i].ExternalPort != s.PortMappings[j].ExternalPort {
+			return s.PortMappings[i].ExternalPort < s.PortMappings[j].ExternalPort
+		}
+		if s.PortMappings[i].Protocol != s.PortMappings[j].Protocol {
+			return s.PortMappings[i].Protocol < s.PortMappings[j].Protocol
+		}
+		return s.PortMappings[i].InternalIP < s.PortMappings[j].InternalIP
+	})
+	return s, nil
+}
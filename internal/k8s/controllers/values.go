@@ -33,6 +33,7 @@ const (
 	LabelAnnounceNode     = "metallb.io/node"
 	LabelServiceName      = "metallb.io/service-name"
 	LabelServiceNamespace = "metallb.io/service-namespace"
+	LabelBGPPeer          = "metallb.io/bgp-peer"
 )
 
 var errRetry = errors.New("event handling failed, retrying")
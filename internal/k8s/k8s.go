@@ -7,6 +7,7 @@ import (
 	"crypto/rand"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -39,6 +40,7 @@ import (
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
@@ -124,8 +126,17 @@ type Config struct {
 	Layer2StatusFetcher controllers.L2StatusFetcher
 	BGPStatusChan       <-chan event.GenericEvent
 	BGPPeersFetcher     controllers.PeersForService
+	BGPAdsFetcher       controllers.AdvertisementsForService
+	UPnPStatusChan      <-chan event.GenericEvent
+	UPnPStatusFetcher   controllers.UPnPStatusFetcher
 	PoolStatusChan      <-chan event.GenericEvent
 	PoolCountersFetcher controllers.PoolCountersFetcher
+	// The BGPSessionStatuses of the node.
+	BGPSessionStatusChan    <-chan event.GenericEvent
+	BGPSessionStatusFetcher controllers.BGPSessionStatusFetcher
+	// Summarize the BGPSessionStatuses of all the nodes in the status
+	// of the BGPPeers.
+	BGPPeerStatus bool
 }
 
 // New connects to masterAddr, using kubeconfig to authenticate.
@@ -138,16 +149,20 @@ func New(cfg *Config) (*Client, error) {
 	}
 
 	objectsPerNamespace := map[client.Object]cache.ByObject{
-		&metallbv1beta1.BFDProfile{}:       namespaceSelector,
-		&metallbv1beta1.BGPAdvertisement{}: namespaceSelector,
-		&metallbv1beta1.BGPPeer{}:          namespaceSelector,
-		&metallbv1beta1.IPAddressPool{}:    namespaceSelector,
-		&metallbv1beta1.L2Advertisement{}:  namespaceSelector,
-		&metallbv1beta2.BGPPeer{}:          namespaceSelector,
-		&metallbv1beta1.Community{}:        namespaceSelector,
-		&metallbv1beta1.ServiceBGPStatus{}: namespaceSelector,
-		&corev1.Secret{}:                   namespaceSelector,
-		&corev1.ConfigMap{}:                namespaceSelector,
+		&metallbv1beta1.BFDProfile{}:           namespaceSelector,
+		&metallbv1beta1.BGPAdvertisement{}:     namespaceSelector,
+		&metallbv1beta1.BGPPeer{}:              namespaceSelector,
+		&metallbv1beta1.IPAddressPool{}:        namespaceSelector,
+		&metallbv1beta1.IPAddressReservation{}: namespaceSelector,
+		&metallbv1beta1.L2Advertisement{}:      namespaceSelector,
+		&metallbv1beta1.UPnPAdvertisement{}:    namespaceSelector,
+		&metallbv1beta2.BGPPeer{}:              namespaceSelector,
+		&metallbv1beta1.Community{}:            namespaceSelector,
+		&metallbv1beta1.ServiceBGPStatus{}:     namespaceSelector,
+		&metallbv1beta1.ServiceUPnPStatus{}:    namespaceSelector,
+		&metallbv1beta1.BGPSessionStatus{}:     namespaceSelector,
+		&corev1.Secret{}:                       namespaceSelector,
+		&corev1.ConfigMap{}:                    namespaceSelector,
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -326,11 +341,67 @@ func New(cfg *Config) (*Client, error) {
 			SpeakerPod:    selfPod.DeepCopy(),
 			ReconcileChan: cfg.BGPStatusChan,
 			PeersFetcher:  cfg.BGPPeersFetcher,
+
+			AdvertisementsFetcher: cfg.BGPAdsFetcher,
 		}).SetupWithManager(mgr); err != nil {
 			level.Error(c.logger).Log("error", err, "unable to create controller", "layer2Status")
 		}
 	}
 
+	if cfg.BGPSessionStatusChan != nil {
+		selfPod, err := clientset.CoreV1().Pods(cfg.Namespace).Get(context.TODO(), cfg.PodName, metav1.GetOptions{})
+		if err != nil {
+			level.Error(c.logger).Log("unable to get speaker pod itself", err)
+			return nil, err
+		}
+		frrK8sNamespace := ""
+		if cfg.WithFRRK8s {
+			frrK8sNamespace = cfg.FRRK8sNamespace
+		}
+		if err = (&controllers.BGPSessionStatusReconciler{
+			Client:          mgr.GetClient(),
+			Logger:          cfg.Logger,
+			NodeName:        cfg.NodeName,
+			Namespace:       cfg.Namespace,
+			SpeakerPod:      selfPod.DeepCopy(),
+			ReconcileChan:   cfg.BGPSessionStatusChan,
+			StatusFetcher:   cfg.BGPSessionStatusFetcher,
+			FRRK8sNamespace: frrK8sNamespace,
+		}).SetupWithManager(mgr); err != nil {
+			level.Error(c.logger).Log("error", err, "unable to create controller", "bgpSessionStatus")
+		}
+	}
+
+	if cfg.BGPPeerStatus {
+		if err = (&controllers.BGPPeerStatusReconciler{
+			Client:    mgr.GetClient(),
+			Logger:    cfg.Logger,
+			Namespace: cfg.Namespace,
+		}).SetupWithManager(mgr); err != nil {
+			level.Error(c.logger).Log("error", err, "unable to create controller", "bgpPeerStatus")
+			return nil, errors.Join(err, errors.New("failed to create bgppeer status reconciler"))
+		}
+	}
+
+	if cfg.UPnPStatusChan != nil {
+		selfPod, err := clientset.CoreV1().Pods(cfg.Namespace).Get(context.TODO(), cfg.PodName, metav1.GetOptions{})
+		if err != nil {
+			level.Error(c.logger).Log("unable to get speaker pod itself", err)
+			return nil, err
+		}
+		if err = (&controllers.UPnPStatusReconciler{
+			Client:        mgr.GetClient(),
+			Logger:        cfg.Logger,
+			NodeName:      cfg.NodeName,
+			Namespace:     cfg.Namespace,
+			SpeakerPod:    selfPod.DeepCopy(),
+			ReconcileChan: cfg.UPnPStatusChan,
+			StatusFetcher: cfg.UPnPStatusFetcher,
+		}).SetupWithManager(mgr); err != nil {
+			level.Error(c.logger).Log("error", err, "unable to create controller", "upnpStatus")
+		}
+	}
+
 	startListeners := make(chan struct{})
 	go func(l log.Logger) {
 		// We start the webhooks and the metric at the same time so the readiness probe will
@@ -469,6 +540,36 @@ func (c *Client) UpdateStatus(svc *corev1.Service) error {
 	return err
 }
 
+// AnnotateService sets the annotation key of svc to value in the Kubernetes
+// cluster, or removes it if value is empty.
+func (c *Client) AnnotateService(svc *corev1.Service, key, value string) error {
+	var v *string
+	if value != "" {
+		v = &value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{key: v},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.client.CoreV1().Services(svc.Namespace).Patch(context.TODO(), svc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// Layer2Holders returns the nodes announcing the given ip of the service in
+// layer2 mode, the most recent holder first.
+func (c *Client) Layer2Holders(svc types.NamespacedName, ip net.IP) []string {
+	holders, err := controllers.Layer2Holders(context.TODO(), c.mgr.GetClient(), svc, ip)
+	if err != nil {
+		level.Error(c.logger).Log("op", "Layer2Holders", "service", svc.String(), "ip", ip, "error", err, "msg", "failed to list the layer2 statuses of the service")
+		return nil
+	}
+	return holders
+}
+
 // Infof logs an informational event about svc to the Kubernetes cluster.
 func (c *Client) Infof(svc *corev1.Service, kind, msg string, args ...interface{}) {
 	c.events.Eventf(svc, corev1.EventTypeNormal, kind, msg, args...)
@@ -30,8 +30,28 @@ func TestValidateIPAddressPool(t *testing.T) {
 		}, nil
 	}
 
+	reservations := &v1beta1.IPAddressReservationList{
+		Items: []v1beta1.IPAddressReservation{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-reservation",
+					Namespace: MetalLBTestNameSpace,
+				},
+				Spec: v1beta1.IPAddressReservationSpec{
+					IPAddressPool: "test-ippool",
+					Addresses:     []string{"10.20.0.1"},
+				},
+			},
+		},
+	}
+	toRestoreReservations := getExistingIPAddressReservations
+	getExistingIPAddressReservations = func() (*v1beta1.IPAddressReservationList, error) {
+		return reservations, nil
+	}
+
 	defer func() {
 		getExistingIPAddressPools = toRestoreIPAddressPools
+		getExistingIPAddressReservations = toRestoreReservations
 	}()
 
 	tests := []struct {
@@ -139,5 +159,8 @@ func TestValidateIPAddressPool(t *testing.T) {
 		if !cmp.Equal(test.expected, mock.ipAddressPools) {
 			t.Fatalf("test %s failed, %s", test.desc, cmp.Diff(test.expected, mock.ipAddressPools))
 		}
+		if test.expected != nil && !cmp.Equal(reservations, mock.reservations) {
+			t.Fatalf("test %s failed, reservations not validated with the pool: %s", test.desc, cmp.Diff(reservations, mock.reservations))
+		}
 	}
 }
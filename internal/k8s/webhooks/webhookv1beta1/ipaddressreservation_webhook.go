@@ -0,0 +1,168 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookv1beta1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/api/v1beta1"
+	v1 "k8s.io/api/admission/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const ipAddressReservationWebhookPath = "/validate-metallb-io-v1beta1-ipaddressreservation"
+
+func (v *IPAddressReservationValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	v.client = mgr.GetClient()
+	v.decoder = admission.NewDecoder(mgr.GetScheme())
+
+	mgr.GetWebhookServer().Register(
+		ipAddressReservationWebhookPath,
+		&webhook.Admission{Handler: v})
+
+	return nil
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-metallb-io-v1beta1-ipaddressreservation,mutating=false,failurePolicy=fail,groups=metallb.io,resources=ipaddressreservations,versions=v1beta1,name=ipaddressreservationvalidationwebhook.metallb.io,sideEffects=None,admissionReviewVersions=v1
+type IPAddressReservationValidator struct {
+	ClusterResourceNamespace string
+
+	client  client.Client
+	decoder admission.Decoder
+}
+
+// Handle handled incoming admission requests for IPAddressReservation objects.
+func (v *IPAddressReservationValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var reservation v1beta1.IPAddressReservation
+	var oldReservation v1beta1.IPAddressReservation
+	if req.Operation == v1.Delete {
+		if err := v.decoder.DecodeRaw(req.OldObject, &reservation); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	} else {
+		if err := v.decoder.Decode(req, &reservation); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if req.OldObject.Size() > 0 {
+			if err := v.decoder.DecodeRaw(req.OldObject, &oldReservation); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+	}
+
+	switch req.Operation {
+	case v1.Create:
+		err := validateIPAddressReservationCreate(&reservation)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	case v1.Update:
+		err := validateIPAddressReservationUpdate(&reservation, &oldReservation)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	case v1.Delete:
+		err := validateIPAddressReservationDelete(&reservation)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	return admission.Allowed("")
+}
+
+// validateIPAddressReservationCreate implements webhook.Validator so a webhook will be registered for IPAddressReservation.
+func validateIPAddressReservationCreate(reservation *v1beta1.IPAddressReservation) error {
+	level.Debug(Logger).Log("webhook", "ipaddressreservation", "action", "create", "name", reservation.Name, "namespace", reservation.Namespace)
+
+	if reservation.Namespace != MetalLBNamespace {
+		return fmt.Errorf("resource must be created in %s namespace", MetalLBNamespace)
+	}
+
+	existingReservations, err := getExistingIPAddressReservations()
+	if err != nil {
+		return err
+	}
+
+	ipAddressPools, err := getExistingIPAddressPools()
+	if err != nil {
+		return err
+	}
+
+	toValidate := ipAddressReservationListWithUpdate(existingReservations, reservation)
+	err = Validator.Validate(toValidate, ipAddressPools)
+	if err != nil {
+		level.Error(Logger).Log("webhook", "ipaddressreservation", "action", "create", "name", reservation.Name, "namespace", reservation.Namespace, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validateIPAddressReservationUpdate implements webhook.Validator so a webhook will be registered for IPAddressReservation.
+func validateIPAddressReservationUpdate(reservation *v1beta1.IPAddressReservation, _ *v1beta1.IPAddressReservation) error {
+	level.Debug(Logger).Log("webhook", "ipaddressreservation", "action", "update", "name", reservation.Name, "namespace", reservation.Namespace)
+
+	existingReservations, err := getExistingIPAddressReservations()
+	if err != nil {
+		return err
+	}
+
+	ipAddressPools, err := getExistingIPAddressPools()
+	if err != nil {
+		return err
+	}
+
+	toValidate := ipAddressReservationListWithUpdate(existingReservations, reservation)
+	err = Validator.Validate(toValidate, ipAddressPools)
+	if err != nil {
+		level.Error(Logger).Log("webhook", "ipaddressreservation", "action", "update", "name", reservation.Name, "namespace", reservation.Namespace, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validateIPAddressReservationDelete implements webhook.Validator so a webhook will be registered for IPAddressReservation.
+func validateIPAddressReservationDelete(reservation *v1beta1.IPAddressReservation) error {
+	return nil
+}
+
+var getExistingIPAddressReservations = func() (*v1beta1.IPAddressReservationList, error) {
+	existingReservationList := &v1beta1.IPAddressReservationList{}
+	err := WebhookClient.List(context.Background(), existingReservationList, &client.ListOptions{Namespace: MetalLBNamespace})
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to get existing IPAddressReservation objects"))
+	}
+	return existingReservationList, nil
+}
+
+func ipAddressReservationListWithUpdate(existing *v1beta1.IPAddressReservationList, toAdd *v1beta1.IPAddressReservation) *v1beta1.IPAddressReservationList {
+	res := existing.DeepCopy()
+	for i, item := range res.Items { // We override the element with the fresh copy
+		if item.Name == toAdd.Name {
+			res.Items[i] = *toAdd.DeepCopy()
+			return res
+		}
+	}
+	res.Items = append(res.Items, *toAdd.DeepCopy())
+	return res
+}
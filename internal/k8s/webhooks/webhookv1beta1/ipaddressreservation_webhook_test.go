@@ -0,0 +1,168 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package webhookv1beta1
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-cmp/cmp"
+	"go.universe.tf/metallb/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateIPAddressReservation(t *testing.T) {
+	MetalLBNamespace = MetalLBTestNameSpace
+	reservation := v1beta1.IPAddressReservation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-reservation",
+			Namespace: MetalLBTestNameSpace,
+		},
+		Spec: v1beta1.IPAddressReservationSpec{
+			IPAddressPool: "test-ippool",
+			Addresses:     []string{"10.20.0.1"},
+		},
+	}
+	pools := &v1beta1.IPAddressPoolList{
+		Items: []v1beta1.IPAddressPool{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-ippool",
+					Namespace: MetalLBTestNameSpace,
+				},
+			},
+		},
+	}
+
+	Logger = log.NewNopLogger()
+
+	toRestore := getExistingIPAddressReservations
+	getExistingIPAddressReservations = func() (*v1beta1.IPAddressReservationList, error) {
+		return &v1beta1.IPAddressReservationList{
+			Items: []v1beta1.IPAddressReservation{
+				reservation,
+			},
+		}, nil
+	}
+	toRestoreIPAddressPools := getExistingIPAddressPools
+	getExistingIPAddressPools = func() (*v1beta1.IPAddressPoolList, error) {
+		return pools, nil
+	}
+
+	defer func() {
+		getExistingIPAddressReservations = toRestore
+		getExistingIPAddressPools = toRestoreIPAddressPools
+	}()
+
+	tests := []struct {
+		desc         string
+		reservation  *v1beta1.IPAddressReservation
+		isNew        bool
+		failValidate bool
+		expected     *v1beta1.IPAddressReservationList
+	}{
+		{
+			desc: "Second reservation",
+			reservation: &v1beta1.IPAddressReservation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: MetalLBTestNameSpace,
+				},
+			},
+			isNew: true,
+			expected: &v1beta1.IPAddressReservationList{
+				Items: []v1beta1.IPAddressReservation{
+					reservation,
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test",
+							Namespace: MetalLBTestNameSpace,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Same, update",
+			reservation: &v1beta1.IPAddressReservation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-reservation",
+					Namespace: MetalLBTestNameSpace,
+				},
+				Spec: v1beta1.IPAddressReservationSpec{
+					IPAddressPool: "test-ippool",
+					Addresses:     []string{"10.20.0.2"},
+				},
+			},
+			isNew: false,
+			expected: &v1beta1.IPAddressReservationList{
+				Items: []v1beta1.IPAddressReservation{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-reservation",
+							Namespace: MetalLBTestNameSpace,
+						},
+						Spec: v1beta1.IPAddressReservationSpec{
+							IPAddressPool: "test-ippool",
+							Addresses:     []string{"10.20.0.2"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Validation fails",
+			reservation: &v1beta1.IPAddressReservation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-reservation",
+					Namespace: MetalLBTestNameSpace,
+				},
+			},
+			isNew: false,
+			expected: &v1beta1.IPAddressReservationList{
+				Items: []v1beta1.IPAddressReservation{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-reservation",
+							Namespace: MetalLBTestNameSpace,
+						},
+					},
+				},
+			},
+			failValidate: true,
+		},
+		{
+			desc: "Validation must fail if created in different namespace",
+			reservation: &v1beta1.IPAddressReservation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-reservation1",
+					Namespace: "default",
+				},
+			},
+			isNew:        true,
+			expected:     nil,
+			failValidate: true,
+		},
+	}
+	for _, test := range tests {
+		var err error
+		mock := &mockValidator{}
+		Validator = mock
+		mock.forceError = test.failValidate
+
+		if test.isNew {
+			err = validateIPAddressReservationCreate(test.reservation)
+		} else {
+			err = validateIPAddressReservationUpdate(test.reservation, nil)
+		}
+		if test.failValidate && err == nil {
+			t.Fatalf("test %s failed, expecting error", test.desc)
+		}
+		if !cmp.Equal(test.expected, mock.reservations) {
+			t.Fatalf("test %s failed, %s", test.desc, cmp.Diff(test.expected, mock.reservations))
+		}
+		if test.expected != nil && !cmp.Equal(pools, mock.ipAddressPools) {
+			t.Fatalf("test %s failed, pools not validated with the reservation: %s", test.desc, cmp.Diff(pools, mock.ipAddressPools))
+		}
+	}
+}
@@ -14,6 +14,8 @@ type mockValidator struct {
 	ipAddressPools *v1beta1.IPAddressPoolList
 	bgpAdvs        *v1beta1.BGPAdvertisementList
 	l2Advs         *v1beta1.L2AdvertisementList
+	upnpAdvs       *v1beta1.UPnPAdvertisementList
+	reservations   *v1beta1.IPAddressReservationList
 	communities    *v1beta1.CommunityList
 	nodes          *v1.NodeList
 	forceError     bool
@@ -26,8 +28,12 @@ func (m *mockValidator) Validate(objects ...client.ObjectList) error {
 			m.bgpAdvs = list
 		case *v1beta1.L2AdvertisementList:
 			m.l2Advs = list
+		case *v1beta1.UPnPAdvertisementList:
+			m.upnpAdvs = list
 		case *v1beta1.IPAddressPoolList:
 			m.ipAddressPools = list
+		case *v1beta1.IPAddressReservationList:
+			m.reservations = list
 		case *v1beta1.CommunityList:
 			m.communities = list
 		case *v1.NodeList:
@@ -0,0 +1,178 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookv1beta1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/log/level"
+	"go.universe.tf/metallb/api/v1beta1"
+	v1 "k8s.io/api/admission/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const upnpAdvertisementWebhookPath = "/validate-metallb-io-v1beta1-upnpadvertisement"
+
+func (v *UPnPAdvertisementValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	v.client = mgr.GetClient()
+	v.decoder = admission.NewDecoder(mgr.GetScheme())
+
+	mgr.GetWebhookServer().Register(
+		upnpAdvertisementWebhookPath,
+		&webhook.Admission{Handler: v})
+
+	return nil
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-metallb-io-v1beta1-upnpadvertisement,mutating=false,failurePolicy=fail,groups=metallb.io,resources=upnpadvertisements,versions=v1beta1,name=upnpadvertisementvalidationwebhook.metallb.io,sideEffects=None,admissionReviewVersions=v1
+type UPnPAdvertisementValidator struct {
+	ClusterResourceNamespace string
+
+	client  client.Client
+	decoder admission.Decoder
+}
+
+// Handle handled incoming admission requests for UPnPAdvertisement objects.
+func (v *UPnPAdvertisementValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var advertisement v1beta1.UPnPAdvertisement
+	var oldAdvertisement v1beta1.UPnPAdvertisement
+	if req.Operation == v1.Delete {
+		if err := v.decoder.DecodeRaw(req.OldObject, &advertisement); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	} else {
+		if err := v.decoder.Decode(req, &advertisement); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if req.OldObject.Size() > 0 {
+			if err := v.decoder.DecodeRaw(req.OldObject, &oldAdvertisement); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+	}
+
+	switch req.Operation {
+	case v1.Create:
+		err := validateUPnPAdvCreate(&advertisement)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	case v1.Update:
+		err := validateUPnPAdvUpdate(&advertisement, &oldAdvertisement)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	case v1.Delete:
+		err := validateUPnPAdvDelete(&advertisement)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	return admission.Allowed("")
+}
+
+// validateUPnPAdvCreate implements webhook.Validator so a webhook will be registered for v1beta1.UPnPAdvertisement.
+func validateUPnPAdvCreate(upnpAdv *v1beta1.UPnPAdvertisement) error {
+	level.Debug(Logger).Log("webhook", "v1beta1.UPnPAdvertisement", "action", "create", "name", upnpAdv.Name, "namespace", upnpAdv.Namespace)
+
+	if upnpAdv.Namespace != MetalLBNamespace {
+		return fmt.Errorf("resource must be created in %s namespace", MetalLBNamespace)
+	}
+
+	existingUPnPAdvList, err := getExistingUPnPAdvs()
+	if err != nil {
+		return err
+	}
+
+	ipAddressPools, err := getExistingIPAddressPools()
+	if err != nil {
+		return err
+	}
+
+	nodes, err := getExistingNodes()
+	if err != nil {
+		return err
+	}
+
+	toValidate := upnpAdvListWithUpdate(existingUPnPAdvList, upnpAdv)
+	err = Validator.Validate(toValidate, ipAddressPools, nodes)
+	if err != nil {
+		level.Error(Logger).Log("webhook", "v1beta1.UPnPAdvertisement", "action", "create", "name", upnpAdv.Name, "namespace", upnpAdv.Namespace, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validateUPnPAdvUpdate implements webhook.Validator so a webhook will be registered for v1beta1.UPnPAdvertisement.
+func validateUPnPAdvUpdate(upnpAdv *v1beta1.UPnPAdvertisement, _ *v1beta1.UPnPAdvertisement) error {
+	level.Debug(Logger).Log("webhook", "v1beta1.UPnPAdvertisement", "action", "update", "name", upnpAdv.Name, "namespace", upnpAdv.Namespace)
+
+	upnpAdvs, err := getExistingUPnPAdvs()
+	if err != nil {
+		return err
+	}
+
+	ipAddressPools, err := getExistingIPAddressPools()
+	if err != nil {
+		return err
+	}
+
+	nodes, err := getExistingNodes()
+	if err != nil {
+		return err
+	}
+
+	toValidate := upnpAdvListWithUpdate(upnpAdvs, upnpAdv)
+	err = Validator.Validate(toValidate, ipAddressPools, nodes)
+	if err != nil {
+		level.Error(Logger).Log("webhook", "v1beta1.UPnPAdvertisement", "action", "update", "name", upnpAdv.Name, "namespace", upnpAdv.Namespace, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validateUPnPAdvDelete implements webhook.Validator so a webhook will be registered for v1beta1.UPnPAdvertisement.
+func validateUPnPAdvDelete(upnpAdv *v1beta1.UPnPAdvertisement) error {
+	return nil
+}
+
+var getExistingUPnPAdvs = func() (*v1beta1.UPnPAdvertisementList, error) {
+	existingUPnPAdvList := &v1beta1.UPnPAdvertisementList{}
+	err := WebhookClient.List(context.Background(), existingUPnPAdvList, &client.ListOptions{Namespace: MetalLBNamespace})
+	if err != nil {
+		return nil, errors.Join(err, errors.New("failed to get existing v1beta1.UPnPAdvertisement objects"))
+	}
+	return existingUPnPAdvList, nil
+}
+
+func upnpAdvListWithUpdate(existing *v1beta1.UPnPAdvertisementList, toAdd *v1beta1.UPnPAdvertisement) *v1beta1.UPnPAdvertisementList {
+	res := existing.DeepCopy()
+	for i, item := range res.Items { // We override the element with the fresh copy
+		if item.Name == toAdd.Name {
+			res.Items[i] = *toAdd.DeepCopy()
+			return res
+		}
+	}
+	res.Items = append(res.Items, *toAdd.DeepCopy())
+	return res
+}
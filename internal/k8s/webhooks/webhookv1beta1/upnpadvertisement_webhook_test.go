@@ -0,0 +1,160 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package webhookv1beta1
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-cmp/cmp"
+	"go.universe.tf/metallb/api/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateUPnPAdvertisement(t *testing.T) {
+	MetalLBNamespace = MetalLBTestNameSpace
+	upnpAdv := v1beta1.UPnPAdvertisement{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-upnpadv",
+			Namespace: MetalLBTestNameSpace,
+		},
+	}
+
+	Logger = log.NewNopLogger()
+
+	toRestore := getExistingUPnPAdvs
+	getExistingUPnPAdvs = func() (*v1beta1.UPnPAdvertisementList, error) {
+		return &v1beta1.UPnPAdvertisementList{
+			Items: []v1beta1.UPnPAdvertisement{
+				upnpAdv,
+			},
+		}, nil
+	}
+	toRestoreIPAddressPools := getExistingIPAddressPools
+	getExistingIPAddressPools = func() (*v1beta1.IPAddressPoolList, error) {
+		return &v1beta1.IPAddressPoolList{}, nil
+	}
+	toRestoreNodes := getExistingNodes
+	getExistingNodes = func() (*v1.NodeList, error) {
+		return &v1.NodeList{}, nil
+	}
+
+	defer func() {
+		getExistingUPnPAdvs = toRestore
+		getExistingIPAddressPools = toRestoreIPAddressPools
+		getExistingNodes = toRestoreNodes
+	}()
+
+	tests := []struct {
+		desc         string
+		upnpAdv      *v1beta1.UPnPAdvertisement
+		isNew        bool
+		failValidate bool
+		expected     *v1beta1.UPnPAdvertisementList
+	}{
+		{
+			desc: "Second Adv",
+			upnpAdv: &v1beta1.UPnPAdvertisement{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: MetalLBTestNameSpace,
+				},
+			},
+			isNew: true,
+			expected: &v1beta1.UPnPAdvertisementList{
+				Items: []v1beta1.UPnPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-upnpadv",
+							Namespace: MetalLBTestNameSpace,
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test",
+							Namespace: MetalLBTestNameSpace,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Same, update",
+			upnpAdv: &v1beta1.UPnPAdvertisement{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-upnpadv",
+					Namespace: MetalLBTestNameSpace,
+				},
+				Spec: v1beta1.UPnPAdvertisementSpec{
+					Duration: 3600,
+				},
+			},
+			isNew: false,
+			expected: &v1beta1.UPnPAdvertisementList{
+				Items: []v1beta1.UPnPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-upnpadv",
+							Namespace: MetalLBTestNameSpace,
+						},
+						Spec: v1beta1.UPnPAdvertisementSpec{
+							Duration: 3600,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Same, new",
+			upnpAdv: &v1beta1.UPnPAdvertisement{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-upnpadv",
+					Namespace: MetalLBTestNameSpace,
+				},
+			},
+			isNew: true,
+			expected: &v1beta1.UPnPAdvertisementList{
+				Items: []v1beta1.UPnPAdvertisement{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-upnpadv",
+							Namespace: MetalLBTestNameSpace,
+						},
+					},
+				},
+			},
+			failValidate: true,
+		},
+		{
+			desc: "Validation must fail if created in different namespace",
+			upnpAdv: &v1beta1.UPnPAdvertisement{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-upnpadv1",
+					Namespace: "default",
+				},
+			},
+			isNew:        true,
+			expected:     nil,
+			failValidate: true,
+		},
+	}
+	for _, test := range tests {
+		var err error
+		mock := &mockValidator{}
+		Validator = mock
+		mock.forceError = test.failValidate
+
+		if test.isNew {
+			err = validateUPnPAdvCreate(test.upnpAdv)
+		} else {
+			err = validateUPnPAdvUpdate(test.upnpAdv, nil)
+		}
+		if test.failValidate && err == nil {
+			t.Fatalf("test %s failed, expecting error", test.desc)
+		}
+		if !cmp.Equal(test.expected, mock.upnpAdvs) {
+			t.Fatalf("test %s failed, %s", test.desc, cmp.Diff(test.expected, mock.upnpAdvs))
+		}
+	}
+}
@@ -51,10 +51,27 @@ func New(l log.Logger, excludeRegexp *regexp.Regexp) (*Announce, error) {
 	return ret, nil
 }
 
+// interfaceScanPeriod is how often the interfaces are listed again when the
+// netlink notifications are not available.
+const interfaceScanPeriod = 10 * time.Second
+
 func (a *Announce) interfaceScan() {
+	// Subscribing first, so that no change happening during the first update
+	// is missed.
+	events, err := interfaceEvents(a.logger)
+	if err != nil {
+		level.Error(a.logger).Log("op", "interfaceScan", "error", err, "msg", "falling back to polling the interfaces")
+	}
+	a.updateInterfaces()
+	for events != nil {
+		if _, ok := <-events; !ok {
+			break
+		}
+		a.updateInterfaces()
+	}
 	for {
+		time.Sleep(interfaceScanPeriod)
 		a.updateInterfaces()
-		time.Sleep(10 * time.Second)
 	}
 }
 
@@ -154,45 +171,91 @@ func (a *Announce) updateInterfaces() {
 	}
 }
 
-func (a *Announce) spamLoop() {
-	// Map IP to spam stop time.
-	type timedSpam struct {
-		until time.Time
-		IPAdvertisement
+// spam is the state of the gratuitous announcements of an IP.
+type spam struct {
+	IPAdvertisement
+	settings GratuitousSettings
+	next     time.Time // when the next announcement is due
+	until    time.Time // when the announcements stop, if Count is not set
+	sent     int
+}
+
+// newSpam returns the state of the announcements of adv starting at now, the
+// first one being sent right away.
+func newSpam(adv IPAdvertisement, now time.Time) *spam {
+	settings := adv.gratuitous.withDefaults()
+	return &spam{
+		IPAdvertisement: adv,
+		settings:        settings,
+		next:            now,
+		until:           now.Add(settings.Duration),
 	}
-	m := map[string]timedSpam{}
-	// We can't create a stopped ticker, so create one with a big period to avoid ticking for nothing
-	ticker := time.NewTicker(time.Hour)
-	ticker.Stop()
+}
+
+// refresh extends the announcements to adv, requested again at now, keeping
+// their cadence.
+func (s *spam) refresh(adv IPAdvertisement, now time.Time) {
+	next := s.next
+	*s = *newSpam(adv, now)
+	s.next = next
+}
+
+// tick tells if an announcement is due at now, and if the announcements are
+// over.
+func (s *spam) tick(now time.Time) (send, done bool) {
+	if now.Before(s.next) {
+		return false, false
+	}
+	if s.settings.Count == 0 && now.After(s.until) {
+		return false, true
+	}
+	s.sent++
+	s.next = now.Add(s.settings.Interval)
+	return true, s.settings.Count > 0 && s.sent >= s.settings.Count
+}
+
+func (a *Announce) spamLoop() {
+	// Map IP to its spam state.
+	m := map[string]*spam{}
+	// We can't create a stopped timer, so create one with a big period to avoid firing for nothing
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
 	for {
+		var now time.Time
 		select {
-		case s := <-a.spamCh:
-			if len(m) == 0 {
-				// See https://github.com/metallb/metallb/issues/172 for the 1100 choice.
-				ticker.Reset(1100 * time.Millisecond)
+		case adv := <-a.spamCh:
+			now = time.Now()
+			ipStr := adv.ip.String()
+			if s, ok := m[ipStr]; ok {
+				s.refresh(adv, now)
+				// Don't spam right away, the cadence of the running announcements is kept.
+				continue
 			}
-			ipStr := s.ip.String()
-			_, ok := m[ipStr]
-			// Set spam stop time to 5 seconds from now.
-			m[ipStr] = timedSpam{time.Now().Add(5 * time.Second), s}
-			if !ok {
-				// Spam right away to avoid waiting up to 1100 milliseconds even if
-				// it means we call gratuitous() twice in a row in a short amount of time.
-				a.gratuitous(s)
+			// Spam right away to avoid waiting for the interval even if it means we
+			// call gratuitous() twice in a row in a short amount of time.
+			m[ipStr] = newSpam(adv, now)
+		case now = <-timer.C:
+		}
+
+		next := time.Time{}
+		for ipStr, s := range m {
+			send, done := s.tick(now)
+			if send {
+				a.gratuitous(s.IPAdvertisement)
 			}
-		case now := <-ticker.C:
-			for ipStr, tSpam := range m {
-				if now.After(tSpam.until) {
-					// We have spammed enough - remove the IP from the map.
-					delete(m, ipStr)
-				} else {
-					a.gratuitous(tSpam.IPAdvertisement)
-				}
+			if done {
+				// We have spammed enough - remove the IP from the map.
+				delete(m, ipStr)
+				continue
 			}
-			if len(m) == 0 {
-				ticker.Stop()
+			if next.IsZero() || s.next.Before(next) {
+				next = s.next
 			}
 		}
+		timer.Stop()
+		if !next.IsZero() {
+			timer.Reset(next.Sub(now))
+		}
 	}
 }
 
@@ -299,17 +362,50 @@ func (a *Announce) DeleteBalancer(name string) {
 	delete(a.ips, name)
 
 	for _, cur := range advs {
-		a.ipRefcnt[cur.ip.String()]--
-		if a.ipRefcnt[cur.ip.String()] > 0 {
-			// Another service is still using this IP, don't touch any
-			// more things.
-			continue
+		a.releaseIP(cur.ip)
+	}
+}
+
+// DeleteBalancerIP deletes a single address of name from the set of
+// addresses we should announce, leaving the others announced.
+func (a *Announce) DeleteBalancerIP(name string, ip net.IP) {
+	a.Lock()
+	defer a.Unlock()
+
+	advs, ok := a.ips[name]
+	if !ok {
+		return
+	}
+	remaining := []IPAdvertisement{}
+	for _, cur := range advs {
+		if !cur.ip.Equal(ip) {
+			remaining = append(remaining, cur)
 		}
+	}
+	if len(remaining) == len(advs) {
+		return
+	}
+	if len(remaining) == 0 {
+		delete(a.ips, name)
+	} else {
+		a.ips[name] = remaining
+	}
+	a.releaseIP(ip)
+}
 
-		for _, client := range a.ndps {
-			if err := client.Unwatch(cur.ip); err != nil {
-				level.Error(a.logger).Log("op", "unwatchMulticastGroup", "error", err, "ip", cur.ip, "interface", client.intf, "msg", "failed to unwatch NDP multicast group for IP")
-			}
+// releaseIP drops a use of ip, and stops watching its NDP multicast group
+// when no service uses it anymore. Must be called with the lock held.
+func (a *Announce) releaseIP(ip net.IP) {
+	a.ipRefcnt[ip.String()]--
+	if a.ipRefcnt[ip.String()] > 0 {
+		// Another service is still using this IP, don't touch any
+		// more things.
+		return
+	}
+
+	for _, client := range a.ndps {
+		if err := client.Unwatch(ip); err != nil {
+			level.Error(a.logger).Log("op", "unwatchMulticastGroup", "error", err, "ip", ip, "interface", client.intf, "msg", "failed to unwatch NDP multicast group for IP")
 		}
 	}
 }
@@ -5,6 +5,7 @@ package layer2
 import (
 	"net"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 )
@@ -62,3 +63,91 @@ func Test_SetBalancer_AddsToAnnouncedServices(t *testing.T) {
 		t.Fatalf("ip 192.168.1.20 has not 2 refcnt: %d", announce.ipRefcnt["192.168.1.20"])
 	}
 }
+
+func Test_DeleteBalancerIP_KeepsOtherIPs(t *testing.T) {
+	announce := &Announce{
+		ips:      map[string][]IPAdvertisement{},
+		ipRefcnt: map[string]int{},
+		spamCh:   make(chan IPAdvertisement, 1),
+	}
+
+	ip1 := net.IPv4(192, 168, 1, 20)
+	ip2 := net.IPv4(192, 168, 1, 21)
+	for _, ip := range []net.IP{ip1, ip2} {
+		announce.SetBalancer("foo", NewIPAdvertisement(ip, true, sets.Set[string]{}))
+		// We need to empty spamCh as spamLoop() is not started.
+		<-announce.spamCh
+	}
+
+	announce.DeleteBalancerIP("foo", ip1)
+	if len(announce.ips["foo"]) != 1 || !announce.ips["foo"][0].ip.Equal(ip2) {
+		t.Fatalf("service foo should announce only %s: %v", ip2, announce.ips["foo"])
+	}
+	if announce.ipRefcnt[ip1.String()] != 0 {
+		t.Fatalf("ip %s has not 0 refcnt: %d", ip1, announce.ipRefcnt[ip1.String()])
+	}
+
+	announce.DeleteBalancerIP("foo", ip2)
+	if announce.AnnounceName("foo") {
+		t.Fatal("service foo is still announced")
+	}
+}
+
+func TestSpamSchedule(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	tests := []struct {
+		desc     string
+		settings GratuitousSettings
+		// the ticks, and whether an announcement is sent on each
+		ticks    []time.Time
+		wantSent []bool
+		// the index of the tick after which the announcements are over
+		wantDone int
+	}{
+		{
+			desc:     "defaults",
+			settings: GratuitousSettings{},
+			ticks:    []time.Time{at(0), at(1100), at(2200), at(3300), at(4400), at(5500)},
+			wantSent: []bool{true, true, true, true, true, false},
+			wantDone: 5,
+		},
+		{
+			desc:     "custom interval and duration",
+			settings: GratuitousSettings{Interval: 300 * time.Millisecond, Duration: time.Second},
+			ticks:    []time.Time{at(0), at(300), at(600), at(900), at(1200)},
+			wantSent: []bool{true, true, true, true, false},
+			wantDone: 4,
+		},
+		{
+			desc:     "count",
+			settings: GratuitousSettings{Interval: 10 * time.Second, Count: 3},
+			ticks:    []time.Time{at(0), at(5000), at(10000), at(20000)},
+			wantSent: []bool{true, false, true, true},
+			wantDone: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			adv := NewIPAdvertisement(net.IPv4(192, 168, 1, 20), true, sets.Set[string]{}).WithGratuitous(test.settings)
+			s := newSpam(adv, start)
+			for i, now := range test.ticks {
+				send, done := s.tick(now)
+				if send != test.wantSent[i] {
+					t.Fatalf("tick %d: got send %v, want %v", i, send, test.wantSent[i])
+				}
+				if done != (i == test.wantDone) {
+					t.Fatalf("tick %d: got done %v", i, done)
+				}
+				if done {
+					return
+				}
+			}
+			t.Fatalf("announcements not over after %d ticks", len(test.ticks))
+		})
+	}
+}
@@ -4,6 +4,7 @@ package layer2
 
 import (
 	"net"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 )
@@ -13,6 +14,38 @@ type IPAdvertisement struct {
 	ip            net.IP
 	interfaces    sets.Set[string]
 	allInterfaces bool
+	gratuitous    GratuitousSettings
+}
+
+const (
+	// DefaultGratuitousInterval is the interval between the gratuitous
+	// announcements. See https://github.com/metallb/metallb/issues/172 for
+	// the 1100 choice.
+	DefaultGratuitousInterval = 1100 * time.Millisecond
+	// DefaultGratuitousDuration is for how long the gratuitous announcements
+	// are sent.
+	DefaultGratuitousDuration = 5 * time.Second
+)
+
+// GratuitousSettings tune the gratuitous ARP / NDP announcements sent when
+// the node starts announcing an IP. Zero values mean the defaults.
+type GratuitousSettings struct {
+	// Interval between two announcements.
+	Interval time.Duration
+	// Duration is for how long the announcements are sent.
+	Duration time.Duration
+	// Count is the number of announcements sent, in place of Duration.
+	Count int
+}
+
+func (g GratuitousSettings) withDefaults() GratuitousSettings {
+	if g.Interval == 0 {
+		g.Interval = DefaultGratuitousInterval
+	}
+	if g.Duration == 0 && g.Count == 0 {
+		g.Duration = DefaultGratuitousDuration
+	}
+	return g
 }
 
 func NewIPAdvertisement(ip net.IP, allInterfaces bool, interfaces sets.Set[string]) IPAdvertisement {
@@ -23,6 +56,13 @@ func NewIPAdvertisement(ip net.IP, allInterfaces bool, interfaces sets.Set[strin
 	}
 }
 
+// WithGratuitous returns a copy of the advertisement sending the gratuitous
+// announcements according to the given settings.
+func (i IPAdvertisement) WithGratuitous(settings GratuitousSettings) IPAdvertisement {
+	i.gratuitous = settings
+	return i
+}
+
 func (i *IPAdvertisement) Equal(other *IPAdvertisement) bool {
 	if i == nil && other == nil {
 		return true
@@ -36,6 +76,9 @@ func (i *IPAdvertisement) Equal(other *IPAdvertisement) bool {
 	if i.allInterfaces != other.allInterfaces {
 		return false
 	}
+	if i.gratuitous != other.gratuitous {
+		return false
+	}
 	if i.allInterfaces {
 		return true
 	}
@@ -63,6 +106,10 @@ func (i *IPAdvertisement) matchInterface(intf string) bool {
 	}
 	return i.interfaces.Has(intf)
 }
+func (i *IPAdvertisement) GetIP() net.IP {
+	return i.ip
+}
+
 func (i *IPAdvertisement) IsAllInterfaces() bool {
 	return i.allInterfaces
 }
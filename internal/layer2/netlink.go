@@ -0,0 +1,55 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package layer2
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"golang.org/x/sys/unix"
+)
+
+// interfaceEvents subscribes to the netlink notifications of the changes of
+// the links and of their addresses, and returns a channel receiving a value
+// after any of them. The notifications are coalesced, a single value is
+// pending at most.
+func interfaceEvents(l log.Logger) (<-chan struct{}, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+	addr := &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	}
+	if err := unix.Bind(fd, addr); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to subscribe to netlink link and address notifications: %w", err)
+	}
+
+	events := make(chan struct{}, 1)
+	go func() {
+		defer unix.Close(fd)
+		buf := make([]byte, unix.Getpagesize())
+		for {
+			_, _, err := unix.Recvfrom(fd, buf, 0)
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			// ENOBUFS means notifications were lost, which requires a
+			// resync as much as any of them.
+			if err != nil && !errors.Is(err, unix.ENOBUFS) {
+				level.Error(l).Log("op", "interfaceEvents", "error", err, "msg", "failed to read netlink notifications, falling back to polling the interfaces")
+				close(events)
+				return
+			}
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return events, nil
+}
@@ -14,7 +14,9 @@ package speakerlist
 
 import (
 	"crypto/sha256"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +30,8 @@ import (
 // SpeakerListInfo contains information about available speaker nodes.
 type SpeakerListInfo struct {
 	Nodes map[string]bool
+	// Tags contains the tags advertised by each node, see SetTags.
+	Tags map[string][]string
 	// Disabled true indicates that the memberlist protocol is off
 	Disabled bool
 }
@@ -48,6 +52,41 @@ type SpeakerList struct {
 
 	mlMux        sync.Mutex // Mutex for mlSpeakerIPs.
 	mlSpeakerIPs []string   // Speaker pod IPs.
+
+	tags *tagsDelegate
+}
+
+// tagsDelegate advertises the tags of this speaker as its memberlist node
+// metadata.
+type tagsDelegate struct {
+	l    log.Logger
+	mux  sync.Mutex
+	tags []string
+}
+
+func (d *tagsDelegate) NodeMeta(limit int) []byte {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	meta := strings.Join(d.tags, ",")
+	if len(meta) > limit {
+		level.Error(d.l).Log("op", "memberlist", "msg", "speaker tags exceed the memberlist metadata limit, truncating", "tags", meta, "limit", limit)
+		meta = meta[:strings.LastIndex(meta[:limit+1], ",")+1]
+		meta = strings.TrimSuffix(meta, ",")
+	}
+	return []byte(meta)
+}
+
+func (d *tagsDelegate) NotifyMsg([]byte)                           {}
+func (d *tagsDelegate) GetBroadcasts(overhead, limit int) [][]byte { return nil }
+func (d *tagsDelegate) LocalState(join bool) []byte                { return nil }
+func (d *tagsDelegate) MergeRemoteState(buf []byte, join bool)     {}
+
+func parseTags(meta []byte) []string {
+	if len(meta) == 0 {
+		return nil
+	}
+	return strings.Split(string(meta), ",")
 }
 
 // New creates a new SpeakerList and returns a pointer to it.
@@ -103,6 +142,9 @@ func New(logger log.Logger, nodeName, bindAddr, bindPort, secret, namespace, lab
 	sl.mlEventCh = make(chan memberlist.NodeEvent, 1024)
 	mconfig.Events = &memberlist.ChannelEventDelegate{Ch: sl.mlEventCh}
 
+	sl.tags = &tagsDelegate{l: logger}
+	mconfig.Delegate = sl.tags
+
 	ml, err := memberlist.Create(mconfig)
 	if err != nil {
 		level.Error(logger).Log("op", "startup", "error", err, "msg", "failed to create memberlist")
@@ -297,16 +339,48 @@ func (sl *SpeakerList) UsableSpeakers() SpeakerListInfo {
 	}
 
 	activeNodes := map[string]bool{}
+	tags := map[string][]string{}
 	for _, n := range sl.ml.Members() {
 		activeNodes[n.Name] = true
+		tags[n.Name] = parseTags(n.Meta)
 	}
 
 	return SpeakerListInfo{
 		Nodes:    activeNodes,
+		Tags:     tags,
 		Disabled: false,
 	}
 }
 
+// SetTags sets the tags this speaker advertises to the other speakers, for
+// example the gateways it can reach. The tags must not contain commas. A
+// change is propagated in the background, and the other speakers resync
+// their services when they receive it.
+func (sl *SpeakerList) SetTags(tags []string) {
+	if sl.ml == nil {
+		return
+	}
+
+	sorted := slices.Clone(tags)
+	slices.Sort(sorted)
+
+	sl.tags.mux.Lock()
+	if slices.Equal(sl.tags.tags, sorted) {
+		sl.tags.mux.Unlock()
+		return
+	}
+	sl.tags.tags = sorted
+	sl.tags.mux.Unlock()
+
+	go func() {
+		if err := sl.ml.UpdateNode(5 * time.Second); err != nil {
+			level.Error(sl.l).Log("op", "memberlist", "msg", "failed to propagate speaker tags", "tags", strings.Join(sorted, ","), "error", err)
+			return
+		}
+		level.Info(sl.l).Log("op", "memberlist", "msg", "propagated speaker tags", "tags", strings.Join(sorted, ","))
+	}()
+}
+
 // Stop stops the SpeakerList.
 func (sl *SpeakerList) Stop() {
 	if sl.ml == nil {
@@ -3,22 +3,30 @@
 package upnp
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/huin/goupnp"
 	"github.com/huin/goupnp/dcps/internetgateway1"
 	"github.com/huin/goupnp/dcps/internetgateway2"
 )
 
 // IGDClient provides UPnP IGD (Internet Gateway Device) functionality
 type IGDClient struct {
-	logger     log.Logger
-	client1    *internetgateway1.WANIPConnection1
-	client2    *internetgateway2.WANIPConnection1
-	version    int
+	logger  log.Logger
+	client1 *internetgateway1.WANIPConnection1
+	client2 *internetgateway2.WANIPConnection1
+	version int
+	device  Device
+	// mutex guards externalIP, refreshed while the mappings are programmed.
+	mutex      sync.Mutex
 	externalIP net.IP
 }
 
@@ -32,62 +40,135 @@ type PortMapping struct {
 	Duration     int // Duration in seconds, 0 means permanent
 }
 
-// New creates a new UPnP IGD client
-func New(logger log.Logger) (*IGDClient, error) {
-	client := &IGDClient{
-		logger: logger,
-	}
+// ServiceStatus describes the port mappings programmed on the gateways
+// on behalf of a service.
+type ServiceStatus struct {
+	ExternalIP net.IP
+	Gateways   []GatewayStatus
+	Mappings   []PortMapping
+}
 
-	// Try IGD2 first (more modern)
-	if err := client.discoverIGD2(); err != nil {
-		level.Debug(logger).Log("msg", "IGD2 discovery failed, trying IGD1", "error", err)
+// GatewayStatus describes a gateway the port mappings are programmed on.
+type GatewayStatus struct {
+	Device
+	ExternalIP net.IP
+}
 
-		// Fallback to IGD1
-		if err := client.discoverIGD1(); err != nil {
-			return nil, fmt.Errorf("failed to discover UPnP IGD device: %v", err)
-		}
+// New creates a client for the UPnP IGD device matching the selector. When
+// several devices match, the first one by UDN is picked.
+func New(logger log.Logger, selector DeviceSelector) (*IGDClient, error) {
+	clients, err := NewIGDClients(logger, selector)
+	if err != nil {
+		return nil, err
 	}
-
-	// Get external IP address
-	if err := client.updateExternalIP(); err != nil {
-		level.Warn(logger).Log("msg", "failed to get external IP address", "error", err)
+	if len(clients) > 1 {
+		level.Warn(logger).Log("msg", "several UPnP IGD devices match, programming the first one", "count", len(clients), "udn", clients[0].device.UDN, "location", clients[0].device.Location)
 	}
-
-	return client, nil
+	return clients[0], nil
 }
 
-// discoverIGD2 attempts to discover IGD2 devices
-func (c *IGDClient) discoverIGD2() error {
-	clients, _, err := internetgateway2.NewWANIPConnection1Clients()
+// NewIGDClients creates a client for every UPnP IGD device matching the
+// selector, sorted by UDN. The devices are discovered via SSDP, unless the
+// selector has a location, in which case only that device is contacted.
+func NewIGDClients(logger log.Logger, selector DeviceSelector) ([]*IGDClient, error) {
+	candidates, err := discoverIGDs(logger, selector.Location)
 	if err != nil {
-		return fmt.Errorf("failed to discover IGD2 clients: %v", err)
+		return nil, err
+	}
+	for _, c := range candidates {
+		if err := c.updateExternalIP(); err != nil {
+			level.Warn(logger).Log("msg", "failed to get external IP address", "location", c.device.Location, "error", err)
+		}
 	}
 
+	clients := selectIGDs(candidates, selector)
 	if len(clients) == 0 {
-		return fmt.Errorf("no IGD2 devices found")
+		return nil, fmt.Errorf("none of the %d discovered UPnP IGD devices matches %s", len(candidates), selector)
 	}
-
-	c.client2 = clients[0]
-	c.version = 2
-	level.Info(c.logger).Log("msg", "discovered UPnP IGD2 device", "location", c.client2.Location)
-	return nil
+	for _, c := range clients {
+		level.Info(logger).Log("msg", "selected UPnP IGD device", "version", c.version, "location", c.device.Location, "udn", c.device.UDN, "name", c.device.FriendlyName, "external_ip", c.externalIP)
+		if c.externalIP != nil {
+			stats.ExternalIP(c.device.Key(), c.externalIP)
+		}
+	}
+	return clients, nil
 }
 
-// discoverIGD1 attempts to discover IGD1 devices
-func (c *IGDClient) discoverIGD1() error {
-	clients, _, err := internetgateway1.NewWANIPConnection1Clients()
-	if err != nil {
-		return fmt.Errorf("failed to discover IGD1 clients: %v", err)
+// discoverIGDs returns a client for every UPnP IGD device found via SSDP, or
+// for the device described at location if not empty. IGD2 clients are
+// preferred over IGD1 ones for the devices answering both searches.
+func discoverIGDs(logger log.Logger, location string) ([]*IGDClient, error) {
+	var clients2 []*internetgateway2.WANIPConnection1
+	var clients1 []*internetgateway1.WANIPConnection1
+	var err2, err1 error
+	if location != "" {
+		loc, err := url.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid UPnP IGD location %q: %v", location, err)
+		}
+		clients2, err2 = internetgateway2.NewWANIPConnection1ClientsByURL(loc)
+		clients1, err1 = internetgateway1.NewWANIPConnection1ClientsByURL(loc)
+	} else {
+		clients2, _, err2 = internetgateway2.NewWANIPConnection1Clients()
+		clients1, _, err1 = internetgateway1.NewWANIPConnection1Clients()
+	}
+	if err2 != nil {
+		level.Debug(logger).Log("msg", "IGD2 discovery failed", "error", err2)
+	}
+	if err1 != nil {
+		level.Debug(logger).Log("msg", "IGD1 discovery failed", "error", err1)
 	}
 
-	if len(clients) == 0 {
-		return fmt.Errorf("no IGD1 devices found")
+	var res []*IGDClient
+	seen := map[Device]bool{}
+	for _, c := range clients2 {
+		client := &IGDClient{logger: logger, client2: c, version: 2, device: deviceFor(c.ServiceClient)}
+		if !seen[client.device] {
+			seen[client.device] = true
+			res = append(res, client)
+		}
+	}
+	for _, c := range clients1 {
+		client := &IGDClient{logger: logger, client1: c, version: 1, device: deviceFor(c.ServiceClient)}
+		if !seen[client.device] {
+			seen[client.device] = true
+			res = append(res, client)
+		}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("failed to discover UPnP IGD device: %v", errors.Join(err2, err1, fmt.Errorf("no IGD devices found")))
 	}
+	return res, nil
+}
 
-	c.client1 = clients[0]
-	c.version = 1
-	level.Info(c.logger).Log("msg", "discovered UPnP IGD1 device", "location", c.client1.Location)
-	return nil
+func deviceFor(c goupnp.ServiceClient) Device {
+	d := Device{Protocol: ProtocolUPnP}
+	if c.Location != nil {
+		d.Location = c.Location.String()
+	}
+	if c.RootDevice != nil {
+		d.UDN = c.RootDevice.Device.UDN
+		d.FriendlyName = c.RootDevice.Device.FriendlyName
+	}
+	return d
+}
+
+// selectIGDs returns the clients whose device matches the selector, sorted by
+// UDN so that the choice does not depend on the discovery order.
+func selectIGDs(clients []*IGDClient, selector DeviceSelector) []*IGDClient {
+	var res []*IGDClient
+	for _, c := range clients {
+		if selector.Matches(c.device, c.externalIP) {
+			res = append(res, c)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].device.UDN != res[j].device.UDN {
+			return res[i].device.UDN < res[j].device.UDN
+		}
+		return res[i].device.Location < res[j].device.Location
+	})
+	return res
 }
 
 // updateExternalIP retrieves and caches the external IP address
@@ -107,17 +188,24 @@ func (c *IGDClient) updateExternalIP() error {
 		return fmt.Errorf("failed to get external IP: %v", err)
 	}
 
-	c.externalIP = net.ParseIP(externalIP)
-	if c.externalIP == nil {
+	ip := net.ParseIP(externalIP)
+	if ip == nil {
 		return fmt.Errorf("invalid external IP address: %s", externalIP)
 	}
 
-	level.Info(c.logger).Log("msg", "retrieved external IP address", "ip", c.externalIP.String())
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !ip.Equal(c.externalIP) {
+		level.Info(c.logger).Log("msg", "retrieved external IP address", "ip", ip.String())
+	}
+	c.externalIP = ip
 	return nil
 }
 
 // GetExternalIP returns the external IP address
 func (c *IGDClient) GetExternalIP() net.IP {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	return c.externalIP
 }
 
@@ -170,6 +258,7 @@ func (c *IGDClient) AddPortMapping(mapping *PortMapping) error {
 	}
 
 	if err != nil {
+		stats.MappingError("add", err)
 		return fmt.Errorf("failed to add port mapping: %v", err)
 	}
 
@@ -225,6 +314,7 @@ func (c *IGDClient) DeletePortMapping(externalPort int, protocol string) error {
 			)
 			return nil
 		}
+		stats.MappingError("delete", err)
 		return fmt.Errorf("failed to delete port mapping: %v", err)
 	}
 
@@ -300,7 +390,16 @@ func (c *IGDClient) GetPortMappings() ([]*PortMapping, error) {
 
 // RefreshExternalIP updates the cached external IP address
 func (c *IGDClient) RefreshExternalIP() error {
-	return c.updateExternalIP()
+	if err := c.updateExternalIP(); err != nil {
+		return err
+	}
+	stats.ExternalIP(c.device.Key(), c.GetExternalIP())
+	return nil
+}
+
+// Device returns the identity of the IGD device
+func (c *IGDClient) Device() Device {
+	return c.device
 }
 
 // IsAvailable checks if the UPnP IGD client is available and functional
@@ -322,8 +421,8 @@ func (c *IGDClient) GetDeviceInfo() map[string]interface{} {
 		info["device_url"] = c.client1.ServiceClient.RootDevice.URLBase.String()
 	}
 
-	if c.externalIP != nil {
-		info["external_ip"] = c.externalIP.String()
+	if externalIP := c.GetExternalIP(); externalIP != nil {
+		info["external_ip"] = externalIP.String()
 	}
 
 	return info
@@ -3,10 +3,15 @@
 package upnp
 
 import (
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/go-kit/log"
+	"github.com/huin/goupnp/soap"
+	ptu "github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestPortMapping(t *testing.T) {
@@ -50,7 +55,7 @@ func TestNewIGDClient(t *testing.T) {
 
 	// This test will likely fail if no UPnP device is available
 	// but we test the function signature and basic error handling
-	_, err := New(logger)
+	_, err := New(logger, DeviceSelector{})
 
 	// We expect this to fail in most test environments
 	// as there won't be a UPnP IGD device available
@@ -216,3 +221,172 @@ func TestGetExternalIP(t *testing.T) {
 		t.Errorf("Expected external IP 203.0.113.1, got %s", ip.String())
 	}
 }
+
+func TestDeviceSelector(t *testing.T) {
+	device := Device{
+		Protocol:     ProtocolUPnP,
+		Location:     "http://192.168.1.1:5000/rootDesc.xml",
+		UDN:          "uuid:11111111-2222-3333-4444-555555555555",
+		FriendlyName: "Main Router",
+	}
+	externalIP := net.ParseIP("203.0.113.7")
+
+	tests := []struct {
+		desc     string
+		selector DeviceSelector
+		matches  bool
+	}{
+		{"empty selector", DeviceSelector{}, true},
+		{"location", DeviceSelector{Location: "http://192.168.1.1:5000/rootDesc.xml"}, true},
+		{"other location", DeviceSelector{Location: "http://192.168.1.2:5000/rootDesc.xml"}, false},
+		{"udn without prefix and different case", DeviceSelector{UDN: "11111111-2222-3333-4444-555555555555"}, true},
+		{"other udn", DeviceSelector{UDN: "uuid:99999999-2222-3333-4444-555555555555"}, false},
+		{"friendly name", DeviceSelector{FriendlyName: "Main Router"}, true},
+		{"other friendly name", DeviceSelector{FriendlyName: "Printer"}, false},
+		{"external ip", DeviceSelector{ExternalIP: "203.0.113.7"}, true},
+		{"other external ip", DeviceSelector{ExternalIP: "203.0.113.8"}, false},
+		{"all fields must match", DeviceSelector{FriendlyName: "Main Router", ExternalIP: "203.0.113.8"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.selector.Matches(device, externalIP); got != test.matches {
+				t.Fatalf("expected match %v, got %v", test.matches, got)
+			}
+		})
+	}
+}
+
+func TestSelectIGDs(t *testing.T) {
+	client := func(udn, name, externalIP string) *IGDClient {
+		return &IGDClient{
+			device:     Device{Protocol: ProtocolUPnP, Location: "http://" + name, UDN: udn, FriendlyName: name},
+			externalIP: net.ParseIP(externalIP),
+		}
+	}
+	// discovery order is not stable
+	clients := []*IGDClient{
+		client("uuid:3", "printer", ""),
+		client("uuid:2", "mesh", "203.0.113.2"),
+		client("uuid:1", "router", "203.0.113.1"),
+	}
+
+	selected := selectIGDs(clients, DeviceSelector{})
+	if len(selected) != 3 || selected[0].device.UDN != "uuid:1" || selected[2].device.UDN != "uuid:3" {
+		t.Fatalf("expected all devices sorted by UDN, got %v", selected)
+	}
+
+	selected = selectIGDs(clients, DeviceSelector{ExternalIP: "203.0.113.2"})
+	if len(selected) != 1 || selected[0].device.FriendlyName != "mesh" {
+		t.Fatalf("expected the mesh device, got %v", selected)
+	}
+
+	if selected = selectIGDs(clients, DeviceSelector{FriendlyName: "nas"}); len(selected) != 0 {
+		t.Fatalf("expected no device, got %v", selected)
+	}
+}
+
+const testRootDesc = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:2</deviceType>
+    <friendlyName>Test Router</friendlyName>
+    <UDN>uuid:11111111-2222-3333-4444-555555555555</UDN>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:2</deviceType>
+        <UDN>uuid:11111111-2222-3333-4444-000000000001</UDN>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:2</deviceType>
+            <UDN>uuid:11111111-2222-3333-4444-000000000002</UDN>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <serviceId>urn:upnp-org:serviceId:WANIPConn1</serviceId>
+                <SCPDURL>/scpd.xml</SCPDURL>
+                <controlURL>/ctl</controlURL>
+                <eventSubURL>/evt</eventSubURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+const testExternalIPResponse = `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+  <s:Body>
+    <u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">
+      <NewExternalIPAddress>203.0.113.9</NewExternalIPAddress>
+    </u:GetExternalIPAddressResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestNewIGDClientsByLocation(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rootDesc.xml", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, testRootDesc)
+	})
+	mux.HandleFunc("/ctl", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+		fmt.Fprint(w, testExternalIPResponse)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	location := server.URL + "/rootDesc.xml"
+
+	clients, err := NewIGDClients(log.NewNopLogger(), DeviceSelector{Location: location, FriendlyName: "Test Router"})
+	if err != nil {
+		t.Fatalf("failed to create clients: %v", err)
+	}
+	// The device answers both the IGD2 and the IGD1 lookups, it must be programmed once.
+	if len(clients) != 1 {
+		t.Fatalf("expected one client, got %d", len(clients))
+	}
+	expected := Device{
+		Protocol:     ProtocolUPnP,
+		Location:     location,
+		UDN:          "uuid:11111111-2222-3333-4444-555555555555",
+		FriendlyName: "Test Router",
+	}
+	if clients[0].Device() != expected {
+		t.Fatalf("expected device %+v, got %+v", expected, clients[0].Device())
+	}
+	if !clients[0].GetExternalIP().Equal(net.ParseIP("203.0.113.9")) {
+		t.Fatalf("expected external IP 203.0.113.9, got %s", clients[0].GetExternalIP())
+	}
+	if value := ptu.ToFloat64(stats.gatewayInfo.WithLabelValues(expected.Key(), "203.0.113.9")); value != 1 {
+		t.Fatalf("expected the external IP to be exported, got %v", value)
+	}
+
+	_, err = NewIGDClients(log.NewNopLogger(), DeviceSelector{Location: location, ExternalIP: "203.0.113.10"})
+	if err == nil {
+		t.Fatalf("expected no device to match another external IP")
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	conflict := &soap.SOAPFaultError{FaultCode: "s:Client", FaultString: "UPnPError"}
+	conflict.Detail.UPnPError.Errorcode = 718
+
+	tests := []struct {
+		desc     string
+		err      error
+		expected string
+	}{
+		{"UPnP error", fmt.Errorf("AddPortMapping: %w", conflict), "718"},
+		{"PCP result", pcpError(11), "11"},
+		{"NAT-PMP result", natpmpError(2), "2"},
+		{"timeout", fmt.Errorf("no response from gateway"), "transport"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if code := errorCode(test.err); code != test.expected {
+				t.Fatalf("expected code %q, got %q", test.expected, code)
+			}
+		})
+	}
+}
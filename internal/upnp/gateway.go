@@ -0,0 +1,168 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package upnp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-kit/log"
+)
+
+const (
+	// ProtocolUPnP programs the gateway via UPnP IGD1/IGD2, discovered with SSDP.
+	ProtocolUPnP = "UPnP"
+	// ProtocolNATPMP programs the gateway via NAT-PMP (RFC 6886).
+	ProtocolNATPMP = "NAT-PMP"
+	// ProtocolPCP programs the gateway via PCP (RFC 6887), falling back to
+	// NAT-PMP when the gateway does not support it.
+	ProtocolPCP = "PCP"
+)
+
+// Gateway is a device that forwards ports of its external address to
+// internal endpoints.
+type Gateway interface {
+	// AddPortMapping creates or refreshes a port mapping. The external port
+	// of the mapping is the one requested from the gateway, which fails if it
+	// can not be granted.
+	AddPortMapping(mapping *PortMapping) error
+	// DeletePortMapping removes the mapping of the given external port.
+	DeletePortMapping(externalPort int, protocol string) error
+	// GetPortMappings returns the enabled port mappings programmed on the
+	// gateway, with the remaining lease time as Duration (0 means permanent).
+	GetPortMappings() ([]*PortMapping, error)
+	// GetExternalIP returns the last known external IP of the gateway.
+	GetExternalIP() net.IP
+	// RefreshExternalIP reads the external IP from the gateway again.
+	RefreshExternalIP() error
+	// Device returns the identity of the gateway.
+	Device() Device
+}
+
+var _ Gateway = &IGDClient{}
+var _ Gateway = &PCPClient{}
+
+// Device identifies a gateway.
+type Device struct {
+	Protocol string
+	// Location is the URL of the device description for UPnP, and the
+	// address of the gateway for NAT-PMP and PCP.
+	Location     string
+	UDN          string
+	FriendlyName string
+}
+
+// Key returns a string identifying the device among the others.
+func (d Device) Key() string {
+	if d.UDN != "" {
+		return d.Protocol + "/" + d.UDN
+	}
+	return d.Protocol + "/" + d.Location
+}
+
+// DeviceSelector picks UPnP IGD devices among the discovered ones. The empty
+// fields match any device.
+type DeviceSelector struct {
+	Location     string
+	UDN          string
+	FriendlyName string
+	ExternalIP   string
+}
+
+// Matches tells if the device, whose external IP is externalIP, is selected.
+func (s DeviceSelector) Matches(d Device, externalIP net.IP) bool {
+	if s.Location != "" && s.Location != d.Location {
+		return false
+	}
+	if s.UDN != "" && !strings.EqualFold(strings.TrimPrefix(s.UDN, "uuid:"), strings.TrimPrefix(d.UDN, "uuid:")) {
+		return false
+	}
+	if s.FriendlyName != "" && s.FriendlyName != d.FriendlyName {
+		return false
+	}
+	if s.ExternalIP != "" && !net.ParseIP(s.ExternalIP).Equal(externalIP) {
+		return false
+	}
+	return true
+}
+
+func (s DeviceSelector) String() string {
+	var res []string
+	for _, f := range []struct{ name, value string }{
+		{"location", s.Location},
+		{"udn", s.UDN},
+		{"friendlyName", s.FriendlyName},
+		{"externalIP", s.ExternalIP},
+	} {
+		if f.value != "" {
+			res = append(res, fmt.Sprintf("%s=%q", f.name, f.value))
+		}
+	}
+	if len(res) == 0 {
+		return "any device"
+	}
+	return strings.Join(res, ", ")
+}
+
+// GatewayConfig describes the gateways to program.
+type GatewayConfig struct {
+	Protocol string
+	// Address of the NAT-PMP or PCP gateway.
+	Address string
+	// Selector of the UPnP IGD devices.
+	Selector DeviceSelector
+	// All programs all the UPnP IGD devices matching the selector instead of
+	// the first one.
+	All bool
+}
+
+// NewGateways returns a client for each gateway described by the config.
+func NewGateways(logger log.Logger, cfg GatewayConfig) ([]Gateway, error) {
+	protocol := cfg.Protocol
+	if protocol == "" {
+		protocol = ProtocolUPnP
+	}
+	gws, err := newGateways(logger, cfg)
+	if err != nil {
+		stats.DiscoveryFailed(protocol)
+		return nil, err
+	}
+	stats.Discovered(protocol)
+	return gws, nil
+}
+
+func newGateways(logger log.Logger, cfg GatewayConfig) ([]Gateway, error) {
+	switch cfg.Protocol {
+	case ProtocolUPnP, "":
+		if !cfg.All {
+			c, err := New(logger, cfg.Selector)
+			if err != nil {
+				return nil, err
+			}
+			return []Gateway{c}, nil
+		}
+		clients, err := NewIGDClients(logger, cfg.Selector)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]Gateway, 0, len(clients))
+		for _, c := range clients {
+			res = append(res, c)
+		}
+		return res, nil
+	case ProtocolNATPMP:
+		c, err := NewPCPClient(logger, cfg.Address, false)
+		if err != nil {
+			return nil, err
+		}
+		return []Gateway{c}, nil
+	case ProtocolPCP:
+		c, err := NewPCPClient(logger, cfg.Address, true)
+		if err != nil {
+			return nil, err
+		}
+		return []Gateway{c}, nil
+	}
+	return nil, fmt.Errorf("unknown gateway protocol %q", cfg.Protocol)
+}
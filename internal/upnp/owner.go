@@ -0,0 +1,60 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package upnp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ownerPrefix marks the port mappings created by MetalLB. It is appended to
+// the mapping description together with the node and the service the
+// mapping was created for, so that mappings left behind by a previous run
+// can be told apart from the ones created by other devices.
+const ownerPrefix = "[metallb:"
+
+// OwnedDescription returns the description with the ownership marker of the
+// given node and service (in namespace/name form) appended.
+func OwnedDescription(description, node, service string) string {
+	return fmt.Sprintf("%s %s%s:%s]", description, ownerPrefix, node, service)
+}
+
+// ParseOwner returns the node and the service encoded in the ownership
+// marker of the description, if any.
+func ParseOwner(description string) (node, service string, ok bool) {
+	i := strings.LastIndex(description, ownerPrefix)
+	if i < 0 || !strings.HasSuffix(description, "]") {
+		return "", "", false
+	}
+	marker := strings.TrimSuffix(description[i+len(ownerPrefix):], "]")
+	node, service, ok = strings.Cut(marker, ":")
+	if !ok || node == "" || !strings.Contains(service, "/") {
+		return "", "", false
+	}
+	return node, service, true
+}
+
+// IsOwnedBy tells if the mapping was created by MetalLB on the given node
+// on behalf of the given service.
+func (m *PortMapping) IsOwnedBy(node, service string) bool {
+	n, s, ok := ParseOwner(m.Description)
+	return ok && n == node && s == service
+}
+
+// OwnedPortMappings returns the port mappings programmed on the gateway that
+// were created by MetalLB on the given node, grouped by service.
+func OwnedPortMappings(g Gateway, node string) (map[string][]*PortMapping, error) {
+	current, err := g.GetPortMappings()
+	if err != nil {
+		return nil, err
+	}
+	res := map[string][]*PortMapping{}
+	for _, m := range current {
+		n, service, ok := ParseOwner(m.Description)
+		if !ok || n != node {
+			continue
+		}
+		res[service] = append(res[service], m)
+	}
+	return res, nil
+}
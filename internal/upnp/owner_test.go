@@ -0,0 +1,43 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package upnp
+
+import "testing"
+
+func TestOwnerMarker(t *testing.T) {
+	desc := OwnedDescription("MetalLB LoadBalancer (web:80)", "node1", "default/web")
+	node, service, ok := ParseOwner(desc)
+	if !ok {
+		t.Fatalf("expected %q to carry an ownership marker", desc)
+	}
+	if node != "node1" || service != "default/web" {
+		t.Fatalf("unexpected owner %s %s parsed from %q", node, service, desc)
+	}
+
+	m := &PortMapping{Description: desc}
+	if !m.IsOwnedBy("node1", "default/web") {
+		t.Errorf("expected mapping to be owned by node1 for default/web")
+	}
+	if m.IsOwnedBy("node2", "default/web") {
+		t.Errorf("expected mapping not to be owned by node2")
+	}
+	if m.IsOwnedBy("node1", "default/other") {
+		t.Errorf("expected mapping not to be owned by default/other")
+	}
+}
+
+func TestParseOwnerForeignDescriptions(t *testing.T) {
+	for _, desc := range []string{
+		"",
+		"Plex Media Server",
+		"MetalLB LoadBalancer (web:80)",
+		"uTorrent [metallb:]",
+		"something [metallb:node1]",
+		"something [metallb:node1:web]",
+		"something [metallb:node1:default/web] trailing",
+	} {
+		if _, _, ok := ParseOwner(desc); ok {
+			t.Errorf("expected %q not to be recognized as owned by metallb", desc)
+		}
+	}
+}
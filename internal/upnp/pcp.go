@@ -0,0 +1,588 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package upnp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const (
+	pcpDefaultPort = 5351
+
+	pcpVersion    = 2
+	natpmpVersion = 0
+
+	pcpOpAnnounce = 0
+	pcpOpMap      = 1
+	pcpResponse   = 0x80
+
+	pcpOptionThirdParty    = 1
+	pcpOptionPreferFailure = 2
+
+	natpmpOpExternalAddress = 0
+	natpmpOpMapUDP          = 1
+	natpmpOpMapTCP          = 2
+
+	pcpHeaderLen     = 24
+	pcpMapPayloadLen = 36
+
+	resultSuccess = 0
+
+	// pcpDefaultLifetime is the lease requested for the mappings wanted as
+	// permanent, which neither PCP nor NAT-PMP can grant. They are renewed
+	// before expiring by the periodic resync.
+	pcpDefaultLifetime = 7200
+
+	pcpInitialTimeout = 250 * time.Millisecond
+	pcpRetries        = 4
+)
+
+var errUnsupportedVersion = errors.New("gateway does not support PCP")
+
+var pcpResults = map[byte]string{
+	1:  "UNSUPP_VERSION",
+	2:  "NOT_AUTHORIZED",
+	3:  "MALFORMED_REQUEST",
+	4:  "UNSUPP_OPCODE",
+	5:  "UNSUPP_OPTION",
+	6:  "MALFORMED_OPTION",
+	7:  "NETWORK_FAILURE",
+	8:  "NO_RESOURCES",
+	9:  "UNSUPP_PROTOCOL",
+	10: "USER_EX_QUOTA",
+	11: "CANNOT_PROVIDE_EXTERNAL",
+	12: "ADDRESS_MISMATCH",
+	13: "EXCESSIVE_REMOTE_PEERS",
+}
+
+var natpmpResults = map[uint16]string{
+	1: "unsupported version",
+	2: "not authorized",
+	3: "network failure",
+	4: "out of resources",
+	5: "unsupported opcode",
+}
+
+// pcpMapping is a mapping created by the PCPClient.
+type pcpMapping struct {
+	mapping PortMapping
+	nonce   [12]byte
+	expires time.Time
+}
+
+// PCPClient programs a gateway via PCP (RFC 6887) or NAT-PMP (RFC 6886).
+// Neither protocol can list the mappings of a gateway, so the client keeps
+// track of the ones it created, and forgets them when the epoch reported by
+// the gateway shows that it lost its state (for example after a reboot).
+type PCPClient struct {
+	logger         log.Logger
+	address        *net.UDPAddr
+	usePCP         bool
+	initialTimeout time.Duration
+	retries        int
+
+	mutex      sync.Mutex
+	externalIP net.IP
+	epoch      uint32
+	epochSeen  time.Time
+	mappings   map[portKey]*pcpMapping
+}
+
+// NewPCPClient creates a client for the gateway at address, with an optional
+// port (5351 by default). When usePCP is set, PCP is used unless the gateway
+// only supports NAT-PMP.
+func NewPCPClient(logger log.Logger, address string, usePCP bool) (*PCPClient, error) {
+	return newPCPClient(logger, address, usePCP, pcpInitialTimeout, pcpRetries)
+}
+
+func newPCPClient(logger log.Logger, address string, usePCP bool, initialTimeout time.Duration, retries int) (*PCPClient, error) {
+	if address == "" {
+		return nil, errors.New("a gateway address is required for PCP and NAT-PMP")
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = strings.Trim(address, "[]"), strconv.Itoa(pcpDefaultPort)
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gateway address %q: %v", address, err)
+	}
+
+	c := &PCPClient{
+		logger:         logger,
+		address:        addr,
+		usePCP:         usePCP,
+		initialTimeout: initialTimeout,
+		retries:        retries,
+		mappings:       map[portKey]*pcpMapping{},
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.usePCP {
+		err = c.announce()
+		if errors.Is(err, errUnsupportedVersion) {
+			level.Info(logger).Log("msg", "gateway does not support PCP, falling back to NAT-PMP", "gateway", addr)
+			c.usePCP = false
+			err = nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach PCP gateway %s: %v", addr, err)
+		}
+	}
+
+	// Get external IP address. PCP gateways usually answer to the NAT-PMP
+	// request too, otherwise the address is learnt from the mappings.
+	if err := c.updateExternalIP(); err != nil {
+		if !c.usePCP {
+			return nil, fmt.Errorf("failed to reach NAT-PMP gateway %s: %v", addr, err)
+		}
+		level.Warn(logger).Log("msg", "failed to get external IP address", "gateway", addr, "error", err)
+	}
+
+	level.Info(logger).Log("msg", "connected to gateway", "gateway", addr, "protocol", c.protocol(), "external_ip", c.externalIP)
+	if c.externalIP != nil {
+		stats.ExternalIP(c.Device().Key(), c.externalIP)
+	}
+	return c, nil
+}
+
+func (c *PCPClient) protocol() string {
+	if c.usePCP {
+		return ProtocolPCP
+	}
+	return ProtocolNATPMP
+}
+
+// Device returns the identity of the gateway
+func (c *PCPClient) Device() Device {
+	return Device{Protocol: c.protocol(), Location: c.address.String()}
+}
+
+// GetExternalIP returns the external IP address
+func (c *PCPClient) GetExternalIP() net.IP {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.externalIP
+}
+
+// RefreshExternalIP updates the cached external IP address
+func (c *PCPClient) RefreshExternalIP() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.updateExternalIP(); err != nil {
+		return err
+	}
+	stats.ExternalIP(c.Device().Key(), c.externalIP)
+	return nil
+}
+
+// AddPortMapping creates or renews a port mapping. The gateway must grant the
+// requested external port.
+func (c *PCPClient) AddPortMapping(mapping *PortMapping) error {
+	if mapping == nil {
+		return fmt.Errorf("port mapping cannot be nil")
+	}
+	protocol := strings.ToUpper(mapping.Protocol)
+	if protocol != "TCP" && protocol != "UDP" {
+		return fmt.Errorf("invalid protocol: %s (must be TCP or UDP)", mapping.Protocol)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	lifetime := uint32(pcpDefaultLifetime)
+	if mapping.Duration > 0 {
+		lifetime = uint32(mapping.Duration)
+	}
+
+	key := keyFor(mapping.ExternalPort, protocol)
+	entry := &pcpMapping{mapping: *mapping}
+	if existing, ok := c.mappings[key]; ok && existing.mapping.InternalIP.Equal(mapping.InternalIP) && existing.mapping.InternalPort == mapping.InternalPort {
+		entry.nonce = existing.nonce
+	} else if _, err := rand.Read(entry.nonce[:]); err != nil {
+		return fmt.Errorf("failed to generate mapping nonce: %v", err)
+	}
+
+	var (
+		granted uint32
+		err     error
+	)
+	if c.usePCP {
+		granted, err = c.pcpMap(entry, lifetime)
+	} else {
+		granted, err = c.natpmpMap(entry, lifetime)
+	}
+	if err != nil {
+		stats.MappingError("add", err)
+		return fmt.Errorf("failed to add port mapping: %v", err)
+	}
+
+	entry.expires = time.Now().Add(time.Duration(granted) * time.Second)
+	c.mappings[key] = entry
+
+	level.Info(c.logger).Log(
+		"msg", "successfully added port mapping",
+		"protocol", c.protocol(),
+		"external_port", mapping.ExternalPort,
+		"internal_ip", mapping.InternalIP.String(),
+		"internal_port", mapping.InternalPort,
+		"lifetime", granted,
+	)
+	return nil
+}
+
+// DeletePortMapping removes a port mapping created by this client
+func (c *PCPClient) DeletePortMapping(externalPort int, protocol string) error {
+	protocol = strings.ToUpper(protocol)
+	if protocol != "TCP" && protocol != "UDP" {
+		return fmt.Errorf("invalid protocol: %s (must be TCP or UDP)", protocol)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := keyFor(externalPort, protocol)
+	entry, ok := c.mappings[key]
+	if !ok {
+		level.Debug(c.logger).Log("msg", "port mapping does not exist", "external_port", externalPort, "protocol", protocol)
+		return nil
+	}
+
+	var err error
+	if c.usePCP {
+		_, err = c.pcpMap(entry, 0)
+	} else {
+		_, err = c.natpmpMap(entry, 0)
+	}
+	if err != nil {
+		stats.MappingError("delete", err)
+		return fmt.Errorf("failed to delete port mapping: %v", err)
+	}
+	delete(c.mappings, key)
+
+	level.Info(c.logger).Log("msg", "successfully deleted port mapping", "protocol", c.protocol(), "external_port", externalPort)
+	return nil
+}
+
+// GetPortMappings returns the mappings created by this client that are still
+// granted by the gateway, with their remaining lease.
+func (c *PCPClient) GetPortMappings() ([]*PortMapping, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Probe the gateway so that a loss of state is noticed.
+	var err error
+	if c.usePCP {
+		err = c.announce()
+	} else {
+		err = c.updateExternalIP()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var res []*PortMapping
+	for key, entry := range c.mappings {
+		remaining := int(entry.expires.Sub(now).Seconds())
+		if remaining <= 0 {
+			delete(c.mappings, key)
+			continue
+		}
+		m := entry.mapping
+		m.Duration = remaining
+		res = append(res, &m)
+	}
+	return res, nil
+}
+
+// checkEpoch validates the epoch reported by the gateway as described in
+// RFC 6887 section 8.5, and forgets the mappings if the gateway lost them.
+func (c *PCPClient) checkEpoch(epoch uint32) {
+	now := time.Now()
+	defer func() {
+		c.epoch = epoch
+		c.epochSeen = now
+	}()
+	if c.epochSeen.IsZero() {
+		return
+	}
+
+	lost := false
+	if int64(epoch) < int64(c.epoch)-1 {
+		lost = true
+	} else {
+		clientDelta := int64(now.Sub(c.epochSeen).Seconds())
+		serverDelta := int64(epoch) - int64(c.epoch)
+		if clientDelta+2 < serverDelta-serverDelta/16 || serverDelta+2 < clientDelta-clientDelta/16 {
+			lost = true
+		}
+	}
+	if lost && len(c.mappings) > 0 {
+		level.Warn(c.logger).Log("msg", "gateway lost its state, forgetting port mappings", "gateway", c.address, "mappings", len(c.mappings))
+		c.mappings = map[portKey]*pcpMapping{}
+	}
+}
+
+// announce sends a PCP ANNOUNCE request, used to check that the gateway
+// speaks PCP and to learn its epoch.
+func (c *PCPClient) announce() error {
+	resp, err := c.request(pcpVersion, pcpOpAnnounce, func(localIP net.IP) []byte {
+		return pcpHeader(pcpOpAnnounce, 0, localIP)
+	})
+	if err != nil {
+		return err
+	}
+	if resp[3] != resultSuccess {
+		return pcpError(resp[3])
+	}
+	c.checkEpoch(binary.BigEndian.Uint32(resp[8:12]))
+	return nil
+}
+
+// updateExternalIP retrieves and caches the external IP address via the
+// NAT-PMP external address request.
+func (c *PCPClient) updateExternalIP() error {
+	resp, err := c.request(natpmpVersion, natpmpOpExternalAddress, func(net.IP) []byte {
+		return []byte{natpmpVersion, natpmpOpExternalAddress}
+	})
+	if err != nil {
+		return err
+	}
+	if len(resp) < 12 {
+		return fmt.Errorf("short NAT-PMP external address response")
+	}
+	if result := binary.BigEndian.Uint16(resp[2:4]); result != resultSuccess {
+		return natpmpError(result)
+	}
+	c.checkEpoch(binary.BigEndian.Uint32(resp[4:8]))
+	c.externalIP = net.IP(append([]byte{}, resp[8:12]...))
+	return nil
+}
+
+// pcpMap sends a PCP MAP request for the mapping and returns the lifetime
+// granted by the gateway.
+func (c *PCPClient) pcpMap(entry *pcpMapping, lifetime uint32) (uint32, error) {
+	m := &entry.mapping
+	proto := byte(6)
+	if strings.EqualFold(m.Protocol, "UDP") {
+		proto = 17
+	}
+
+	resp, err := c.request(pcpVersion, pcpOpMap, func(localIP net.IP) []byte {
+		b := pcpHeader(pcpOpMap, lifetime, localIP)
+		payload := make([]byte, pcpMapPayloadLen)
+		copy(payload[0:12], entry.nonce[:])
+		payload[12] = proto
+		binary.BigEndian.PutUint16(payload[16:18], uint16(m.InternalPort))
+		binary.BigEndian.PutUint16(payload[18:20], uint16(m.ExternalPort))
+		// Let the gateway choose the external address: the IPv4 one for
+		// IPv4 mappings, while IPv6 mappings are firewall pinholes.
+		if m.InternalIP.To4() != nil {
+			copy(payload[20:36], net.IPv4zero.To16())
+		}
+		b = append(b, payload...)
+		if !m.InternalIP.Equal(localIP) {
+			b = append(b, pcpOptionThirdParty, 0, 0, 16)
+			b = append(b, m.InternalIP.To16()...)
+		}
+		if lifetime > 0 {
+			// The external port is part of the service contract, fail
+			// rather than getting a different one.
+			b = append(b, pcpOptionPreferFailure, 0, 0, 0)
+		}
+		return b
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp) < pcpHeaderLen+pcpMapPayloadLen {
+		return 0, fmt.Errorf("short PCP MAP response")
+	}
+	c.checkEpoch(binary.BigEndian.Uint32(resp[8:12]))
+	if resp[3] != resultSuccess {
+		return 0, pcpError(resp[3])
+	}
+	payload := resp[pcpHeaderLen:]
+	if !bytes.Equal(payload[0:12], entry.nonce[:]) {
+		return 0, fmt.Errorf("PCP MAP response nonce mismatch")
+	}
+	if lifetime == 0 {
+		return 0, nil
+	}
+	if assigned := int(binary.BigEndian.Uint16(payload[18:20])); assigned != m.ExternalPort {
+		c.releaseUnwanted(entry, assigned, c.pcpMap)
+		return 0, fmt.Errorf("gateway assigned external port %d instead of %d", assigned, m.ExternalPort)
+	}
+	if ip := net.IP(payload[20:36]); ip.To4() != nil && !ip.IsUnspecified() {
+		c.externalIP = append(net.IP{}, ip.To4()...)
+	}
+	return binary.BigEndian.Uint32(resp[4:8]), nil
+}
+
+// natpmpMap sends a NAT-PMP mapping request for the mapping and returns the
+// lifetime granted by the gateway. NAT-PMP can only forward ports to the
+// host sending the request.
+func (c *PCPClient) natpmpMap(entry *pcpMapping, lifetime uint32) (uint32, error) {
+	m := &entry.mapping
+	op := byte(natpmpOpMapTCP)
+	if strings.EqualFold(m.Protocol, "UDP") {
+		op = natpmpOpMapUDP
+	}
+
+	if lifetime > 0 {
+		localIP, err := c.localIP()
+		if err != nil {
+			return 0, err
+		}
+		if !m.InternalIP.Equal(localIP) {
+			return 0, fmt.Errorf("NAT-PMP can only forward ports to the requesting host %s, not to %s, use PCP instead", localIP, m.InternalIP)
+		}
+	}
+
+	resp, err := c.request(natpmpVersion, op, func(net.IP) []byte {
+		b := make([]byte, 12)
+		b[0] = natpmpVersion
+		b[1] = op
+		binary.BigEndian.PutUint16(b[4:6], uint16(m.InternalPort))
+		if lifetime > 0 {
+			binary.BigEndian.PutUint16(b[6:8], uint16(m.ExternalPort))
+		}
+		binary.BigEndian.PutUint32(b[8:12], lifetime)
+		return b
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp) < 16 {
+		return 0, fmt.Errorf("short NAT-PMP mapping response")
+	}
+	c.checkEpoch(binary.BigEndian.Uint32(resp[4:8]))
+	if result := binary.BigEndian.Uint16(resp[2:4]); result != resultSuccess {
+		return 0, natpmpError(result)
+	}
+	if lifetime == 0 {
+		return 0, nil
+	}
+	if assigned := int(binary.BigEndian.Uint16(resp[10:12])); assigned != m.ExternalPort {
+		c.releaseUnwanted(entry, assigned, c.natpmpMap)
+		return 0, fmt.Errorf("gateway assigned external port %d instead of %d", assigned, m.ExternalPort)
+	}
+	return binary.BigEndian.Uint32(resp[12:16]), nil
+}
+
+// releaseUnwanted deletes the mapping the gateway just granted on another
+// external port than the requested one. It is not recorded, so nothing else
+// would delete it before its lifetime runs out.
+func (c *PCPClient) releaseUnwanted(entry *pcpMapping, assigned int, mapFn func(*pcpMapping, uint32) (uint32, error)) {
+	if _, err := mapFn(entry, 0); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to delete the mapping granted on an unwanted external port", "protocol", c.protocol(), "external_port", assigned, "internal_port", entry.mapping.InternalPort, "error", err)
+	}
+}
+
+// request sends the request built by build, which receives the local address
+// used to reach the gateway, and returns the matching response. Requests are
+// retransmitted with an exponential backoff.
+func (c *PCPClient) request(version, op byte, build func(localIP net.IP) []byte) ([]byte, error) {
+	conn, err := net.DialUDP("udp", nil, c.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+	req := build(localIP)
+	buf := make([]byte, 1100)
+	timeout := c.initialTimeout
+	for i := 0; i < c.retries; i++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(timeout)
+		timeout *= 2
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+		for {
+			n, err := conn.Read(buf)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if n < 4 || buf[1] != pcpResponse|op {
+				continue
+			}
+			// A NAT-PMP only gateway answers to PCP requests with a NAT-PMP
+			// unsupported version error.
+			if version == pcpVersion && buf[0] == natpmpVersion {
+				return nil, errUnsupportedVersion
+			}
+			if buf[0] != version {
+				continue
+			}
+			if version == pcpVersion && n < pcpHeaderLen {
+				continue
+			}
+			return append([]byte{}, buf[:n]...), nil
+		}
+	}
+	return nil, fmt.Errorf("no response from gateway %s", c.address)
+}
+
+// localIP returns the local address used to reach the gateway.
+func (c *PCPClient) localIP() (net.IP, error) {
+	conn, err := net.DialUDP("udp", nil, c.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+func pcpHeader(op byte, lifetime uint32, localIP net.IP) []byte {
+	b := make([]byte, pcpHeaderLen)
+	b[0] = pcpVersion
+	b[1] = op
+	binary.BigEndian.PutUint32(b[4:8], lifetime)
+	copy(b[8:24], localIP.To16())
+	return b
+}
+
+// resultError is returned when the gateway answers with a result code other
+// than success.
+type resultError struct {
+	protocol string
+	code     int
+	name     string
+}
+
+func (e *resultError) Error() string {
+	if e.name != "" {
+		return fmt.Sprintf("%s gateway returned %s", e.protocol, e.name)
+	}
+	return fmt.Sprintf("%s gateway returned result %d", e.protocol, e.code)
+}
+
+func pcpError(result byte) error {
+	return &resultError{protocol: ProtocolPCP, code: int(result), name: pcpResults[result]}
+}
+
+func natpmpError(result uint16) error {
+	return &resultError{protocol: ProtocolNATPMP, code: int(result), name: natpmpResults[result]}
+}
@@ -0,0 +1,489 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package upnp
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	ptu "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeMappingKey struct {
+	protocol     byte
+	internalIP   string
+	internalPort uint16
+}
+
+type fakeMapping struct {
+	externalPort uint16
+	lifetime     uint32
+	nonce        []byte
+}
+
+// fakeGateway is an in-process PCP / NAT-PMP gateway.
+type fakeGateway struct {
+	t          *testing.T
+	conn       *net.UDPConn
+	pcp        bool
+	externalIP net.IP
+
+	mutex     sync.Mutex
+	epochBase uint32
+	started   time.Time
+	mappings  map[fakeMappingKey]fakeMapping
+	// reserved are the external ports used by other hosts.
+	reserved map[uint16]bool
+	// ignorePreferFailure makes the gateway grant another external port
+	// even when asked to fail instead.
+	ignorePreferFailure bool
+}
+
+func newFakeGateway(t *testing.T, pcp bool) *fakeGateway {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	g := &fakeGateway{
+		t:          t,
+		conn:       conn,
+		pcp:        pcp,
+		externalIP: net.ParseIP("203.0.113.7").To4(),
+		epochBase:  1000,
+		started:    time.Now(),
+		mappings:   map[fakeMappingKey]fakeMapping{},
+		reserved:   map[uint16]bool{},
+	}
+	go g.serve()
+	t.Cleanup(func() { conn.Close() })
+	return g
+}
+
+func (g *fakeGateway) address() string {
+	return g.conn.LocalAddr().String()
+}
+
+func (g *fakeGateway) epoch() uint32 {
+	return g.epochBase + uint32(time.Since(g.started).Seconds())
+}
+
+// reboot makes the gateway lose its mappings and restart its epoch.
+func (g *fakeGateway) reboot() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.mappings = map[fakeMappingKey]fakeMapping{}
+	g.epochBase = 0
+	g.started = time.Now()
+}
+
+func (g *fakeGateway) reserve(port uint16) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.reserved[port] = true
+}
+
+func (g *fakeGateway) mapping(protocol byte, internalIP string, internalPort uint16) (fakeMapping, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	m, ok := g.mappings[fakeMappingKey{protocol, internalIP, internalPort}]
+	return m, ok
+}
+
+func (g *fakeGateway) externalPortUsed(port uint16, protocol byte, except fakeMappingKey) bool {
+	if g.reserved[port] {
+		return true
+	}
+	for k, m := range g.mappings {
+		if k != except && k.protocol == protocol && m.externalPort == port {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *fakeGateway) serve() {
+	buf := make([]byte, 1100)
+	for {
+		n, from, err := g.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		req := append([]byte{}, buf[:n]...)
+		var resp []byte
+		g.mutex.Lock()
+		switch {
+		case req[0] == pcpVersion && g.pcp:
+			resp = g.handlePCP(req)
+		case req[0] == pcpVersion:
+			resp = make([]byte, 8)
+			resp[1] = pcpResponse | req[1]
+			binary.BigEndian.PutUint16(resp[2:4], 1)
+			binary.BigEndian.PutUint32(resp[4:8], g.epoch())
+		case req[0] == natpmpVersion:
+			resp = g.handleNATPMP(req, from)
+		}
+		g.mutex.Unlock()
+		if resp != nil {
+			_, _ = g.conn.WriteToUDP(resp, from)
+		}
+	}
+}
+
+func (g *fakeGateway) handlePCP(req []byte) []byte {
+	op := req[1]
+	resp := make([]byte, pcpHeaderLen)
+	resp[0] = pcpVersion
+	resp[1] = pcpResponse | op
+	binary.BigEndian.PutUint32(resp[8:12], g.epoch())
+	if op == pcpOpAnnounce {
+		return resp
+	}
+
+	lifetime := binary.BigEndian.Uint32(req[4:8])
+	payload := req[pcpHeaderLen : pcpHeaderLen+pcpMapPayloadLen]
+	internalIP := net.IP(req[8:24])
+	preferFailure := false
+	for opts := req[pcpHeaderLen+pcpMapPayloadLen:]; len(opts) >= 4; {
+		length := int(binary.BigEndian.Uint16(opts[2:4]))
+		switch opts[0] {
+		case pcpOptionThirdParty:
+			internalIP = net.IP(opts[4 : 4+length])
+		case pcpOptionPreferFailure:
+			preferFailure = true
+		}
+		opts = opts[4+length:]
+	}
+
+	key := fakeMappingKey{payload[12], internalIP.String(), binary.BigEndian.Uint16(payload[16:18])}
+	respPayload := append([]byte{}, payload...)
+	resp = append(resp, respPayload...)
+	if lifetime == 0 {
+		delete(g.mappings, key)
+		return resp
+	}
+
+	external := binary.BigEndian.Uint16(payload[18:20])
+	if g.externalPortUsed(external, key.protocol, key) {
+		if preferFailure && !g.ignorePreferFailure {
+			resp[3] = 11 // CANNOT_PROVIDE_EXTERNAL
+			return resp
+		}
+		external = 40000
+	}
+	if lifetime > 3600 {
+		lifetime = 3600
+	}
+	g.mappings[key] = fakeMapping{externalPort: external, lifetime: lifetime, nonce: payload[0:12]}
+	binary.BigEndian.PutUint32(resp[4:8], lifetime)
+	binary.BigEndian.PutUint16(resp[pcpHeaderLen+18:pcpHeaderLen+20], external)
+	if internalIP.To4() != nil {
+		copy(resp[pcpHeaderLen+20:pcpHeaderLen+36], g.externalIP.To16())
+	} else {
+		copy(resp[pcpHeaderLen+20:pcpHeaderLen+36], internalIP.To16())
+	}
+	return resp
+}
+
+func (g *fakeGateway) handleNATPMP(req []byte, from *net.UDPAddr) []byte {
+	op := req[1]
+	if op == natpmpOpExternalAddress {
+		resp := make([]byte, 12)
+		resp[1] = pcpResponse
+		binary.BigEndian.PutUint32(resp[4:8], g.epoch())
+		copy(resp[8:12], g.externalIP)
+		return resp
+	}
+
+	protocol := byte(6)
+	if op == natpmpOpMapUDP {
+		protocol = 17
+	}
+	key := fakeMappingKey{protocol, from.IP.String(), binary.BigEndian.Uint16(req[4:6])}
+	external := binary.BigEndian.Uint16(req[6:8])
+	lifetime := binary.BigEndian.Uint32(req[8:12])
+	resp := make([]byte, 16)
+	resp[1] = pcpResponse | op
+	binary.BigEndian.PutUint32(resp[4:8], g.epoch())
+	copy(resp[8:10], req[4:6])
+	if lifetime == 0 {
+		delete(g.mappings, key)
+		return resp
+	}
+	if g.externalPortUsed(external, protocol, key) {
+		external = 40000
+	}
+	g.mappings[key] = fakeMapping{externalPort: external, lifetime: lifetime}
+	binary.BigEndian.PutUint16(resp[10:12], external)
+	binary.BigEndian.PutUint32(resp[12:16], lifetime)
+	return resp
+}
+
+func newTestPCPClient(t *testing.T, g *fakeGateway, usePCP bool) *PCPClient {
+	c, err := newPCPClient(log.NewNopLogger(), g.address(), usePCP, 50*time.Millisecond, 3)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
+
+func TestPCPAddAndDeleteMapping(t *testing.T) {
+	g := newFakeGateway(t, true)
+	c := newTestPCPClient(t, g, true)
+
+	if !c.usePCP {
+		t.Fatalf("expected the client to use PCP")
+	}
+	if !c.GetExternalIP().Equal(g.externalIP) {
+		t.Fatalf("expected external IP %s, got %s", g.externalIP, c.GetExternalIP())
+	}
+
+	// The LB IP is not the address of the host, so THIRD_PARTY must be used.
+	err := c.AddPortMapping(&PortMapping{
+		ExternalPort: 8443,
+		InternalPort: 443,
+		InternalIP:   net.ParseIP("192.168.1.100"),
+		Protocol:     "tcp",
+	})
+	if err != nil {
+		t.Fatalf("failed to add mapping: %v", err)
+	}
+	m, ok := g.mapping(6, "192.168.1.100", 443)
+	if !ok {
+		t.Fatalf("expected the gateway to have a mapping for 192.168.1.100:443")
+	}
+	if m.externalPort != 8443 {
+		t.Fatalf("expected external port 8443, got %d", m.externalPort)
+	}
+
+	mappings, err := c.GetPortMappings()
+	if err != nil {
+		t.Fatalf("failed to list mappings: %v", err)
+	}
+	if len(mappings) != 1 || mappings[0].ExternalPort != 8443 {
+		t.Fatalf("unexpected mappings %+v", mappings)
+	}
+	// Permanent mappings get the lifetime granted by the gateway.
+	if mappings[0].Duration <= 0 || mappings[0].Duration > 3600 {
+		t.Fatalf("unexpected remaining lease %d", mappings[0].Duration)
+	}
+
+	if err := c.DeletePortMapping(8443, "TCP"); err != nil {
+		t.Fatalf("failed to delete mapping: %v", err)
+	}
+	if _, ok := g.mapping(6, "192.168.1.100", 443); ok {
+		t.Fatalf("expected the mapping to be deleted from the gateway")
+	}
+	mappings, err = c.GetPortMappings()
+	if err != nil {
+		t.Fatalf("failed to list mappings: %v", err)
+	}
+	if len(mappings) != 0 {
+		t.Fatalf("expected no mappings, got %+v", mappings)
+	}
+}
+
+func TestPCPRequestedExternalPortUnavailable(t *testing.T) {
+	g := newFakeGateway(t, true)
+	g.reserve(443)
+	c := newTestPCPClient(t, g, true)
+	errors := ptu.ToFloat64(stats.mappingErrors.WithLabelValues("add", "11"))
+
+	err := c.AddPortMapping(&PortMapping{
+		ExternalPort: 443,
+		InternalPort: 443,
+		InternalIP:   net.ParseIP("192.168.1.100"),
+		Protocol:     "tcp",
+	})
+	if err == nil || !strings.Contains(err.Error(), "CANNOT_PROVIDE_EXTERNAL") {
+		t.Fatalf("expected the gateway to refuse the external port, got %v", err)
+	}
+	if value := ptu.ToFloat64(stats.mappingErrors.WithLabelValues("add", "11")); value != errors+1 {
+		t.Fatalf("expected the error to be counted with its result code, got %v after %v", value, errors)
+	}
+	if _, ok := g.mapping(6, "192.168.1.100", 443); ok {
+		t.Fatalf("expected no mapping on the gateway")
+	}
+}
+
+func TestGrantedExternalPortMismatchIsReleased(t *testing.T) {
+	for _, usePCP := range []bool{true, false} {
+		g := newFakeGateway(t, usePCP)
+		g.ignorePreferFailure = true
+		g.reserve(8081)
+		c := newTestPCPClient(t, g, usePCP)
+
+		// The gateway grants another external port than the requested one.
+		err := c.AddPortMapping(&PortMapping{
+			ExternalPort: 8081,
+			InternalPort: 81,
+			InternalIP:   net.ParseIP("127.0.0.1"),
+			Protocol:     "udp",
+		})
+		if err == nil {
+			t.Fatalf("pcp %t: expected an error when the gateway assigns a different external port", usePCP)
+		}
+		if m, ok := g.mapping(17, "127.0.0.1", 81); ok {
+			t.Fatalf("pcp %t: expected the mapping granted on port %d to be deleted from the gateway", usePCP, m.externalPort)
+		}
+		if len(c.mappings) != 0 {
+			t.Fatalf("pcp %t: expected no mapping to be recorded, got %v", usePCP, c.mappings)
+		}
+	}
+}
+
+func TestPCPIPv6Pinhole(t *testing.T) {
+	g := newFakeGateway(t, true)
+	c := newTestPCPClient(t, g, true)
+
+	err := c.AddPortMapping(&PortMapping{
+		ExternalPort: 443,
+		InternalPort: 443,
+		InternalIP:   net.ParseIP("2001:db8::10"),
+		Protocol:     "tcp",
+		Duration:     600,
+	})
+	if err != nil {
+		t.Fatalf("failed to add pinhole: %v", err)
+	}
+	m, ok := g.mapping(6, "2001:db8::10", 443)
+	if !ok {
+		t.Fatalf("expected the gateway to have a pinhole for [2001:db8::10]:443")
+	}
+	if m.lifetime != 600 {
+		t.Fatalf("expected lifetime 600, got %d", m.lifetime)
+	}
+	// The pinhole address must not replace the IPv4 external address.
+	if !c.GetExternalIP().Equal(g.externalIP) {
+		t.Fatalf("expected external IP %s, got %s", g.externalIP, c.GetExternalIP())
+	}
+}
+
+func TestPCPGatewayReboot(t *testing.T) {
+	g := newFakeGateway(t, true)
+	c := newTestPCPClient(t, g, true)
+
+	err := c.AddPortMapping(&PortMapping{
+		ExternalPort: 443,
+		InternalPort: 443,
+		InternalIP:   net.ParseIP("192.168.1.100"),
+		Protocol:     "tcp",
+	})
+	if err != nil {
+		t.Fatalf("failed to add mapping: %v", err)
+	}
+
+	g.reboot()
+
+	mappings, err := c.GetPortMappings()
+	if err != nil {
+		t.Fatalf("failed to list mappings: %v", err)
+	}
+	if len(mappings) != 0 {
+		t.Fatalf("expected the mappings lost by the gateway to be forgotten, got %+v", mappings)
+	}
+}
+
+func TestNATPMPFallback(t *testing.T) {
+	g := newFakeGateway(t, false)
+	c := newTestPCPClient(t, g, true)
+
+	if c.usePCP {
+		t.Fatalf("expected the client to fall back to NAT-PMP")
+	}
+	if !c.GetExternalIP().Equal(g.externalIP) {
+		t.Fatalf("expected external IP %s, got %s", g.externalIP, c.GetExternalIP())
+	}
+
+	// NAT-PMP can only forward to the requesting host.
+	err := c.AddPortMapping(&PortMapping{
+		ExternalPort: 443,
+		InternalPort: 443,
+		InternalIP:   net.ParseIP("192.168.1.100"),
+		Protocol:     "tcp",
+	})
+	if err == nil {
+		t.Fatalf("expected NAT-PMP to refuse a mapping to another host")
+	}
+
+	err = c.AddPortMapping(&PortMapping{
+		ExternalPort: 8080,
+		InternalPort: 80,
+		InternalIP:   net.ParseIP("127.0.0.1"),
+		Protocol:     "udp",
+		Duration:     120,
+	})
+	if err != nil {
+		t.Fatalf("failed to add mapping: %v", err)
+	}
+	m, ok := g.mapping(17, "127.0.0.1", 80)
+	if !ok || m.externalPort != 8080 || m.lifetime != 120 {
+		t.Fatalf("unexpected mapping on the gateway %+v", m)
+	}
+
+	// A port taken by another host is not silently replaced.
+	g.reserve(8081)
+	err = c.AddPortMapping(&PortMapping{
+		ExternalPort: 8081,
+		InternalPort: 81,
+		InternalIP:   net.ParseIP("127.0.0.1"),
+		Protocol:     "udp",
+	})
+	if err == nil {
+		t.Fatalf("expected an error when the gateway assigns a different external port")
+	}
+
+	if err := c.DeletePortMapping(8080, "udp"); err != nil {
+		t.Fatalf("failed to delete mapping: %v", err)
+	}
+	if _, ok := g.mapping(17, "127.0.0.1", 80); ok {
+		t.Fatalf("expected the mapping to be deleted from the gateway")
+	}
+}
+
+func TestPCPReconcile(t *testing.T) {
+	g := newFakeGateway(t, true)
+	c := newTestPCPClient(t, g, true)
+
+	desired := map[string][]*PortMapping{
+		"default/web": {{
+			ExternalPort: 443,
+			InternalPort: 443,
+			InternalIP:   net.ParseIP("192.168.1.100"),
+			Protocol:     "tcp",
+		}},
+	}
+	if err := c.AddPortMapping(desired["default/web"][0]); err != nil {
+		t.Fatalf("failed to add mapping: %v", err)
+	}
+
+	g.reboot()
+
+	repairs, err := Reconcile(log.NewNopLogger(), c, desired, 60)
+	if err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if len(repairs["default/web"]) != 1 || repairs["default/web"][0].Reason != RepairMissing || repairs["default/web"][0].Err != nil {
+		t.Fatalf("unexpected repairs %+v", repairs)
+	}
+	if _, ok := g.mapping(6, "192.168.1.100", 443); !ok {
+		t.Fatalf("expected the mapping to be programmed again")
+	}
+}
+
+func TestPCPUnreachableGateway(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = newPCPClient(log.NewNopLogger(), conn.LocalAddr().String(), true, 10*time.Millisecond, 2)
+	if err == nil {
+		t.Fatalf("expected an error for a gateway that does not answer")
+	}
+}
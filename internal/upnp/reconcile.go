@@ -0,0 +1,104 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package upnp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const (
+	// RepairMissing is used when a desired mapping is not programmed on the gateway.
+	RepairMissing = "missing"
+	// RepairChanged is used when the gateway forwards the external port to a
+	// different internal endpoint than the desired one.
+	RepairChanged = "changed"
+	// RepairExpiring is used when the lease of a desired mapping is about to expire.
+	RepairExpiring = "expiring"
+)
+
+// Repair describes a desired port mapping that had to be added again to the gateway.
+type Repair struct {
+	Mapping *PortMapping
+	Reason  string
+	// Err is set when adding the mapping back failed.
+	Err error
+}
+
+// Reconcile reads the port mappings programmed on the gateway and adds back
+// the desired ones that are missing, that point to a different internal
+// endpoint or whose lease expires within renewBefore seconds. The desired
+// mappings are grouped by an opaque key (typically the service), and the
+// repairs are returned grouped by the same key.
+func Reconcile(logger log.Logger, g Gateway, desired map[string][]*PortMapping, renewBefore int) (map[string][]Repair, error) {
+	current, err := g.GetPortMappings()
+	if err != nil {
+		stats.ResyncFailed()
+		return nil, fmt.Errorf("failed to read port mappings from the gateway: %v", err)
+	}
+
+	res := map[string][]Repair{}
+	for key, mappings := range desired {
+		repairs := diffPortMappings(current, mappings, renewBefore)
+		for i := range repairs {
+			r := &repairs[i]
+			level.Info(logger).Log(
+				"msg", "port mapping drifted from the desired state",
+				"key", key,
+				"external_port", r.Mapping.ExternalPort,
+				"protocol", r.Mapping.Protocol,
+				"reason", r.Reason,
+			)
+			r.Err = g.AddPortMapping(r.Mapping)
+			if r.Err != nil {
+				stats.RepairFailed(r.Reason)
+				continue
+			}
+			stats.Repaired(r.Reason)
+		}
+		if len(repairs) > 0 {
+			res[key] = repairs
+		}
+	}
+	stats.Refreshed(g.Device().Key())
+	return res, nil
+}
+
+// diffPortMappings returns the desired mappings that need to be added again
+// to the gateway given the ones currently programmed.
+func diffPortMappings(current, desired []*PortMapping, renewBefore int) []Repair {
+	programmed := make(map[portKey]*PortMapping, len(current))
+	for _, m := range current {
+		programmed[keyFor(m.ExternalPort, m.Protocol)] = m
+	}
+
+	var res []Repair
+	for _, d := range desired {
+		m, ok := programmed[keyFor(d.ExternalPort, d.Protocol)]
+		switch {
+		case !ok:
+			res = append(res, Repair{Mapping: d, Reason: RepairMissing})
+		case m.InternalPort != d.InternalPort || !m.InternalIP.Equal(d.InternalIP):
+			res = append(res, Repair{Mapping: d, Reason: RepairChanged})
+		// The gateway reports the remaining lease time, 0 meaning a permanent mapping.
+		// Gateways that can't grant permanent mappings (PCP, NAT-PMP) report a
+		// finite lease even for the mappings wanted as permanent.
+		case m.Duration > 0 && m.Duration <= renewBefore:
+			res = append(res, Repair{Mapping: d, Reason: RepairExpiring})
+		}
+	}
+	return res
+}
+
+// portKey identifies an external port of the gateway.
+type portKey struct {
+	port     int
+	protocol string
+}
+
+func keyFor(externalPort int, protocol string) portKey {
+	return portKey{port: externalPort, protocol: strings.ToUpper(protocol)}
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package upnp
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDiffPortMappings(t *testing.T) {
+	desired := func(port int, duration int) *PortMapping {
+		return &PortMapping{
+			ExternalPort: port,
+			InternalPort: port,
+			InternalIP:   net.ParseIP("192.168.1.100"),
+			Protocol:     "tcp",
+			Duration:     duration,
+		}
+	}
+	programmed := func(port int, ip string, remaining int) *PortMapping {
+		return &PortMapping{
+			ExternalPort: port,
+			InternalPort: port,
+			InternalIP:   net.ParseIP(ip),
+			Protocol:     "TCP",
+			Duration:     remaining,
+		}
+	}
+
+	tests := []struct {
+		desc     string
+		current  []*PortMapping
+		desired  []*PortMapping
+		expected []string
+	}{
+		{
+			desc:     "in sync",
+			current:  []*PortMapping{programmed(80, "192.168.1.100", 0)},
+			desired:  []*PortMapping{desired(80, 0)},
+			expected: nil,
+		},
+		{
+			desc:     "missing mapping",
+			current:  []*PortMapping{programmed(80, "192.168.1.100", 0)},
+			desired:  []*PortMapping{desired(80, 0), desired(443, 0)},
+			expected: []string{RepairMissing},
+		},
+		{
+			desc:     "same protocol is required",
+			current:  []*PortMapping{{ExternalPort: 80, InternalPort: 80, InternalIP: net.ParseIP("192.168.1.100"), Protocol: "UDP"}},
+			desired:  []*PortMapping{desired(80, 0)},
+			expected: []string{RepairMissing},
+		},
+		{
+			desc:     "forwarded to another endpoint",
+			current:  []*PortMapping{programmed(80, "192.168.1.101", 0)},
+			desired:  []*PortMapping{desired(80, 0)},
+			expected: []string{RepairChanged},
+		},
+		{
+			desc:     "lease about to expire",
+			current:  []*PortMapping{programmed(80, "192.168.1.100", 30)},
+			desired:  []*PortMapping{desired(80, 3600)},
+			expected: []string{RepairExpiring},
+		},
+		{
+			desc:     "lease far from expiring",
+			current:  []*PortMapping{programmed(80, "192.168.1.100", 1800)},
+			desired:  []*PortMapping{desired(80, 3600)},
+			expected: nil,
+		},
+		{
+			desc:     "permanent mapping granted with a finite lease",
+			current:  []*PortMapping{programmed(80, "192.168.1.100", 30)},
+			desired:  []*PortMapping{desired(80, 0)},
+			expected: []string{RepairExpiring},
+		},
+		{
+			desc:     "empty router table",
+			current:  nil,
+			desired:  []*PortMapping{desired(80, 3600), desired(443, 3600)},
+			expected: []string{RepairMissing, RepairMissing},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			repairs := diffPortMappings(test.current, test.desired, 60)
+			if len(repairs) != len(test.expected) {
+				t.Fatalf("expected %d repairs, got %d: %v", len(test.expected), len(repairs), repairs)
+			}
+			for i, r := range repairs {
+				if r.Reason != test.expected[i] {
+					t.Errorf("expected repair %d to be %q, got %q", i, test.expected[i], r.Reason)
+				}
+			}
+		})
+	}
+}
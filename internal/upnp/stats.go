@@ -0,0 +1,179 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package upnp
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/huin/goupnp/soap"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var stats = metrics{
+	repaired: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "mappings_repaired",
+		Help:      "Number of port mappings programmed again on the gateway because they were missing, changed or about to expire",
+	}, []string{
+		"reason",
+	}),
+
+	repairFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "mappings_repair_failed",
+		Help:      "Number of port mappings that could not be programmed again on the gateway",
+	}, []string{
+		"reason",
+	}),
+
+	resyncFailed: prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "resync_failed",
+		Help:      "Number of times the port mappings could not be read back from the gateway",
+	}),
+
+	discoveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "gateway_discoveries",
+		Help:      "Number of times the gateways were looked up successfully",
+	}, []string{
+		"protocol",
+	}),
+
+	discoveryFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "gateway_discovery_failed",
+		Help:      "Number of times no gateway could be found or reached",
+	}, []string{
+		"protocol",
+	}),
+
+	mappings: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "mappings",
+		Help:      "Number of port mappings programmed on the gateways for the service",
+	}, []string{
+		"service",
+	}),
+
+	mappingErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "mapping_errors",
+		Help:      "Number of port mappings the gateway failed to add or delete, by UPnP error or PCP / NAT-PMP result code",
+	}, []string{
+		"operation",
+		"code",
+	}),
+
+	lastRefresh: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "last_refresh_timestamp_seconds",
+		Help:      "Unix time of the last time the port mappings of the gateway were read back and refreshed successfully",
+	}, []string{
+		"gateway",
+	}),
+
+	gatewayInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "metallb",
+		Subsystem: "upnp",
+		Name:      "gateway_info",
+		Help:      "Information about the gateway, with its current external IP",
+	}, []string{
+		"gateway",
+		"external_ip",
+	}),
+}
+
+type metrics struct {
+	repaired        *prometheus.CounterVec
+	repairFailed    *prometheus.CounterVec
+	resyncFailed    prometheus.Counter
+	discoveries     *prometheus.CounterVec
+	discoveryFailed *prometheus.CounterVec
+	mappings        *prometheus.GaugeVec
+	mappingErrors   *prometheus.CounterVec
+	lastRefresh     *prometheus.GaugeVec
+	gatewayInfo     *prometheus.GaugeVec
+}
+
+func init() {
+	prometheus.MustRegister(stats.repaired)
+	prometheus.MustRegister(stats.repairFailed)
+	prometheus.MustRegister(stats.resyncFailed)
+	prometheus.MustRegister(stats.discoveries)
+	prometheus.MustRegister(stats.discoveryFailed)
+	prometheus.MustRegister(stats.mappings)
+	prometheus.MustRegister(stats.mappingErrors)
+	prometheus.MustRegister(stats.lastRefresh)
+	prometheus.MustRegister(stats.gatewayInfo)
+}
+
+func (m *metrics) Repaired(reason string) {
+	m.repaired.WithLabelValues(reason).Add(1)
+}
+
+func (m *metrics) RepairFailed(reason string) {
+	m.repairFailed.WithLabelValues(reason).Add(1)
+}
+
+func (m *metrics) ResyncFailed() {
+	m.resyncFailed.Add(1)
+}
+
+func (m *metrics) Discovered(protocol string) {
+	m.discoveries.WithLabelValues(protocol).Add(1)
+}
+
+func (m *metrics) DiscoveryFailed(protocol string) {
+	m.discoveryFailed.WithLabelValues(protocol).Add(1)
+}
+
+func (m *metrics) MappingError(operation string, err error) {
+	m.mappingErrors.WithLabelValues(operation, errorCode(err)).Add(1)
+}
+
+func (m *metrics) Refreshed(gateway string) {
+	m.lastRefresh.WithLabelValues(gateway).Set(float64(time.Now().Unix()))
+}
+
+func (m *metrics) ExternalIP(gateway string, ip net.IP) {
+	m.gatewayInfo.DeletePartialMatch(prometheus.Labels{"gateway": gateway})
+	m.gatewayInfo.WithLabelValues(gateway, ip.String()).Set(1)
+}
+
+// ServiceMappings reports the number of port mappings programmed on the
+// gateways for the service. The metric of the service is removed when n is
+// zero.
+func ServiceMappings(service string, n int) {
+	if n == 0 {
+		stats.mappings.DeleteLabelValues(service)
+		return
+	}
+	stats.mappings.WithLabelValues(service).Set(float64(n))
+}
+
+// errorCode returns the UPnP error code, or the PCP / NAT-PMP result code,
+// the gateway answered with. Errors not coming from the gateway, like
+// timeouts, are reported as "transport".
+func errorCode(err error) string {
+	var soapErr *soap.SOAPFaultError
+	if errors.As(err, &soapErr) {
+		return strconv.Itoa(soapErr.Detail.UPnPError.Errorcode)
+	}
+	var resultErr *resultError
+	if errors.As(err, &resultErr) {
+		return strconv.Itoa(resultErr.code)
+	}
+	return "transport"
+}
@@ -34,6 +34,7 @@ import (
 	discovery "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
 
 	"errors"
 )
@@ -67,13 +68,18 @@ type bgpController struct {
 	nodeLabels         labels.Set
 	peers              []*peer
 	svcAds             map[string][]*bgp.Advertisement
-	activeAds          map[string]sets.Set[string] // svc -> the peers it is advertised to
+	activeAds          map[string]sets.Set[string]                // svc -> the peers it is advertised to
+	activeSvcAds       map[string]map[string][]*bgp.Advertisement // svc -> peer -> the advertisements of the svc sent to it
 	activeAdsMutex     sync.RWMutex
 	adsChangedCallback func(string)
 	bgpType            bgpImplementation
 	secretHandling     SecretHandling
 	sessionManager     bgp.SessionManager
 	ignoreExcludeLB    bool
+	// The running sessions by peer name, read when reporting their status.
+	sessions                map[string]bgp.Session
+	sessionsMutex           sync.RWMutex
+	sessionsChangedCallback func(string)
 }
 
 func (c *bgpController) SetConfig(l log.Logger, cfg *config.Config) error {
@@ -115,6 +121,7 @@ newPeers:
 			if err := p.session.Close(); err != nil {
 				level.Error(l).Log("op", "setConfig", "error", err, "peer", p.id, "msg", "failed to shut down BGP session")
 			}
+			c.setSession(p, nil)
 		}
 		level.Debug(l).Log("event", "peerRemoved", "peer", p.id, "reason", "removedFromConfig", "msg", "peer deconfigured, BGP session closed")
 	}
@@ -232,7 +239,7 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 			if err := p.session.Close(); err != nil {
 				level.Error(l).Log("op", "syncPeers", "error", err, "peer", p.id, "msg", "failed to shut down BGP session")
 			}
-			p.session = nil
+			c.setSession(p, nil)
 		} else if p.session == nil && shouldRun {
 			// Session doesn't exist, but should be running. Create
 			// it.
@@ -251,6 +258,7 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 				PeerPort:               p.cfg.Port,
 				PeerInterface:          p.cfg.Iface,
 				SourceAddress:          p.cfg.SrcAddr,
+				IPv6NextHop:            p.cfg.IPv6NextHop,
 				MyASN:                  p.cfg.MyASN,
 				RouterID:               routerID,
 				PeerASN:                p.cfg.ASN,
@@ -261,11 +269,13 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 				CurrentNode:            c.myNode,
 				BFDProfile:             p.cfg.BFDProfile,
 				GracefulRestart:        p.cfg.EnableGracefulRestart,
+				GracefulRestartTime:    p.cfg.GracefulRestartTime,
 				EBGPMultiHop:           p.cfg.EBGPMultiHop,
 				SessionName:            p.cfg.Name,
 				VRFName:                p.cfg.VRF,
 				DualStackAddressFamily: p.cfg.DualStackAddressFamily,
 				DisableMP:              p.cfg.DisableMP, //nolint:staticcheck // SA1019: intentionally using deprecated field for translation
+				StatusChanged:          c.sessionStatusChanged(p.cfg.Name),
 			}
 			sessionParams.Password, sessionParams.PasswordRef = passwordForSession(p.cfg, c.bgpType, c.secretHandling)
 
@@ -275,7 +285,7 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 				level.Error(l).Log("op", "syncPeers", "error", err, "peer", p.id, "msg", "failed to create BGP session")
 				errs++
 			} else {
-				p.session = s
+				c.setSession(p, s)
 				needUpdateAds = true
 			}
 		}
@@ -293,6 +303,55 @@ func (c *bgpController) syncPeers(l log.Logger) error {
 	return nil
 }
 
+// setSession sets the session of the peer, nil once it is closed, and
+// notifies the change of its status.
+func (c *bgpController) setSession(p *peer, s bgp.Session) {
+	p.session = s
+
+	c.sessionsMutex.Lock()
+	if c.sessions == nil {
+		c.sessions = map[string]bgp.Session{}
+	}
+	if s == nil {
+		delete(c.sessions, p.cfg.Name)
+	} else {
+		c.sessions[p.cfg.Name] = s
+	}
+	c.sessionsMutex.Unlock()
+
+	if c.sessionsChangedCallback != nil {
+		c.sessionsChangedCallback(p.cfg.Name)
+	}
+}
+
+// sessionStatusChanged returns the function the session with the given peer
+// calls when it gets established or goes down, so that its status is
+// reported right away.
+func (c *bgpController) sessionStatusChanged(peer string) func() {
+	return func() {
+		if c.sessionsChangedCallback != nil {
+			c.sessionsChangedCallback(peer)
+		}
+	}
+}
+
+// SessionStatus returns the status of the session with the given peer, and
+// false if this node has no session with it. The status is empty when the
+// session doesn't report it.
+func (c *bgpController) SessionStatus(peer string) (bgp.SessionStatus, bool) {
+	c.sessionsMutex.RLock()
+	s, ok := c.sessions[peer]
+	c.sessionsMutex.RUnlock()
+	if !ok {
+		return bgp.SessionStatus{}, false
+	}
+	reporter, ok := s.(bgp.StatusReporter)
+	if !ok {
+		return bgp.SessionStatus{}, true
+	}
+	return reporter.Status(), true
+}
+
 func passwordForSession(cfg *config.Peer, bgpType bgpImplementation, secret SecretHandling) (string, v1.SecretReference) {
 	if cfg.SecretPassword != "" && cfg.Password != "" {
 		panic(fmt.Sprintf("non empty password and secret password for peer %s", cfg.Name))
@@ -342,7 +401,11 @@ func (c *bgpController) SetBalancer(l log.Logger, name string, lbIPs []net.IP, p
 					IP:   lbIP.Mask(m),
 					Mask: m,
 				},
-				LocalPref: adCfg.LocalPref,
+				LocalPref:     adCfg.LocalPref,
+				ASPathPrepend: adCfg.ASPathPrepend,
+			}
+			if adCfg.MED != nil {
+				ad.MED = ptr.To(*adCfg.MED)
 			}
 			if len(adCfg.Peers) > 0 {
 				ad.Peers = make([]string, 0, len(adCfg.Peers))
@@ -422,6 +485,20 @@ func (c *bgpController) notifyAdsChanged(newAds map[string][]*bgp.Advertisement)
 
 	oldActiveAds := c.activeAds
 	newActiveAds := map[string]sets.Set[string]{}
+	oldActiveSvcAds := c.activeSvcAds
+	newActiveSvcAds := map[string]map[string][]*bgp.Advertisement{}
+	for peer := range newAds {
+		for svc, ads := range c.svcAds {
+			peerAds := adsForPeer(peer, ads)
+			if peerAds == nil {
+				continue
+			}
+			if _, ok := newActiveSvcAds[svc]; !ok {
+				newActiveSvcAds[svc] = map[string][]*bgp.Advertisement{}
+			}
+			newActiveSvcAds[svc][peer] = peerAds
+		}
+	}
 	for peer, ads := range newAds {
 		for _, ad := range ads {
 			adSvcs := pfxToSvc[ad.Prefix.String()]
@@ -441,7 +518,7 @@ func (c *bgpController) notifyAdsChanged(newAds map[string][]*bgp.Advertisement)
 			changedSvcs = append(changedSvcs, svc)
 			continue
 		}
-		if !oldPeers.Equal(newPeers) {
+		if !oldPeers.Equal(newPeers) || !reflect.DeepEqual(oldActiveSvcAds[svc], newActiveSvcAds[svc]) {
 			changedSvcs = append(changedSvcs, svc)
 		}
 	}
@@ -453,6 +530,7 @@ func (c *bgpController) notifyAdsChanged(newAds map[string][]*bgp.Advertisement)
 		}
 	}
 	c.activeAds = newActiveAds
+	c.activeSvcAds = newActiveSvcAds
 }
 
 func adsForPeer(peerName string, ads []*bgp.Advertisement) []*bgp.Advertisement {
@@ -498,9 +576,9 @@ func (c *bgpController) SetNode(l log.Logger, node *v1.Node) error {
 var newBGP = func(cfg controllerConfig) bgp.SessionManager {
 	switch cfg.bgpType {
 	case bgpNative:
-		return bgpnative.NewSessionManager(cfg.Logger)
+		return bgpnative.NewSessionManager(cfg.Logger, cfg.BGPBackoffMin, cfg.BGPBackoffMax)
 	case bgpFrr:
-		return bgpfrr.NewSessionManager(cfg.Logger, cfg.LogLevel)
+		return bgpfrr.NewSessionManager(cfg.Logger, cfg.LogLevel, cfg.FRRNeighborsURL)
 	case bgpFrrK8s:
 		return bgpfrrk8s.NewSessionManager(cfg.Logger, cfg.LogLevel, cfg.MyNode, cfg.FRRK8sNamespace)
 	default:
@@ -522,3 +600,32 @@ func (c *bgpController) PeersForService(key string) sets.Set[string] {
 	defer c.activeAdsMutex.RUnlock()
 	return c.activeAds[key]
 }
+
+// AdvertisementsForService returns the advertisements of the service sent to
+// each of the peers, by peer name. The ones a session does not send to its
+// peer, because it can't advertise their address family, are left out.
+func (c *bgpController) AdvertisementsForService(key string) map[string][]*bgp.Advertisement {
+	c.activeAdsMutex.RLock()
+	ads := c.activeSvcAds[key]
+	c.activeAdsMutex.RUnlock()
+	if ads == nil {
+		return nil
+	}
+
+	c.sessionsMutex.RLock()
+	defer c.sessionsMutex.RUnlock()
+	res := make(map[string][]*bgp.Advertisement, len(ads))
+	for peer, peerAds := range ads {
+		reporter, ok := c.sessions[peer].(bgp.PrefixReporter)
+		if !ok {
+			res[peer] = peerAds
+			continue
+		}
+		for _, ad := range peerAds {
+			if reporter.Advertises(ad.Prefix) {
+				res[peer] = append(res[peer], ad)
+			}
+		}
+	}
+	return res
+}
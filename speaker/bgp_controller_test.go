@@ -105,6 +105,8 @@ type fakeBGPSessionManager struct {
 	sync.Mutex
 	// peer IP -> advertisements
 	gotAds map[string][]*bgp.Advertisement
+	// prefixes the sessions don't send to their peer
+	unsent map[string]bool
 }
 
 func (f *fakeBGPSessionManager) NewSession(_ log.Logger, args bgp.SessionParameters) (bgp.Session, error) {
@@ -189,6 +191,13 @@ func (f *fakeSession) Set(ads ...*bgp.Advertisement) error {
 	return nil
 }
 
+func (f *fakeSession) Advertises(prefix *net.IPNet) bool {
+	f.f.Lock()
+	defer f.f.Unlock()
+
+	return !f.f.unsent[prefix.String()]
+}
+
 // testK8S implements service by recording what the controller wants
 // to do to k8s.
 type testK8S struct {
@@ -200,6 +209,10 @@ func (s *testK8S) UpdateStatus(svc *v1.Service) error {
 	panic("never called")
 }
 
+func (s *testK8S) AnnotateService(svc *v1.Service, key, value string) error {
+	panic("never called")
+}
+
 func (s *testK8S) Infof(_ *v1.Service, evtType string, msg string, args ...interface{}) {
 	s.t.Logf("k8s Info event %q: %s", evtType, fmt.Sprintf(msg, args...))
 }
@@ -1940,3 +1953,201 @@ func TestPeersForService(t *testing.T) {
 		}
 	}
 }
+
+func TestSessionStatus(t *testing.T) {
+	changed := []string{}
+	b := &fakeBGP{
+		t: t,
+	}
+	newBGP = b.NewSessionManager
+	c, err := newController(controllerConfig{
+		MyNode:                "pandora",
+		DisableLayer2:         true,
+		bgpType:               bgpNative,
+		BGPAdsChangedCallback: noopCallback,
+		BGPSessionsChangedCallback: func(peer string) {
+			changed = append(changed, peer)
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	c.client = &testK8S{t: t}
+
+	peer1 := &config.Peer{
+		Name:          "peer1",
+		Addr:          net.ParseIP("1.2.3.4"),
+		NodeSelectors: []labels.Selector{labels.Everything()},
+	}
+	peer2 := &config.Peer{
+		Name:          "peer2",
+		Addr:          net.ParseIP("1.2.3.5"),
+		NodeSelectors: []labels.Selector{mustSelector("host=frontend")},
+	}
+
+	tests := []struct {
+		desc        string
+		peers       []*config.Peer
+		wantChanged []string
+		wantRunning map[string]bool
+	}{
+		{
+			desc:        "peer2 not selecting the node",
+			peers:       []*config.Peer{peer1, peer2},
+			wantChanged: []string{"peer1"},
+			wantRunning: map[string]bool{"peer1": true, "peer2": false},
+		},
+		{
+			desc:        "peer1 removed",
+			peers:       []*config.Peer{peer2},
+			wantChanged: []string{"peer1"},
+			wantRunning: map[string]bool{"peer1": false, "peer2": false},
+		},
+	}
+
+	l := log.NewNopLogger()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			changed = []string{}
+			cfg := &config.Config{
+				Peers: map[string]*config.Peer{},
+				Pools: &config.Pools{ByName: map[string]*config.Pool{}},
+			}
+			for _, p := range test.peers {
+				cfg.Peers[p.Name] = p
+			}
+			if c.SetConfig(l, cfg) == controllers.SyncStateError {
+				t.Fatalf("SetConfig failed")
+			}
+			if !cmp.Equal(test.wantChanged, changed) {
+				t.Fatalf("unexpected changed sessions (-want +got)\n%s", cmp.Diff(test.wantChanged, changed))
+			}
+			fetcher := c.bgpSessionStatusFetcher
+			for peer, want := range test.wantRunning {
+				if _, got := fetcher(peer); got != want {
+					t.Fatalf("expected session with %s running %v, got %v", peer, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAdvertisementsForService(t *testing.T) {
+	callbackCounters := map[string]int{}
+	b := &fakeBGP{
+		t: t,
+	}
+	newBGP = b.NewSessionManager
+	c, err := newController(controllerConfig{
+		MyNode:        "pandora",
+		DisableLayer2: true,
+		bgpType:       bgpNative,
+		BGPAdsChangedCallback: func(key string) {
+			callbackCounters[key]++
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating controller: %s", err)
+	}
+	c.client = &testK8S{t: t}
+
+	cfgWithMED := func(med *uint32) *config.Config {
+		return &config.Config{
+			Peers: map[string]*config.Peer{
+				"peer1": {
+					Name:          "peer1",
+					Addr:          net.ParseIP("1.2.3.4"),
+					NodeSelectors: []labels.Selector{labels.Everything()},
+				},
+				"peer2": {
+					Name:          "peer2",
+					Addr:          net.ParseIP("1.2.3.5"),
+					NodeSelectors: []labels.Selector{labels.Everything()},
+				},
+			},
+			Pools: &config.Pools{ByName: map[string]*config.Pool{
+				"pool1": {
+					CIDR: []*net.IPNet{ipnet("10.20.30.0/24")},
+					BGPAdvertisements: []*config.BGPAdvertisement{
+						{
+							AggregationLength: 24,
+							LocalPref:         100,
+							MED:               med,
+							Nodes:             map[string]bool{"pandora": true},
+							Peers:             []string{"peer1"},
+						},
+					},
+				},
+			}},
+		}
+	}
+	svc := &v1.Service{
+		Spec: v1.ServiceSpec{
+			Type:                  "LoadBalancer",
+			ExternalTrafficPolicy: "Cluster",
+		},
+		Status: statusAssigned("10.20.30.1"),
+	}
+	eps := []discovery.EndpointSlice{
+		{
+			Endpoints: []discovery.Endpoint{
+				{
+					Addresses: []string{
+						"2.3.4.5",
+					},
+					NodeName: ptr.To("iris"),
+					Conditions: discovery.EndpointConditions{
+						Ready: ptr.To(true),
+					},
+				},
+			},
+		},
+	}
+
+	l := log.NewNopLogger()
+	if c.SetConfig(l, cfgWithMED(nil)) == controllers.SyncStateError {
+		t.Fatalf("SetConfig failed")
+	}
+	if c.SetBalancer(l, "test1", svc, eps) == controllers.SyncStateError {
+		t.Fatalf("SetBalancer failed")
+	}
+
+	want := map[string][]*bgp.Advertisement{
+		"peer1": {
+			{
+				Prefix:    ipnet("10.20.30.0/24"),
+				LocalPref: 100,
+				Peers:     []string{"peer1"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, c.bgpAdsFetcher("test1")); diff != "" {
+		t.Fatalf("unexpected advertisements (-want +got)\n%s", diff)
+	}
+	if callbackCounters["test1"] != 1 {
+		t.Fatalf("expected 1 callback for test1, got %d", callbackCounters["test1"])
+	}
+
+	// Changing the attributes sent to the same peer must notify the service too.
+	if c.SetConfig(l, cfgWithMED(ptr.To(uint32(50)))) == controllers.SyncStateError {
+		t.Fatalf("SetConfig failed")
+	}
+	if c.SetBalancer(l, "test1", svc, eps) == controllers.SyncStateError {
+		t.Fatalf("SetBalancer failed")
+	}
+	want["peer1"][0].MED = ptr.To(uint32(50))
+	if diff := cmp.Diff(want, c.bgpAdsFetcher("test1")); diff != "" {
+		t.Fatalf("unexpected advertisements after changing the MED (-want +got)\n%s", diff)
+	}
+	if callbackCounters["test1"] != 2 {
+		t.Fatalf("expected 2 callbacks for test1, got %d", callbackCounters["test1"])
+	}
+
+	// The prefixes a session can't send to its peer are not reported.
+	b.sessionManager.Lock()
+	b.sessionManager.unsent = map[string]bool{"10.20.30.0/24": true}
+	b.sessionManager.Unlock()
+	if diff := cmp.Diff(map[string][]*bgp.Advertisement{}, c.bgpAdsFetcher("test1")); diff != "" {
+		t.Fatalf("unexpected advertisements for a prefix not sent (-want +got)\n%s", diff)
+	}
+}
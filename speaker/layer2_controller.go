@@ -18,7 +18,9 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"maps"
+	"math"
 	"net"
+	"slices"
 	"sort"
 
 	"github.com/go-kit/log"
@@ -41,6 +43,12 @@ type layer2Controller struct {
 	ignoreExcludeLB bool
 	sList           SpeakerList
 	onStatusChange  func(types.NamespacedName)
+	// holders returns the nodes announcing an IP of a service, the most
+	// recent holder first, used by the sticky advertisements.
+	holders func(types.NamespacedName, net.IP) []string
+	// ownedIPs are the IPs of the services won by this node, when the node
+	// announcing each IP is elected independently.
+	ownedIPs map[string][]net.IP
 }
 
 func (c *layer2Controller) SetConfig(log.Logger, *config.Config) error {
@@ -103,30 +111,71 @@ func (c *layer2Controller) ShouldAnnounce(l log.Logger, name string, toAnnounce
 
 	level.Debug(l).Log("event", "shouldannounce", "protocol", "l2", "nodes", availableNodes, "service", name)
 
-	// Using the first IP should work for both single and dual stack.
-	ipString := toAnnounce[0].String()
-	// Sort the slice by the hash of node + load balancer ips. This
-	// produces an ordering of ready nodes that is unique to all the services
-	// with the same ip.
-	sort.Slice(availableNodes, func(i, j int) bool {
-		hi := sha256.Sum256([]byte(availableNodes[i] + "#" + ipString))
-		hj := sha256.Sum256([]byte(availableNodes[j] + "#" + ipString))
+	if !isPerIPElection(pool) {
+		delete(c.ownedIPs, name)
+		// Using the first IP should work for both single and dual stack.
+		// Are we elected? If so, we win and should announce.
+		if c.electedNode(l, name, svc, toAnnounce[0], availableNodes, pool) == c.myNode {
+			return ""
+		}
+		// Either not eligible, or lost the election entirely.
+		return "notOwner"
+	}
 
-		return bytes.Compare(hi[:], hj[:]) < 0
-	})
+	owned := []net.IP{}
+	for _, ip := range toAnnounce {
+		if c.electedNode(l, name, svc, ip, slices.Clone(availableNodes), pool) == c.myNode {
+			owned = append(owned, ip)
+		}
+	}
+	level.Debug(l).Log("event", "shouldannounce", "protocol", "l2", "service", name, "owned", owned, "message", "per ip election")
+	if len(owned) == 0 {
+		delete(c.ownedIPs, name)
+		return "notOwner"
+	}
+	if c.ownedIPs == nil {
+		c.ownedIPs = map[string][]net.IP{}
+	}
+	c.ownedIPs[name] = owned
+	return ""
+}
 
-	// Are we first in the list? If so, we win and should announce.
-	if len(availableNodes) > 0 && availableNodes[0] == c.myNode {
-		return ""
+// electedNode returns the node that announces ip among the available ones,
+// which are sorted in place.
+func (c *layer2Controller) electedNode(l log.Logger, name string, svc *v1.Service, ip net.IP, availableNodes []string, pool *config.Pool) string {
+	// The current holder keeps announcing the ip while it's available.
+	if isSticky(pool) {
+		if holder := c.availableHolder(svc, ip, availableNodes); holder != "" {
+			level.Debug(l).Log("event", "shouldannounce", "protocol", "l2", "service", name, "ip", ip, "holder", holder, "message", "keeping the current holder")
+			return holder
+		}
 	}
 
-	// Either not eligible, or lost the election entirely.
-	return "notOwner"
+	sortNodesByPriority(availableNodes, ip, pool.L2Advertisements)
+	return availableNodes[0]
+}
+
+// AnnouncedIPs returns the IPs of the service announced from this node.
+func (c *layer2Controller) AnnouncedIPs(name string, lbIPs []net.IP) []net.IP {
+	if owned, ok := c.ownedIPs[name]; ok {
+		return owned
+	}
+	return lbIPs
 }
 
 func (c *layer2Controller) SetBalancer(l log.Logger, name string, lbIPs []net.IP, pool *config.Pool, client service, svc *v1.Service) error {
 	ifs := c.announcer.GetInterfaces()
 	updateStatus := false
+	if owned, ok := c.ownedIPs[name]; ok {
+		// Stop announcing the IPs elected on other nodes.
+		for _, adv := range c.announcer.GetStatus(types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}) {
+			if !slices.ContainsFunc(owned, adv.GetIP().Equal) {
+				c.announcer.DeleteBalancerIP(name, adv.GetIP())
+				updateStatus = true
+			}
+		}
+		lbIPs = owned
+	}
 	for _, lbIP := range lbIPs {
 		ipAdv := ipAdvertisementFor(lbIP, c.myNode, pool.L2Advertisements)
 		if !ipAdv.MatchInterfaces(ifs...) {
@@ -145,6 +194,7 @@ func (c *layer2Controller) SetBalancer(l log.Logger, name string, lbIPs []net.IP
 }
 
 func (c *layer2Controller) DeleteBalancer(l log.Logger, name, reason string) error {
+	delete(c.ownedIPs, name)
 	if !c.announcer.AnnounceName(name) {
 		return nil
 	}
@@ -173,16 +223,140 @@ func (c *layer2Controller) SetEventCallback(callback func(interface{})) {
 
 func ipAdvertisementFor(ip net.IP, localNode string, l2Advertisements []*config.L2Advertisement) layer2.IPAdvertisement {
 	ifs := sets.Set[string]{}
+	allInterfaces := false
+	var gratuitous *layer2.GratuitousSettings
 	for _, l2 := range l2Advertisements {
 		if matchNode := l2.Nodes[localNode]; !matchNode {
 			continue
 		}
+		g := layer2.GratuitousSettings{
+			Interval: l2.GratuitousInterval,
+			Duration: l2.GratuitousDuration,
+			Count:    l2.GratuitousCount,
+		}
+		if gratuitous != nil {
+			g = mergeGratuitous(*gratuitous, g)
+		}
+		gratuitous = &g
 		if l2.AllInterfaces {
-			return layer2.NewIPAdvertisement(ip, true, sets.Set[string]{})
+			allInterfaces = true
+			continue
 		}
 		ifs = ifs.Insert(l2.Interfaces...)
 	}
-	return layer2.NewIPAdvertisement(ip, false, ifs)
+	adv := layer2.NewIPAdvertisement(ip, false, ifs)
+	if allInterfaces {
+		adv = layer2.NewIPAdvertisement(ip, true, sets.Set[string]{})
+	}
+	if gratuitous != nil {
+		adv = adv.WithGratuitous(*gratuitous)
+	}
+	return adv
+}
+
+// mergeGratuitous returns the gratuitous settings to use when two
+// advertisements apply to the same IP, the ones sending the most announcements:
+// the shortest interval, and the longest duration or the highest count, a
+// duration winning over a count. The defaults are left as zero values.
+func mergeGratuitous(a, b layer2.GratuitousSettings) layer2.GratuitousSettings {
+	withDefaults := func(g layer2.GratuitousSettings) layer2.GratuitousSettings {
+		if g.Interval == 0 {
+			g.Interval = layer2.DefaultGratuitousInterval
+		}
+		if g.Duration == 0 && g.Count == 0 {
+			g.Duration = layer2.DefaultGratuitousDuration
+		}
+		return g
+	}
+	a, b = withDefaults(a), withDefaults(b)
+
+	res := layer2.GratuitousSettings{
+		Interval: min(a.Interval, b.Interval),
+		Duration: max(a.Duration, b.Duration),
+	}
+	if res.Duration == 0 {
+		res.Count = max(a.Count, b.Count)
+	}
+	if res.Interval == layer2.DefaultGratuitousInterval {
+		res.Interval = 0
+	}
+	if res.Duration == layer2.DefaultGratuitousDuration {
+		res.Duration = 0
+	}
+	return res
+}
+
+// availableHolder returns the most recent holder of the ip of the service
+// among the available nodes, if any.
+func (c *layer2Controller) availableHolder(svc *v1.Service, ip net.IP, availableNodes []string) string {
+	if c.holders == nil {
+		return ""
+	}
+	for _, holder := range c.holders(types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, ip) {
+		if slices.Contains(availableNodes, holder) {
+			return holder
+		}
+	}
+	return ""
+}
+
+// sortNodesByPriority sorts the nodes by the priority the L2Advertisements
+// give them, the nodes with the same priority being sorted by hash.
+func sortNodesByPriority(nodes []string, ip net.IP, l2Advertisements []*config.L2Advertisement) {
+	sortNodesByHash(nodes, ip)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodePriority(nodes[i], l2Advertisements) < nodePriority(nodes[j], l2Advertisements)
+	})
+}
+
+// nodePriority returns the highest priority given to the node by the
+// L2Advertisements selecting it, the lowest possible one if none does.
+func nodePriority(node string, l2Advertisements []*config.L2Advertisement) int {
+	res := math.MaxInt
+	for _, l2 := range l2Advertisements {
+		if !l2.Nodes[node] {
+			continue
+		}
+		if p, ok := l2.NodePriorities[node]; ok && p < res {
+			res = p
+		}
+	}
+	return res
+}
+
+// isSticky tells if the node announcing an IP of the pool keeps it while
+// available.
+func isSticky(pool *config.Pool) bool {
+	for _, l2 := range pool.L2Advertisements {
+		if l2.Sticky {
+			return true
+		}
+	}
+	return false
+}
+
+// isPerIPElection tells if the node announcing each IP of the pool is elected
+// independently.
+func isPerIPElection(pool *config.Pool) bool {
+	for _, l2 := range pool.L2Advertisements {
+		if l2.PerIPElection {
+			return true
+		}
+	}
+	return false
+}
+
+// sortNodesByHash sorts the nodes by the hash of node + load balancer ip.
+// This produces an ordering of the nodes that is unique to all the services
+// with the same ip.
+func sortNodesByHash(nodes []string, ip net.IP) {
+	ipString := ip.String()
+	sort.Slice(nodes, func(i, j int) bool {
+		hi := sha256.Sum256([]byte(nodes[i] + "#" + ipString))
+		hj := sha256.Sum256([]byte(nodes[j] + "#" + ipString))
+
+		return bytes.Compare(hi[:], hj[:]) < 0
+	})
 }
 
 // nodesWithActiveSpeakers returns the list of nodes with active speakers.
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"slices"
 	"sort"
 	"testing"
+	"time"
 
 	"go.universe.tf/metallb/internal/config"
 	"go.universe.tf/metallb/internal/k8s/controllers"
@@ -18,23 +20,31 @@ import (
 	v1 "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/ptr"
 )
 
 type fakeSpeakerList struct {
-	speakers map[string]bool
+	speakers  map[string]bool
+	tags      map[string][]string
+	published []string
 }
 
 func (sl *fakeSpeakerList) UsableSpeakers() speakerlist.SpeakerListInfo {
 	return speakerlist.SpeakerListInfo{
 		Nodes:    sl.speakers,
+		Tags:     sl.tags,
 		Disabled: sl.speakers == nil,
 	}
 }
 
 func (sl *fakeSpeakerList) Rejoin() {}
 
+func (sl *fakeSpeakerList) SetTags(tags []string) {
+	sl.published = tags
+}
+
 func compareUseableNodesReturnedValue(a, b []string) bool {
 	if &a == &b {
 		return true
@@ -1698,6 +1708,231 @@ func TestClusterPolicy(t *testing.T) {
 	}
 }
 
+func TestShouldAnnounceWithPriorities(t *testing.T) {
+	allNodes := map[string]bool{"iris1": true, "iris2": true, "iris3": true}
+	prioritized := &config.L2Advertisement{
+		Nodes:          allNodes,
+		NodePriorities: map[string]int{"iris2": 1, "iris3": 0},
+	}
+	sticky := &config.L2Advertisement{
+		Nodes:          allNodes,
+		NodePriorities: map[string]int{"iris2": 1, "iris3": 0},
+		Sticky:         true,
+	}
+	eps := []discovery.EndpointSlice{
+		{
+			Endpoints: []discovery.Endpoint{
+				{
+					Addresses: []string{
+						"2.3.4.5",
+					},
+					NodeName: ptr.To("iris1"),
+					Conditions: discovery.EndpointConditions{
+						Ready: ptr.To(true),
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		desc       string
+		advs       []*config.L2Advertisement
+		speakers   map[string]bool
+		holders    []string
+		wantWinner string
+	}{
+		{
+			desc:       "the node with the highest priority wins",
+			advs:       []*config.L2Advertisement{prioritized},
+			speakers:   allNodes,
+			wantWinner: "iris3",
+		},
+		{
+			desc:       "the next priority wins when the highest one is not available",
+			advs:       []*config.L2Advertisement{prioritized},
+			speakers:   map[string]bool{"iris1": true, "iris2": true},
+			wantWinner: "iris2",
+		},
+		{
+			desc:       "the holder is ignored when not sticky",
+			advs:       []*config.L2Advertisement{prioritized},
+			speakers:   allNodes,
+			holders:    []string{"iris1"},
+			wantWinner: "iris3",
+		},
+		{
+			desc:       "sticky, the holder keeps the ip",
+			advs:       []*config.L2Advertisement{sticky},
+			speakers:   allNodes,
+			holders:    []string{"iris1"},
+			wantWinner: "iris1",
+		},
+		{
+			desc:       "sticky, the most recent available holder keeps the ip",
+			advs:       []*config.L2Advertisement{sticky},
+			speakers:   allNodes,
+			holders:    []string{"iris2", "iris1"},
+			wantWinner: "iris2",
+		},
+		{
+			desc:       "sticky, the holder is not available",
+			advs:       []*config.L2Advertisement{sticky},
+			speakers:   map[string]bool{"iris2": true, "iris3": true},
+			holders:    []string{"iris1"},
+			wantWinner: "iris3",
+		},
+		{
+			desc:       "sticky, no holder",
+			advs:       []*config.L2Advertisement{sticky},
+			speakers:   allNodes,
+			wantWinner: "iris3",
+		},
+	}
+
+	l := log.NewNopLogger()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			pool := &config.Pool{L2Advertisements: test.advs}
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "svc1",
+					Namespace: "default",
+				},
+				Spec: v1.ServiceSpec{
+					Type:                  "LoadBalancer",
+					ExternalTrafficPolicy: v1.ServiceExternalTrafficPolicyTypeCluster,
+				},
+			}
+			for i := 1; i < 256; i++ {
+				lbIP := net.ParseIP(fmt.Sprintf("10.20.30.%d", i))
+				winners := []string{}
+				for node := range test.speakers {
+					c := &layer2Controller{
+						myNode: node,
+						sList:  &fakeSpeakerList{speakers: test.speakers},
+						holders: func(types.NamespacedName, net.IP) []string {
+							return test.holders
+						},
+					}
+					if c.ShouldAnnounce(l, "default/svc1", []net.IP{lbIP}, pool, svc, eps, nil) == "" {
+						winners = append(winners, node)
+					}
+				}
+				if len(winners) != 1 || winners[0] != test.wantWinner {
+					t.Fatalf("ip %s: expected %s to announce, got %v", lbIP, test.wantWinner, winners)
+				}
+			}
+		})
+	}
+}
+
+func TestShouldAnnouncePerIP(t *testing.T) {
+	allNodes := map[string]bool{"iris1": true, "iris2": true, "iris3": true}
+	eps := []discovery.EndpointSlice{
+		{
+			Endpoints: []discovery.Endpoint{
+				{
+					Addresses: []string{
+						"2.3.4.5",
+					},
+					NodeName: ptr.To("iris1"),
+					Conditions: discovery.EndpointConditions{
+						Ready: ptr.To(true),
+					},
+				},
+			},
+		},
+	}
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "svc1",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Type:                  "LoadBalancer",
+			ExternalTrafficPolicy: v1.ServiceExternalTrafficPolicyTypeCluster,
+		},
+	}
+	lbIPs := []net.IP{}
+	for i := 1; i <= 30; i++ {
+		lbIPs = append(lbIPs, net.ParseIP(fmt.Sprintf("10.20.30.%d", i)))
+	}
+	stickyIP := lbIPs[0]
+
+	tests := []struct {
+		desc    string
+		adv     *config.L2Advertisement
+		holders map[string][]string
+		check   func(t *testing.T, owners map[string][]string)
+	}{
+		{
+			desc: "a single node announces all the ips",
+			adv:  &config.L2Advertisement{Nodes: allNodes},
+			check: func(t *testing.T, owners map[string][]string) {
+				if len(owners) != 1 {
+					t.Fatalf("expected a single node to announce the ips, got %v", owners)
+				}
+			},
+		},
+		{
+			desc: "the ips are spread over the nodes",
+			adv:  &config.L2Advertisement{Nodes: allNodes, PerIPElection: true},
+			check: func(t *testing.T, owners map[string][]string) {
+				if len(owners) != len(allNodes) {
+					t.Fatalf("expected all the nodes to announce some ips, got %v", owners)
+				}
+			},
+		},
+		{
+			desc: "sticky, the holder keeps its ip only",
+			adv:  &config.L2Advertisement{Nodes: allNodes, PerIPElection: true, Sticky: true},
+			holders: map[string][]string{
+				stickyIP.String(): {"iris1"},
+			},
+			check: func(t *testing.T, owners map[string][]string) {
+				if !slices.Contains(owners["iris1"], stickyIP.String()) {
+					t.Fatalf("expected iris1 to keep announcing %s, got %v", stickyIP, owners)
+				}
+				if len(owners) != len(allNodes) {
+					t.Fatalf("expected all the nodes to announce some ips, got %v", owners)
+				}
+			},
+		},
+	}
+
+	l := log.NewNopLogger()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			pool := &config.Pool{L2Advertisements: []*config.L2Advertisement{test.adv}}
+			owners := map[string][]string{}
+			announced := map[string]int{}
+			for node := range allNodes {
+				c := &layer2Controller{
+					myNode: node,
+					sList:  &fakeSpeakerList{speakers: allNodes},
+					holders: func(_ types.NamespacedName, ip net.IP) []string {
+						return test.holders[ip.String()]
+					},
+				}
+				if c.ShouldAnnounce(l, "default/svc1", lbIPs, pool, svc, eps, nil) != "" {
+					continue
+				}
+				for _, ip := range c.AnnouncedIPs("default/svc1", lbIPs) {
+					owners[node] = append(owners[node], ip.String())
+					announced[ip.String()]++
+				}
+			}
+			for _, ip := range lbIPs {
+				if announced[ip.String()] != 1 {
+					t.Fatalf("expected ip %s to be announced by a single node, got %v", ip, owners)
+				}
+			}
+			test.check(t, owners)
+		})
+	}
+}
+
 func TestIPAdvertisementFor(t *testing.T) {
 	tests := []struct {
 		desc             string
@@ -1812,6 +2047,73 @@ func TestIPAdvertisementFor(t *testing.T) {
 				},
 			},
 			expect: layer2.NewIPAdvertisement(net.IP{192, 168, 10, 3}, true, sets.Set[string]{}),
+		}, {
+			desc:      "LocalNode match L2Advertisement with gratuitous settings",
+			ip:        net.IP{192, 168, 10, 3},
+			localNode: "nodeA",
+			l2Advertisements: []*config.L2Advertisement{
+				{
+					Nodes: map[string]bool{
+						"nodeA": true,
+					},
+					AllInterfaces:      true,
+					GratuitousInterval: 500 * time.Millisecond,
+					GratuitousCount:    3,
+				},
+			},
+			expect: layer2.NewIPAdvertisement(net.IP{192, 168, 10, 3}, true, sets.Set[string]{}).WithGratuitous(layer2.GratuitousSettings{
+				Interval: 500 * time.Millisecond,
+				Count:    3,
+			}),
+		}, {
+			desc:      "LocalNode match multi-L2Advertisement with gratuitous settings, the most announcements win",
+			ip:        net.IP{192, 168, 10, 3},
+			localNode: "nodeA",
+			l2Advertisements: []*config.L2Advertisement{
+				{
+					Nodes: map[string]bool{
+						"nodeA": true,
+					},
+					Interfaces:         []string{"eth0"},
+					GratuitousInterval: 500 * time.Millisecond,
+					GratuitousCount:    3,
+				}, {
+					Nodes: map[string]bool{
+						"nodeA": true,
+					},
+					Interfaces:         []string{"eth1"},
+					GratuitousInterval: 2 * time.Second,
+					GratuitousDuration: 10 * time.Second,
+				}, {
+					Nodes: map[string]bool{
+						"nodeA": true,
+					},
+					Interfaces: []string{"eth2"},
+				},
+			},
+			expect: layer2.NewIPAdvertisement(net.IP{192, 168, 10, 3}, false, sets.New("eth0", "eth1", "eth2")).WithGratuitous(layer2.GratuitousSettings{
+				Interval: 500 * time.Millisecond,
+				Duration: 10 * time.Second,
+			}),
+		}, {
+			desc:      "LocalNode match multi-L2Advertisement, the default duration wins over a count",
+			ip:        net.IP{192, 168, 10, 3},
+			localNode: "nodeA",
+			l2Advertisements: []*config.L2Advertisement{
+				{
+					Nodes: map[string]bool{
+						"nodeA": true,
+					},
+					AllInterfaces:   true,
+					GratuitousCount: 3,
+				}, {
+					Nodes: map[string]bool{
+						"nodeA": true,
+					},
+					AllInterfaces: true,
+				},
+			},
+			expect: layer2.NewIPAdvertisement(net.IP{192, 168, 10, 3}, true, sets.Set[string]{}),
 		},
 	}
 	for _, test := range tests {
@@ -22,8 +22,11 @@ import (
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -37,6 +40,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"go.universe.tf/metallb/internal/bgp"
+	bgpnative "go.universe.tf/metallb/internal/bgp/native"
 	"go.universe.tf/metallb/internal/config"
 	"go.universe.tf/metallb/internal/k8s"
 	"go.universe.tf/metallb/internal/k8s/controllers"
@@ -66,6 +70,7 @@ const (
 // Service offers methods to mutate a Kubernetes service object.
 type service interface {
 	UpdateStatus(svc *v1.Service) error
+	AnnotateService(svc *v1.Service, key, value string) error
 	Infof(svc *v1.Service, desc, msg string, args ...interface{})
 	Errorf(svc *v1.Service, desc, msg string, args ...interface{})
 }
@@ -89,6 +94,9 @@ func main() {
 		loadBalancerClass = flag.String("lb-class", "", "load balancer class. When enabled, metallb will handle only services whose spec.loadBalancerClass matches the given lb class")
 		ignoreLBExclude   = flag.Bool("ignore-exclude-lb", false, "ignore the exclude-from-external-load-balancers label")
 		frrK8sNamespace   = flag.String("frrk8s-namespace", os.Getenv("FRRK8S_NAMESPACE"), "the namespace frr-k8s is being deployed on")
+		bgpBackoffMin     = flag.Duration("bgp-backoff-min", bgpnative.DefaultBackoffMin, "first delay between connection attempts to a BGP peer without connect time, native mode only")
+		bgpBackoffMax     = flag.Duration("bgp-backoff-max", bgpnative.DefaultBackoffMax, "maximum delay between connection attempts to a BGP peer without connect time, native mode only")
+		frrMetricsPort    = flag.Int("frr-metrics-port", 7473, "port of the FRR metrics exporter, used to read the state of the BGP sessions, frr mode only")
 	)
 	flag.Parse()
 
@@ -121,6 +129,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *bgpBackoffMin <= 0 || *bgpBackoffMax < *bgpBackoffMin {
+		level.Error(logger).Log("op", "startup", "error", "--bgp-backoff-min must be positive and not greater than --bgp-backoff-max", "msg", "invalid configuration")
+		os.Exit(1)
+	}
+
 	stopCh := make(chan struct{})
 	go func() {
 		c1 := make(chan os.Signal, 1)
@@ -159,8 +172,16 @@ func main() {
 		frrK8sNamespace = namespace
 	}
 
+	// The FRR metrics exporter listens on the same address as the speaker.
+	frrMetricsHost := *host
+	if frrMetricsHost == "" {
+		frrMetricsHost = "localhost"
+	}
+
 	l2StatusChan := make(chan event.GenericEvent)
 	bgpStatusChan := make(chan event.GenericEvent)
+	upnpStatusChan := make(chan event.GenericEvent)
+	bgpSessionStatusChan := make(chan event.GenericEvent)
 
 	// Setup all clients and speakers, config decides what is being done runtime.
 	ctrl, err := newController(controllerConfig{
@@ -173,6 +194,10 @@ func main() {
 		bgpType:                bgpImplementation(bgpType),
 		InterfaceExcludeRegexp: interfacesToExclude,
 		IgnoreExcludeLB:        *ignoreLBExclude,
+		BGPBackoffMin:          *bgpBackoffMin,
+		BGPBackoffMax:          *bgpBackoffMax,
+		FRRNeighborsURL:        fmt.Sprintf("http://%s/bgp/neighbors", net.JoinHostPort(frrMetricsHost, strconv.Itoa(*frrMetricsPort))),
+		StopCh:                 stopCh,
 		Layer2StatusChange: func(namespacedName types.NamespacedName) {
 			l2StatusChan <- controllers.NewL2StatusEvent(namespacedName.Namespace, namespacedName.Name)
 		},
@@ -184,6 +209,12 @@ func main() {
 			}
 			bgpStatusChan <- controllers.NewBGPStatusEvent(ns, name)
 		},
+		UPnPStatusChange: func(namespacedName types.NamespacedName) {
+			upnpStatusChan <- controllers.NewUPnPStatusEvent(namespacedName.Namespace, namespacedName.Name)
+		},
+		BGPSessionsChangedCallback: func(peer string) {
+			bgpSessionStatusChan <- controllers.NewBGPSessionStatusEvent(*namespace, peer)
+		},
 	})
 	if err != nil {
 		level.Error(logger).Log("op", "startup", "error", err, "msg", "failed to create MetalLB controller")
@@ -191,9 +222,12 @@ func main() {
 	}
 
 	var validateConfig config.Validate
-	if bgpType == "native" {
+	switch bgpType {
+	case "native":
 		validateConfig = config.DiscardFRROnly
-	} else {
+	case string(bgpFrrK8s):
+		validateConfig = config.DiscardNotInFRRK8s
+	default:
 		validateConfig = config.DiscardNativeOnly
 	}
 
@@ -228,6 +262,12 @@ func main() {
 		Layer2StatusFetcher: ctrl.layer2StatusFetchFunc,
 		BGPStatusChan:       bgpStatusChan,
 		BGPPeersFetcher:     ctrl.bgpPeersFetcher,
+		BGPAdsFetcher:       ctrl.bgpAdsFetcher,
+		UPnPStatusChan:      upnpStatusChan,
+		UPnPStatusFetcher:   ctrl.upnpStatusFetchFunc,
+
+		BGPSessionStatusChan:    bgpSessionStatusChan,
+		BGPSessionStatusFetcher: ctrl.bgpSessionStatusFetcher,
 	})
 	if err != nil {
 		level.Error(logger).Log("op", "startup", "error", err, "msg", "failed to create k8s client")
@@ -235,6 +275,9 @@ func main() {
 	}
 	ctrl.client = client
 	ctrl.protocolHandlers[config.BGP].SetEventCallback(client.BGPEventCallback)
+	if l2, ok := ctrl.protocolHandlers[config.Layer2].(*layer2Controller); ok {
+		l2.holders = client.Layer2Holders
+	}
 
 	sList.Start(client)
 	defer sList.Stop()
@@ -259,8 +302,11 @@ type controller struct {
 
 	protocols []config.Proto
 
-	layer2StatusFetchFunc controllers.L2StatusFetcher
-	bgpPeersFetcher       controllers.PeersForService
+	layer2StatusFetchFunc   controllers.L2StatusFetcher
+	bgpPeersFetcher         controllers.PeersForService
+	bgpAdsFetcher           controllers.AdvertisementsForService
+	upnpStatusFetchFunc     controllers.UPnPStatusFetcher
+	bgpSessionStatusFetcher controllers.BGPSessionStatusFetcher
 }
 
 type controllerConfig struct {
@@ -272,6 +318,14 @@ type controllerConfig struct {
 	SList           SpeakerList
 
 	bgpType bgpImplementation
+	// Bounds of the delay between connection attempts of native BGP sessions.
+	BGPBackoffMin time.Duration
+	BGPBackoffMax time.Duration
+	// Endpoint of the FRR metrics exporter serving the BGP neighbors.
+	FRRNeighborsURL string
+	// Closed on shutdown, stops the background loops. They are not started
+	// when nil, the tests drive them by hand.
+	StopCh <-chan struct{}
 
 	// For testing only, and will be removed in a future release.
 	// See: https://github.com/metallb/metallb/issues/152.
@@ -282,6 +336,8 @@ type controllerConfig struct {
 	IgnoreExcludeLB              bool
 	Layer2StatusChange           func(types.NamespacedName)
 	BGPAdsChangedCallback        func(string)
+	UPnPStatusChange             func(types.NamespacedName)
+	BGPSessionsChangedCallback   func(string)
 }
 
 func newController(cfg controllerConfig) (*controller, error) {
@@ -302,6 +358,8 @@ func newController(cfg controllerConfig) (*controller, error) {
 		sessionManager:     newBGP(cfg),
 		ignoreExcludeLB:    cfg.IgnoreExcludeLB,
 		secretHandling:     secretHandling,
+
+		sessionsChangedCallback: cfg.BGPSessionsChangedCallback,
 	}
 	bgpPeersFetcher := bgpController.PeersForService
 
@@ -327,25 +385,27 @@ func newController(cfg controllerConfig) (*controller, error) {
 		protocols = append(protocols, config.Layer2)
 	}
 
-	// Initialize UPnP controller
-	upnpController, err := newUPnPController(cfg.Logger, cfg.MyNode, cfg.Layer2StatusChange)
-	if err != nil {
-		level.Warn(cfg.Logger).Log("msg", "failed to initialize UPnP controller, UPnP support will be disabled", "error", err)
-	} else {
-		handlers[config.UPnP] = upnpController
-		protocols = append(protocols, config.UPnP)
-		level.Info(cfg.Logger).Log("msg", "UPnP controller initialized successfully")
+	// The UPnP controller reaches the gateways lazily, when an advertisement
+	// selects this node.
+	upnpController := newUPnPController(cfg.Logger, cfg.MyNode, cfg.SList, cfg.IgnoreExcludeLB, cfg.UPnPStatusChange)
+	if cfg.StopCh != nil {
+		go upnpController.resyncLoop(upnpResyncInterval, cfg.StopCh)
 	}
+	handlers[config.UPnP] = upnpController
+	protocols = append(protocols, config.UPnP)
 
 	ret := &controller{
-		myNode:                cfg.MyNode,
-		bgpType:               cfg.bgpType,
-		protocolHandlers:      handlers,
-		announced:             map[config.Proto]map[string]bool{},
-		svcIPs:                map[string][]net.IP{},
-		protocols:             protocols,
-		layer2StatusFetchFunc: layer2StatusFetcher,
-		bgpPeersFetcher:       bgpPeersFetcher,
+		myNode:                  cfg.MyNode,
+		bgpType:                 cfg.bgpType,
+		protocolHandlers:        handlers,
+		announced:               map[config.Proto]map[string]bool{},
+		svcIPs:                  map[string][]net.IP{},
+		protocols:               protocols,
+		layer2StatusFetchFunc:   layer2StatusFetcher,
+		bgpPeersFetcher:         bgpPeersFetcher,
+		bgpAdsFetcher:           bgpController.AdvertisementsForService,
+		upnpStatusFetchFunc:     upnpController.GetStatus,
+		bgpSessionStatusFetcher: bgpController.SessionStatus,
 	}
 	ret.announced[config.BGP] = map[string]bool{}
 	ret.announced[config.Layer2] = map[string]bool{}
@@ -444,13 +504,21 @@ func (c *controller) handleService(l log.Logger,
 		c.svcIPs[name] = lbIPs
 	}
 
+	announcedIPs := lbIPs
+	if h, ok := handler.(partialAnnouncer); ok {
+		announcedIPs = h.AnnouncedIPs(name, lbIPs)
+	}
 	for _, ip := range lbIPs {
+		value := 0.0
+		if slices.ContainsFunc(announcedIPs, ip.Equal) {
+			value = 1
+		}
 		announcing.With(prometheus.Labels{
 			"protocol": string(protocol),
 			"service":  name,
 			"node":     c.myNode,
 			"ip":       ip.String(),
-		}).Set(1)
+		}).Set(value)
 	}
 	level.Info(l).Log("event", "serviceAnnounced", "msg", "service has IP, announcing", "protocol", protocol)
 	c.client.Infof(svc, "nodeAssigned", "announcing from node %q with protocol %q", c.myNode, protocol)
@@ -637,8 +705,15 @@ type Protocol interface {
 	SetEventCallback(func(interface{}))
 }
 
+// partialAnnouncer is implemented by the protocols that may announce only
+// some of the IPs of a service from the node.
+type partialAnnouncer interface {
+	AnnouncedIPs(string, []net.IP) []net.IP
+}
+
 // Speakerlist represents a list of healthy speakers.
 type SpeakerList interface {
 	UsableSpeakers() speakerlist.SpeakerListInfo
 	Rejoin()
+	SetTags([]string)
 }
@@ -16,19 +16,26 @@ package main
 
 import (
 	"fmt"
+	"hash/fnv"
+	"maps"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	v1 "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 
 	"go.universe.tf/metallb/internal/config"
+	k8snodes "go.universe.tf/metallb/internal/k8s/nodes"
+	"go.universe.tf/metallb/internal/speakerlist"
 	"go.universe.tf/metallb/internal/upnp"
 )
 
@@ -39,46 +46,167 @@ const (
 	UPnPDescriptionAnnotation = "metallb.universe.tf/upnp-description"
 	// Annotation to set UPnP port mapping duration
 	UPnPDurationAnnotation = "metallb.universe.tf/upnp-duration"
+	// Annotation mapping service ports to external ports, e.g. "443=8443,80=8080"
+	UPnPExternalPortsAnnotation = "metallb.universe.tf/upnp-external-ports"
+	// Annotation with the range external ports are allocated from, e.g. "30000-30099"
+	UPnPExternalPortRangeAnnotation = "metallb.universe.tf/upnp-external-port-range"
+	// Annotation to publish the external IP of the gateway on the service
+	UPnPPublishExternalIPAnnotation = "metallb.universe.tf/upnp-publish-external-ip"
+	// Annotation set by the speaker to the external IP of the gateway
+	UPnPExternalIPAnnotation = "metallb.universe.tf/upnp-external-ip"
 	// Default UPnP port mapping description
 	DefaultUPnPDescription = "MetalLB LoadBalancer"
 	// Default UPnP port mapping duration (0 = permanent)
 	DefaultUPnPDuration = 0
 )
 
+// upnpResyncInterval is how often the mappings programmed on the gateways are
+// compared with the desired ones. Leases expiring within two intervals are
+// renewed, so that they never lapse between two resyncs.
+const upnpResyncInterval = 30 * time.Second
+
+// upnpAdoptionGracePeriod is how long the mappings found on a gateway when it
+// is first reached are kept while waiting for the services they were created
+// for to be assigned to this node again. The ones that are not claimed in
+// time are deleted.
+const upnpAdoptionGracePeriod = 2 * upnpResyncInterval
+
 type upnpController struct {
-	myNode         string
-	client         *upnp.IGDClient
-	mappings       map[string][]*upnp.PortMapping // service key -> port mappings
-	config         *config.Config
-	mutex          sync.RWMutex
-	onStatusChange func(types.NamespacedName)
+	logger          log.Logger
+	myNode          string
+	sList           SpeakerList
+	ignoreExcludeLB bool
+	gateways        map[string]upnp.Gateway         // device key -> client, created on first use
+	resolved        map[upnp.GatewayConfig][]string // gateway config -> keys of the devices it selects, for the gateways reached
+	newGateways     func(logger log.Logger, cfg upnp.GatewayConfig) ([]upnp.Gateway, error)
+	mappings        map[string][]*upnp.PortMapping // service key -> port mappings
+	svcGateways     map[string][]string            // service key -> keys of the gateways its mappings are on
+	svcs            map[string]*v1.Service         // service key -> last service seen, used for events
+	adopted         map[string]time.Time           // service key -> deadline of the mappings found on a gateway and not claimed yet
+	events          service
+	config          *config.Config
+	mutex           sync.RWMutex
+	onStatusChange  func(types.NamespacedName)
 }
 
-func newUPnPController(logger log.Logger, myNode string, onStatusChange func(types.NamespacedName)) (*upnpController, error) {
-	// Try to create UPnP IGD client
-	client, err := upnp.New(logger)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize UPnP IGD client: %v", err)
-	}
-
+func newUPnPController(logger log.Logger, myNode string, sList SpeakerList, ignoreExcludeLB bool, onStatusChange func(types.NamespacedName)) *upnpController {
 	controller := &upnpController{
-		myNode:         myNode,
-		client:         client,
-		mappings:       make(map[string][]*upnp.PortMapping),
-		onStatusChange: onStatusChange,
+		logger:          logger,
+		myNode:          myNode,
+		sList:           sList,
+		ignoreExcludeLB: ignoreExcludeLB,
+		gateways:        make(map[string]upnp.Gateway),
+		resolved:        make(map[upnp.GatewayConfig][]string),
+		newGateways:     upnp.NewGateways,
+		mappings:        make(map[string][]*upnp.PortMapping),
+		svcGateways:     make(map[string][]string),
+		svcs:            make(map[string]*v1.Service),
+		adopted:         make(map[string]time.Time),
+		onStatusChange:  onStatusChange,
 	}
 
-	level.Info(logger).Log("msg", "UPnP IGD controller initialized", "external_ip", client.GetExternalIP())
-	return controller, nil
+	return controller
 }
 
 func (c *upnpController) SetConfig(l log.Logger, cfg *config.Config) error {
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
 	c.config = cfg
+
+	// Look the gateways up again, so that the devices that appeared since the
+	// last configuration change are found. The clients of the devices already
+	// known are kept.
+	c.resolved = make(map[upnp.GatewayConfig][]string)
+	c.mutex.Unlock()
+
+	// Reach the gateways this node may program right away, so that the
+	// mappings left behind by a previous run are adopted or collected even if
+	// no service is assigned to this node, and so that the other speakers
+	// know this node is eligible.
+	c.resolveGateways(l)
 	return nil
 }
 
+// resolveGateways reaches the gateways of the advertisements that apply to
+// this node and were not reached yet, and tells the other speakers which
+// ones this node can program. It must be called without holding the lock:
+// the gateways are looked up on a snapshot of the known ones, so that a slow
+// discovery does not hold up the services handled meanwhile.
+func (c *upnpController) resolveGateways(l log.Logger) {
+	c.mutex.RLock()
+	unresolved := c.unresolvedConfigs()
+	known := maps.Clone(c.gateways)
+	c.mutex.RUnlock()
+
+	discovered := map[upnp.GatewayConfig][]discoveredGateway{}
+	for cfg, pool := range unresolved {
+		d, err := c.discoverGateways(cfg, known)
+		if err != nil {
+			level.Warn(l).Log("op", "resolveGateways", "protocol", "upnp", "pool", pool, "error", err, "msg", "gateway not reachable, will retry at the next resync")
+			continue
+		}
+		discovered[cfg] = d
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	// The config may have changed, or a service may have reached the
+	// gateways, while they were looked up.
+	wanted := c.unresolvedConfigs()
+	for cfg, d := range discovered {
+		if _, ok := wanted[cfg]; ok {
+			c.registerGateways(l, cfg, d)
+		}
+	}
+	c.publishReachableGateways()
+}
+
+// unresolvedConfigs returns the gateway configs of the advertisements that
+// apply to this node and were not reached yet, with the name of a pool using
+// each of them.
+func (c *upnpController) unresolvedConfigs() map[upnp.GatewayConfig]string {
+	res := map[upnp.GatewayConfig]string{}
+	if c.config == nil || c.config.Pools == nil {
+		return res
+	}
+	for _, pool := range c.config.Pools.ByName {
+		adv := c.advertisementFor(pool)
+		if adv == nil {
+			continue
+		}
+		cfg := gatewayConfigFor(adv)
+		if _, ok := c.resolved[cfg]; ok {
+			continue
+		}
+		res[cfg] = pool.Name
+	}
+	return res
+}
+
+// publishReachableGateways advertises the gateway configs this node reached
+// to the other speakers via memberlist, so that only the speakers able to
+// program the gateways of a service take part in its election.
+func (c *upnpController) publishReachableGateways() {
+	// Without a speaker list, the speakers are assumed to reach the gateways.
+	if c.sList == nil {
+		return
+	}
+	tags := make([]string, 0, len(c.resolved))
+	for cfg := range c.resolved {
+		tags = append(tags, gatewayTag(cfg))
+	}
+	c.sList.SetTags(tags)
+}
+
+// gatewayTag returns the memberlist tag telling that a speaker reached the
+// gateways selected by the config. The config is hashed to fit the tags of
+// many advertisements in the memberlist metadata.
+func gatewayTag(cfg upnp.GatewayConfig) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%s|%t", cfg.Protocol, cfg.Address,
+		cfg.Selector.Location, cfg.Selector.UDN, cfg.Selector.FriendlyName, cfg.Selector.ExternalIP, cfg.All)
+	return fmt.Sprintf("upnp-%08x", h.Sum32())
+}
+
 func (c *upnpController) ShouldAnnounce(l log.Logger, name string, toAnnounce []net.IP, pool *config.Pool, svc *v1.Service, eps []discovery.EndpointSlice, nodes map[string]*v1.Node) string {
 	// Check if UPnP is enabled for this service
 	if !c.isUPnPEnabled(svc) {
@@ -104,17 +232,23 @@ func (c *upnpController) ShouldAnnounce(l log.Logger, name string, toAnnounce []
 		return "notOwner"
 	}
 
-	// UPnP is typically handled by a single node (the one with access to the router)
-	// We'll use a simple election based on node name lexicographic ordering
-	eligibleNodes := c.getEligibleNodes(pool, nodes)
-	if len(eligibleNodes) == 0 {
+	// A single node programs the gateways for the service, elected among the
+	// speakers that can reach them like layer2 does, so that the services are
+	// spread across the nodes and move as soon as memberlist reports the
+	// owner dead.
+	speakers := c.speakersForPool(l, name, pool, nodes)
+	availableNodes := nodesWithActiveSpeakers(speakers)
+	if svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
+		availableNodes = nodesWithEndpoint(eps, speakers)
+	}
+	if len(availableNodes) == 0 {
 		level.Debug(l).Log("event", "shouldannounce", "protocol", "upnp", "message", "no eligible nodes", "service", name)
 		return "notOwner"
 	}
 
-	// Sort nodes and check if we're the first one (simple leader election)
-	if eligibleNodes[0] != c.myNode {
-		level.Debug(l).Log("event", "shouldannounce", "protocol", "upnp", "message", "not the elected node for UPnP", "service", name, "elected", eligibleNodes[0], "mynode", c.myNode)
+	sortNodesByHash(availableNodes, toAnnounce[0])
+	if availableNodes[0] != c.myNode {
+		level.Debug(l).Log("event", "shouldannounce", "protocol", "upnp", "message", "not the elected node for UPnP", "service", name, "elected", availableNodes[0], "mynode", c.myNode)
 		return "notOwner"
 	}
 
@@ -131,14 +265,30 @@ func (c *upnpController) SetBalancer(l log.Logger, name string, lbIPs []net.IP,
 		return nil
 	}
 
-	// Clean up existing mappings for this service
-	if err := c.deleteExistingMappings(l, name); err != nil {
-		level.Warn(l).Log("msg", "failed to clean up existing UPnP mappings", "service", name, "error", err)
+	adv := c.advertisementFor(pool)
+	if adv == nil {
+		level.Debug(l).Log("msg", "no UPnP advertisement for this node", "service", name, "pool", pool.Name)
+		return nil
 	}
+	gwKeys, err := c.gatewaysFor(l, gatewayConfigFor(adv))
+	if err != nil {
+		level.Error(l).Log("msg", "failed to reach the gateway", "service", name, "protocol", adv.Protocol, "error", err)
+		client.Errorf(svc, "UPnPGatewayUnreachable", "Failed to reach the %s gateway: %v", adv.Protocol, err)
+		return err
+	}
+	_, assigned := c.mappings[name]
 
-	// Create new port mappings
-	var newMappings []*upnp.PortMapping
+	// The mappings of the service are on other gateways, because the
+	// advertisement changed: remove them from there first.
+	if current, ok := c.svcGateways[name]; ok && !slices.Equal(current, gwKeys) {
+		level.Info(l).Log("msg", "service moved to other gateways, deleting its mappings from the previous ones", "service", name, "gateways", strings.Join(current, ","))
+		if err := c.deleteExistingMappings(l, name); err != nil {
+			return err
+		}
+	}
+	c.svcGateways[name] = gwKeys
 
+	var desired []*upnp.PortMapping
 	for _, lbIP := range lbIPs {
 		for _, port := range svc.Spec.Ports {
 			// Skip headless services
@@ -152,33 +302,75 @@ func (c *upnpController) SetBalancer(l log.Logger, name string, lbIPs []net.IP,
 				continue
 			}
 
-			mapping := &upnp.PortMapping{
+			desired = append(desired, &upnp.PortMapping{
 				ExternalPort: int(port.Port),
 				InternalPort: int(port.Port),
 				InternalIP:   lbIP,
 				Protocol:     protocol,
 				Description:  c.getPortMappingDescription(svc, port),
 				Duration:     c.getPortMappingDuration(svc),
-			}
+			})
+		}
+	}
+
+	policy, err := parseExternalPortPolicy(svc)
+	if err != nil {
+		level.Error(l).Log("msg", "invalid UPnP external port annotations", "service", name, "error", err)
+		client.Errorf(svc, "UPnPInvalidExternalPorts", "Invalid UPnP external port annotations: %v", err)
+		return nil
+	}
+	desired, conflicts, skipped := assignExternalPorts(desired, c.mappings[name], policy, c.usedExternalPorts(l, name))
+	for _, m := range skipped {
+		level.Debug(l).Log("msg", "UPnP external port forwards to another IP of the service, set an external port range to forward this one too", "service", name, "internal_ip", m.InternalIP, "port", m.InternalPort, "protocol", m.Protocol)
+	}
+	for _, m := range conflicts {
+		level.Error(l).Log("msg", "UPnP external port already in use", "service", name, "external_port", m.ExternalPort, "protocol", m.Protocol)
+		client.Errorf(svc, "UPnPPortConflict", "Failed to create UPnP port mapping for port %d/%s: external port %d is already in use or the range is exhausted", m.InternalPort, m.Protocol, m.ExternalPort)
+	}
 
-			if err := c.client.AddPortMapping(mapping); err != nil {
-				level.Error(l).Log("msg", "failed to add UPnP port mapping", "service", name, "external_port", mapping.ExternalPort, "internal_ip", mapping.InternalIP, "error", err)
-				client.Errorf(svc, "UPnPMappingFailed", "Failed to create UPnP port mapping for port %d: %v", mapping.ExternalPort, err)
+	// Clean up the mappings of this service that are not wanted anymore, including
+	// the ones adopted at startup. The others are refreshed in place.
+	c.deleteStaleMappings(l, name, desired)
+	delete(c.adopted, name)
+
+	// Create new port mappings. A mapping created on some of the gateways
+	// only is kept, the resync adds it to the others.
+	var newMappings []*upnp.PortMapping
+	for _, mapping := range desired {
+		created := false
+		for _, key := range gwKeys {
+			if err := c.gateways[key].AddPortMapping(mapping); err != nil {
+				level.Error(l).Log("msg", "failed to add UPnP port mapping", "service", name, "external_port", mapping.ExternalPort, "internal_ip", mapping.InternalIP, "gateway", key, "error", err)
+				client.Errorf(svc, "UPnPMappingFailed", "Failed to create UPnP port mapping for port %d on %s: %v", mapping.ExternalPort, key, err)
 				continue
 			}
-
+			created = true
+			level.Info(l).Log("msg", "created UPnP port mapping", "service", name, "external_port", mapping.ExternalPort, "internal_ip", mapping.InternalIP, "protocol", mapping.Protocol, "gateway", key)
+		}
+		if created {
 			newMappings = append(newMappings, mapping)
-			level.Info(l).Log("msg", "created UPnP port mapping", "service", name, "external_port", mapping.ExternalPort, "internal_ip", mapping.InternalIP, "protocol", mapping.Protocol)
 		}
 	}
 
+	// The service just failed over to this node: remove what the previous
+	// owner left on the gateways.
+	if !assigned {
+		c.deletePreviousOwnerMappings(l, name)
+	}
+
 	// Store the new mappings
 	c.mappings[name] = newMappings
+	upnp.ServiceMappings(name, len(newMappings))
+	c.svcs[name] = svc
+	c.events = client
 
+	var externalIP net.IP
 	if len(newMappings) > 0 {
-		client.Infof(svc, "UPnPMappingCreated", "Created %d UPnP port mapping(s) with external IP %s", len(newMappings), c.client.GetExternalIP())
-		c.onStatusChange(types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace})
+		externalIP = c.gateways[gwKeys[0]].GetExternalIP()
+		client.Infof(svc, "UPnPMappingCreated", "Created %d %s port mapping(s) with external IP %s: %s", len(newMappings), adv.Protocol, c.describeExternalIPs(gwKeys), describeExternalPorts(newMappings))
 	}
+	c.publishExternalIP(l, client, name, externalIP)
+	c.onStatusChange(types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace})
 
 	return nil
 }
@@ -187,6 +379,9 @@ func (c *upnpController) DeleteBalancer(l log.Logger, name, reason string) error
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if c.events != nil {
+		c.publishExternalIP(l, c.events, name, nil)
+	}
 	if err := c.deleteExistingMappings(l, name); err != nil {
 		level.Error(l).Log("msg", "failed to delete UPnP mappings", "service", name, "reason", reason, "error", err)
 		return err
@@ -212,14 +407,45 @@ func (c *upnpController) SetEventCallback(callback func(interface{})) {
 	// UPnP controller doesn't use event callbacks currently
 }
 
+// GetStatus returns the port mappings currently programmed on the gateway
+// for the given service.
+func (c *upnpController) GetStatus(nn types.NamespacedName) upnp.ServiceStatus {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	mappings := c.mappings[nn.String()]
+	if len(mappings) == 0 {
+		return upnp.ServiceStatus{}
+	}
+
+	res := upnp.ServiceStatus{
+		Mappings: make([]upnp.PortMapping, 0, len(mappings)),
+	}
+	for i, key := range c.svcGateways[nn.String()] {
+		gw := c.gateways[key]
+		if i == 0 {
+			res.ExternalIP = gw.GetExternalIP()
+		}
+		res.Gateways = append(res.Gateways, upnp.GatewayStatus{Device: gw.Device(), ExternalIP: gw.GetExternalIP()})
+	}
+	for _, m := range mappings {
+		res.Mappings = append(res.Mappings, *m)
+	}
+	return res
+}
+
 // Helper methods
 
 func (c *upnpController) isUPnPEnabled(svc *v1.Service) bool {
+	return isAnnotationTrue(svc, UPnPEnabledAnnotation)
+}
+
+func isAnnotationTrue(svc *v1.Service, annotation string) bool {
 	if svc.Annotations == nil {
 		return false
 	}
 
-	enabled, exists := svc.Annotations[UPnPEnabledAnnotation]
+	enabled, exists := svc.Annotations[annotation]
 	if !exists {
 		return false
 	}
@@ -232,45 +458,149 @@ func (c *upnpController) poolSupportsUPnP(pool *config.Pool) bool {
 }
 
 func (c *upnpController) nodeMatchesUPnPAdvertisements(pool *config.Pool) bool {
+	return c.advertisementFor(pool) != nil
+}
+
+// advertisementFor returns the advertisement of the pool that applies to this
+// node.
+func (c *upnpController) advertisementFor(pool *config.Pool) *config.UPnPAdvertisement {
+	return upnpAdvertisementForNode(pool, c.myNode)
+}
+
+// upnpAdvertisementForNode returns the advertisement of the pool that applies
+// to the node. The advertisements overlapping on a node share the same
+// settings, so the first one is as good as any.
+func upnpAdvertisementForNode(pool *config.Pool, node string) *config.UPnPAdvertisement {
 	for _, adv := range pool.UPnPAdvertisements {
 		if !adv.Enabled {
 			continue
 		}
-		if adv.Nodes[c.myNode] {
-			return true
+		if adv.Nodes[node] {
+			return adv
 		}
 	}
-	return false
+	return nil
 }
 
-func (c *upnpController) getEligibleNodes(pool *config.Pool, nodes map[string]*v1.Node) []string {
-	var eligible []string
+func gatewayConfigFor(adv *config.UPnPAdvertisement) upnp.GatewayConfig {
+	return upnp.GatewayConfig{
+		Protocol: adv.Protocol,
+		Address:  adv.GatewayAddress,
+		Selector: upnp.DeviceSelector{
+			Location:     adv.GatewaySelector.Location,
+			UDN:          adv.GatewaySelector.UDN,
+			FriendlyName: adv.GatewaySelector.FriendlyName,
+			ExternalIP:   adv.GatewaySelector.ExternalIP,
+		},
+		All: adv.AllGateways,
+	}
+}
 
-	for _, adv := range pool.UPnPAdvertisements {
-		if !adv.Enabled {
-			continue
-		}
-		for nodeName := range adv.Nodes {
-			if _, exists := nodes[nodeName]; exists {
-				// Check if node is ready and not excluded from load balancers
-				node := nodes[nodeName]
-				if isNodeReady(node) && !isNodeExcludedFromBalancers(node) {
-					eligible = append(eligible, nodeName)
-				}
+// gatewaysFor returns the keys of the gateways the config selects, looking
+// them up on first use. The mappings this node left on a gateway before a
+// restart are adopted when it is first reached.
+func (c *upnpController) gatewaysFor(l log.Logger, cfg upnp.GatewayConfig) ([]string, error) {
+	if keys, ok := c.resolved[cfg]; ok {
+		return keys, nil
+	}
+
+	discovered, err := c.discoverGateways(cfg, c.gateways)
+	if err != nil {
+		return nil, err
+	}
+	return c.registerGateways(l, cfg, discovered), nil
+}
+
+// discoveredGateway is a gateway found when looking up a config, with the
+// mappings this node left on it by service when it was not known before.
+type discoveredGateway struct {
+	gw    upnp.Gateway
+	owned map[string][]*upnp.PortMapping
+}
+
+// discoverGateways looks up the gateways the config selects, and lists the
+// mappings this node owns on the ones that are not among the known gateways.
+// It only talks to the network, and does not need the lock.
+func (c *upnpController) discoverGateways(cfg upnp.GatewayConfig, known map[string]upnp.Gateway) ([]discoveredGateway, error) {
+	gws, err := c.newGateways(c.logger, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s gateway client: %w", cfg.Protocol, err)
+	}
+	res := make([]discoveredGateway, 0, len(gws))
+	for _, gw := range gws {
+		d := discoveredGateway{gw: gw}
+		if _, ok := known[gw.Device().Key()]; !ok {
+			d.owned, err = upnp.OwnedPortMappings(gw, c.myNode)
+			if err != nil {
+				level.Warn(c.logger).Log("op", "adopt", "protocol", "upnp", "gateway", gw.Device().Key(), "error", err, "msg", "failed to list existing UPnP port mappings")
 			}
 		}
+		res = append(res, d)
 	}
+	return res, nil
+}
 
-	// Sort for consistent ordering
-	for i := 0; i < len(eligible)-1; i++ {
-		for j := i + 1; j < len(eligible); j++ {
-			if eligible[i] > eligible[j] {
-				eligible[i], eligible[j] = eligible[j], eligible[i]
-			}
+// registerGateways records the gateways found for the config, and adopts
+// the mappings found on the ones reached for the first time.
+func (c *upnpController) registerGateways(l log.Logger, cfg upnp.GatewayConfig, discovered []discoveredGateway) []string {
+	keys := make([]string, 0, len(discovered))
+	for _, d := range discovered {
+		key := d.gw.Device().Key()
+		keys = append(keys, key)
+		if _, ok := c.gateways[key]; ok {
+			continue
 		}
+		c.gateways[key] = d.gw
+		level.Info(l).Log("op", "gateway", "protocol", "upnp", "gateway", key, "location", d.gw.Device().Location, "name", d.gw.Device().FriendlyName, "external_ip", d.gw.GetExternalIP(), "msg", "gateway client initialized")
+		c.adoptMappings(key, d.owned)
 	}
+	c.resolved[cfg] = keys
+	return keys
+}
+
+// speakersForPool returns the nodes whose speaker can program the gateways
+// for the pool: the ready nodes selected by an advertisement of the pool,
+// whose speaker is alive and reached the gateways of the advertisement. When
+// memberlist is disabled, the speakers are assumed to run on all the nodes
+// and to reach the gateways, as they are without a speaker list.
+func (c *upnpController) speakersForPool(l log.Logger, name string, pool *config.Pool, nodes map[string]*v1.Node) map[string]bool {
+	sl := speakerlist.SpeakerListInfo{Disabled: true}
+	if c.sList != nil {
+		sl = c.sList.UsableSpeakers()
+	}
+	eligibleNodes := maps.Keys(sl.Nodes)
+	if sl.Disabled {
+		eligibleNodes = maps.Keys(nodes)
+	}
+	res := map[string]bool{}
+	for s := range eligibleNodes {
+		node, ok := nodes[s]
+		if !ok || !isNodeReady(node) {
+			continue
+		}
+
+		if k8snodes.IsNetworkUnavailable(node) {
+			level.Debug(l).Log("event", "shouldannounce", "protocol", "upnp", "service", name, "node", s, "message", "speaker's node has NodeNetworkUnavailable condition")
+			continue
+		}
+
+		if !c.ignoreExcludeLB && k8snodes.IsNodeExcludedFromBalancers(node) {
+			level.Debug(l).Log("event", "shouldannounce", "protocol", "upnp", "service", name, "node", s, "message", "speaker's node has labeled 'node.kubernetes.io/exclude-from-external-load-balancers'")
+			continue
+		}
+
+		adv := upnpAdvertisementForNode(pool, s)
+		if adv == nil {
+			continue
+		}
 
-	return eligible
+		if !sl.Disabled && !slices.Contains(sl.Tags[s], gatewayTag(gatewayConfigFor(adv))) {
+			level.Debug(l).Log("event", "shouldannounce", "protocol", "upnp", "service", name, "node", s, "message", "speaker did not reach the gateway")
+			continue
+		}
+		res[s] = true
+	}
+	return res
 }
 
 func (c *upnpController) deleteExistingMappings(l log.Logger, name string) error {
@@ -279,23 +609,362 @@ func (c *upnpController) deleteExistingMappings(l log.Logger, name string) error
 		return nil
 	}
 
-	for _, mapping := range mappings {
-		if err := c.client.DeletePortMapping(mapping.ExternalPort, mapping.Protocol); err != nil {
-			level.Warn(l).Log("msg", "failed to delete UPnP port mapping", "service", name, "external_port", mapping.ExternalPort, "protocol", mapping.Protocol, "error", err)
-		}
-	}
+	c.deleteMappings(l, name, mappings)
 
 	delete(c.mappings, name)
+	delete(c.svcGateways, name)
+	delete(c.svcs, name)
+	delete(c.adopted, name)
+	upnp.ServiceMappings(name, 0)
 	return nil
 }
 
+// deleteStaleMappings deletes the mappings of the service that are not part
+// of the desired ones.
+func (c *upnpController) deleteStaleMappings(l log.Logger, name string, desired []*upnp.PortMapping) {
+	var stale []*upnp.PortMapping
+OUTER:
+	for _, m := range c.mappings[name] {
+		for _, d := range desired {
+			if m.ExternalPort == d.ExternalPort && strings.EqualFold(m.Protocol, d.Protocol) {
+				continue OUTER
+			}
+		}
+		stale = append(stale, m)
+	}
+	c.deleteMappings(l, name, stale)
+}
+
+// deleteMappings removes the given mappings of the service from its gateways.
+// The mappings whose external port was taken over by another node or device
+// in the meantime are left alone.
+func (c *upnpController) deleteMappings(l log.Logger, name string, mappings []*upnp.PortMapping) {
+	if len(mappings) == 0 {
+		return
+	}
+
+	for _, key := range c.svcGateways[name] {
+		gw := c.gateways[key]
+		current, err := gw.GetPortMappings()
+		if err != nil {
+			level.Warn(l).Log("msg", "failed to list UPnP port mappings, deleting without checking ownership", "service", name, "gateway", key, "error", err)
+		}
+
+		for _, mapping := range mappings {
+			if current != nil && !c.ownsMapping(current, name, mapping) {
+				level.Debug(l).Log("msg", "UPnP port mapping is not owned by this node anymore, skipping deletion", "service", name, "gateway", key, "external_port", mapping.ExternalPort, "protocol", mapping.Protocol)
+				continue
+			}
+			if err := gw.DeletePortMapping(mapping.ExternalPort, mapping.Protocol); err != nil {
+				level.Warn(l).Log("msg", "failed to delete UPnP port mapping", "service", name, "gateway", key, "external_port", mapping.ExternalPort, "protocol", mapping.Protocol, "error", err)
+			}
+		}
+	}
+}
+
+// ownsMapping tells if the entry programmed on the gateway for the external
+// port and protocol of the mapping was created by this node for the service.
+// A mapping that is not programmed anymore is considered owned, deleting it
+// is harmless.
+func (c *upnpController) ownsMapping(current []*upnp.PortMapping, name string, mapping *upnp.PortMapping) bool {
+	for _, m := range current {
+		if m.ExternalPort != mapping.ExternalPort || !strings.EqualFold(m.Protocol, mapping.Protocol) {
+			continue
+		}
+		return m.IsOwnedBy(c.myNode, name)
+	}
+	return true
+}
+
+// deletePreviousOwnerMappings deletes the mappings another node created for
+// the service on its gateways, which owned the service before it failed over
+// to this node. The ones on the external ports this node programmed were
+// replaced already.
+func (c *upnpController) deletePreviousOwnerMappings(l log.Logger, name string) {
+	for _, key := range c.svcGateways[name] {
+		gw := c.gateways[key]
+		current, err := gw.GetPortMappings()
+		if err != nil {
+			level.Warn(l).Log("msg", "failed to list UPnP port mappings, the mappings of the previous owner are left behind", "service", name, "gateway", key, "error", err)
+			continue
+		}
+		for _, m := range current {
+			node, owner, ok := upnp.ParseOwner(m.Description)
+			if !ok || owner != name || node == c.myNode {
+				continue
+			}
+			if err := gw.DeletePortMapping(m.ExternalPort, m.Protocol); err != nil {
+				level.Warn(l).Log("msg", "failed to delete UPnP port mapping of the previous owner", "service", name, "gateway", key, "node", node, "external_port", m.ExternalPort, "protocol", m.Protocol, "error", err)
+				continue
+			}
+			level.Info(l).Log("msg", "deleted UPnP port mapping of the previous owner", "service", name, "gateway", key, "node", node, "external_port", m.ExternalPort, "protocol", m.Protocol)
+		}
+	}
+}
+
+// adoptMappings picks up the mappings that this node created on the gateway
+// before a restart, so that they can be refreshed when their service is
+// assigned again, or deleted once the grace period expires.
+func (c *upnpController) adoptMappings(key string, owned map[string][]*upnp.PortMapping) {
+	deadline := time.Now().Add(upnpAdoptionGracePeriod)
+	for name, mappings := range owned {
+		if _, ok := c.adopted[name]; ok {
+			// Found on another gateway too, when programming all of them.
+			c.svcGateways[name] = append(c.svcGateways[name], key)
+			continue
+		}
+		if _, ok := c.mappings[name]; ok {
+			continue
+		}
+		c.mappings[name] = mappings
+		c.svcGateways[name] = []string{key}
+		c.adopted[name] = deadline
+		upnp.ServiceMappings(name, len(mappings))
+		level.Info(c.logger).Log("op", "adopt", "protocol", "upnp", "gateway", key, "service", name, "mappings", len(mappings), "msg", "adopted existing UPnP port mappings")
+	}
+}
+
+// collectOrphans deletes the adopted mappings whose service was not assigned
+// to this node within the grace period.
+func (c *upnpController) collectOrphans() {
+	now := time.Now()
+	for name, deadline := range c.adopted {
+		if now.Before(deadline) {
+			continue
+		}
+		level.Info(c.logger).Log("op", "adopt", "protocol", "upnp", "service", name, "msg", "deleting orphaned UPnP port mappings")
+		if err := c.deleteExistingMappings(c.logger, name); err != nil {
+			level.Warn(c.logger).Log("op", "adopt", "protocol", "upnp", "service", name, "error", err, "msg", "failed to delete orphaned UPnP port mappings")
+		}
+	}
+}
+
+func (c *upnpController) resyncLoop(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			c.resync(interval)
+		}
+	}
+}
+
+// resync adds back the mappings that the gateways lost, for example after a
+// reboot, or whose lease is about to expire, and propagates the changes of
+// the external IPs of the gateways. As in SetConfig, the gateways are talked
+// to without holding the lock, on a snapshot of the state taken under it, so
+// that a slow gateway or discovery does not hold up the services handled
+// meanwhile.
+func (c *upnpController) resync(interval time.Duration) {
+	// The orphans are deleted under the lock, so that a service assigned to
+	// this node again meanwhile does not lose the mappings just created for
+	// it. There are some only after a restart.
+	c.mutex.Lock()
+	c.collectOrphans()
+	c.mutex.Unlock()
+
+	unreachable := c.refreshExternalIPs()
+
+	c.mutex.Lock()
+	// Forget the gateway configs none of whose gateways answered, so that
+	// this node stops taking part in the election of their services until
+	// they are reached again.
+	for cfg, keys := range c.resolved {
+		if !slices.ContainsFunc(keys, func(key string) bool { return !unreachable[key] }) {
+			level.Warn(c.logger).Log("op", "resync", "protocol", "upnp", "gateways", strings.Join(keys, ","), "msg", "gateways not reachable anymore")
+			delete(c.resolved, cfg)
+		}
+	}
+	c.mutex.Unlock()
+
+	c.resolveGateways(c.logger)
+
+	c.mutex.Lock()
+	gateways := map[string]upnp.Gateway{}
+	byGateway := map[string]map[string][]*upnp.PortMapping{}
+	for name, mappings := range c.mappings {
+		for _, key := range c.svcGateways[name] {
+			if byGateway[key] == nil {
+				byGateway[key] = map[string][]*upnp.PortMapping{}
+				gateways[key] = c.gateways[key]
+			}
+			byGateway[key][name] = mappings
+		}
+	}
+	c.mutex.Unlock()
+
+	repairs := map[string]map[string][]upnp.Repair{}
+	for key, desired := range byGateway {
+		r, err := upnp.Reconcile(c.logger, gateways[key], desired, int(2*interval/time.Second))
+		if err != nil {
+			level.Error(c.logger).Log("op", "resync", "protocol", "upnp", "gateway", key, "error", err, "msg", "failed to compare UPnP port mappings with the gateway")
+			continue
+		}
+		repairs[key] = r
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, r := range repairs {
+		c.undoStaleRepairs(key, gateways[key], r)
+		c.reportRepairs(r)
+	}
+}
+
+// undoStaleRepairs deletes the mappings the resync added back to the gateway
+// for the services that stopped wanting them while it was running, because
+// they were deleted or moved to other ports or gateways meanwhile.
+func (c *upnpController) undoStaleRepairs(key string, gw upnp.Gateway, repairs map[string][]upnp.Repair) {
+	for name, rs := range repairs {
+		for _, r := range rs {
+			if r.Err != nil || c.wantsMapping(name, key, r.Mapping) {
+				continue
+			}
+			level.Info(c.logger).Log("op", "resync", "protocol", "upnp", "service", name, "gateway", key, "external_port", r.Mapping.ExternalPort, "protocol", r.Mapping.Protocol, "msg", "deleting UPnP port mapping not wanted anymore")
+			if err := gw.DeletePortMapping(r.Mapping.ExternalPort, r.Mapping.Protocol); err != nil {
+				level.Warn(c.logger).Log("op", "resync", "protocol", "upnp", "service", name, "gateway", key, "external_port", r.Mapping.ExternalPort, "protocol", r.Mapping.Protocol, "error", err, "msg", "failed to delete UPnP port mapping")
+			}
+		}
+	}
+}
+
+// wantsMapping tells if the service has a mapping on the external port and
+// protocol of the given one on the gateway.
+func (c *upnpController) wantsMapping(name, key string, mapping *upnp.PortMapping) bool {
+	if !slices.Contains(c.svcGateways[name], key) {
+		return false
+	}
+	return slices.ContainsFunc(c.mappings[name], func(m *upnp.PortMapping) bool {
+		return m.ExternalPort == mapping.ExternalPort && strings.EqualFold(m.Protocol, mapping.Protocol)
+	})
+}
+
+// externalIPChange is a change of the external IP of a gateway.
+type externalIPChange struct {
+	old, new net.IP
+}
+
+// refreshExternalIPs reads the external IP of the gateways again, without
+// holding the lock. When it changed, the status of the services forwarded by
+// the gateway is updated and the new address is published on the services
+// that asked for it. The keys of the gateways that did not answer are
+// returned.
+func (c *upnpController) refreshExternalIPs() map[string]bool {
+	c.mutex.RLock()
+	gateways := maps.Clone(c.gateways)
+	c.mutex.RUnlock()
+
+	unreachable := map[string]bool{}
+	changes := map[string]externalIPChange{}
+	for key, gw := range gateways {
+		old := gw.GetExternalIP()
+		if err := gw.RefreshExternalIP(); err != nil {
+			level.Warn(c.logger).Log("op", "resync", "protocol", "upnp", "gateway", key, "error", err, "msg", "failed to refresh the external IP of the gateway")
+			unreachable[key] = true
+			continue
+		}
+		externalIP := gw.GetExternalIP()
+		if externalIP.Equal(old) {
+			continue
+		}
+		level.Info(c.logger).Log("op", "resync", "protocol", "upnp", "gateway", key, "old", old, "new", externalIP, "msg", "external IP of the gateway changed")
+		changes[key] = externalIPChange{old: old, new: externalIP}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, change := range changes {
+		for name, gwKeys := range c.svcGateways {
+			svc := c.svcs[name]
+			if !slices.Contains(gwKeys, key) || svc == nil || len(c.mappings[name]) == 0 {
+				continue
+			}
+			if c.events != nil {
+				c.events.Infof(svc, "UPnPExternalIPChanged", "External IP of gateway %s changed from %s to %s", key, change.old, change.new)
+				// The first gateway of the service is the one published.
+				if gwKeys[0] == key {
+					c.publishExternalIP(c.logger, c.events, name, change.new)
+				}
+			}
+			c.onStatusChange(types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace})
+		}
+	}
+	return unreachable
+}
+
+// publishExternalIP sets the external IP annotation of the service to the
+// given address when the service opted in, and removes it otherwise or when
+// externalIP is nil.
+func (c *upnpController) publishExternalIP(l log.Logger, client service, name string, externalIP net.IP) {
+	svc := c.svcs[name]
+	if svc == nil {
+		return
+	}
+	value := ""
+	if externalIP != nil && isAnnotationTrue(svc, UPnPPublishExternalIPAnnotation) {
+		value = externalIP.String()
+	}
+	if svc.Annotations[UPnPExternalIPAnnotation] == value {
+		return
+	}
+
+	if err := client.AnnotateService(svc, UPnPExternalIPAnnotation, value); err != nil {
+		if apierrors.IsNotFound(err) {
+			return
+		}
+		level.Error(l).Log("msg", "failed to publish the external IP on the service", "service", name, "external_ip", value, "error", err)
+		client.Errorf(svc, "UPnPPublishExternalIPFailed", "Failed to publish external IP %q on the service: %v", value, err)
+		return
+	}
+	level.Info(l).Log("msg", "published the external IP on the service", "service", name, "external_ip", value)
+
+	// Keep the cached service in sync, so that the annotation is not written
+	// again before the update comes back from the informer.
+	updated := svc.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	if value == "" {
+		delete(updated.Annotations, UPnPExternalIPAnnotation)
+	} else {
+		updated.Annotations[UPnPExternalIPAnnotation] = value
+	}
+	c.svcs[name] = updated
+}
+
+func (c *upnpController) reportRepairs(repairs map[string][]upnp.Repair) {
+	for name, rs := range repairs {
+		svc := c.svcs[name]
+		repaired := 0
+		for _, r := range rs {
+			if r.Err != nil {
+				level.Error(c.logger).Log("op", "resync", "protocol", "upnp", "service", name, "external_port", r.Mapping.ExternalPort, "protocol", r.Mapping.Protocol, "reason", r.Reason, "error", r.Err, "msg", "failed to repair UPnP port mapping")
+				if svc != nil && c.events != nil {
+					c.events.Errorf(svc, "UPnPMappingRepairFailed", "Failed to re-create %s UPnP port mapping for port %d: %v", r.Reason, r.Mapping.ExternalPort, r.Err)
+				}
+				continue
+			}
+			repaired++
+		}
+		if repaired > 0 && svc != nil && c.events != nil {
+			c.events.Infof(svc, "UPnPMappingRepaired", "Re-created %d UPnP port mapping(s) that were missing, changed or about to expire on the gateway", repaired)
+		}
+	}
+}
+
+// getPortMappingDescription returns the description of the mapping, tagged
+// with the ownership marker of this node and of the service.
 func (c *upnpController) getPortMappingDescription(svc *v1.Service, port v1.ServicePort) string {
+	desc := DefaultUPnPDescription
 	if svc.Annotations != nil {
-		if desc, exists := svc.Annotations[UPnPDescriptionAnnotation]; exists && desc != "" {
-			return fmt.Sprintf("%s (%s:%d)", desc, svc.Name, port.Port)
+		if d, exists := svc.Annotations[UPnPDescriptionAnnotation]; exists && d != "" {
+			desc = d
 		}
 	}
-	return fmt.Sprintf("%s (%s:%d)", DefaultUPnPDescription, svc.Name, port.Port)
+	return upnp.OwnedDescription(fmt.Sprintf("%s (%s:%d)", desc, svc.Name, port.Port), c.myNode, svc.Namespace+"/"+svc.Name)
 }
 
 func (c *upnpController) getPortMappingDuration(svc *v1.Service) int {
@@ -309,22 +978,238 @@ func (c *upnpController) getPortMappingDuration(svc *v1.Service) int {
 	return DefaultUPnPDuration
 }
 
-// Utility functions for node status checking
+// upnpPortKey identifies an external port on a gateway.
+type upnpPortKey struct {
+	port     int
+	protocol string
+}
 
-func isNodeReady(node *v1.Node) bool {
-	for _, condition := range node.Status.Conditions {
-		if condition.Type == v1.NodeReady {
-			return condition.Status == v1.ConditionTrue
+func portKeyFor(m *upnp.PortMapping) upnpPortKey {
+	return upnpPortKey{port: m.ExternalPort, protocol: strings.ToUpper(m.Protocol)}
+}
+
+// externalPortPolicy describes how the service ports are published on the gateway.
+type externalPortPolicy struct {
+	// fixed maps a service port to the external port it must be published on.
+	fixed map[int]int
+	// first and last delimit the range the external ports of the service ports
+	// not listed in fixed are allocated from. They are zero when no range is set,
+	// in which case the service port is used.
+	first, last int
+}
+
+func parseExternalPortPolicy(svc *v1.Service) (externalPortPolicy, error) {
+	res := externalPortPolicy{fixed: map[int]int{}}
+
+	if s := svc.Annotations[UPnPExternalPortsAnnotation]; s != "" {
+		for _, entry := range strings.Split(s, ",") {
+			from, to, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if !ok {
+				return externalPortPolicy{}, fmt.Errorf("invalid external port mapping %q, expected <port>=<external port>", entry)
+			}
+			port, err := parsePort(from)
+			if err != nil {
+				return externalPortPolicy{}, err
+			}
+			external, err := parsePort(to)
+			if err != nil {
+				return externalPortPolicy{}, err
+			}
+			if _, ok := res.fixed[port]; ok {
+				return externalPortPolicy{}, fmt.Errorf("duplicate external port mapping for port %d", port)
+			}
+			res.fixed[port] = external
 		}
 	}
-	return false
+
+	if s := svc.Annotations[UPnPExternalPortRangeAnnotation]; s != "" {
+		from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+		if !ok {
+			return externalPortPolicy{}, fmt.Errorf("invalid external port range %q, expected <first>-<last>", s)
+		}
+		first, err := parsePort(from)
+		if err != nil {
+			return externalPortPolicy{}, err
+		}
+		last, err := parsePort(to)
+		if err != nil {
+			return externalPortPolicy{}, err
+		}
+		if first > last {
+			return externalPortPolicy{}, fmt.Errorf("invalid external port range %q, first port is greater than the last one", s)
+		}
+		res.first, res.last = first, last
+	}
+
+	return res, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
 }
 
-func isNodeExcludedFromBalancers(node *v1.Node) bool {
-	if node.Labels != nil {
-		if _, exists := node.Labels["node.kubernetes.io/exclude-from-external-load-balancers"]; exists {
+// usedExternalPorts returns the external ports that can't be given to the
+// service on its gateways: the ones programmed by other devices or for other
+// services, and the ones this node assigned to other services.
+func (c *upnpController) usedExternalPorts(l log.Logger, name string) map[upnpPortKey]bool {
+	used := map[upnpPortKey]bool{}
+	for svc, mappings := range c.mappings {
+		if svc == name || !sharesGateway(c.svcGateways[svc], c.svcGateways[name]) {
+			continue
+		}
+		for _, m := range mappings {
+			used[portKeyFor(m)] = true
+		}
+	}
+
+	for _, key := range c.svcGateways[name] {
+		current, err := c.gateways[key].GetPortMappings()
+		if err != nil {
+			level.Warn(l).Log("msg", "failed to list UPnP port mappings, conflicts with other devices won't be detected", "service", name, "gateway", key, "error", err)
+			continue
+		}
+		for _, m := range current {
+			// The mappings of the service are reused, including the ones
+			// left by the node that owned it before.
+			if _, owner, ok := upnp.ParseOwner(m.Description); ok && owner == name {
+				continue
+			}
+			used[portKeyFor(m)] = true
+		}
+	}
+	return used
+}
+
+func sharesGateway(a, b []string) bool {
+	for _, key := range a {
+		if slices.Contains(b, key) {
 			return true
 		}
 	}
 	return false
 }
+
+// assignExternalPorts sets the external port of the desired mappings, whose
+// external port is initially the service port, according to the policy.
+// An external port already used by a previous mapping of the service for the
+// same service port is kept if still allowed, so that the service does not
+// move around the range on every update. The mappings that can't get an
+// external port not in used are returned separately.
+// An external port of the gateway forwards to a single address, so the
+// service port or the fixed external port goes to the first IPv4 address of
+// the service, or its first address if it has none. The mappings of its other
+// addresses get their own ports from the range, and are skipped when the
+// policy has no range.
+func assignExternalPorts(desired, previous []*upnp.PortMapping, policy externalPortPolicy, used map[upnpPortKey]bool) (assigned, conflicts, skipped []*upnp.PortMapping) {
+	taken := make(map[upnpPortKey]bool, len(used))
+	for k := range used {
+		taken[k] = true
+	}
+	inRange := func(port int) bool {
+		return port >= policy.first && port <= policy.last
+	}
+	isFree := func(port int, protocol string) bool {
+		return !taken[upnpPortKey{port: port, protocol: strings.ToUpper(protocol)}]
+	}
+
+	var primary net.IP
+	for _, m := range desired {
+		if primary == nil || (primary.To4() == nil && m.InternalIP.To4() != nil) {
+			primary = m.InternalIP
+		}
+	}
+
+	var toAllocate []*upnp.PortMapping
+	for _, m := range desired {
+		if !m.InternalIP.Equal(primary) {
+			if policy.first == 0 {
+				skipped = append(skipped, m)
+				continue
+			}
+			toAllocate = append(toAllocate, m)
+			continue
+		}
+		if external, ok := policy.fixed[m.InternalPort]; ok {
+			m.ExternalPort = external
+		} else if policy.first != 0 {
+			toAllocate = append(toAllocate, m)
+			continue
+		}
+		if !isFree(m.ExternalPort, m.Protocol) {
+			conflicts = append(conflicts, m)
+			continue
+		}
+		taken[portKeyFor(m)] = true
+		assigned = append(assigned, m)
+	}
+
+	// Allocate from the range after the fixed ports are assigned, first trying
+	// to keep the ports the service already had.
+	var unassigned []*upnp.PortMapping
+	for _, m := range toAllocate {
+		kept := false
+		for _, p := range previous {
+			if p.InternalPort != m.InternalPort || !strings.EqualFold(p.Protocol, m.Protocol) || !p.InternalIP.Equal(m.InternalIP) {
+				continue
+			}
+			if inRange(p.ExternalPort) && isFree(p.ExternalPort, m.Protocol) {
+				m.ExternalPort = p.ExternalPort
+				taken[portKeyFor(m)] = true
+				assigned = append(assigned, m)
+				kept = true
+			}
+			break
+		}
+		if !kept {
+			unassigned = append(unassigned, m)
+		}
+	}
+	for _, m := range unassigned {
+		found := false
+		for port := policy.first; port <= policy.last; port++ {
+			if isFree(port, m.Protocol) {
+				m.ExternalPort = port
+				taken[portKeyFor(m)] = true
+				assigned = append(assigned, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			conflicts = append(conflicts, m)
+		}
+	}
+
+	return assigned, conflicts, skipped
+}
+
+func (c *upnpController) describeExternalIPs(gwKeys []string) string {
+	ips := make([]string, 0, len(gwKeys))
+	for _, key := range gwKeys {
+		ips = append(ips, c.gateways[key].GetExternalIP().String())
+	}
+	return strings.Join(ips, ", ")
+}
+
+func describeExternalPorts(mappings []*upnp.PortMapping) string {
+	ports := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		ports = append(ports, fmt.Sprintf("%d/%s->%d", m.ExternalPort, strings.ToUpper(m.Protocol), m.InternalPort))
+	}
+	return strings.Join(ports, ", ")
+}
+
+// Utility functions for node status checking
+
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,714 @@
+// SPDX-License-Identifier:Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	"go.universe.tf/metallb/internal/config"
+	"go.universe.tf/metallb/internal/upnp"
+)
+
+func TestParseExternalPortPolicy(t *testing.T) {
+	tests := []struct {
+		desc        string
+		annotations map[string]string
+		expected    externalPortPolicy
+		mustFail    bool
+	}{
+		{
+			desc:     "no annotations",
+			expected: externalPortPolicy{fixed: map[int]int{}},
+		},
+		{
+			desc: "fixed ports and range",
+			annotations: map[string]string{
+				UPnPExternalPortsAnnotation:     "443=8443, 80=8080",
+				UPnPExternalPortRangeAnnotation: "30000-30099",
+			},
+			expected: externalPortPolicy{fixed: map[int]int{443: 8443, 80: 8080}, first: 30000, last: 30099},
+		},
+		{
+			desc:        "invalid mapping",
+			annotations: map[string]string{UPnPExternalPortsAnnotation: "443:8443"},
+			mustFail:    true,
+		},
+		{
+			desc:        "duplicate mapping",
+			annotations: map[string]string{UPnPExternalPortsAnnotation: "443=8443,443=9443"},
+			mustFail:    true,
+		},
+		{
+			desc:        "port out of range",
+			annotations: map[string]string{UPnPExternalPortsAnnotation: "443=70000"},
+			mustFail:    true,
+		},
+		{
+			desc:        "reversed range",
+			annotations: map[string]string{UPnPExternalPortRangeAnnotation: "30099-30000"},
+			mustFail:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations}}
+			policy, err := parseExternalPortPolicy(svc)
+			if test.mustFail {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.expected, policy, cmp.AllowUnexported(externalPortPolicy{})); diff != "" {
+				t.Fatalf("unexpected policy (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAssignExternalPorts(t *testing.T) {
+	ip := net.ParseIP("192.168.1.100")
+	ipv6 := net.ParseIP("2001:db8::100")
+	mapping := func(port int, protocol string) *upnp.PortMapping {
+		return &upnp.PortMapping{ExternalPort: port, InternalPort: port, InternalIP: ip, Protocol: protocol}
+	}
+	mappingFor := func(internalIP net.IP, port int) *upnp.PortMapping {
+		return &upnp.PortMapping{ExternalPort: port, InternalPort: port, InternalIP: internalIP, Protocol: "tcp"}
+	}
+
+	tests := []struct {
+		desc              string
+		desired           []*upnp.PortMapping
+		previous          []*upnp.PortMapping
+		policy            externalPortPolicy
+		used              map[upnpPortKey]bool
+		expectedPorts     map[int]int
+		expectedConflicts int
+		expectedSkipped   int
+	}{
+		{
+			desc:          "same port by default",
+			desired:       []*upnp.PortMapping{mapping(443, "tcp")},
+			expectedPorts: map[int]int{443: 443},
+		},
+		{
+			desc:              "same port already used",
+			desired:           []*upnp.PortMapping{mapping(443, "tcp")},
+			used:              map[upnpPortKey]bool{{443, "TCP"}: true},
+			expectedPorts:     map[int]int{},
+			expectedConflicts: 1,
+		},
+		{
+			desc:          "same port used with another protocol",
+			desired:       []*upnp.PortMapping{mapping(443, "tcp")},
+			used:          map[upnpPortKey]bool{{443, "UDP"}: true},
+			expectedPorts: map[int]int{443: 443},
+		},
+		{
+			desc:          "fixed port",
+			desired:       []*upnp.PortMapping{mapping(443, "tcp"), mapping(80, "tcp")},
+			policy:        externalPortPolicy{fixed: map[int]int{443: 8443}},
+			expectedPorts: map[int]int{443: 8443, 80: 80},
+		},
+		{
+			desc:          "allocated from range skipping used ports",
+			desired:       []*upnp.PortMapping{mapping(443, "tcp"), mapping(80, "tcp")},
+			policy:        externalPortPolicy{first: 30000, last: 30010},
+			used:          map[upnpPortKey]bool{{30000, "TCP"}: true},
+			expectedPorts: map[int]int{443: 30001, 80: 30002},
+		},
+		{
+			desc:          "previous port is kept",
+			desired:       []*upnp.PortMapping{mapping(443, "tcp"), mapping(80, "tcp")},
+			previous:      []*upnp.PortMapping{{ExternalPort: 30005, InternalPort: 80, InternalIP: ip, Protocol: "TCP"}},
+			policy:        externalPortPolicy{first: 30000, last: 30010},
+			expectedPorts: map[int]int{443: 30000, 80: 30005},
+		},
+		{
+			desc:              "range exhausted",
+			desired:           []*upnp.PortMapping{mapping(443, "tcp"), mapping(80, "tcp")},
+			policy:            externalPortPolicy{first: 30000, last: 30000},
+			expectedPorts:     map[int]int{443: 30000},
+			expectedConflicts: 1,
+		},
+		{
+			desc:          "fixed ports take precedence over the range",
+			desired:       []*upnp.PortMapping{mapping(443, "tcp"), mapping(80, "tcp")},
+			policy:        externalPortPolicy{fixed: map[int]int{443: 30000}, first: 30000, last: 30010},
+			expectedPorts: map[int]int{443: 30000, 80: 30001},
+		},
+		{
+			desc:            "dual stack, the service port goes to the IPv4 address",
+			desired:         []*upnp.PortMapping{mappingFor(ipv6, 443), mappingFor(ip, 443)},
+			expectedPorts:   map[int]int{443: 443},
+			expectedSkipped: 1,
+		},
+		{
+			desc:          "dual stack, each address gets its own port from the range",
+			desired:       []*upnp.PortMapping{mappingFor(ip, 443), mappingFor(ipv6, 443)},
+			policy:        externalPortPolicy{fixed: map[int]int{443: 8443}, first: 30000, last: 30010},
+			expectedPorts: map[int]int{443: 8443, 10443: 30000},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assigned, conflicts, skipped := assignExternalPorts(test.desired, test.previous, test.policy, test.used)
+			ports := map[int]int{}
+			for _, m := range assigned {
+				// The ports of the IPv6 address are told apart by a prefix.
+				if m.InternalIP.To4() == nil {
+					ports[10000+m.InternalPort] = m.ExternalPort
+					continue
+				}
+				ports[m.InternalPort] = m.ExternalPort
+			}
+			if diff := cmp.Diff(test.expectedPorts, ports); diff != "" {
+				t.Errorf("unexpected external ports (-want +got)\n%s", diff)
+			}
+			if len(conflicts) != test.expectedConflicts {
+				t.Errorf("expected %d conflicts, got %d", test.expectedConflicts, len(conflicts))
+			}
+			if len(skipped) != test.expectedSkipped {
+				t.Errorf("expected %d skipped, got %d", test.expectedSkipped, len(skipped))
+			}
+		})
+	}
+}
+
+// fakeUPnPGateway is a gateway whose external IP changes to next when it is
+// refreshed.
+type fakeUPnPGateway struct {
+	device      upnp.Device
+	externalIP  net.IP
+	next        net.IP
+	unreachable bool
+	mappings    map[upnpPortKey]*upnp.PortMapping
+	// When set, RefreshExternalIP signals refreshing and waits for release.
+	refreshing chan struct{}
+	release    chan struct{}
+}
+
+func (g *fakeUPnPGateway) AddPortMapping(m *upnp.PortMapping) error {
+	if g.mappings == nil {
+		g.mappings = map[upnpPortKey]*upnp.PortMapping{}
+	}
+	g.mappings[portKeyFor(m)] = m
+	return nil
+}
+
+func (g *fakeUPnPGateway) DeletePortMapping(port int, protocol string) error {
+	delete(g.mappings, upnpPortKey{port: port, protocol: strings.ToUpper(protocol)})
+	return nil
+}
+
+func (g *fakeUPnPGateway) GetPortMappings() ([]*upnp.PortMapping, error) {
+	var res []*upnp.PortMapping
+	for _, m := range g.mappings {
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+func (g *fakeUPnPGateway) GetExternalIP() net.IP { return g.externalIP }
+func (g *fakeUPnPGateway) Device() upnp.Device   { return g.device }
+
+func (g *fakeUPnPGateway) RefreshExternalIP() error {
+	if g.refreshing != nil {
+		g.refreshing <- struct{}{}
+		<-g.release
+	}
+	if g.unreachable {
+		return fmt.Errorf("gateway unreachable")
+	}
+	if g.next != nil {
+		g.externalIP = g.next
+	}
+	return nil
+}
+
+// annotatingK8S records the annotations the controller sets on services.
+type annotatingK8S struct {
+	testK8S
+	annotations map[string]string // service name -> external IP annotation
+}
+
+func (s *annotatingK8S) AnnotateService(svc *v1.Service, key, value string) error {
+	if key != UPnPExternalIPAnnotation {
+		s.t.Fatalf("unexpected annotation %s", key)
+	}
+	s.annotations[svc.Name] = value
+	return nil
+}
+
+func TestPublishExternalIP(t *testing.T) {
+	gw := &fakeUPnPGateway{externalIP: net.ParseIP("203.0.113.1")}
+	k := &annotatingK8S{testK8S: testK8S{t: t}, annotations: map[string]string{}}
+	service := func(name string, annotations map[string]string) *v1.Service {
+		return &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations}}
+	}
+	var changed []string
+	c := &upnpController{
+		logger:   log.NewNopLogger(),
+		myNode:   "node1",
+		gateways: map[string]upnp.Gateway{"UPnP": gw},
+		mappings: map[string][]*upnp.PortMapping{
+			"default/published": {{ExternalPort: 80, Protocol: "tcp"}},
+			"default/private":   {{ExternalPort: 81, Protocol: "tcp"}},
+			"default/stale":     {{ExternalPort: 82, Protocol: "tcp"}},
+		},
+		svcGateways: map[string][]string{
+			"default/published": {"UPnP"},
+			"default/private":   {"UPnP"},
+			"default/stale":     {"UPnP"},
+		},
+		svcs: map[string]*v1.Service{
+			"default/published": service("published", map[string]string{UPnPPublishExternalIPAnnotation: "true"}),
+			"default/private":   service("private", nil),
+			// opted out after the address was published
+			"default/stale": service("stale", map[string]string{UPnPExternalIPAnnotation: "192.0.2.1"}),
+		},
+		events: k,
+		onStatusChange: func(nn types.NamespacedName) {
+			changed = append(changed, nn.String())
+		},
+	}
+
+	for name := range c.svcs {
+		c.publishExternalIP(c.logger, k, name, gw.GetExternalIP())
+	}
+	expected := map[string]string{"published": "203.0.113.1", "stale": ""}
+	if diff := cmp.Diff(expected, k.annotations); diff != "" {
+		t.Fatalf("unexpected annotations (-want +got)\n%s", diff)
+	}
+
+	// Nothing is written again while the address does not change.
+	k.annotations = map[string]string{}
+	c.refreshExternalIPs()
+	if len(k.annotations) != 0 || len(changed) != 0 {
+		t.Fatalf("unexpected update with the same external IP: annotations %v, status changes %v", k.annotations, changed)
+	}
+
+	gw.next = net.ParseIP("198.51.100.1")
+	c.refreshExternalIPs()
+	expected = map[string]string{"published": "198.51.100.1"}
+	if diff := cmp.Diff(expected, k.annotations); diff != "" {
+		t.Fatalf("unexpected annotations after the external IP changed (-want +got)\n%s", diff)
+	}
+	sort.Strings(changed)
+	if diff := cmp.Diff([]string{"default/private", "default/published", "default/stale"}, changed); diff != "" {
+		t.Fatalf("unexpected status changes (-want +got)\n%s", diff)
+	}
+
+	// The annotation is removed when the service is not forwarded anymore.
+	k.annotations = map[string]string{}
+	if err := c.DeleteBalancer(c.logger, "default/published", "test"); err != nil {
+		t.Fatalf("failed to delete balancer: %v", err)
+	}
+	expected = map[string]string{"published": ""}
+	if diff := cmp.Diff(expected, k.annotations); diff != "" {
+		t.Fatalf("unexpected annotations after delete (-want +got)\n%s", diff)
+	}
+}
+
+func TestSetBalancerAllGateways(t *testing.T) {
+	router := &fakeUPnPGateway{
+		device:     upnp.Device{Protocol: upnp.ProtocolUPnP, Location: "http://192.168.1.1/rootDesc.xml", UDN: "uuid:1", FriendlyName: "router"},
+		externalIP: net.ParseIP("203.0.113.1"),
+	}
+	mesh := &fakeUPnPGateway{
+		device:     upnp.Device{Protocol: upnp.ProtocolUPnP, Location: "http://192.168.1.2/rootDesc.xml", UDN: "uuid:2", FriendlyName: "mesh"},
+		externalIP: net.ParseIP("203.0.113.2"),
+	}
+	var requested []upnp.GatewayConfig
+	c := newTestUPnPController(func(_ log.Logger, cfg upnp.GatewayConfig) ([]upnp.Gateway, error) {
+		requested = append(requested, cfg)
+		if cfg.All {
+			return []upnp.Gateway{router, mesh}, nil
+		}
+		return []upnp.Gateway{mesh}, nil
+	})
+
+	adv := &config.UPnPAdvertisement{
+		Name:            "adv",
+		Nodes:           map[string]bool{"node1": true},
+		Enabled:         true,
+		Protocol:        upnp.ProtocolUPnP,
+		GatewaySelector: config.UPnPGatewaySelector{FriendlyName: "mesh"},
+	}
+	pool := &config.Pool{Name: "pool", UPnPAdvertisements: []*config.UPnPAdvertisement{adv}}
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "default", Annotations: map[string]string{UPnPEnabledAnnotation: "true"}},
+		Spec:       v1.ServiceSpec{Ports: []v1.ServicePort{{Port: 80, Protocol: v1.ProtocolTCP}}},
+	}
+	k := &testK8S{t: t}
+	lbIPs := []net.IP{net.ParseIP("192.168.1.100")}
+
+	if err := c.SetBalancer(c.logger, "default/svc", lbIPs, pool, k, svc); err != nil {
+		t.Fatalf("SetBalancer failed: %v", err)
+	}
+	if requested[0].Selector.FriendlyName != "mesh" || requested[0].All {
+		t.Fatalf("unexpected gateway config %+v", requested[0])
+	}
+	if len(mesh.mappings) != 1 || len(router.mappings) != 0 {
+		t.Fatalf("expected the mapping on the mesh only, got %d on the mesh and %d on the router", len(mesh.mappings), len(router.mappings))
+	}
+
+	// Programming all the gateways moves the service.
+	adv.GatewaySelector = config.UPnPGatewaySelector{}
+	adv.AllGateways = true
+	if err := c.SetBalancer(c.logger, "default/svc", lbIPs, pool, k, svc); err != nil {
+		t.Fatalf("SetBalancer failed: %v", err)
+	}
+	if len(mesh.mappings) != 1 || len(router.mappings) != 1 {
+		t.Fatalf("expected the mapping on both gateways, got %d on the mesh and %d on the router", len(mesh.mappings), len(router.mappings))
+	}
+
+	status := c.GetStatus(types.NamespacedName{Name: "svc", Namespace: "default"})
+	expected := []upnp.GatewayStatus{
+		{Device: router.device, ExternalIP: router.externalIP},
+		{Device: mesh.device, ExternalIP: mesh.externalIP},
+	}
+	if diff := cmp.Diff(expected, status.Gateways); diff != "" {
+		t.Fatalf("unexpected gateways in status (-want +got)\n%s", diff)
+	}
+	if !status.ExternalIP.Equal(router.externalIP) {
+		t.Fatalf("expected external IP %s, got %s", router.externalIP, status.ExternalIP)
+	}
+
+	if err := c.DeleteBalancer(c.logger, "default/svc", "test"); err != nil {
+		t.Fatalf("DeleteBalancer failed: %v", err)
+	}
+	if len(mesh.mappings) != 0 || len(router.mappings) != 0 {
+		t.Fatalf("expected no mapping left, got %d on the mesh and %d on the router", len(mesh.mappings), len(router.mappings))
+	}
+}
+
+func newTestUPnPController(newGateways func(log.Logger, upnp.GatewayConfig) ([]upnp.Gateway, error)) *upnpController {
+	return &upnpController{
+		logger:         log.NewNopLogger(),
+		myNode:         "node1",
+		gateways:       map[string]upnp.Gateway{},
+		resolved:       map[upnp.GatewayConfig][]string{},
+		newGateways:    newGateways,
+		mappings:       map[string][]*upnp.PortMapping{},
+		svcGateways:    map[string][]string{},
+		svcs:           map[string]*v1.Service{},
+		adopted:        map[string]time.Time{},
+		onStatusChange: func(types.NamespacedName) {},
+	}
+}
+
+func TestUPnPShouldAnnounce(t *testing.T) {
+	adv := &config.UPnPAdvertisement{
+		Name:     "adv",
+		Nodes:    map[string]bool{"node1": true, "node2": true, "node3": true},
+		Enabled:  true,
+		Protocol: upnp.ProtocolUPnP,
+	}
+	pool := &config.Pool{Name: "pool", UPnPAdvertisements: []*config.UPnPAdvertisement{adv}}
+	tag := gatewayTag(gatewayConfigFor(adv))
+	readyNode := func(name string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}},
+		}
+	}
+	nodes := map[string]*v1.Node{
+		"node1": readyNode("node1"),
+		"node2": readyNode("node2"),
+		"node3": readyNode("node3"),
+		"node4": readyNode("node4"),
+	}
+	endpointsOn := func(nodes ...string) []discovery.EndpointSlice {
+		slice := discovery.EndpointSlice{}
+		for _, n := range nodes {
+			slice.Endpoints = append(slice.Endpoints, discovery.Endpoint{
+				Addresses:  []string{"10.0.0.1"},
+				NodeName:   ptr.To(n),
+				Conditions: discovery.EndpointConditions{Ready: ptr.To(true)},
+			})
+		}
+		return []discovery.EndpointSlice{slice}
+	}
+	lbIP := net.ParseIP("192.168.1.100")
+
+	tests := []struct {
+		desc          string
+		noSpeakerList bool
+		speakers      map[string]bool
+		tags          map[string][]string
+		trafficPolicy v1.ServiceExternalTrafficPolicy
+		eps           []discovery.EndpointSlice
+		eligible      []string
+	}{
+		{
+			desc:     "memberlist disabled, all the selected nodes",
+			eps:      endpointsOn("node4"),
+			eligible: []string{"node1", "node2", "node3"},
+		},
+		{
+			desc:          "no speaker list, all the selected nodes",
+			noSpeakerList: true,
+			eps:           endpointsOn("node4"),
+			eligible:      []string{"node1", "node2", "node3"},
+		},
+		{
+			desc:     "speakers that reached the gateway",
+			speakers: map[string]bool{"node1": true, "node2": true, "node3": true, "node4": true},
+			tags:     map[string][]string{"node1": {tag}, "node2": {"upnp-other"}, "node3": {"upnp-other", tag}, "node4": {tag}},
+			eps:      endpointsOn("node4"),
+			eligible: []string{"node1", "node3"},
+		},
+		{
+			desc:     "owner dead",
+			speakers: map[string]bool{"node1": true, "node3": true},
+			tags:     map[string][]string{"node1": {tag}, "node3": {tag}},
+			eps:      endpointsOn("node4"),
+			eligible: []string{"node1", "node3"},
+		},
+		{
+			desc:          "local traffic policy",
+			speakers:      map[string]bool{"node1": true, "node2": true, "node3": true},
+			tags:          map[string][]string{"node1": {tag}, "node2": {tag}, "node3": {tag}},
+			trafficPolicy: v1.ServiceExternalTrafficPolicyTypeLocal,
+			eps:           endpointsOn("node2", "node4"),
+			eligible:      []string{"node2"},
+		},
+		{
+			desc:     "no speaker reached the gateway",
+			speakers: map[string]bool{"node1": true, "node2": true},
+			tags:     map[string][]string{},
+			eps:      endpointsOn("node1"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "default", Annotations: map[string]string{UPnPEnabledAnnotation: "true"}},
+				Spec:       v1.ServiceSpec{ExternalTrafficPolicy: test.trafficPolicy},
+			}
+			expected := ""
+			if len(test.eligible) > 0 {
+				sortNodesByHash(test.eligible, lbIP)
+				expected = test.eligible[0]
+			}
+
+			var owners []string
+			for name := range nodes {
+				c := newTestUPnPController(nil)
+				c.myNode = name
+				if !test.noSpeakerList {
+					c.sList = &fakeSpeakerList{speakers: test.speakers, tags: test.tags}
+				}
+				if c.ShouldAnnounce(c.logger, "default/svc", []net.IP{lbIP}, pool, svc, test.eps, nodes) == "" {
+					owners = append(owners, name)
+				}
+			}
+			if expected == "" {
+				if len(owners) != 0 {
+					t.Fatalf("expected no owner, got %v", owners)
+				}
+				return
+			}
+			if diff := cmp.Diff([]string{expected}, owners); diff != "" {
+				t.Fatalf("unexpected owners (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetBalancerTakesOverPreviousOwner(t *testing.T) {
+	gw := &fakeUPnPGateway{
+		device:     upnp.Device{Protocol: upnp.ProtocolUPnP, Location: "http://192.168.1.1/rootDesc.xml", UDN: "uuid:1"},
+		externalIP: net.ParseIP("203.0.113.1"),
+	}
+	// node2 owned the service, then died.
+	for _, port := range []int{80, 443} {
+		_ = gw.AddPortMapping(&upnp.PortMapping{
+			ExternalPort: port,
+			InternalPort: port,
+			InternalIP:   net.ParseIP("192.168.1.100"),
+			Protocol:     "tcp",
+			Description:  upnp.OwnedDescription("svc", "node2", "default/svc"),
+		})
+	}
+	// Another service forwarded by node2 is left alone.
+	_ = gw.AddPortMapping(&upnp.PortMapping{
+		ExternalPort: 8080,
+		InternalPort: 8080,
+		InternalIP:   net.ParseIP("192.168.1.101"),
+		Protocol:     "tcp",
+		Description:  upnp.OwnedDescription("other", "node2", "default/other"),
+	})
+
+	sl := &fakeSpeakerList{}
+	c := newTestUPnPController(func(log.Logger, upnp.GatewayConfig) ([]upnp.Gateway, error) {
+		return []upnp.Gateway{gw}, nil
+	})
+	c.sList = sl
+	adv := &config.UPnPAdvertisement{
+		Name:     "adv",
+		Nodes:    map[string]bool{"node1": true, "node2": true},
+		Enabled:  true,
+		Protocol: upnp.ProtocolUPnP,
+	}
+	pool := &config.Pool{Name: "pool", UPnPAdvertisements: []*config.UPnPAdvertisement{adv}}
+	cfg := &config.Config{Pools: &config.Pools{ByName: map[string]*config.Pool{"pool": pool}}}
+	if err := c.SetConfig(c.logger, cfg); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+	tag := gatewayTag(gatewayConfigFor(adv))
+	if diff := cmp.Diff([]string{tag}, sl.published); diff != "" {
+		t.Fatalf("unexpected tags (-want +got)\n%s", diff)
+	}
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "default", Annotations: map[string]string{UPnPEnabledAnnotation: "true"}},
+		Spec:       v1.ServiceSpec{Ports: []v1.ServicePort{{Port: 80, Protocol: v1.ProtocolTCP}}},
+	}
+	k := &testK8S{t: t}
+	if err := c.SetBalancer(c.logger, "default/svc", []net.IP{net.ParseIP("192.168.1.100")}, pool, k, svc); err != nil {
+		t.Fatalf("SetBalancer failed: %v", err)
+	}
+	if k.loggedWarning {
+		t.Fatalf("unexpected warning event, the port of the previous owner should be reused")
+	}
+
+	owners := map[int]string{}
+	for key, m := range gw.mappings {
+		node, _, _ := upnp.ParseOwner(m.Description)
+		owners[key.port] = node
+	}
+	if diff := cmp.Diff(map[int]string{80: "node1", 8080: "node2"}, owners); diff != "" {
+		t.Fatalf("unexpected mappings on the gateway (-want +got)\n%s", diff)
+	}
+
+	// The node stops advertising the gateway once it does not answer anymore.
+	gw.unreachable = true
+	c.newGateways = func(log.Logger, upnp.GatewayConfig) ([]upnp.Gateway, error) {
+		return nil, fmt.Errorf("no gateway found")
+	}
+	c.resync(upnpResyncInterval)
+	if len(sl.published) != 0 {
+		t.Fatalf("expected no tag for an unreachable gateway, got %v", sl.published)
+	}
+}
+
+func TestResyncDoesNotHoldTheLock(t *testing.T) {
+	gw := &fakeUPnPGateway{
+		externalIP: net.ParseIP("203.0.113.1"),
+		refreshing: make(chan struct{}),
+		release:    make(chan struct{}),
+	}
+	c := newTestUPnPController(nil)
+	c.gateways["UPnP"] = gw
+	c.mappings["default/svc"] = []*upnp.PortMapping{{ExternalPort: 80, InternalPort: 80, InternalIP: net.ParseIP("192.168.1.100"), Protocol: "tcp"}}
+	c.svcGateways["default/svc"] = []string{"UPnP"}
+
+	done := make(chan struct{})
+	go func() {
+		c.resync(upnpResyncInterval)
+		close(done)
+	}()
+	<-gw.refreshing
+
+	// The controller answers while the gateway is slow to.
+	status := make(chan upnp.ServiceStatus)
+	go func() {
+		status <- c.GetStatus(types.NamespacedName{Namespace: "default", Name: "svc"})
+	}()
+	select {
+	case s := <-status:
+		if len(s.Mappings) != 1 {
+			t.Fatalf("expected 1 mapping, got %v", s.Mappings)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetStatus blocked by the resync talking to the gateway")
+	}
+
+	close(gw.release)
+	<-done
+	if len(gw.mappings) != 1 {
+		t.Fatalf("expected the missing mapping to be added back, got %v", gw.mappings)
+	}
+}
+
+func TestSetConfigDoesNotHoldTheLock(t *testing.T) {
+	gw := &fakeUPnPGateway{externalIP: net.ParseIP("203.0.113.1")}
+	discovering := make(chan struct{})
+	release := make(chan struct{})
+	c := newTestUPnPController(func(log.Logger, upnp.GatewayConfig) ([]upnp.Gateway, error) {
+		close(discovering)
+		<-release
+		return []upnp.Gateway{gw}, nil
+	})
+	adv := &config.UPnPAdvertisement{
+		Name:     "adv",
+		Nodes:    map[string]bool{"node1": true},
+		Enabled:  true,
+		Protocol: upnp.ProtocolUPnP,
+	}
+	pool := &config.Pool{Name: "pool", UPnPAdvertisements: []*config.UPnPAdvertisement{adv}}
+	cfg := &config.Config{Pools: &config.Pools{ByName: map[string]*config.Pool{"pool": pool}}}
+
+	done := make(chan struct{})
+	go func() {
+		if err := c.SetConfig(c.logger, cfg); err != nil {
+			t.Errorf("SetConfig failed: %v", err)
+		}
+		close(done)
+	}()
+	<-discovering
+
+	// The controller answers while the gateways are looked up.
+	status := make(chan upnp.ServiceStatus)
+	go func() {
+		status <- c.GetStatus(types.NamespacedName{Namespace: "default", Name: "svc"})
+	}()
+	select {
+	case <-status:
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetStatus blocked by SetConfig looking the gateways up")
+	}
+
+	close(release)
+	<-done
+	if _, ok := c.resolved[gatewayConfigFor(adv)]; !ok {
+		t.Fatalf("expected the gateways of the advertisement to be resolved, got %v", c.resolved)
+	}
+}
+
+func TestResyncLoopStops(t *testing.T) {
+	c := newTestUPnPController(nil)
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.resyncLoop(time.Hour, stopCh)
+		close(done)
+	}()
+	close(stopCh)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resync loop did not stop")
+	}
+}